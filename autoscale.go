@@ -0,0 +1,196 @@
+package pool
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AutoscaleInput is what an AutoscalePolicy knows about a pool when deciding how many workers it needs. Rates and
+// latencies cover the time since the pool's previous evaluation.
+type AutoscaleInput struct {
+	Key string
+	// Workers is how many workers the pool is running
+	Workers int
+	// MaxWorkers is the pool's size, which it can't be scaled beyond
+	MaxWorkers int
+	// QueueDepth is the number of submitted tasks waiting for a worker
+	QueueDepth int
+	// QueueWait is how long the tasks picked up by a worker waited in the queue
+	QueueWait LatencyPercentiles
+	// CompletionRate is the number of tasks completed per second
+	CompletionRate float64
+	// Interval is how long it has been since the previous evaluation
+	Interval time.Duration
+}
+
+// AutoscalePolicy decides how many workers a pool should run, see WithAutoscaling
+type AutoscalePolicy interface {
+	// DesiredWorkers returns how many workers the pool described by input should run. Values outside of 1 to
+	// input.MaxWorkers are clamped to that range.
+	DesiredWorkers(input AutoscaleInput) int
+}
+
+// AutoscalePolicyFunc adapts an ordinary function to an AutoscalePolicy
+type AutoscalePolicyFunc func(input AutoscaleInput) int
+
+// DesiredWorkers calls f
+func (f AutoscalePolicyFunc) DesiredWorkers(input AutoscaleInput) int {
+	return f(input)
+}
+
+// WithAutoscaling hands control of each pool's worker count to policy, which is evaluated every interval for every pool
+// in use. Autoscaled pools start with a single worker instead of spawning one per unit of GetPool's sendSize, and are
+// scaled between one worker and their pool size. Workers removed by scaling down finish their current task first.
+func WithAutoscaling(interval time.Duration, policy AutoscalePolicy) Option {
+	return func(o *options) {
+		o.autoscaleInterval = interval
+		o.autoscalePolicy = policy
+	}
+}
+
+// TargetLatencyPolicy is an AutoscalePolicy which aims to keep the 95th percentile of queue wait under target. Pools
+// missing the target grow in proportion to how far they're missing it, by up to double at a time, and pools
+// comfortably under it with nothing queued shrink by one worker at a time.
+func TargetLatencyPolicy(target time.Duration) AutoscalePolicy {
+	return AutoscalePolicyFunc(func(input AutoscaleInput) int {
+		wait := input.QueueWait.P95
+		switch {
+		case wait > target:
+			growth := math.Min(2, float64(wait)/float64(target))
+			return int(math.Max(math.Ceil(float64(input.Workers)*growth), float64(input.Workers+1)))
+		case wait < target/2 && input.QueueDepth == 0:
+			return input.Workers - 1
+		default:
+			return input.Workers
+		}
+	})
+}
+
+// autoscaler runs an autoscaled pool's workers, and evaluates its policy
+type autoscaler struct {
+	lock       *sync.Mutex
+	policy     AutoscalePolicy
+	interval   time.Duration
+	maxWorkers int
+	// Closing a worker's channel stops it, and the newest worker is stopped first
+	workers []chan bool
+	timer   Timer
+	stopped bool
+
+	// Stats as of the previous evaluation
+	lastEvaluated time.Time
+	lastCompleted uint64
+	lastQueueWait [histogramBuckets]uint64
+}
+
+// The number of workers the pool is running
+func (p *BaseWorkerPool) spawnedWorkers() int {
+	if p.autoscale == nil {
+		p.sizeLock.Lock()
+		defer p.sizeLock.Unlock()
+		return p.workerCount
+	}
+	p.autoscale.lock.Lock()
+	defer p.autoscale.lock.Unlock()
+	return len(p.autoscale.workers)
+}
+
+// Start an autoscaled pool off with a worker, and begin evaluating its policy
+func (p *BaseWorkerPool) startAutoscaling() {
+	a := p.autoscale
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if len(a.workers) > 0 || a.stopped {
+		return
+	}
+	p.scaleLocked(1)
+	a.lastEvaluated = p.clock.Now()
+	a.timer = p.clock.AfterFunc(a.interval, p.evaluateAutoscaling)
+}
+
+func (p *BaseWorkerPool) evaluateAutoscaling() {
+	a := p.autoscale
+	a.lock.Lock()
+	defer a.lock.Unlock()
+	if a.stopped {
+		return
+	}
+
+	now := p.clock.Now()
+	completed := atomic.LoadUint64(&p.stats.completed)
+	queueWait := p.stats.queueWaitLatency.counts()
+	interval := now.Sub(a.lastEvaluated)
+	input := AutoscaleInput{
+		Key:        p.key,
+		Workers:    len(a.workers),
+		MaxWorkers: a.maxWorkers,
+		QueueDepth: p.queue.len(),
+		Interval:   interval,
+	}
+	if interval > 0 {
+		input.CompletionRate = float64(completed-a.lastCompleted) / interval.Seconds()
+	}
+	recent := queueWait
+	for i := range recent {
+		recent[i] -= a.lastQueueWait[i]
+	}
+	input.QueueWait = histogramPercentiles(&recent)
+	a.lastEvaluated, a.lastCompleted, a.lastQueueWait = now, completed, queueWait
+
+	p.scaleLocked(a.policy.DesiredWorkers(input))
+	a.timer = p.clock.AfterFunc(a.interval, p.evaluateAutoscaling)
+}
+
+// Spawn or stop workers to run desired of them. It's not thread-safe, lock above this.
+func (p *BaseWorkerPool) scaleLocked(desired int) {
+	a := p.autoscale
+	if desired > a.maxWorkers {
+		desired = a.maxWorkers
+	}
+	if desired < 1 {
+		desired = 1
+	}
+	if desired == len(a.workers) {
+		return
+	}
+	for len(a.workers) < desired {
+		stop := make(chan bool)
+		a.workers = append(a.workers, stop)
+		p.workers.Add(1)
+		go p.runWorker(stop)
+	}
+	for len(a.workers) > desired {
+		last := len(a.workers) - 1
+		close(a.workers[last])
+		a.workers = a.workers[:last]
+	}
+	p.options.gauge(MetricWorkers, p.key, float64(desired))
+}
+
+// Follow the pool's size, when it's changed with SetPoolSize
+func (p *BaseWorkerPool) setAutoscaleLimit(maxWorkers int) {
+	if p.autoscale == nil {
+		return
+	}
+	p.autoscale.lock.Lock()
+	defer p.autoscale.lock.Unlock()
+	p.autoscale.maxWorkers = maxWorkers
+	if len(p.autoscale.workers) > maxWorkers {
+		p.scaleLocked(maxWorkers)
+	}
+}
+
+// Stop evaluating the policy once the pool is disposed. Its workers stop along with the rest of the pool.
+func (p *BaseWorkerPool) stopAutoscaling() {
+	if p.autoscale == nil {
+		return
+	}
+	p.autoscale.lock.Lock()
+	defer p.autoscale.lock.Unlock()
+	p.autoscale.stopped = true
+	if p.autoscale.timer != nil {
+		p.autoscale.timer.Stop()
+	}
+}
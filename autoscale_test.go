@@ -0,0 +1,134 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestAutoscalingFollowsPolicy(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var desired int32 = 3
+	inputs := make(chan AutoscaleInput, 10)
+	policy := AutoscalePolicyFunc(func(input AutoscaleInput) int {
+		inputs <- input
+		return int(atomic.LoadInt32(&desired))
+	})
+	// The policy is evaluated by hand rather than on the interval
+	pool, _ := NewWorkerPoolWithOptions(4, WithAutoscaling(time.Hour, policy))
+	base := pool.(*BaseWorkerPool)
+	base.spawnWorkers(4)
+	assert.Equal(t, 1, base.spawnedWorkers())
+
+	var running int32
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(5)
+	for i := 0; i < 5; i++ {
+		pool.Submit(func() {
+			defer wg.Done()
+			atomic.AddInt32(&running, 1)
+			<-release
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&running) == 1
+	}, time.Second, time.Millisecond)
+
+	base.evaluateAutoscaling()
+	input := <-inputs
+	assert.Equal(t, 1, input.Workers)
+	assert.Equal(t, 4, input.MaxWorkers)
+	assert.Equal(t, 4, input.QueueDepth)
+	assert.Positive(t, input.Interval)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&running) == 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, base.spawnedWorkers())
+
+	// Desired workers are clamped to the pool's size
+	atomic.StoreInt32(&desired, 10)
+	base.evaluateAutoscaling()
+	<-inputs
+	assert.Equal(t, 4, base.spawnedWorkers())
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&running) == 4
+	}, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	atomic.StoreInt32(&desired, 0)
+	base.evaluateAutoscaling()
+	input = <-inputs
+	assert.Equal(t, 4, input.Workers)
+	assert.Positive(t, input.CompletionRate)
+	assert.Equal(t, 1, base.spawnedWorkers())
+
+	pool.Dispose()
+	base.waitForWorkers()
+}
+
+func TestAutoscaledPoolsShrinkInPlace(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour, WithAutoscaling(time.Hour, AutoscalePolicyFunc(
+		func(input AutoscaleInput) int {
+			return input.MaxWorkers
+		},
+	)))
+	pool, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	base := pool.(*BaseWorkerPool)
+	base.evaluateAutoscaling()
+	assert.Equal(t, 4, pm.Snapshot()["key"].Workers)
+
+	pm.SetPoolSize(2)
+	resized, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Same(t, pool, resized)
+	assert.Equal(t, 2, pm.Snapshot()["key"].Workers)
+	pm.Dispose()
+}
+
+func TestTargetLatencyPolicy(t *testing.T) {
+	policy := TargetLatencyPolicy(100 * time.Millisecond)
+	desired := func(workers int, wait time.Duration, queued int) int {
+		return policy.DesiredWorkers(AutoscaleInput{
+			Workers: workers, MaxWorkers: 100, QueueDepth: queued, QueueWait: LatencyPercentiles{P95: wait},
+		})
+	}
+	// Missing the target grows the pool in proportion, by up to double
+	assert.Equal(t, 15, desired(10, 150*time.Millisecond, 5))
+	assert.Equal(t, 20, desired(10, time.Second, 5))
+	assert.Equal(t, 2, desired(1, 110*time.Millisecond, 5))
+	// Close to the target holds steady
+	assert.Equal(t, 10, desired(10, 80*time.Millisecond, 0))
+	// Comfortably under it shrinks, unless work is queued
+	assert.Equal(t, 9, desired(10, 10*time.Millisecond, 0))
+	assert.Equal(t, 10, desired(10, 10*time.Millisecond, 1))
+}
+
+func TestAutoscalingEvaluatesOnInterval(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var evaluations int32
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour, WithAutoscaling(time.Millisecond, AutoscalePolicyFunc(
+		func(input AutoscaleInput) int {
+			atomic.AddInt32(&evaluations, 1)
+			return 2
+		},
+	)))
+	_, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&evaluations) >= 3
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 2, pm.Snapshot()["key"].Workers)
+	pm.Dispose()
+}
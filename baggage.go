@@ -0,0 +1,50 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// WithBaggage copies values from the context of each task submitted with SubmitContext into its TaskInfo.Baggage,
+// using extract, so that request IDs, tenant IDs and the like follow the task into hooks and log messages about it.
+// extract returns alternating keys and values, as a Logger takes them, and is called on the submitting goroutine.
+func WithBaggage(extract func(ctx context.Context) []interface{}) Option {
+	return func(o *options) {
+		o.baggage = extract
+	}
+}
+
+// SubmitContext submits w to be executed with the values of ctx, described by info, as SubmitTask does. The context w
+// is passed carries ctx's values but not its deadline or cancellation, as the submitter's request has often finished
+// by the time the task executes - cancel work explicitly, e.g. with a Scope, where that's wanted.
+func SubmitContext(p WorkerPool, ctx context.Context, info TaskInfo, w func(ctx context.Context)) error {
+	detached := valuesOnly{ctx}
+	return p.enqueue(task{info: info, ctx: ctx, work: func() {
+		w(detached)
+	}})
+}
+
+// Copy the baggage of a task submitted with SubmitContext into its TaskInfo
+func (o *options) carryBaggage(t *task) {
+	if t.ctx != nil && o != nil && o.baggage != nil {
+		t.info.Baggage = o.baggage(t.ctx)
+	}
+	t.ctx = nil
+}
+
+// valuesOnly is a context with another's values, but which is never done
+type valuesOnly struct {
+	context.Context
+}
+
+func (valuesOnly) Deadline() (time.Time, bool) {
+	return time.Time{}, false
+}
+
+func (valuesOnly) Done() <-chan struct{} {
+	return nil
+}
+
+func (valuesOnly) Err() error {
+	return nil
+}
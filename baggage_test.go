@@ -0,0 +1,49 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type requestIDKey struct{}
+
+func TestSubmitContextCarriesBaggage(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	logger := &recordingLogger{}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithLogger(logger), WithPanicRecovery(),
+		WithBaggage(func(ctx context.Context) []interface{} {
+			return []interface{}{"request_id", ctx.Value(requestIDKey{})}
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	ctx, cancel := context.WithCancel(context.WithValue(context.Background(), requestIDKey{}, "r-1"))
+	cancel()
+	var wg sync.WaitGroup
+	wg.Add(2)
+	assert.NoError(t, SubmitContext(pool, ctx, TaskInfo{}, func(ctx context.Context) {
+		defer wg.Done()
+		// The values outlive the submitter's cancellation
+		assert.Equal(t, "r-1", ctx.Value(requestIDKey{}))
+		assert.Nil(t, ctx.Err())
+	}))
+	assert.NoError(t, SubmitContext(pool, ctx, TaskInfo{Label: "export"}, func(ctx context.Context) {
+		defer wg.Done()
+		panic("boom")
+	}))
+	wg.Wait()
+	close(doneUsing)
+	assert.Eventually(t, func() bool {
+		return len(logger.logged()) == 2
+	}, time.Second, time.Millisecond)
+	pm.Dispose()
+
+	assert.Equal(t, []interface{}{"key", "key", "panic", "boom", "label", "export", "request_id", "r-1"},
+		logger.logged()[1].keysAndValues)
+}
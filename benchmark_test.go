@@ -0,0 +1,193 @@
+package pool
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func benchmarkSubmit(b *testing.B, opts ...Option) {
+	p, _ := NewWorkerPoolWithOptions(4, opts...)
+	p.spawnWorkers(4)
+	defer p.Dispose()
+
+	var wg sync.WaitGroup
+	wg.Add(b.N)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Submit(wg.Done)
+	}
+	wg.Wait()
+}
+
+func BenchmarkSubmit(b *testing.B) {
+	benchmarkSubmit(b)
+}
+
+func BenchmarkSubmitDebug(b *testing.B) {
+	benchmarkSubmit(b, WithDebug())
+}
+
+// countdownRunner is a preallocated task, as used by callers avoiding per-submission allocations
+type countdownRunner struct {
+	wg *sync.WaitGroup
+}
+
+func (r *countdownRunner) Run() {
+	r.wg.Done()
+}
+
+// Submitting a closure allocates the closure, while submitting a preallocated Runner allocates nothing
+func BenchmarkSubmitRunner(b *testing.B) {
+	p, _ := NewWorkerPool(4)
+	p.spawnWorkers(4)
+	defer p.Dispose()
+
+	var wg sync.WaitGroup
+	wg.Add(b.N)
+	runner := &countdownRunner{wg: &wg}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.SubmitRunner(runner)
+	}
+	wg.Wait()
+}
+
+// Wrapper variants which carry bookkeeping, like retry counts, recycle it rather than allocating per submission. The
+// only remaining allocation is the caller's closure.
+func BenchmarkSubmitVariants(b *testing.B) {
+	p, _ := NewWorkerPool(4)
+	p.spawnWorkers(4)
+	defer p.Dispose()
+
+	var wg sync.WaitGroup
+	done := func() error {
+		wg.Done()
+		return nil
+	}
+	doneWithState := func(interface{}) {
+		wg.Done()
+	}
+	for _, variant := range []struct {
+		name   string
+		submit func()
+	}{
+		{"SubmitTask", func() { _ = SubmitTask(p, TaskInfo{Label: "export"}, wg.Done) }},
+		{"SubmitWithRetry", func() { _ = SubmitWithRetry(p, TaskInfo{Label: "export"}, 3, done) }},
+		{"SubmitWithWorkerState", func() { SubmitWithWorkerState(p, doneWithState) }},
+	} {
+		b.Run(variant.name, func(b *testing.B) {
+			wg.Add(b.N)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				variant.submit()
+			}
+			wg.Wait()
+		})
+	}
+}
+
+// Round trip latency of handing a trivial task to an idle worker and waiting for it to finish, for each dispatcher
+func BenchmarkDispatchLatency(b *testing.B) {
+	for _, dispatcher := range []struct {
+		name string
+		opts []Option
+	}{
+		{"channel", nil},
+		{"condvar", []Option{WithCondvarDispatch()}},
+	} {
+		b.Run(dispatcher.name, func(b *testing.B) {
+			p, _ := NewWorkerPoolWithOptions(1, dispatcher.opts...)
+			p.spawnWorkers(1)
+			defer p.Dispose()
+
+			done := make(chan bool)
+			runner := &signalRunner{done: done}
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				p.SubmitRunner(runner)
+				<-done
+			}
+		})
+	}
+}
+
+// Throughput of many submitters feeding a pool of several workers, for each dispatcher
+func BenchmarkDispatchThroughput(b *testing.B) {
+	for _, dispatcher := range []struct {
+		name string
+		opts []Option
+	}{
+		{"channel", nil},
+		{"condvar", []Option{WithCondvarDispatch()}},
+	} {
+		b.Run(dispatcher.name, func(b *testing.B) {
+			p, _ := NewWorkerPoolWithOptions(8, dispatcher.opts...)
+			p.spawnWorkers(8)
+			defer p.Dispose()
+
+			var wg sync.WaitGroup
+			wg.Add(b.N)
+			runner := &countdownRunner{wg: &wg}
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					p.SubmitRunner(runner)
+				}
+			})
+			wg.Wait()
+		})
+	}
+}
+
+// Throughput of trivial tasks spread over many keys, for per-key workers and for the dispatchers which share workers
+// between keys
+func BenchmarkTinyTasks(b *testing.B) {
+	const keys = 1000
+	for _, dispatcher := range []struct {
+		name string
+		opts []Option
+	}{
+		{"per-key", nil},
+		{"shared-fleet", []Option{WithSharedFleet(runtime.GOMAXPROCS(0))}},
+		{"multiplexed", []Option{WithMultiplexedDispatch()}},
+	} {
+		b.Run(dispatcher.name, func(b *testing.B) {
+			pm := NewWorkerPoolManager(4, time.Hour, time.Hour, append(dispatcher.opts, WithQueueCapacity(256))...)
+			defer pm.Dispose()
+			pools := make([]WorkerPool, keys)
+			for i := range pools {
+				var doneUsing chan<- bool
+				pools[i], doneUsing = pm.GetPool(fmt.Sprint(i), 4)
+				defer close(doneUsing)
+			}
+
+			var wg sync.WaitGroup
+			wg.Add(b.N)
+			runner := &countdownRunner{wg: &wg}
+			var next uint32
+			b.ReportAllocs()
+			b.ResetTimer()
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					pools[atomic.AddUint32(&next, 1)%keys].SubmitRunner(runner)
+				}
+			})
+			wg.Wait()
+		})
+	}
+}
+
+type signalRunner struct {
+	done chan bool
+}
+
+func (r *signalRunner) Run() {
+	r.done <- true
+}
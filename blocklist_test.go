@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestBlockedKeysRejectSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Second, time.Hour)
+	pool, doneUsing := pm.GetPool("cached", 1)
+
+	// Tasks queued before the key is blocked are discarded
+	unblock := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() {
+		<-unblock
+	}))
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() {
+		t.Error("blocked pool executed a queued task")
+	}))
+	pm.Block("cached")
+	pm.Block("new")
+	assert.True(t, pm.Blocked("cached"))
+	assert.Equal(t, ErrKeyBlocked, SubmitTask(pool, TaskInfo{}, func() {}))
+	close(unblock)
+	assert.Nil(t, pm.Quiesce(context.Background()))
+
+	newPool, newDoneUsing := pm.GetPool("new", 1)
+	assert.Equal(t, ErrKeyBlocked, SubmitTask(newPool, TaskInfo{}, func() {}))
+	close(newDoneUsing)
+
+	pm.Unblock("cached")
+	assert.False(t, pm.Blocked("cached"))
+	done := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() {
+		close(done)
+	}))
+	<-done
+
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -0,0 +1,143 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrBulkheadFull is returned by Bulkhead.Execute when the bulkhead already has as many calls executing and waiting as
+// its BulkheadConfig allows
+var ErrBulkheadFull = errors.New("bulkhead is full")
+
+// ErrBulkheadTimeout is returned by Bulkhead.Execute when a call waits longer than BulkheadConfig.MaxWait to execute
+var ErrBulkheadTimeout = errors.New("bulkhead wait timed out")
+
+// BulkheadConfig configures a Bulkhead
+type BulkheadConfig struct {
+	// MaxConcurrent is how many calls may execute at once, 1 if unset. It's also how many workers the key's pool is
+	// asked for, up to the manager's pool size.
+	MaxConcurrent int
+	// MaxQueue is how many calls may wait for one of the executing calls to finish. Calls beyond it are rejected with
+	// ErrBulkheadFull, and none may wait if it's unset.
+	MaxQueue int
+	// MaxWait is how long a call may wait to execute before it's rejected with ErrBulkheadTimeout, as long as its
+	// context allows if unset
+	MaxWait time.Duration
+}
+
+// Bulkhead isolates calls to a downstream behind a key's pool, with the max concurrency, max queue and max wait of the
+// bulkhead pattern as resilience libraries describe it, so that a struggling downstream can hold up at most its own
+// share of callers. Each failure mode has an error of its own, ErrBulkheadFull and ErrBulkheadTimeout, which callers
+// can fall back on.
+type Bulkhead struct {
+	manager *WorkerPoolManager
+	key     string
+	config  BulkheadConfig
+	// A token for each executing call
+	executing chan bool
+	// Calls executing and waiting, accessed atomically
+	admitted int64
+}
+
+// NewBulkhead returns a Bulkhead whose calls execute on key's pool
+func NewBulkhead(manager *WorkerPoolManager, key string, config BulkheadConfig) *Bulkhead {
+	if config.MaxConcurrent < 1 {
+		config.MaxConcurrent = 1
+	}
+	if config.MaxQueue < 0 {
+		config.MaxQueue = 0
+	}
+	return &Bulkhead{
+		manager:   manager,
+		key:       key,
+		config:    config,
+		executing: make(chan bool, config.MaxConcurrent),
+	}
+}
+
+// Execute calls w on the bulkhead's pool and returns its error, once there's room for it under MaxConcurrent. It
+// returns ErrBulkheadFull without waiting if MaxQueue calls are already waiting, ErrBulkheadTimeout if the call waits
+// longer than MaxWait, ctx's error if it's done before w is called, GetPoolContext's if the pool can't be checked
+// out, or the same errors as SubmitTask if the pool rejects the call. w is passed ctx, and Execute returns as soon as
+// ctx is done, even while w is executing.
+func (b *Bulkhead) Execute(ctx context.Context, w func(ctx context.Context) error) error {
+	if atomic.AddInt64(&b.admitted, 1) > int64(b.config.MaxConcurrent+b.config.MaxQueue) {
+		atomic.AddInt64(&b.admitted, -1)
+		return ErrBulkheadFull
+	}
+	defer atomic.AddInt64(&b.admitted, -1)
+	if err := b.wait(ctx); err != nil {
+		return err
+	}
+
+	pool, doneUsing, err := b.manager.GetPoolContext(ctx, b.key, b.config.MaxConcurrent, nil)
+	if err != nil {
+		<-b.executing
+		return err
+	}
+	// Buffered, so w's result doesn't block its worker once Execute has returned
+	result := make(chan error, 1)
+	done := func() {
+		<-b.executing
+		close(doneUsing)
+	}
+	err = pool.enqueue(task{work: func() {
+		defer done()
+		result <- w(ctx)
+	}, dropped: func() {
+		done()
+		result <- ErrTaskPurged
+	}})
+	if err != nil {
+		done()
+		return err
+	}
+	select {
+	case err := <-result:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Executing returns how many calls are executing
+func (b *Bulkhead) Executing() int {
+	return len(b.executing)
+}
+
+// Waiting returns how many calls are waiting to execute
+func (b *Bulkhead) Waiting() int {
+	if waiting := int(atomic.LoadInt64(&b.admitted)) - len(b.executing); waiting > 0 {
+		return waiting
+	}
+	return 0
+}
+
+// Wait for a call's turn to execute
+func (b *Bulkhead) wait(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	select {
+	case b.executing <- true:
+		return nil
+	default:
+	}
+
+	var timeout <-chan time.Time
+	if b.config.MaxWait > 0 {
+		timer := time.NewTimer(b.config.MaxWait)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case b.executing <- true:
+		return nil
+	case <-timeout:
+		return ErrBulkheadTimeout
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
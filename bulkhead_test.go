@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestBulkheadRejectsCallsBeyondItsLimits(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	defer pm.Dispose()
+	bulkhead := NewBulkhead(pm, "downstream", BulkheadConfig{
+		MaxConcurrent: 1,
+		MaxQueue:      1,
+		MaxWait:       20 * time.Millisecond,
+	})
+
+	started := make(chan bool)
+	unblock := make(chan bool)
+	executed := make(chan error, 1)
+	go func() {
+		executed <- bulkhead.Execute(context.Background(), func(ctx context.Context) error {
+			started <- true
+			<-unblock
+			return errors.New("downstream failed")
+		})
+	}()
+	<-started
+	assert.Equal(t, 1, bulkhead.Executing())
+
+	waited := make(chan error, 1)
+	go func() {
+		waited <- bulkhead.Execute(context.Background(), func(ctx context.Context) error {
+			return nil
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		return bulkhead.Waiting() == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, ErrBulkheadFull, bulkhead.Execute(context.Background(), func(ctx context.Context) error {
+		t.Error("Expected the call to be rejected")
+		return nil
+	}))
+	assert.Equal(t, ErrBulkheadTimeout, <-waited)
+
+	close(unblock)
+	assert.EqualError(t, <-executed, "downstream failed")
+	assert.Nil(t, bulkhead.Execute(context.Background(), func(ctx context.Context) error {
+		return nil
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	assert.Equal(t, context.Canceled, bulkhead.Execute(ctx, func(ctx context.Context) error {
+		t.Error("Expected the call to be abandoned")
+		return nil
+	}))
+}
@@ -0,0 +1,88 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// WithBurst lets each pool temporarily run up to extraWorkers workers beyond its size, for downstreams which cope
+// better with short bursts of concurrency than with queues growing.
+//
+// A burst starts when a submission finds the pool's queue full, and its extra workers work through the queue for
+// maxBurstDuration, then stop once they've finished their current tasks. Another burst can start as soon as the
+// queue fills up again.
+func WithBurst(extraWorkers int, maxBurstDuration time.Duration) Option {
+	return func(o *options) {
+		o.burstWorkers = extraWorkers
+		o.burstDuration = maxBurstDuration
+	}
+}
+
+// burstState tracks a pool's current burst
+type burstState struct {
+	lock         *sync.Mutex
+	extraWorkers int
+	duration     time.Duration
+	// Closed to stop the extra workers, nil when the pool isn't bursting
+	stop  chan bool
+	timer Timer
+}
+
+func newBurstState(extraWorkers int, duration time.Duration) *burstState {
+	return &burstState{lock: &sync.Mutex{}, extraWorkers: extraWorkers, duration: duration}
+}
+
+// Spawn extra workers if the pool can burst and isn't already
+func (p *BaseWorkerPool) startBurst() {
+	b := p.burst
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.stop != nil || isClosed(p.disposed) {
+		return
+	}
+
+	stop := make(chan bool)
+	b.stop = stop
+	p.workers.Add(b.extraWorkers)
+	for i := 0; i < b.extraWorkers; i++ {
+		go p.runWorker(stop)
+	}
+	b.timer = p.clock.AfterFunc(b.duration, func() {
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		if b.stop == stop {
+			b.end()
+		}
+	})
+}
+
+// Stop the current burst, if there is one, once the pool is disposed
+func (p *BaseWorkerPool) stopBursting() {
+	if p.burst == nil {
+		return
+	}
+	p.burst.lock.Lock()
+	defer p.burst.lock.Unlock()
+	if p.burst.stop != nil {
+		p.burst.timer.Stop()
+		p.burst.end()
+	}
+}
+
+func (b *burstState) end() {
+	close(b.stop)
+	b.stop = nil
+	b.timer = nil
+}
+
+func (p *BaseWorkerPool) bursting() bool {
+	if p.burst == nil {
+		return false
+	}
+	p.burst.lock.Lock()
+	defer p.burst.lock.Unlock()
+	return p.burst.stop != nil
+}
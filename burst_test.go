@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestBurstTemporarilyExceedsPoolSize(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(1), WithBurst(2, 20*time.Millisecond))
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	var running int32
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	task := func() {
+		defer wg.Done()
+		atomic.AddInt32(&running, 1)
+		<-release
+	}
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		// The third submission finds the queue full, starting a burst which picks up the rest
+		pool.Submit(task)
+	}
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&running) == 3
+	}, time.Second, time.Millisecond)
+	assert.True(t, pm.Snapshot()["key"].Bursting)
+	close(release)
+	wg.Wait()
+
+	// Once the burst is over the pool is back to a single worker
+	assert.Eventually(t, func() bool {
+		return !pm.Snapshot()["key"].Bursting
+	}, time.Second, time.Millisecond)
+	started := make(chan bool, 3)
+	block := make(chan bool)
+	wg.Add(2)
+	for i := 0; i < 2; i++ {
+		pool.Submit(func() {
+			defer wg.Done()
+			started <- true
+			<-block
+		})
+	}
+	<-started
+	time.Sleep(5 * time.Millisecond)
+	assert.Len(t, started, 0)
+	close(block)
+	wg.Wait()
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestDisposeEndsBurst(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pool, _ := NewWorkerPoolWithOptions(1, WithBurst(2, time.Hour))
+	base := pool.(*BaseWorkerPool)
+	base.startBurst()
+	assert.True(t, base.bursting())
+	pool.Dispose()
+	assert.False(t, base.bursting())
+	base.waitForWorkers()
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// How often the manager's cache metrics are sampled at most
+const cacheSampleInterval = time.Second
+
+// CacheStats describes the manager's cache of pools, as reported by CacheStats
+type CacheStats struct {
+	// Pools is the number of pools cached right now
+	Pools int
+	// Hits and Misses count the cache's lookups which did and didn't find a pool. They count every lookup the manager
+	// makes, including by PauseKey, Blocked and the like, so they run ahead of MetricPoolsReused and
+	// MetricPoolsCreated, which only count checkouts.
+	Hits   uint64
+	Misses uint64
+	// Insertions counts the pools added to the cache, and Evictions those removed from it for any reason
+	Insertions uint64
+	Evictions  uint64
+}
+
+// CacheStats reads the counters of the manager's cache of pools, so the cache's behavior can be monitored without
+// depending on its implementation. With WithMetrics, they're also reported as metrics, sampled at most once a second
+// as pools are checked out.
+func (m *WorkerPoolManager) CacheStats() CacheStats {
+	metrics := m.workerPoolCache.Metrics()
+	return CacheStats{
+		Pools:      m.workerPoolCache.Len(),
+		Hits:       metrics.Hits,
+		Misses:     metrics.Misses,
+		Insertions: metrics.Insertions,
+		Evictions:  metrics.Evictions,
+	}
+}
+
+// cacheSampler reports the manager's cache metrics, as the changes since they were last sampled
+type cacheSampler struct {
+	// When the metrics were last sampled, in Unix nanoseconds, accessed atomically. It's first to keep it 64-bit
+	// aligned.
+	sampled int64
+	lock    *sync.Mutex
+	last    ttlcache.Metrics
+}
+
+// Report the cache's metrics, unless they've been sampled within the last cacheSampleInterval
+func (m *WorkerPoolManager) sampleCache() {
+	s := m.cacheSampler
+	collector := m.options.metrics
+	if collector == nil {
+		return
+	}
+	now := m.clock.Now().UnixNano()
+	sampled := atomic.LoadInt64(&s.sampled)
+	if now-sampled < int64(cacheSampleInterval) || !atomic.CompareAndSwapInt64(&s.sampled, sampled, now) {
+		return
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	metrics := m.workerPoolCache.Metrics()
+	for name, delta := range map[string]uint64{
+		MetricCacheHits:      metrics.Hits - s.last.Hits,
+		MetricCacheMisses:    metrics.Misses - s.last.Misses,
+		MetricCacheEvictions: metrics.Evictions - s.last.Evictions,
+	} {
+		if delta > 0 {
+			collector.Count(name, MetricKeyManager, int64(delta))
+		}
+	}
+	collector.Gauge(MetricCachedPools, MetricKeyManager, float64(m.workerPoolCache.Len()))
+	s.last = metrics
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrCheckoutLimit is returned when submitting through a checkout which already has as many unfinished submissions as
+// it's allowed, see WithCheckoutLimit
+var ErrCheckoutLimit = errors.New("checkout has too many unfinished submissions")
+
+// WithCheckoutLimit caps how many unfinished submissions each checkout - each pool handed out by GetPool - may have at
+// once, so that a runaway producer can't fill its key's queue and starve the other callers sharing the pool.
+// Submissions beyond the limit are rejected with ErrCheckoutLimit until earlier ones finish executing.
+//
+// The limit covers Submit, SubmitRunner, SubmitTask, SubmitOnWorker and SubmitWithWorkerState, but not the
+// coalescing, debouncing and retrying variants, which track their own submissions. It applies to every way of checking
+// out a pool - GetPool, GetPoolWithFactory, GetPoolWithSize, GetPoolContext and those of ChildManager and TypedManager
+// - so callers which type assert the pool they checked out, e.g. to a custom pool built by their factory, should do
+// so on UnwrapCheckout's result.
+func WithCheckoutLimit(limit int) Option {
+	return func(o *options) {
+		o.checkoutLimit = limit
+	}
+}
+
+// UnwrapCheckout returns the pool behind a checkout handed out with WithCheckoutLimit, for type assertions, or pool
+// itself if it isn't such a checkout. Submissions made on the unwrapped pool aren't limited.
+func UnwrapCheckout(pool WorkerPool) WorkerPool {
+	if checkout, ok := pool.(*limitedCheckout); ok {
+		return checkout.WorkerPool
+	}
+	return pool
+}
+
+// CheckoutStats counts the submissions made through a checkout, see GetPoolWithStats
+type CheckoutStats struct {
+	Key string
+	// Submitted counts the submissions accepted through the checkout
+	Submitted int64
+	// Completed counts the submissions which had finished executing when the checkout was released. Those still queued
+	// or executing, e.g. from a caller which doesn't wait for its work before releasing, are left out.
+	Completed int64
+	// Held is how long the checkout was held for
+	Held time.Duration
+}
+
+// GetPoolWithStats returns the WorkerPool for this key like GetPool, attributing the submissions made through it to
+// the checkout, and a release func which releases the checkout as closing GetPool's done channel does and returns its
+// CheckoutStats - so that callers batching work on a shared pool can log how much each batch pushed through it.
+// Calling release again is harmless, and returns the stats as of the first call.
+func (m *WorkerPoolManager) GetPoolWithStats(key string, sendSize int) (WorkerPool, func() CheckoutStats) {
+	pool, doneUsing := m.GetPool(key, sendSize)
+	checkout, limited := pool.(*limitedCheckout)
+	if !limited {
+		checkout = &limitedCheckout{WorkerPool: pool}
+	}
+	checkedOut := m.clock.Now()
+	once := &sync.Once{}
+	var stats CheckoutStats
+	return checkout, func() CheckoutStats {
+		once.Do(func() {
+			stats = CheckoutStats{
+				Key:       key,
+				Submitted: atomic.LoadInt64(&checkout.counts.submitted),
+				Completed: atomic.LoadInt64(&checkout.counts.completed),
+				Held:      m.clock.Now().Sub(checkedOut),
+			}
+			close(doneUsing)
+		})
+		return stats
+	}
+}
+
+// checkoutCounts counts the submissions made through a checkout, accessed atomically
+type checkoutCounts struct {
+	// Submissions which haven't finished executing yet
+	pending   int64
+	submitted int64
+	completed int64
+}
+
+// limitedCheckout is a pool checked out with a checkout limit, or by GetPoolWithStats, counting the submissions made
+// through it
+type limitedCheckout struct {
+	// First to keep it 64-bit aligned
+	counts checkoutCounts
+	// Unlimited if zero
+	limit int64
+	// The manager's options and the key checked out, to count rejected submissions with
+	options *options
+	key     string
+	WorkerPool
+}
+
+func (c *limitedCheckout) Submit(w Work) {
+	_ = c.enqueue(task{work: w})
+}
+
+func (c *limitedCheckout) SubmitRunner(r Runner) {
+	_ = c.enqueue(task{runner: r})
+}
+
+func (c *limitedCheckout) enqueue(t task) error {
+	if pending := atomic.AddInt64(&c.counts.pending, 1); c.limit > 0 && pending > c.limit {
+		atomic.AddInt64(&c.counts.pending, -1)
+		c.options.count(MetricTasksRejected, c.key, 1)
+		return ErrCheckoutLimit
+	}
+	t.checkout = &c.counts
+	if err := c.WorkerPool.enqueue(t); err != nil {
+		atomic.AddInt64(&c.counts.pending, -1)
+		return err
+	}
+	atomic.AddInt64(&c.counts.submitted, 1)
+	return nil
+}
+
+// Account for t no longer being unfinished, whether it executed or was dropped
+func (p *BaseWorkerPool) finish(t task) {
+	atomic.AddInt64(&p.stats.unfinished, -1)
+	if t.checkout != nil {
+		atomic.AddInt64(&t.checkout.pending, -1)
+	}
+	p.memory.release(t.info.MemoryCost)
+}
+
+// Account for t having executed, for the checkout it was submitted through
+func (t task) completed() {
+	if t.checkout != nil {
+		atomic.AddInt64(&t.checkout.completed, 1)
+	}
+}
@@ -0,0 +1,138 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestCheckoutLimitCapsEachCheckoutsUnfinishedSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(
+		1, time.Hour, time.Hour, WithCheckoutLimit(2), WithQueueCapacity(10), WithMetrics(collector),
+	)
+	runaway, doneUsingRunaway := pm.GetPool("key", 1)
+	other, doneUsingOther := pm.GetPool("key", 1)
+
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	blocked := func() {
+		defer wg.Done()
+		<-release
+	}
+	assert.Nil(t, SubmitTask(runaway, TaskInfo{}, blocked))
+	assert.Nil(t, SubmitTask(runaway, TaskInfo{}, blocked))
+	assert.Equal(t, ErrCheckoutLimit, SubmitTask(runaway, TaskInfo{}, blocked))
+	runaway.Submit(func() {
+		t.Error("submitted beyond the checkout limit")
+	})
+	assert.Equal(t, int64(2), collector.count(MetricTasksRejected, "key"))
+
+	// Other checkouts of the same pool have limits of their own
+	assert.Nil(t, SubmitTask(other, TaskInfo{}, blocked))
+
+	close(release)
+	wg.Wait()
+	done := make(chan bool)
+	assert.Eventually(t, func() bool {
+		return SubmitTask(runaway, TaskInfo{}, func() { done <- true }) == nil
+	}, time.Second, time.Millisecond)
+	<-done
+	assert.Contains(t, pm.Config().Features, "checkout limit")
+
+	close(doneUsingRunaway)
+	close(doneUsingOther)
+	pm.Dispose()
+}
+
+func TestCheckoutLimitAppliesToEveryCheckout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithCheckoutLimit(1), WithQueueCapacity(10))
+	defer pm.Dispose()
+	paused, doneUsing := pm.GetPool("key", 1)
+	paused.Pause()
+	close(doneUsing)
+	checkouts := map[string]func() (WorkerPool, chan<- bool, error){
+		"GetPool": func() (WorkerPool, chan<- bool, error) {
+			pool, doneUsing := pm.GetPool("key", 1)
+			return pool, doneUsing, nil
+		},
+		"GetPoolWithFactory": func() (WorkerPool, chan<- bool, error) {
+			return pm.GetPoolWithFactory("key", 1, nil)
+		},
+		"GetPoolWithSize": func() (WorkerPool, chan<- bool, error) {
+			return pm.GetPoolWithSize("key", 1, 1, nil)
+		},
+		"GetPoolContext": func() (WorkerPool, chan<- bool, error) {
+			return pm.GetPoolContext(context.Background(), "key", 1, nil)
+		},
+	}
+	for name, checkout := range checkouts {
+		pool, doneUsing, err := checkout()
+		assert.Nil(t, err)
+		// The paused pool keeps the first submission unfinished
+		assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() {}), name)
+		assert.Equal(t, ErrCheckoutLimit, SubmitTask(pool, TaskInfo{}, func() {}), name)
+		assert.IsType(t, &BaseWorkerPool{}, UnwrapCheckout(pool), name)
+		close(doneUsing)
+	}
+}
+
+func TestCheckoutLimitReleasesRejectedAndDroppedSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithCheckoutLimit(1), WithQueueCapacity(10))
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	pm.Block("key")
+	assert.Equal(t, ErrKeyBlocked, SubmitTask(pool, TaskInfo{}, func() {}))
+	pm.Unblock("key")
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, wg.Done))
+	wg.Wait()
+	wg.Add(1)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, wg.Done))
+	wg.Wait()
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestGetPoolWithStatsAttributesSubmissionsToItsCheckout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	defer pm.Dispose()
+	batch, release := pm.GetPoolWithStats("key", 2)
+	other, doneUsing := pm.GetPool("key", 2)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 3; i++ {
+		batch.Submit(wg.Done)
+	}
+	other.Submit(wg.Done)
+	wg.Wait()
+	close(doneUsing)
+
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt64(&batch.(*limitedCheckout).counts.pending) == 0
+	}, time.Second, time.Millisecond)
+	stats := release()
+	assert.Equal(t, "key", stats.Key)
+	assert.Equal(t, int64(3), stats.Submitted)
+	assert.Equal(t, int64(3), stats.Completed)
+	assert.Positive(t, stats.Held)
+	assert.Equal(t, stats, release(), "releasing again returns the same stats")
+}
@@ -0,0 +1,233 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// WithPoolSize overrides the max number of workers for each key of a Child. A manager's own pool size is the one
+// passed to NewWorkerPoolManager.
+func WithPoolSize(poolSize int) Option {
+	return func(o *options) {
+		o.poolSize = poolSize
+	}
+}
+
+// WithStalePoolExpiration overrides how long a Child caches unused pools for. A manager's own stale pool expiration is
+// the one passed to NewWorkerPoolManager.
+func WithStalePoolExpiration(expiration time.Duration) Option {
+	return func(o *options) {
+		o.stalePoolExpiration = expiration
+	}
+}
+
+// WithMaxPoolLifetime overrides the max time a Child's pools live for. A manager's own max pool lifetime is the one
+// passed to NewWorkerPoolManager.
+func WithMaxPoolLifetime(lifetime time.Duration) Option {
+	return func(o *options) {
+		o.maxPoolLifetime = lifetime
+		o.withoutMaxLifetime = false
+	}
+}
+
+// ChildManager is a view of a WorkerPoolManager with a keyspace of its own, see WorkerPoolManager.Child
+type ChildManager struct {
+	manager *WorkerPoolManager
+	// The child this one was made from, nil if it was made from the manager
+	parent    *ChildManager
+	prefix    string
+	overrides []Option
+
+	// The options the child's pools are configured with, and the options they were derived from, which change as the
+	// manager's are replaced by UpdateConfig. Both are guarded by the manager's poolReservationLock.
+	base    *options
+	options *options
+}
+
+// Child returns a view of the manager whose keys are prefixed with prefix - e.g. "billing/", separator included - so
+// that a library embedded in a service gets a keyspace of its own, and can't collide with the service's keys. The
+// child shares the manager's cache and its budgets, such as a shared fleet or a memory budget, and is disposed along
+// with the manager.
+//
+// overrides configure the child's pools on top of the manager's options, as options passed to
+// NewWorkerPoolWithOptions would, and WithPoolSize, WithStalePoolExpiration and WithMaxPoolLifetime override the
+// manager's configuration. Manager-wide behavior, such as the manager's clock, leases and hooks for its own events,
+// stays the manager's. The overrides apply to every key with the prefix, however it's checked out, and calling Child
+// again with the same prefix replaces them.
+func (m *WorkerPoolManager) Child(prefix string, overrides ...Option) *ChildManager {
+	return m.child(nil, prefix, overrides)
+}
+
+// Child returns a view of the child whose keys are prefixed with prefix in turn, inheriting the child's overrides, see
+// WorkerPoolManager.Child
+func (c *ChildManager) Child(prefix string, overrides ...Option) *ChildManager {
+	return c.manager.child(c, c.prefix+prefix, overrides)
+}
+
+func (m *WorkerPoolManager) child(parent *ChildManager, prefix string, overrides []Option) *ChildManager {
+	c := &ChildManager{manager: m, parent: parent, prefix: prefix, overrides: overrides}
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	if m.children == nil {
+		m.children = make(map[string]*ChildManager)
+	}
+	m.children[prefix] = c
+	return c
+}
+
+// Prefix returns the prefix of the child's keys in the manager
+func (c *ChildManager) Prefix() string {
+	return c.prefix
+}
+
+// Manager returns the manager the child is a view of, which is keyed by prefixed key
+func (c *ChildManager) Manager() *WorkerPoolManager {
+	return c.manager
+}
+
+// GetPool returns the WorkerPool for key, see WorkerPoolManager.GetPool
+func (c *ChildManager) GetPool(key string, sendSize int) (WorkerPool, chan<- bool) {
+	return c.manager.GetPool(c.prefix+key, sendSize)
+}
+
+// GetPoolWithFactory returns the WorkerPool for key, building it with factory if need be, see
+// WorkerPoolManager.GetPoolWithFactory
+func (c *ChildManager) GetPoolWithFactory(key string, sendSize int, factory Factory) (WorkerPool, chan<- bool, error) {
+	return c.manager.GetPoolWithFactory(c.prefix+key, sendSize, factory)
+}
+
+// GetPoolWithSize returns the WorkerPool for key, building it with at most size workers if need be, see
+// WorkerPoolManager.GetPoolWithSize
+func (c *ChildManager) GetPoolWithSize(
+	key string, sendSize int, size int, factory Factory,
+) (WorkerPool, chan<- bool, error) {
+	return c.manager.GetPoolWithSize(c.prefix+key, sendSize, size, factory)
+}
+
+// GetPoolWithStats returns the WorkerPool for key, and a release func returning the checkout's stats, see
+// WorkerPoolManager.GetPoolWithStats. The stats' key is the prefixed key.
+func (c *ChildManager) GetPoolWithStats(key string, sendSize int) (WorkerPool, func() CheckoutStats) {
+	return c.manager.GetPoolWithStats(c.prefix+key, sendSize)
+}
+
+// GetPoolContext returns the WorkerPool for key, waiting for a checkout to be released if need be, see
+// WorkerPoolManager.GetPoolContext
+func (c *ChildManager) GetPoolContext(
+	ctx context.Context, key string, sendSize int, factory Factory,
+) (WorkerPool, chan<- bool, error) {
+	return c.manager.GetPoolContext(ctx, c.prefix+key, sendSize, factory)
+}
+
+// Snapshot returns a PoolSnapshot of every cached pool in the child's keyspace, by key without the prefix
+func (c *ChildManager) Snapshot() map[string]PoolSnapshot {
+	snapshots := make(map[string]PoolSnapshot)
+	for key, snapshot := range c.manager.Snapshot() {
+		if strings.HasPrefix(key, c.prefix) {
+			snapshots[strings.TrimPrefix(key, c.prefix)] = snapshot
+		}
+	}
+	return snapshots
+}
+
+// SetKeySize changes the max number of workers for key's cached pool, see WorkerPoolManager.SetKeySize
+func (c *ChildManager) SetKeySize(key string, n int) bool {
+	return c.manager.SetKeySize(c.prefix+key, n)
+}
+
+// PauseKey pauses key's pool, see WorkerPoolManager.PauseKey
+func (c *ChildManager) PauseKey(key string) bool {
+	return c.manager.PauseKey(c.prefix + key)
+}
+
+// ResumeKey resumes key's pool, see WorkerPoolManager.ResumeKey
+func (c *ChildManager) ResumeKey(key string) bool {
+	return c.manager.ResumeKey(c.prefix + key)
+}
+
+// Block suspends key, see WorkerPoolManager.Block
+func (c *ChildManager) Block(key string) {
+	c.manager.Block(c.prefix + key)
+}
+
+// Unblock lifts a Block on key
+func (c *ChildManager) Unblock(key string) {
+	c.manager.Unblock(c.prefix + key)
+}
+
+// Blocked returns whether key is blocked
+func (c *ChildManager) Blocked(key string) bool {
+	return c.manager.Blocked(c.prefix + key)
+}
+
+// The options the child's pools are configured with. It's not thread-safe, lock above this.
+func (c *ChildManager) poolOptions() *options {
+	base := c.manager.poolOptions
+	if c.parent != nil {
+		base = c.parent.poolOptions()
+	}
+	if c.base != base {
+		o := base.clone()
+		for _, override := range c.overrides {
+			override(o)
+		}
+		c.base = base
+		c.options = o
+	}
+	return c.options
+}
+
+// The child whose keyspace key is in, the one with the longest prefix if several are, or nil. It's not thread-safe,
+// lock above this.
+func (m *WorkerPoolManager) childFor(key string) *ChildManager {
+	var child *ChildManager
+	for prefix, c := range m.children {
+		if strings.HasPrefix(key, prefix) && (child == nil || len(prefix) > len(child.prefix)) {
+			child = c
+		}
+	}
+	return child
+}
+
+// The options key's pool is configured with. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) poolOptionsFor(key string) *options {
+	if c := m.childFor(key); c != nil {
+		return c.poolOptions()
+	}
+	return m.poolOptions
+}
+
+// How long key's pool is cached for while unused. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) staleExpirationFor(key string) time.Duration {
+	if expiration := m.poolOptionsFor(key).stalePoolExpiration; expiration > 0 {
+		return expiration
+	}
+	return m.stalePoolExpiration
+}
+
+// How long key's pool lives for, or false if it's never rotated. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) maxLifetimeFor(key string) (time.Duration, bool) {
+	o := m.poolOptionsFor(key)
+	if o.maxPoolLifetime > 0 {
+		return o.maxPoolLifetime, true
+	}
+	if o.withoutMaxLifetime {
+		return 0, false
+	}
+	return m.maxPoolLifetime, true
+}
+
+// A copy of o, whose slices and maps overrides can add to without changing o's
+func (o *options) clone() *options {
+	c := *o
+	c.hooks = append([]Hooks(nil), o.hooks...)
+	c.factoryMiddleware = append([]func(next Factory) Factory(nil), o.factoryMiddleware...)
+	c.interceptors = append([]func(key string, t TaskInfo) error(nil), o.interceptors...)
+	if o.labelLimits != nil {
+		c.labelLimits = make(map[string]int, len(o.labelLimits))
+		for label, limit := range o.labelLimits {
+			c.labelLimits[label] = limit
+		}
+	}
+	return &c
+}
@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestChildPrefixesKeys(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour)
+	child := pm.Child("library/")
+	pool, doneUsing := child.GetPool("key", 1)
+	close(doneUsing)
+	parentPool, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.NotSame(t, pool, parentPool)
+
+	prefixed, doneUsing := pm.GetPool("library/key", 1)
+	close(doneUsing)
+	assert.Same(t, pool, prefixed)
+	assert.Len(t, pm.Snapshot(), 2)
+	assert.Len(t, child.Snapshot(), 1)
+	assert.Contains(t, child.Snapshot(), "key")
+
+	nested := child.Child("nested/")
+	assert.Equal(t, "library/nested/", nested.Prefix())
+	_, doneUsing = nested.GetPool("key", 1)
+	close(doneUsing)
+	assert.Len(t, child.Snapshot(), 2)
+	assert.Len(t, nested.Snapshot(), 1)
+
+	pm.Dispose()
+}
+
+func TestChildOverridesConfiguration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Hour, 10*time.Hour, WithQueueCapacity(10))
+	child := pm.Child("library/", WithPoolSize(1), WithStalePoolExpiration(20*time.Millisecond),
+		WithLabelLimit("bulk", 1))
+	// Nested children inherit the overrides
+	nested := child.Child("nested/", WithPoolSize(2))
+
+	_, doneUsing := child.GetPool("key", 4)
+	close(doneUsing)
+	_, doneUsing = nested.GetPool("key", 4)
+	close(doneUsing)
+	_, doneUsing = pm.GetPool("key", 4)
+	close(doneUsing)
+	assert.Equal(t, 1, child.Snapshot()["key"].Workers)
+	assert.Equal(t, 2, nested.Snapshot()["key"].Workers)
+	assert.Equal(t, 4, pm.Snapshot()["key"].Workers)
+
+	// The parent's label limits are left alone
+	assert.Nil(t, pm.poolOptions.labelLimits)
+
+	// The child's pools expire sooner than the parent's
+	assert.Eventually(t, func() bool {
+		return len(child.Snapshot()) == 0
+	}, time.Second, time.Millisecond)
+	assert.Contains(t, pm.Snapshot(), "key")
+
+	pm.Dispose()
+}
@@ -0,0 +1,59 @@
+package pool
+
+import "time"
+
+// Clock is the source of time for a manager and its pools. The real clock is used unless another is configured with
+// WithClock, which is mostly useful for simulating time in tests - see the pooltest package.
+type Clock interface {
+	Now() time.Time
+	// AfterFunc waits for d to elapse and then calls f, returning a Timer which can be used to cancel the call
+	AfterFunc(d time.Duration, f func()) Timer
+}
+
+// Timer is a pending call scheduled with Clock.AfterFunc
+type Timer interface {
+	// Stop prevents the call from happening, returning false if it has already happened or been stopped
+	Stop() bool
+	// Reset reschedules the call to happen after d, returning false if it had already happened or been stopped
+	Reset(d time.Duration) bool
+}
+
+// WithClock makes the manager and its pools read time from clock.
+//
+// Stale pool expiration is normally handled by the cache's own janitor, which only understands the real clock, so
+// managers with a custom clock schedule expiration through the clock instead.
+func WithClock(clock Clock) Option {
+	return func(o *options) {
+		if clock != nil {
+			o.clock = clock
+		}
+	}
+}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time {
+	return time.Now()
+}
+
+func (realClock) AfterFunc(d time.Duration, f func()) Timer {
+	return time.AfterFunc(d, f)
+}
+
+// Wait on clock for d to elapse, returning false if done is closed first
+func sleep(clock Clock, d time.Duration, done <-chan bool) bool {
+	if d <= 0 {
+		return true
+	}
+	elapsed := make(chan bool)
+	timer := clock.AfterFunc(d, func() {
+		close(elapsed)
+	})
+	select {
+	case <-elapsed:
+		return true
+	case <-done:
+		timer.Stop()
+		return false
+	}
+}
@@ -0,0 +1,71 @@
+package pool
+
+// coalescedWork is a pending coalesced submission, waiting in the pool's queue for a worker
+type coalescedWork struct {
+	key     string
+	payload interface{}
+	handler func(interface{})
+}
+
+// SubmitCoalesce submits handler to be executed against payload, collapsing it into any submission for the same
+// coalesceKey which is still waiting in the pool's queue.
+//
+// When a submission for coalesceKey is already pending, no new Work is enqueued - instead, merge is called with the
+// pending payload and this payload, and the result replaces the pending payload. The most recently submitted handler
+// is the one executed. Once a worker picks up the submission it is no longer pending, so later submissions for the same
+// coalesceKey are enqueued again. Nor is it pending once it's rejected or purged from the queue, so later submissions
+// aren't merged into work which will never execute.
+//
+// merge is called while the pool's coalescing lock is held, so it should be cheap and must not submit to the pool.
+func SubmitCoalesce[T any](p WorkerPool, coalesceKey string, payload T, merge func(old, new T) T, handler func(T)) {
+	p.submitCoalesce(
+		coalesceKey,
+		payload,
+		func(old, new interface{}) interface{} {
+			return merge(old.(T), new.(T))
+		},
+		func(merged interface{}) {
+			handler(merged.(T))
+		},
+	)
+}
+
+func (p *BaseWorkerPool) submitCoalesce(
+	coalesceKey string, payload interface{}, merge func(old, new interface{}) interface{}, handler func(interface{}),
+) {
+	p.coalesceLock.Lock()
+	if pending, ok := p.coalescing[coalesceKey]; ok {
+		pending.payload = merge(pending.payload, payload)
+		pending.handler = handler
+		p.coalesceLock.Unlock()
+		return
+	}
+	pending := &coalescedWork{key: coalesceKey, payload: payload, handler: handler}
+	p.coalescing[coalesceKey] = pending
+	p.coalesceLock.Unlock()
+
+	err := p.enqueue(task{
+		work: func() {
+			// Once picked up, the submission is no longer pending and can't absorb any further payloads
+			p.forgetCoalesced(pending)
+			p.coalesceLock.Lock()
+			payload, handler := pending.payload, pending.handler
+			p.coalesceLock.Unlock()
+
+			handler(payload)
+		},
+		coalesced: pending,
+	})
+	if err != nil {
+		p.forgetCoalesced(pending)
+	}
+}
+
+// Stop pending absorbing later submissions, once it's executing or won't execute at all
+func (p *BaseWorkerPool) forgetCoalesced(pending *coalescedWork) {
+	p.coalesceLock.Lock()
+	defer p.coalesceLock.Unlock()
+	if p.coalescing[pending.key] == pending {
+		delete(p.coalescing, pending.key)
+	}
+}
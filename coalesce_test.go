@@ -0,0 +1,125 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubmitCoalesceMergesPendingSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(4)
+	p.spawnWorkers(1)
+
+	// Occupy the only worker so the coalesced submissions stay pending
+	blocker := make(chan bool)
+	p.Submit(func() {
+		<-blocker
+	})
+
+	var wg sync.WaitGroup
+	var results []int
+	sum := func(old, new int) int {
+		return old + new
+	}
+	handler := func(total int) {
+		results = append(results, total)
+		wg.Done()
+	}
+
+	wg.Add(1)
+	for i := 1; i <= 4; i++ {
+		SubmitCoalesce(p, "entity", i, sum, handler)
+	}
+	close(blocker)
+	wg.Wait()
+	assert.Equal(t, []int{10}, results)
+
+	// Once executed, the key is no longer pending, so the next submission runs on its own
+	wg.Add(1)
+	SubmitCoalesce(p, "entity", 5, sum, handler)
+	wg.Wait()
+	assert.Equal(t, []int{10, 5}, results)
+
+	p.Dispose()
+}
+
+func TestSubmitCoalesceKeepsKeysSeparate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(4)
+	p.spawnWorkers(1)
+
+	blocker := make(chan bool)
+	p.Submit(func() {
+		<-blocker
+	})
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	results := map[string]string{}
+	latest := func(old, new string) string {
+		return new
+	}
+	handlerFor := func(key string) func(string) {
+		return func(value string) {
+			lock.Lock()
+			results[key] = value
+			lock.Unlock()
+			wg.Done()
+		}
+	}
+
+	wg.Add(2)
+	SubmitCoalesce(p, "a", "a1", latest, handlerFor("a"))
+	SubmitCoalesce(p, "b", "b1", latest, handlerFor("b"))
+	SubmitCoalesce(p, "a", "a2", latest, handlerFor("a"))
+	close(blocker)
+	wg.Wait()
+
+	assert.Equal(t, map[string]string{"a": "a2", "b": "b1"}, results)
+	p.Dispose()
+}
+
+func TestSubmitCoalesceForgetsRejectedAndPurgedSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	veto := true
+	p, _ := NewWorkerPoolWithOptions(4, WithQueueInspection(),
+		WithSubmitInterceptor(func(key string, info TaskInfo) error {
+			if veto {
+				return errors.New("vetoed")
+			}
+			return nil
+		}))
+
+	results := make(chan int, 2)
+	latest := func(old, new int) int {
+		return new
+	}
+	handler := func(value int) {
+		results <- value
+	}
+
+	// A rejected submission isn't pending, so the next one is enqueued rather than merged into it
+	SubmitCoalesce(p, "entity", 1, latest, handler)
+	veto = false
+	SubmitCoalesce(p, "entity", 2, latest, handler)
+	// Nor is a purged one
+	assert.Equal(t, 1, p.PurgeQueue(func(TaskInfo) bool {
+		return true
+	}))
+	SubmitCoalesce(p, "entity", 3, latest, handler)
+
+	p.spawnWorkers(1)
+	select {
+	case value := <-results:
+		assert.Equal(t, 3, value)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the last submission to execute")
+	}
+	assert.Len(t, results, 0)
+	p.Dispose()
+}
@@ -0,0 +1,181 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// Config is the part of a manager's configuration which can be changed at runtime with UpdateConfig
+type Config struct {
+	// PoolSize is the max number of workers for each key
+	PoolSize int
+	// StalePoolExpiration is how long to cache unused pools for
+	StalePoolExpiration time.Duration
+	// MaxPoolLifetime is the max time to allow pools to live
+	MaxPoolLifetime time.Duration
+	// QueueCapacity is how many tasks each pool queues before Submit blocks. Zero means PoolSize, see
+	// WithQueueCapacity.
+	QueueCapacity int
+	// ThrottleStarts and ThrottleInterval rate limit each pool, see WithThrottle. Zero ThrottleStarts means no limit.
+	ThrottleStarts   int
+	ThrottleInterval time.Duration
+}
+
+// ErrInvalidConfig is returned by UpdateConfig for configurations which would build pools that can't execute work.
+// The returned error wraps it with the offending field.
+var ErrInvalidConfig = errors.New("invalid config")
+
+// Validate checks that c can be applied with UpdateConfig: PoolSize must be at least 1, and QueueCapacity and
+// ThrottleStarts can't be negative
+func (c Config) Validate() error {
+	if c.PoolSize < 1 {
+		return fmt.Errorf("%w: PoolSize must be at least 1, got %d", ErrInvalidConfig, c.PoolSize)
+	}
+	if c.QueueCapacity < 0 {
+		return fmt.Errorf("%w: QueueCapacity can't be negative, got %d", ErrInvalidConfig, c.QueueCapacity)
+	}
+	if c.ThrottleStarts < 0 {
+		return fmt.Errorf("%w: ThrottleStarts can't be negative, got %d", ErrInvalidConfig, c.ThrottleStarts)
+	}
+	return nil
+}
+
+// ManagerConfig is a manager's effective configuration, as reported by Config
+type ManagerConfig struct {
+	// Config is the configuration applied to pools built from now on, including any changes made by UpdateConfig
+	Config
+	// Name is the manager's name, see WithName
+	Name string
+	// Features lists the optional behaviors enabled by the manager's options, such as "watchdog" or "priority queue",
+	// in alphabetical order
+	Features []string
+}
+
+// WithQueueCapacity sets how many tasks each pool queues before Submit blocks, instead of one per worker
+func WithQueueCapacity(capacity int) Option {
+	return func(o *options) {
+		o.queueCapacity = capacity
+	}
+}
+
+// UpdateConfig replaces the manager's configuration, so config pushes can be applied without restarting. Every field
+// of config is applied, so callers changing a single setting should pass the rest unchanged.
+//
+// Pools built from now on use the new configuration. Cached pools are updated as follows:
+//
+// * PoolSize is reconciled straight away, as described for SetPoolSize
+// * StalePoolExpiration and MaxPoolLifetime apply from the pool's next use
+// * QueueCapacity and the throttle can't be changed on a running pool, so cached pools keep theirs until they expire
+// or are rotated
+//
+// Pools built with their own options by NewWorkerPoolWithOptions keep those options. Configurations which fail
+// Validate are rejected with its error, leaving the manager's configuration as it was, so a bad config push can't
+// stop the manager executing work.
+func (m *WorkerPoolManager) UpdateConfig(config Config) error {
+	if err := config.Validate(); err != nil {
+		return err
+	}
+	m.poolReservationLock.Lock()
+	m.stalePoolExpiration = config.StalePoolExpiration
+	m.maxPoolLifetime = config.MaxPoolLifetime
+
+	// Cached pools hold on to the options they were configured with, so they're copied rather than changed
+	poolOptions := *m.poolOptions
+	poolOptions.queueCapacity = config.QueueCapacity
+	poolOptions.throttleStarts = config.ThrottleStarts
+	poolOptions.throttleInterval = config.ThrottleInterval
+	m.poolOptions = &poolOptions
+	m.poolReservationLock.Unlock()
+
+	m.SetPoolSize(config.PoolSize)
+	return nil
+}
+
+// Config returns the manager's effective configuration, for display by operational tooling and debug endpoints
+func (m *WorkerPoolManager) Config() ManagerConfig {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	return ManagerConfig{
+		Config: Config{
+			PoolSize:            m.workerPoolMaxSize,
+			StalePoolExpiration: m.stalePoolExpiration,
+			MaxPoolLifetime:     m.maxPoolLifetime,
+			QueueCapacity:       m.poolOptions.queueCapacity,
+			ThrottleStarts:      m.poolOptions.throttleStarts,
+			ThrottleInterval:    m.poolOptions.throttleInterval,
+		},
+		Name:     m.options.name,
+		Features: m.poolOptions.features(),
+	}
+}
+
+// The names of the optional behaviors o enables, besides those covered by Config
+func (o *options) features() []string {
+	enabled := map[string]bool{
+		"metrics":              o.metrics != nil,
+		"metric key limit":     o.metricKeys != nil,
+		"pool description":     o.describe != nil,
+		"debug":                o.debug,
+		"panic stacks":         o.panicStacks,
+		"condvar dispatch":     o.condvarDispatch,
+		"queue inspection":     o.queueInspection,
+		"custom clock":         o.clock != realClock{},
+		"worker init":          o.workerInit != nil,
+		"worker teardown":      o.workerTeardown != nil,
+		"worker loop":          o.workerLoop != nil,
+		"label limits":         len(o.labelLimits) > 0,
+		"watchdog":             o.watchdog != nil,
+		"panic recovery":       o.recoverPanics,
+		"quarantine":           o.quarantine != nil,
+		"disposal queue":       o.disposal != nil,
+		"disposal timeout":     o.disposalTimeout > 0,
+		"leak detection":       o.leakThreshold > 0,
+		"factory middleware":   len(o.factoryMiddleware) > 0,
+		"submit interceptors":  len(o.interceptors) > 0,
+		"load shedding":        o.loadShedder != nil,
+		"size resolver":        o.sizeResolver != nil,
+		"baggage":              o.baggage != nil,
+		"growth policy":        o.growthPolicy != GrowthExact,
+		"zero send size":       o.zeroSendSize != ZeroSendSizeAllowed,
+		"without max lifetime": o.withoutMaxLifetime,
+		"drain before rotate":  o.drainBeforeRotate,
+		"max checkouts":        o.maxCheckouts > 0,
+		"faults":               o.faults != nil,
+		"throttle store":       o.throttleStore != nil,
+		"failure backoff":      o.failureBackoffMax > 0,
+		"auto pause":           o.autoPause != nil,
+		"burst":                o.burstWorkers > 0,
+		"autoscaling":          o.autoscalePolicy != nil,
+		"leases":               o.leases != nil,
+		"shared fleet":         o.fleetWorkers > 0,
+		"multiplexed dispatch": o.multiplexed,
+		"checkout limit":       o.checkoutLimit > 0,
+		"durable tasks":        o.durable != nil,
+		"memory budget":        o.memoryBudget != nil,
+		"task sampling":        o.sampling != nil,
+		"reserved workers":     o.reservedFraction > 0,
+		"fair share":           o.fairShare != nil && (o.fleetWorkers > 0 || o.multiplexed),
+		o.queueOrder:           o.queueOrder != "",
+	}
+	var features []string
+	for feature, on := range enabled {
+		if on {
+			features = append(features, feature)
+		}
+	}
+	sort.Strings(features)
+	return features
+}
+
+// The TTL to cache pools with. It's not thread-safe, lock above this
+func (m *WorkerPoolManager) cacheTTL(key string) time.Duration {
+	expiration := m.staleExpirationFor(key)
+	if m.clockDrivenExpiry() || expiration <= 0 {
+		return ttlcache.NoTTL
+	}
+	return expiration
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestUpdateConfig(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 10)
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- eviction
+		},
+	}))
+	cached, doneUsing := pm.GetPool("cached", 1)
+	close(doneUsing)
+
+	assert.Nil(t, pm.UpdateConfig(Config{
+		PoolSize:            4,
+		StalePoolExpiration: 20 * time.Millisecond,
+		MaxPoolLifetime:     time.Hour,
+		QueueCapacity:       10,
+		ThrottleStarts:      100,
+		ThrottleInterval:    time.Second,
+	}))
+
+	built, doneUsing := pm.GetPool("built", 1)
+	close(doneUsing)
+	assert.Equal(t, 10, built.(*BaseWorkerPool).queue.cap())
+	assert.NotNil(t, built.(*BaseWorkerPool).pacer)
+
+	// Cached pools keep their queue and throttle, but pick up the new size and expiration on their next use
+	reused, doneUsing := pm.GetPool("cached", 4)
+	close(doneUsing)
+	assert.Same(t, cached, reused)
+	assert.Equal(t, 2, cached.(*BaseWorkerPool).queue.cap())
+	assert.Nil(t, cached.(*BaseWorkerPool).pacer)
+	assert.Equal(t, 4, pm.Snapshot()["cached"].Workers)
+
+	expired := map[string]EvictionReason{}
+	for i := 0; i < 2; i++ {
+		eviction := <-evictions
+		expired[eviction.Key] = eviction.Reason
+	}
+	assert.Equal(t, map[string]EvictionReason{
+		"cached": EvictionReasonExpired,
+		"built":  EvictionReasonExpired,
+	}, expired)
+	pm.Dispose()
+}
+
+func TestConfigReportsEffectiveConfiguration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Minute, time.Hour,
+		WithName("sends"), WithThrottle(10, time.Second), WithWatchdog(Watchdog{Threshold: time.Minute}),
+		WithPriorityQueue(time.Second), WithQuarantine(Quarantine{Panics: 1, Window: time.Minute}),
+	)
+	defer pm.Dispose()
+
+	config := pm.Config()
+	assert.Equal(t, Config{
+		PoolSize:            2,
+		StalePoolExpiration: time.Minute,
+		MaxPoolLifetime:     time.Hour,
+		ThrottleStarts:      10,
+		ThrottleInterval:    time.Second,
+	}, config.Config)
+	assert.Equal(t, "sends", config.Name)
+	assert.Equal(t, []string{"panic recovery", "priority queue", "quarantine", "watchdog"}, config.Features)
+
+	updated := config.Config
+	updated.PoolSize = 4
+	updated.QueueCapacity = 8
+	assert.Nil(t, pm.UpdateConfig(updated))
+	assert.Equal(t, updated, pm.Config().Config)
+}
+
+func TestUpdateConfigRejectsInvalidConfigs(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Minute, time.Hour)
+	defer pm.Dispose()
+	valid := pm.Config().Config
+	for _, invalid := range []Config{
+		{PoolSize: 0, StalePoolExpiration: time.Minute, MaxPoolLifetime: time.Hour},
+		{PoolSize: -1, StalePoolExpiration: time.Minute, MaxPoolLifetime: time.Hour},
+		{PoolSize: 2, QueueCapacity: -1},
+		{PoolSize: 2, ThrottleStarts: -1},
+	} {
+		err := pm.UpdateConfig(invalid)
+		assert.ErrorIs(t, err, ErrInvalidConfig)
+		assert.Equal(t, valid, pm.Config().Config)
+	}
+	assert.EqualError(t, pm.UpdateConfig(Config{}), "invalid config: PoolSize must be at least 1, got 0")
+}
@@ -0,0 +1,255 @@
+// Package consumer dispatches the messages of a queue or stream, such as a Kafka topic, to the pools of a
+// pool.WorkerPoolManager by key, handling messages with the same sub-key one at a time in the order they're received.
+// It's written against the small Source interface rather than a client library, so using it doesn't add dependencies.
+package consumer
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// Message is a message received from a Source
+type Message struct {
+	// Key picks the pool the message is handled on, e.g. a tenant's ID
+	Key string
+	// SubKey orders the key's messages: those with the same key and sub-key are handled one at a time, in the order
+	// they're received, e.g. a Kafka partition or an entity ID. Messages without a sub-key aren't ordered.
+	SubKey string
+	Value  interface{}
+	// Ack is called with the handler's error once the message has been handled, e.g. to commit its offset. It may be
+	// nil.
+	Ack func(err error)
+}
+
+// Source is where a consumer receives its messages from, e.g. an adapter around a Kafka consumer group's client
+type Source interface {
+	// Receive blocks until the next message arrives, returning io.EOF once the source has no more, or another error if
+	// it fails or ctx is done
+	Receive(ctx context.Context) (Message, error)
+}
+
+// ChannelSource returns a Source receiving from messages, which returns io.EOF once messages is closed
+func ChannelSource(messages <-chan Message) Source {
+	return channelSource(messages)
+}
+
+type channelSource <-chan Message
+
+func (s channelSource) Receive(ctx context.Context) (Message, error) {
+	select {
+	case msg, ok := <-s:
+		if !ok {
+			return Message{}, io.EOF
+		}
+		return msg, nil
+	case <-ctx.Done():
+		return Message{}, ctx.Err()
+	}
+}
+
+// Handler handles a message on its key's pool
+type Handler func(ctx context.Context, msg Message) error
+
+// Config configures Run
+type Config struct {
+	Manager *pool.WorkerPoolManager
+	Source  Source
+	Handler Handler
+	// SendSize is passed to GetPoolContext for each message, spawning up to that many workers for its key, 1 if unset
+	SendSize int
+	// MaxInFlight caps how many received messages may be waiting or being handled at once, which holds up receiving
+	// more. Unset, receiving is only held up by full pool queues - messages waiting on an earlier one with the same
+	// sub-key aren't queued, so a busy sub-key can build up a backlog in memory.
+	MaxInFlight int
+}
+
+// Run receives messages from the source until it returns an error, or ctx is done, and dispatches each one to its
+// key's pool, checked out with GetPoolContext, to be handled by the handler. Messages with the same key and sub-key are
+// handled one at a time, on a single task which occupies one of the pool's workers until they've caught up, while
+// different sub-keys are handled concurrently.
+//
+// Once receiving stops, Run waits for the messages it has received to be handled, and returns nil if the source ran
+// out of messages, or else the source's error. Messages which the pool rejects, e.g. because their key is blocked,
+// or whose pool can't be checked out, are acked with that error without being handled.
+func Run(ctx context.Context, config Config) error {
+	c := &consumer{
+		config:  config,
+		ctx:     ctx,
+		lock:    &sync.Mutex{},
+		chains:  make(map[chainKey][]Message),
+		pending: &sync.WaitGroup{},
+	}
+	if c.config.SendSize < 1 {
+		c.config.SendSize = 1
+	}
+	if config.MaxInFlight > 0 {
+		c.inFlight = make(chan bool, config.MaxInFlight)
+	}
+
+	for {
+		msg, err := config.Source.Receive(ctx)
+		if err != nil {
+			c.pending.Wait()
+			if errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		if c.inFlight != nil {
+			select {
+			case c.inFlight <- true:
+			case <-ctx.Done():
+				c.ack(msg, ctx.Err())
+				c.pending.Wait()
+				return ctx.Err()
+			}
+		}
+		c.pending.Add(1)
+		c.dispatch(msg)
+	}
+}
+
+// The messages with the same key and sub-key, which are handled in order
+type chainKey struct {
+	key    string
+	subKey string
+}
+
+type consumer struct {
+	config Config
+	ctx    context.Context
+	// A token for each message in flight, with MaxInFlight
+	inFlight chan bool
+	// Messages received but not handled yet
+	pending *sync.WaitGroup
+
+	lock *sync.Mutex
+	// The messages waiting on the message being handled for each chain. A chain is only present while one of its
+	// messages is being handled.
+	chains map[chainKey][]Message
+}
+
+// Hand msg to its key's pool, or to the task handling its chain if it has one
+func (c *consumer) dispatch(msg Message) {
+	if msg.SubKey == "" {
+		c.submit(msg, func() {
+			c.handle(msg)
+		})
+		return
+	}
+
+	chain := chainKey{key: msg.Key, subKey: msg.SubKey}
+	c.lock.Lock()
+	if waiting, ok := c.chains[chain]; ok {
+		c.chains[chain] = append(waiting, msg)
+		c.lock.Unlock()
+		return
+	}
+	c.chains[chain] = nil
+	c.lock.Unlock()
+	c.submit(msg, func() {
+		c.handleChain(chain, msg)
+	})
+}
+
+// Submit the task handling msg to its key's pool, acking msg with the pool's error if it's rejected or can't be
+// checked out
+func (c *consumer) submit(msg Message, handle func()) {
+	p, doneUsing, err := c.config.Manager.GetPoolContext(c.ctx, msg.Key, c.config.SendSize, nil)
+	if err == nil {
+		err = pool.SubmitTask(p, pool.TaskInfo{}, func() {
+			defer close(doneUsing)
+			handle()
+		})
+		if err == nil {
+			return
+		}
+		close(doneUsing)
+	}
+	if msg.SubKey == "" {
+		c.reject(msg, err)
+		return
+	}
+	// The rest of the chain is rejected along with it, as its order can't be kept otherwise
+	chain := chainKey{key: msg.Key, subKey: msg.SubKey}
+	c.lock.Lock()
+	waiting := c.chains[chain]
+	delete(c.chains, chain)
+	c.lock.Unlock()
+	c.reject(msg, err)
+	for _, msg := range waiting {
+		c.reject(msg, err)
+	}
+}
+
+// Handle msg, then the messages of its chain which arrived meanwhile, until the chain has caught up
+func (c *consumer) handleChain(chain chainKey, msg Message) {
+	caughtUp := false
+	defer func() {
+		if !caughtUp {
+			// The handler panicked, and the pool recovered it, so the rest of the chain goes on in a new task
+			go c.resume(chain)
+		}
+	}()
+	for {
+		c.handle(msg)
+		next, ok := c.next(chain)
+		if !ok {
+			caughtUp = true
+			return
+		}
+		msg = next
+	}
+}
+
+// Submit a task handling the rest of chain, after the task handling it stopped, if there's anything left
+func (c *consumer) resume(chain chainKey) {
+	next, ok := c.next(chain)
+	if !ok {
+		return
+	}
+	c.submit(next, func() {
+		c.handleChain(chain, next)
+	})
+}
+
+// The next message waiting in chain, or false once it has caught up, in which case it's removed
+func (c *consumer) next(chain chainKey) (Message, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	waiting := c.chains[chain]
+	if len(waiting) == 0 {
+		delete(c.chains, chain)
+		return Message{}, false
+	}
+	c.chains[chain] = waiting[1:]
+	return waiting[0], true
+}
+
+func (c *consumer) handle(msg Message) {
+	defer c.done()
+	c.ack(msg, c.config.Handler(c.ctx, msg))
+}
+
+func (c *consumer) reject(msg Message, err error) {
+	defer c.done()
+	c.ack(msg, err)
+}
+
+// Account for a message having been handled or rejected
+func (c *consumer) done() {
+	if c.inFlight != nil {
+		<-c.inFlight
+	}
+	c.pending.Done()
+}
+
+func (c *consumer) ack(msg Message, err error) {
+	if msg.Ack != nil {
+		msg.Ack(err)
+	}
+}
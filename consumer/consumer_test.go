@@ -0,0 +1,133 @@
+package consumer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+func TestRunHandlesEachSubKeysMessagesInOrder(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(4, time.Hour, time.Hour)
+	defer pm.Dispose()
+	pm.Block("blocked")
+
+	messages := make(chan Message, 100)
+	lock := &sync.Mutex{}
+	handled := make(map[string][]int)
+	acks := make(map[string]error)
+	for i := 0; i < 20; i++ {
+		for _, key := range []string{"a", "b"} {
+			msg := Message{Key: key, SubKey: fmt.Sprint(i % 3), Value: i}
+			id := fmt.Sprintf("%s/%d", key, i)
+			msg.Ack = func(err error) {
+				lock.Lock()
+				defer lock.Unlock()
+				acks[id] = err
+			}
+			messages <- msg
+		}
+	}
+	messages <- Message{Key: "blocked", SubKey: "0", Ack: func(err error) {
+		lock.Lock()
+		defer lock.Unlock()
+		acks["blocked"] = err
+	}}
+	close(messages)
+
+	err := Run(context.Background(), Config{
+		Manager: pm,
+		Source:  ChannelSource(messages),
+		Handler: func(ctx context.Context, msg Message) error {
+			time.Sleep(time.Duration(msg.Value.(int)%4) * time.Millisecond)
+			lock.Lock()
+			defer lock.Unlock()
+			subKey := msg.Key + "/" + msg.SubKey
+			handled[subKey] = append(handled[subKey], msg.Value.(int))
+			if msg.Value.(int) == 7 {
+				return errors.New("handler failed")
+			}
+			return nil
+		},
+		SendSize:    4,
+		MaxInFlight: 10,
+	})
+	assert.Nil(t, err)
+
+	for _, key := range []string{"a", "b"} {
+		for subKey := 0; subKey < 3; subKey++ {
+			var expected []int
+			for i := subKey; i < 20; i += 3 {
+				expected = append(expected, i)
+			}
+			assert.Equal(t, expected, handled[fmt.Sprintf("%s/%d", key, subKey)])
+		}
+	}
+	assert.Len(t, acks, 41)
+	assert.EqualError(t, acks["a/7"], "handler failed")
+	assert.Nil(t, acks["a/8"])
+	assert.Equal(t, pool.ErrKeyBlocked, acks["blocked"])
+}
+
+func TestRunReturnsTheSourcesError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	messages := make(chan Message, 1)
+	handled := make(chan bool, 1)
+	messages <- Message{Key: "key"}
+	err := Run(ctx, Config{
+		Manager: pm,
+		Source:  ChannelSource(messages),
+		Handler: func(ctx context.Context, msg Message) error {
+			handled <- true
+			cancel()
+			return nil
+		},
+	})
+	assert.Equal(t, context.Canceled, err)
+	assert.Len(t, handled, 1)
+}
+
+func TestRunAcksMessagesWhosePoolCantBeCheckedOut(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+	failure := errors.New("failure")
+	assert.Nil(t, pm.RegisterFactory("failing", func(int) (pool.WorkerPool, error) {
+		return nil, failure
+	}))
+
+	messages := make(chan Message, 2)
+	acks := make(chan error, 2)
+	for _, subKey := range []string{"", "0"} {
+		messages <- Message{Key: "failing", SubKey: subKey, Ack: func(err error) {
+			acks <- err
+		}}
+	}
+	close(messages)
+	err := Run(context.Background(), Config{
+		Manager: pm,
+		Source:  ChannelSource(messages),
+		Handler: func(ctx context.Context, msg Message) error {
+			t.Error("handled a message whose pool can't be built")
+			return nil
+		},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, failure, <-acks)
+	assert.Equal(t, failure, <-acks)
+}
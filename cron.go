@@ -0,0 +1,132 @@
+package pool
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronSchedule is a parsed cron expression, with a bit set for each field
+type cronSchedule struct {
+	minute, hour, dayOfMonth, month, dayOfWeek uint64
+	// Whether the day fields were "*", which changes how they combine
+	anyDayOfMonth, anyDayOfWeek bool
+}
+
+var cronShorthands = map[string]string{
+	"@hourly":  "0 * * * *",
+	"@daily":   "0 0 * * *",
+	"@weekly":  "0 0 * * 0",
+	"@monthly": "0 0 1 * *",
+	"@yearly":  "0 0 1 1 *",
+}
+
+// Parse a standard five field cron expression - minute, hour, day of month, month and day of week - where each field
+// is "*" or a comma separated list of numbers and ranges, optionally stepped, e.g. "*/15", "1-5" or "0,30"
+func parseCron(spec string) (*cronSchedule, error) {
+	if expanded, ok := cronShorthands[spec]; ok {
+		spec = expanded
+	}
+	fields := strings.Fields(spec)
+	if len(fields) != 5 {
+		return nil, fmt.Errorf("pool: cron expression %q doesn't have 5 fields", spec)
+	}
+
+	s := &cronSchedule{anyDayOfMonth: fields[2] == "*", anyDayOfWeek: fields[4] == "*"}
+	for _, field := range []struct {
+		bits     *uint64
+		min, max int
+	}{
+		{&s.minute, 0, 59},
+		{&s.hour, 0, 23},
+		{&s.dayOfMonth, 1, 31},
+		{&s.month, 1, 12},
+		// Sunday is both 0 and 7
+		{&s.dayOfWeek, 0, 7},
+	} {
+		bits, err := parseCronField(fields[0], field.min, field.max)
+		if err != nil {
+			return nil, fmt.Errorf("pool: cron expression %q: %w", spec, err)
+		}
+		*field.bits = bits
+		fields = fields[1:]
+	}
+	if s.dayOfWeek&(1<<7) != 0 {
+		s.dayOfWeek |= 1
+	}
+	return s, nil
+}
+
+func parseCronField(field string, min int, max int) (uint64, error) {
+	var bits uint64
+	for _, item := range strings.Split(field, ",") {
+		rangePart, step := item, 1
+		if i := strings.IndexByte(item, '/'); i >= 0 {
+			var err error
+			rangePart = item[:i]
+			if step, err = strconv.Atoi(item[i+1:]); err != nil || step < 1 {
+				return 0, fmt.Errorf("invalid step in %q", item)
+			}
+		}
+
+		low, high := min, max
+		if rangePart != "*" {
+			var err error
+			bounds := strings.SplitN(rangePart, "-", 2)
+			if low, err = strconv.Atoi(bounds[0]); err != nil {
+				return 0, fmt.Errorf("invalid value %q", item)
+			}
+			high = low
+			if len(bounds) == 2 {
+				if high, err = strconv.Atoi(bounds[1]); err != nil {
+					return 0, fmt.Errorf("invalid range %q", item)
+				}
+			} else if step > 1 {
+				// "5/15" means from 5 onwards
+				high = max
+			}
+		}
+		if low < min || high > max || low > high {
+			return 0, fmt.Errorf("%q is out of range %d-%d", item, min, max)
+		}
+		for v := low; v <= high; v += step {
+			bits |= 1 << uint(v)
+		}
+	}
+	return bits, nil
+}
+
+// The first minute matching the schedule which is strictly after t, or the zero time if there isn't one in the next
+// five years, e.g. for February 30th
+func (s *cronSchedule) next(t time.Time) time.Time {
+	loc := t.Location()
+	t = t.Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		year, month, day := t.Date()
+		switch {
+		case s.month&(1<<uint(month)) == 0:
+			t = time.Date(year, month+1, 1, 0, 0, 0, 0, loc)
+		case !s.dayMatches(t):
+			t = time.Date(year, month, day+1, 0, 0, 0, 0, loc)
+		case s.hour&(1<<uint(t.Hour())) == 0:
+			t = time.Date(year, month, day, t.Hour()+1, 0, 0, 0, loc)
+		case s.minute&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// As in standard cron, when both day fields are restricted a day matching either of them matches
+func (s *cronSchedule) dayMatches(t time.Time) bool {
+	dayOfMonth := s.dayOfMonth&(1<<uint(t.Day())) != 0
+	dayOfWeek := s.dayOfWeek&(1<<uint(t.Weekday())) != 0
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return dayOfMonth && dayOfWeek
+	}
+	return dayOfMonth || dayOfWeek
+}
@@ -0,0 +1,43 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCronNext(t *testing.T) {
+	// A Wednesday
+	start := time.Date(2020, time.January, 1, 8, 30, 0, 0, time.UTC)
+	for _, test := range []struct {
+		spec string
+		want time.Time
+	}{
+		{"* * * * *", time.Date(2020, time.January, 1, 8, 31, 0, 0, time.UTC)},
+		{"0 9 * * *", time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)},
+		{"30 8 * * *", time.Date(2020, time.January, 2, 8, 30, 0, 0, time.UTC)},
+		{"*/20 * * * *", time.Date(2020, time.January, 1, 8, 40, 0, 0, time.UTC)},
+		{"5/20 * * * *", time.Date(2020, time.January, 1, 8, 45, 0, 0, time.UTC)},
+		{"0 9 * * 6,7", time.Date(2020, time.January, 4, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2020, time.January, 1, 9, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2020, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		// Restricting both day fields matches either
+		{"0 0 15 * 5", time.Date(2020, time.January, 3, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2020, time.February, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 0 30 2 *", time.Time{}},
+	} {
+		schedule, err := parseCron(test.spec)
+		if assert.NoError(t, err, test.spec) {
+			assert.Equal(t, test.want, schedule.next(start), test.spec)
+		}
+	}
+}
+
+func TestCronRejectsInvalidExpressions(t *testing.T) {
+	for _, spec := range []string{"", "* * * *", "60 * * * *", "* 24 * * *", "* * 0 * *", "*/0 * * * *", "a * * * *",
+		"5-1 * * * *"} {
+		_, err := parseCron(spec)
+		assert.Error(t, err, spec)
+	}
+}
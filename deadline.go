@@ -0,0 +1,24 @@
+package pool
+
+import "math"
+
+// WithDeadlineQueue makes pools execute queued tasks earliest TaskInfo.Deadline first, instead of in submission order,
+// so time-sensitive tasks overtake those with looser deadlines in the same pool. Tasks without a deadline execute after
+// every task with one, and tasks with equal deadlines execute in submission order.
+//
+// Ordering is all that changes: tasks whose deadline has passed still execute, ahead of everything else. It replaces
+// WithPriorityQueue and WithSubQueues if they're passed too, and is replaced by them if they're passed after it.
+func WithDeadlineQueue() Option {
+	return func(o *options) {
+		o.rankTasks = deadlineRank
+		o.subQueues = nil
+		o.queueOrder = "deadline queue"
+	}
+}
+
+func deadlineRank(t task) int64 {
+	if t.info.Deadline.IsZero() {
+		return math.MaxInt64
+	}
+	return t.info.Deadline.UnixNano()
+}
@@ -0,0 +1,74 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestDeadlineQueueOrdersEarliestDeadlineFirst(t *testing.T) {
+	q := newRankedQueue(10, deadlineRank)
+	now := time.Now()
+	q.push(task{info: TaskInfo{Label: "none"}})
+	q.push(task{info: TaskInfo{Label: "later", Deadline: now.Add(time.Minute)}})
+	q.push(task{info: TaskInfo{Label: "soon", Deadline: now.Add(time.Second)}})
+	q.push(task{info: TaskInfo{Label: "none again"}})
+	q.push(task{info: TaskInfo{Label: "missed", Deadline: now.Add(-time.Second)}})
+	q.push(task{info: TaskInfo{Label: "also later", Deadline: now.Add(time.Minute)}})
+	assert.Equal(t, []string{"missed", "soon", "later", "also later", "none", "none again"}, popLabels(q))
+}
+
+func TestDeadlineQueueInPool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, 10*time.Hour, WithDeadlineQueue(), WithQueueCapacity(10))
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	release := make(chan bool)
+	started := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	now := time.Now()
+	for _, send := range []struct {
+		label    string
+		deadline time.Time
+	}{
+		{"digest", now.Add(time.Hour)},
+		{"password reset", now.Add(10 * time.Second)},
+		{"newsletter", time.Time{}},
+		{"receipt", now.Add(time.Minute)},
+	} {
+		label := send.label
+		wg.Add(1)
+		assert.NoError(t, SubmitTask(pool, TaskInfo{Label: label, Deadline: send.deadline}, func() {
+			lock.Lock()
+			order = append(order, label)
+			lock.Unlock()
+			wg.Done()
+		}))
+	}
+	close(release)
+	wg.Wait()
+	assert.Equal(t, []string{"password reset", "receipt", "digest", "newsletter"}, order)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestLastQueueOrderWins(t *testing.T) {
+	o := newOptions([]Option{WithDeadlineQueue(), WithPriorityQueue(0)})
+	q := newRankedQueue(10, o.rankTasks)
+	q.push(task{info: TaskInfo{Label: "urgent", Deadline: time.Now()}})
+	q.push(task{info: TaskInfo{Label: "important", Priority: 1}})
+	assert.Equal(t, []string{"important", "urgent"}, popLabels(q))
+}
@@ -0,0 +1,89 @@
+package pool
+
+import "time"
+
+// debouncedWork is a debounced submission waiting for its quiet period to elapse before being enqueued
+type debouncedWork struct {
+	work     Work
+	first    time.Time
+	deadline time.Time
+	timer    Timer
+	// Where the pending Work was submitted, only recorded in debug mode
+	site *CallSite
+}
+
+// nextDeadline is when the pending Work should be enqueued following a submission at now
+func (d *debouncedWork) nextDeadline(now time.Time, quietPeriod time.Duration, maxWait time.Duration) time.Time {
+	deadline := now.Add(quietPeriod)
+	if limit := d.first.Add(maxWait); maxWait > 0 && deadline.After(limit) {
+		return limit
+	}
+	return deadline
+}
+
+// SubmitDebounce submits w to be executed once submissions for debounceKey have been quiet for quietPeriod.
+//
+// Each submission for a debounceKey which hasn't been enqueued yet replaces the pending Work and pushes its execution
+// back by quietPeriod, so a burst of submissions results in a single execution of the most recent Work. When maxWait is
+// positive, the pending Work is enqueued no later than maxWait after the first submission of the burst, even if
+// submissions never go quiet. A maxWait of zero means there is no bound.
+//
+// Debounced Work which is still waiting when the pool is disposed is dropped.
+func SubmitDebounce(p WorkerPool, debounceKey string, quietPeriod time.Duration, maxWait time.Duration, w Work) {
+	p.submitDebounce(debounceKey, quietPeriod, maxWait, w)
+}
+
+func (p *BaseWorkerPool) submitDebounce(debounceKey string, quietPeriod time.Duration, maxWait time.Duration, w Work) {
+	now := p.clock.Now()
+	var site *CallSite
+	if p.debugging() {
+		site = submitSite()
+	}
+
+	p.debounceLock.Lock()
+	defer p.debounceLock.Unlock()
+
+	if pending, ok := p.debouncing[debounceKey]; ok {
+		// The timer reschedules itself when it fires before the deadline, so only the deadline needs moving
+		pending.work = w
+		pending.site = site
+		pending.deadline = pending.nextDeadline(now, quietPeriod, maxWait)
+		return
+	}
+
+	pending := &debouncedWork{work: w, first: now, site: site}
+	pending.deadline = pending.nextDeadline(now, quietPeriod, maxWait)
+	p.debouncing[debounceKey] = pending
+	pending.timer = p.clock.AfterFunc(pending.deadline.Sub(now), func() {
+		p.fireDebounce(debounceKey, pending)
+	})
+}
+
+func (p *BaseWorkerPool) fireDebounce(debounceKey string, pending *debouncedWork) {
+	p.debounceLock.Lock()
+	if p.debouncing[debounceKey] != pending {
+		p.debounceLock.Unlock()
+		return
+	}
+	if remaining := pending.deadline.Sub(p.clock.Now()); remaining > 0 {
+		pending.timer.Reset(remaining)
+		p.debounceLock.Unlock()
+		return
+	}
+	delete(p.debouncing, debounceKey)
+	p.debounceLock.Unlock()
+
+	// Submitting from the timer would record it as the submit site
+	_ = p.enqueue(task{work: pending.work, site: pending.site})
+}
+
+// stopDebouncing drops all debounced Work which hasn't been enqueued yet
+func (p *BaseWorkerPool) stopDebouncing() {
+	p.debounceLock.Lock()
+	defer p.debounceLock.Unlock()
+
+	for debounceKey, pending := range p.debouncing {
+		pending.timer.Stop()
+		delete(p.debouncing, debounceKey)
+	}
+}
@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubmitDebounceExecutesOnceAfterQuietPeriod(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(2)
+	p.spawnWorkers(2)
+
+	var executions int32
+	executed := make(chan int, 10)
+	for i := 0; i < 5; i++ {
+		i := i
+		SubmitDebounce(p, "segment", 30*time.Millisecond, 0, func() {
+			atomic.AddInt32(&executions, 1)
+			executed <- i
+		})
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	assert.Equal(t, 4, <-executed)
+	time.Sleep(40 * time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&executions))
+
+	p.Dispose()
+}
+
+func TestSubmitDebounceRespectsMaxWait(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(2)
+	p.spawnWorkers(2)
+
+	executed := make(chan bool, 10)
+	start := time.Now()
+	stop := time.After(150 * time.Millisecond)
+	var firstExecution time.Duration
+loop:
+	for {
+		select {
+		case <-stop:
+			break loop
+		case <-executed:
+			if firstExecution == 0 {
+				firstExecution = time.Since(start)
+			}
+		default:
+			SubmitDebounce(p, "segment", 40*time.Millisecond, 60*time.Millisecond, func() {
+				executed <- true
+			})
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	assert.NotZero(t, firstExecution, "Expected maxWait to force an execution despite constant submissions")
+	assert.Less(t, firstExecution, 120*time.Millisecond)
+
+	p.Dispose()
+}
+
+func TestDisposeDropsPendingDebouncedWork(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(1)
+	p.spawnWorkers(1)
+
+	var executions int32
+	SubmitDebounce(p, "segment", 10*time.Millisecond, 0, func() {
+		atomic.AddInt32(&executions, 1)
+	})
+	p.Dispose()
+
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&executions))
+}
@@ -0,0 +1,98 @@
+package pool
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// CallSite is where in the calling code something happened, e.g. where a task was submitted
+type CallSite struct {
+	Function string
+	File     string
+	Line     int
+}
+
+func (s CallSite) String() string {
+	return fmt.Sprintf("%s:%d (%s)", s.File, s.Line, s.Function)
+}
+
+// WithDebug enables debug mode, which records the call site of every submission so reports about a task can say
+// who queued it. Submit sites are included in panic and stuck task reports, and queue wait latencies are broken down
+// by submit site in PoolSnapshot.QueueWaitBySite. Recording the call site costs a couple of microseconds per
+// submission - see BenchmarkSubmitDebug.
+func WithDebug() Option {
+	return func(o *options) {
+		o.debug = true
+	}
+}
+
+// WithPanicStacks captures the stack of each panicking task into TaskPanic.Stack. It implies WithPanicRecovery.
+func WithPanicStacks() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+		o.panicStacks = true
+	}
+}
+
+// The directory of this package's source, to tell its frames apart from its callers'
+var packageDir = func() string {
+	_, file, _, _ := runtime.Caller(0)
+	return filepath.Dir(file)
+}()
+
+// The first caller from outside this package, which is where a submission was made
+func submitSite() *CallSite {
+	var pcs [16]uintptr
+	frames := runtime.CallersFrames(pcs[:runtime.Callers(2, pcs[:])])
+	for {
+		frame, more := frames.Next()
+		if filepath.Dir(frame.File) != packageDir || strings.HasSuffix(frame.File, "_test.go") {
+			return &CallSite{Function: frame.Function, File: frame.File, Line: frame.Line}
+		}
+		if !more {
+			return nil
+		}
+	}
+}
+
+func (p *BaseWorkerPool) debugging() bool {
+	return p.options != nil && p.options.debug
+}
+
+// Whether workers note their goroutine ids, for stuck task stacks and for checking dependencies in debug mode
+func (o *options) tracksGoroutines() bool {
+	return o != nil && (o.debug || o.watchdog != nil && o.watchdog.CaptureStack)
+}
+
+func (p *BaseWorkerPool) recordSiteQueueWait(site *CallSite, wait time.Duration) {
+	if site == nil {
+		return
+	}
+	p.siteLock.Lock()
+	if p.siteQueueWait == nil {
+		p.siteQueueWait = make(map[CallSite]*latencyHistogram)
+	}
+	histogram, ok := p.siteQueueWait[*site]
+	if !ok {
+		histogram = &latencyHistogram{}
+		p.siteQueueWait[*site] = histogram
+	}
+	p.siteLock.Unlock()
+	histogram.record(wait)
+}
+
+func (p *BaseWorkerPool) siteQueueWaitPercentiles() map[string]LatencyPercentiles {
+	p.siteLock.Lock()
+	defer p.siteLock.Unlock()
+	if p.siteQueueWait == nil {
+		return nil
+	}
+	percentiles := make(map[string]LatencyPercentiles, len(p.siteQueueWait))
+	for site, histogram := range p.siteQueueWait {
+		percentiles[site.String()] = histogram.percentiles()
+	}
+	return percentiles
+}
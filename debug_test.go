@@ -0,0 +1,99 @@
+package pool
+
+import (
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestPanicReportsIncludeStackAndSubmitSite(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	panics := make(chan TaskPanic, 1)
+	p, _ := NewWorkerPoolWithOptions(1, WithDebug(), WithPanicStacks(), WithHooks(Hooks{
+		OnTaskPanic: func(recovered TaskPanic) {
+			panics <- recovered
+		},
+	}))
+	p.spawnWorkers(1)
+
+	SubmitWithWorkerState(p, func(interface{}) {
+		panicInTask()
+	})
+	recovered := <-panics
+	assert.Contains(t, string(recovered.Stack), "panicInTask")
+	assert.Equal(t, "debug_test.go", filepath.Base(recovered.SubmitSite.File))
+	assert.Contains(t, recovered.SubmitSite.Function, "TestPanicReportsIncludeStackAndSubmitSite")
+	assert.Contains(t, recovered.SubmitSite.String(), "debug_test.go:")
+	p.Dispose()
+}
+
+func panicInTask() {
+	panic("poisoned task")
+}
+
+func TestSubmitSitesOnlyRecordedInDebugMode(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	panics := make(chan TaskPanic, 1)
+	p, _ := NewWorkerPoolWithOptions(1, WithPanicRecovery(), WithHooks(Hooks{
+		OnTaskPanic: func(recovered TaskPanic) {
+			panics <- recovered
+		},
+	}))
+	p.spawnWorkers(1)
+	p.Submit(panicInTask)
+
+	recovered := <-panics
+	assert.Nil(t, recovered.SubmitSite)
+	assert.Nil(t, recovered.Stack)
+	p.Dispose()
+}
+
+func TestDebugModeBreaksDownQueueWaitBySubmitSite(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPoolWithOptions(2, WithDebug())
+	p.spawnWorkers(1)
+
+	var wg sync.WaitGroup
+	wg.Add(3)
+	p.Submit(wg.Done)
+	p.Submit(wg.Done)
+	SubmitDebounce(p, "debounced", time.Millisecond, 0, wg.Done)
+	wg.Wait()
+
+	var files []string
+	for site := range p.snapshot().QueueWaitBySite {
+		files = append(files, filepath.Base(strings.Split(site, ":")[0]))
+	}
+	assert.Equal(t, []string{"debug_test.go", "debug_test.go", "debug_test.go"}, files)
+	p.Dispose()
+}
+
+func TestStuckTaskReportsIncludeSubmitSite(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	reports := make(chan StuckTask, 1)
+	watchdog := WithWatchdog(Watchdog{Threshold: time.Millisecond})
+	p, _ := NewWorkerPoolWithOptions(1, WithDebug(), watchdog, WithHooks(Hooks{
+		OnStuckTask: func(stuck StuckTask) {
+			reports <- stuck
+		},
+	}))
+	p.spawnWorkers(1)
+
+	unblock := make(chan bool)
+	p.Submit(func() {
+		<-unblock
+	})
+	stuck := <-reports
+	assert.Contains(t, stuck.SubmitSite.Function, "TestStuckTaskReportsIncludeSubmitSite")
+	close(unblock)
+	p.Dispose()
+}
@@ -0,0 +1,135 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// ErrDependencyCycle is returned when a dependency between pools would complete a cycle, in which every pool could
+// end up with all its workers waiting on the next one's. The returned error wraps it with the cycle.
+var ErrDependencyCycle = errors.New("pool dependency cycle")
+
+// DeclareDependency declares that tasks in key's pool may synchronously wait on tasks in dependsOn's pool, e.g. with
+// SubmitAndWait. It returns ErrDependencyCycle, without declaring anything, if dependsOn's pool may already end up
+// waiting on key's - so that a trio of pools which would deadlock under load is refused at startup instead.
+//
+// In debug mode, SubmitAndWait also checks the dependencies it's used for as tasks execute, whether they've been
+// declared or not.
+func (m *WorkerPoolManager) DeclareDependency(key string, dependsOn string) error {
+	return m.options.dependencies.add(key, dependsOn)
+}
+
+// SubmitAndWait submits w to p and blocks until it has executed, returning the same errors as SubmitTask if the
+// submission is rejected, or ErrTaskPurged if w is purged from the queue, see PurgeQueue. In debug mode, when it's
+// called by a task executing in another pool of the same manager, that pool's dependency on p is checked as if it
+// had been declared with DeclareDependency, and ErrDependencyCycle is returned instead of waiting if it completes a
+// cycle.
+func SubmitAndWait(p WorkerPool, w Work) error {
+	return p.submitAndWait(w)
+}
+
+func (p *BaseWorkerPool) submitAndWait(w Work) error {
+	if p.debugging() {
+		if caller, ok := p.options.dependencies.caller(currentGoroutine()); ok {
+			if err := p.options.dependencies.add(caller, p.key); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Closed once w has executed, or handed ErrTaskPurged if it never will
+	done := make(chan error, 1)
+	err := p.enqueue(task{work: func() {
+		defer close(done)
+		w()
+	}, dropped: func() {
+		done <- ErrTaskPurged
+	}})
+	if err != nil {
+		return err
+	}
+	return <-done
+}
+
+// dependencyGraph is the dependencies between a manager's pools, by key
+type dependencyGraph struct {
+	lock *sync.Mutex
+	// The keys each key's pool may wait on
+	edges map[string]map[string]bool
+	// The key of the pool each worker goroutine is executing a task for, only tracked in debug mode
+	executing map[uint64]string
+}
+
+func newDependencyGraph() *dependencyGraph {
+	return &dependencyGraph{
+		lock:      &sync.Mutex{},
+		edges:     make(map[string]map[string]bool),
+		executing: make(map[uint64]string),
+	}
+}
+
+// Add a dependency of from's pool on to's, unless it would complete a cycle
+func (g *dependencyGraph) add(from string, to string) error {
+	if g == nil {
+		return nil
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.edges[from][to] {
+		return nil
+	}
+	if path := g.path(to, from, make(map[string]bool)); path != nil {
+		return fmt.Errorf("%w: %s -> %s", ErrDependencyCycle, from, strings.Join(path, " -> "))
+	}
+	if g.edges[from] == nil {
+		g.edges[from] = make(map[string]bool)
+	}
+	g.edges[from][to] = true
+	return nil
+}
+
+// The keys along a chain of dependencies from from's pool to to's, or nil if there isn't one. It's not thread-safe,
+// lock above this.
+func (g *dependencyGraph) path(from string, to string, visited map[string]bool) []string {
+	if from == to {
+		return []string{to}
+	}
+	visited[from] = true
+	for next := range g.edges[from] {
+		if visited[next] {
+			continue
+		}
+		if path := g.path(next, to, visited); path != nil {
+			return append([]string{from}, path...)
+		}
+	}
+	return nil
+}
+
+// Note that goroutine is executing a task for key's pool, returning a func which notes it has finished
+func (g *dependencyGraph) enter(goroutine uint64, key string) func() {
+	if g == nil || goroutine == 0 {
+		return func() {}
+	}
+	g.lock.Lock()
+	g.executing[goroutine] = key
+	g.lock.Unlock()
+	return func() {
+		g.lock.Lock()
+		delete(g.executing, goroutine)
+		g.lock.Unlock()
+	}
+}
+
+// The key of the pool goroutine is executing a task for, if it's a worker
+func (g *dependencyGraph) caller(goroutine uint64) (string, bool) {
+	if g == nil {
+		return "", false
+	}
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	key, ok := g.executing[goroutine]
+	return key, ok
+}
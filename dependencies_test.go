@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestDeclareDependencyRefusesCycles(t *testing.T) {
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+
+	assert.Nil(t, pm.DeclareDependency("api", "renderer"))
+	assert.Nil(t, pm.DeclareDependency("renderer", "templates"))
+	assert.Nil(t, pm.DeclareDependency("api", "renderer"))
+	err := pm.DeclareDependency("templates", "api")
+	assert.True(t, errors.Is(err, ErrDependencyCycle))
+	assert.EqualError(t, err, "pool dependency cycle: templates -> api -> renderer -> templates")
+	assert.EqualError(t, pm.DeclareDependency("api", "api"), "pool dependency cycle: api -> api")
+	// Refused dependencies aren't declared
+	assert.Nil(t, pm.DeclareDependency("templates", "assets"))
+}
+
+func TestSubmitAndWaitChecksDependenciesInDebugMode(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithDebug())
+	api, doneUsing := pm.GetPool("api", 2)
+	defer close(doneUsing)
+	renderer, doneUsing := pm.GetPool("renderer", 2)
+	defer close(doneUsing)
+
+	rendered := false
+	assert.Nil(t, SubmitAndWait(api, func() {
+		assert.Nil(t, SubmitAndWait(renderer, func() {
+			rendered = true
+		}))
+	}))
+	assert.True(t, rendered)
+
+	// The api pool was seen waiting on the renderer, so the renderer can't wait on it
+	var errs []error
+	assert.Nil(t, SubmitAndWait(renderer, func() {
+		errs = append(errs, SubmitAndWait(api, func() {}))
+		errs = append(errs, SubmitAndWait(renderer, func() {}))
+	}))
+	assert.EqualError(t, errs[0], "pool dependency cycle: renderer -> api -> renderer")
+	assert.EqualError(t, errs[1], "pool dependency cycle: renderer -> renderer")
+	assert.True(t, errors.Is(pm.DeclareDependency("renderer", "api"), ErrDependencyCycle))
+
+	pm.Block("api")
+	assert.Equal(t, ErrKeyBlocked, SubmitAndWait(api, func() {}))
+	pm.Dispose()
+}
@@ -0,0 +1,205 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// Disposal configures the disposal queue enabled by WithDisposalQueue
+type Disposal struct {
+	// Workers is how many evicted pools may be disposed at once, 1 if unset. Disposing a pool waits for its callers to
+	// be done using it, so a pool evicted while in use holds up one worker until it's released.
+	Workers int
+	// QueueSize is how many evicted pools may be waiting for a worker, 1024 if unset. Evictions beyond that wait for
+	// room, in the background.
+	QueueSize int
+	// Rate and Interval cap disposals to Rate started per Interval, evenly paced as with WithThrottle. Zero Rate means
+	// no limit.
+	Rate     int
+	Interval time.Duration
+}
+
+// StuckDisposal describes an evicted pool whose disposal has waited longer than the WithDisposalTimeout timeout for its
+// callers to release it
+type StuckDisposal struct {
+	Key    string
+	Pool   WorkerPool
+	Reason EvictionReason
+	// Reservations is how many callers were still using the pool when it was reported
+	Reservations int
+	// Waiting is how long ago the pool was evicted
+	Waiting time.Duration
+}
+
+// Default Disposal.QueueSize
+const defaultDisposalQueueSize = 1024
+
+// WithDisposalQueue disposes evicted pools on a fixed set of workers, instead of each eviction disposing its pool
+// straight away. When thousands of pools expire at once this smooths out the spike of disposals, and of the work
+// done by DisposeE and io.Closer implementations, at the cost of OnPoolEvicted hooks firing later.
+func WithDisposalQueue(disposal Disposal) Option {
+	return func(o *options) {
+		o.disposal = &disposal
+	}
+}
+
+// WithDisposalTimeout reports evicted pools which are still waiting to be disposed after timeout to the
+// OnStuckDisposal hook. An evicted pool is only disposed once every caller has released it, so a checkout which is
+// never released, e.g. a doneUsing channel which is never closed, otherwise delays its disposal forever without a
+// trace. Reported pools are still disposed if they're released later, and each is reported at most once.
+//
+// WorkerPoolManager.PendingDisposals counts the pools waiting to be disposed, with or without a timeout.
+func WithDisposalTimeout(timeout time.Duration) Option {
+	return func(o *options) {
+		o.disposalTimeout = timeout
+	}
+}
+
+type pendingDisposal struct {
+	reason ttlcache.EvictionReason
+	key    string
+	pool   WorkerPool
+}
+
+// disposalQueue feeds evicted pools to the disposal workers
+type disposalQueue struct {
+	pending chan pendingDisposal
+	pacer   *pacer
+	workers *sync.WaitGroup
+}
+
+func newDisposalQueue(clock Clock, disposal Disposal, dispose func(pendingDisposal)) *disposalQueue {
+	workers := disposal.Workers
+	if workers < 1 {
+		workers = 1
+	}
+	size := disposal.QueueSize
+	if size < 1 {
+		size = defaultDisposalQueueSize
+	}
+	q := &disposalQueue{
+		pending: make(chan pendingDisposal, size),
+		workers: &sync.WaitGroup{},
+	}
+	if disposal.Rate > 0 {
+		q.pacer = newPacer(clock, disposal.Rate, disposal.Interval)
+	}
+
+	q.workers.Add(workers)
+	for i := 0; i < workers; i++ {
+		go func() {
+			defer q.workers.Done()
+			for next := range q.pending {
+				if q.pacer != nil {
+					// Pending disposals are never abandoned, so there's nothing to cut the wait short
+					q.pacer.wait(nil)
+				}
+				dispose(next)
+			}
+		}()
+	}
+	return q
+}
+
+func (q *disposalQueue) add(next pendingDisposal) {
+	q.pending <- next
+}
+
+// Stop accepting disposals once every queued one has been added, letting the workers exit after disposing them
+func (q *disposalQueue) close() {
+	close(q.pending)
+}
+
+// evictedPools tracks the pools which have been evicted from a manager's cache but not disposed yet
+type evictedPools struct {
+	lock *sync.Mutex
+	// The timer reporting each pool stuck, without a timeout just nil
+	pools map[WorkerPool]Timer
+}
+
+func newEvictedPools() *evictedPools {
+	return &evictedPools{lock: &sync.Mutex{}, pools: make(map[WorkerPool]Timer)}
+}
+
+// PendingDisposals returns how many evicted pools are waiting to be disposed, which includes pools still in use by
+// callers who checked them out before they were evicted, and pools waiting for the disposal queue
+func (m *WorkerPoolManager) PendingDisposals() int {
+	m.evicted.lock.Lock()
+	defer m.evicted.lock.Unlock()
+	return len(m.evicted.pools)
+}
+
+// Track an evicted pool until it's disposed, reporting it if it's still pending after the disposal timeout
+func (m *WorkerPoolManager) pendDisposal(reason ttlcache.EvictionReason, key string, pool WorkerPool) {
+	var timer Timer
+	if timeout := m.options.disposalTimeout; timeout > 0 {
+		evicted := m.clock.Now()
+		timer = m.clock.AfterFunc(timeout, func() {
+			stuck := StuckDisposal{
+				Key:          key,
+				Pool:         pool,
+				Reason:       evictionReason(reason, pool),
+				Reservations: pool.reservations(),
+				Waiting:      m.clock.Now().Sub(evicted),
+			}
+			m.options.count(MetricStuckDisposals, key, 1)
+			m.options.disposalStuck(stuck)
+		})
+	}
+	m.evicted.lock.Lock()
+	defer m.evicted.lock.Unlock()
+	m.evicted.pools[pool] = timer
+}
+
+func (m *WorkerPoolManager) disposed(pool WorkerPool) {
+	m.evicted.lock.Lock()
+	defer m.evicted.lock.Unlock()
+	if timer := m.evicted.pools[pool]; timer != nil {
+		timer.Stop()
+	}
+	delete(m.evicted.pools, pool)
+}
+
+func (o *options) disposalStuck(stuck StuckDisposal) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnStuckDisposal != nil {
+			hooks.OnStuckDisposal(stuck)
+		}
+	}
+}
+
+// Handle the cache's evictions, through the disposal queue if there is one
+func (m *WorkerPoolManager) handleEvictions() {
+	if m.options.disposal == nil {
+		m.workerPoolCache.OnEviction(func(
+			_ context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[string, WorkerPool],
+		) {
+			m.pendDisposal(reason, item.Key(), item.Value())
+			m.disposeEvicted(reason, item.Key(), item.Value())
+		})
+		return
+	}
+
+	m.disposals = newDisposalQueue(m.clock, *m.options.disposal, func(next pendingDisposal) {
+		m.disposeEvicted(next.reason, next.key, next.pool)
+	})
+	m.stopEvictions = m.workerPoolCache.OnEviction(func(
+		_ context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[string, WorkerPool],
+	) {
+		m.pendDisposal(reason, item.Key(), item.Value())
+		m.disposals.add(pendingDisposal{reason: reason, key: item.Key(), pool: item.Value()})
+	})
+}
+
+// Once the cache has been cleared, close the disposal queue. Queued pools are still disposed in the background.
+func (m *WorkerPoolManager) closeDisposals() {
+	if m.disposals == nil {
+		return
+	}
+	// Waits for every eviction to have been queued
+	m.stopEvictions()
+	m.disposals.close()
+}
@@ -0,0 +1,152 @@
+package pool
+
+import (
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// slowDisposePool tracks how many pools are being disposed at once
+type slowDisposePool struct {
+	WorkerPool
+	disposing *int32
+	peak      *int32
+}
+
+func (p *slowDisposePool) DisposeE() error {
+	disposing := atomic.AddInt32(p.disposing, 1)
+	for {
+		peak := atomic.LoadInt32(p.peak)
+		if disposing <= peak || atomic.CompareAndSwapInt32(p.peak, peak, disposing) {
+			break
+		}
+	}
+	time.Sleep(2 * time.Millisecond)
+	atomic.AddInt32(p.disposing, -1)
+	p.WorkerPool.Dispose()
+	return nil
+}
+
+func TestDisposalQueueBoundsConcurrentDisposals(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var evicted sync.WaitGroup
+	evicted.Add(20)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithDisposalQueue(Disposal{Workers: 2, QueueSize: 4}),
+		WithHooks(Hooks{
+			OnPoolEvicted: func(PoolEviction) {
+				evicted.Done()
+			},
+		}),
+	)
+
+	var disposing, peak int32
+	var factory Factory = func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &slowDisposePool{WorkerPool: basePool, disposing: &disposing, peak: &peak}, nil
+	}
+	for i := 0; i < 20; i++ {
+		_, doneUsing, _ := pm.GetPoolWithFactory(strconv.Itoa(i), 1, factory)
+		close(doneUsing)
+	}
+	pm.Dispose()
+	evicted.Wait()
+
+	assert.Equal(t, int32(2), peak)
+}
+
+func TestDisposalQueuePacesDisposals(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var lock sync.Mutex
+	var evictedAt []time.Time
+	var evicted sync.WaitGroup
+	evicted.Add(5)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithDisposalQueue(Disposal{Workers: 5, Rate: 1, Interval: 10 * time.Millisecond}),
+		WithHooks(Hooks{
+			OnPoolEvicted: func(eviction PoolEviction) {
+				assert.Equal(t, EvictionReasonDeleted, eviction.Reason)
+				lock.Lock()
+				evictedAt = append(evictedAt, time.Now())
+				lock.Unlock()
+				evicted.Done()
+			},
+		}),
+	)
+	for i := 0; i < 5; i++ {
+		_, doneUsing := pm.GetPool(strconv.Itoa(i), 1)
+		close(doneUsing)
+	}
+	start := time.Now()
+	pm.Dispose()
+	evicted.Wait()
+
+	assert.Len(t, evictedAt, 5)
+	assert.GreaterOrEqual(t, time.Since(start), 40*time.Millisecond)
+}
+
+func TestDisposalQueueWaitsForPoolsInUse(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 1)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithDisposalQueue(Disposal{}),
+		WithHooks(Hooks{
+			OnPoolEvicted: func(eviction PoolEviction) {
+				evictions <- eviction
+			},
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+	pm.Dispose()
+
+	select {
+	case <-evictions:
+		t.Fatal("Expected disposal to wait for the pool to be released")
+	case <-time.After(10 * time.Millisecond):
+	}
+	close(doneUsing)
+	assert.Same(t, pool, (<-evictions).Pool)
+}
+
+func TestDisposalTimeoutReportsStuckDisposals(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	stuck := make(chan StuckDisposal, 1)
+	evicted := make(chan bool)
+	// Without a max lifetime, each pool is evicted as soon as it's checked out
+	pm := NewWorkerPoolManager(1, time.Hour, 0,
+		WithDisposalTimeout(10*time.Millisecond),
+		WithHooks(Hooks{
+			OnStuckDisposal: func(disposal StuckDisposal) {
+				stuck <- disposal
+			},
+			OnPoolEvicted: func(PoolEviction) {
+				close(evicted)
+			},
+		}),
+	)
+	_, doneUsing := pm.GetPool("key", 1)
+	assert.Eventually(t, func() bool {
+		return pm.PendingDisposals() == 1
+	}, time.Second, time.Millisecond)
+
+	disposal := <-stuck
+	assert.Equal(t, "key", disposal.Key)
+	assert.Equal(t, EvictionReasonMaxLifetime, disposal.Reason)
+	assert.Equal(t, 1, disposal.Reservations)
+	assert.GreaterOrEqual(t, disposal.Waiting, 10*time.Millisecond)
+	assert.Equal(t, 1, pm.PendingDisposals())
+
+	close(doneUsing)
+	<-evicted
+	assert.Equal(t, 0, pm.PendingDisposals())
+	pm.Dispose()
+}
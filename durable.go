@@ -0,0 +1,310 @@
+package pool
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrNoQueueStore is returned by SubmitDurable for pools without durable tasks, see WithDurableTasks
+var ErrNoQueueStore = errors.New("durable tasks need a queue store")
+
+// ErrDeliveriesExhausted is reported to DurableTasks.OnError for tasks nacked on their last delivery
+var ErrDeliveriesExhausted = errors.New("durable task was nacked on its last delivery")
+
+// DurableTask describes a task submitted with SubmitDurable. Unlike a closure, it can be journaled by a QueueStore and
+// executed again by a restarted process.
+type DurableTask struct {
+	// ID is assigned by the QueueStore when the task is appended
+	ID uint64
+	// Key is the key of the pool the task was submitted to
+	Key string
+	// Type picks the DurableHandler which executes the task
+	Type string
+	// IdempotencyKey identifies repeats of the same task, see DurableTasks.IdempotencyWindow. It may be empty.
+	IdempotencyKey string
+	Payload        []byte
+}
+
+// QueueStore journals durable tasks until they've executed. The queuestore package implements it in memory and in a
+// file.
+type QueueStore interface {
+	// Append journals task, returning the ID it's stored under
+	Append(task DurableTask) (id uint64, err error)
+	// Ack removes the task stored under id, once it has executed
+	Ack(id uint64) error
+	// Replay returns the tasks which haven't been acknowledged, in the order they were appended
+	Replay() ([]DurableTask, error)
+}
+
+// DurableHandler executes the payload of a durable task submitted to key's pool. Returning nil acks the task, and
+// returning an error nacks it, see Delivery.
+type DurableHandler func(key string, payload []byte) error
+
+// DeliveryHandler executes a durable task, acking or nacking the delivery itself - possibly after returning, e.g.
+// once work it hands off elsewhere has finished. A delivery which is never acked or nacked stays journaled, and is
+// executed again when it's next replayed.
+type DeliveryHandler func(delivery *Delivery)
+
+// DurableTasks configures durable tasks, see WithDurableTasks
+type DurableTasks struct {
+	Store QueueStore
+	// Handlers executes each type of durable task
+	Handlers map[string]DurableHandler
+	// DeliveryHandlers executes types of durable task which ack their deliveries explicitly, instead of Handlers
+	DeliveryHandlers map[string]DeliveryHandler
+	// RedeliveryDelay is how long a nacked task waits before it's delivered again
+	RedeliveryDelay time.Duration
+	// MaxDeliveries caps how many times each task is delivered, unlimited if unset. Tasks nacked on their last
+	// delivery are reported to OnError with ErrDeliveriesExhausted, and stay journaled until they're next replayed.
+	MaxDeliveries int
+	// IdempotencyWindow is how long each pool remembers the idempotency keys of the tasks it has acked, skipping tasks
+	// submitted or delivered again with the same key meanwhile. Keys are remembered in memory, so tasks replayed by a
+	// restarted process or executing concurrently aren't deduplicated. Keys aren't remembered if it's unset.
+	IdempotencyWindow time.Duration
+	// OnError is called when a durable task is nacked, or can't be executed or acknowledged. It may be nil.
+	OnError func(task DurableTask, err error)
+}
+
+// WithDurableTasks journals tasks submitted with SubmitDurable to a QueueStore until they've executed, so that a
+// process which crashes or is killed can pick up the work it hadn't finished with ReplayDurableTasks when it restarts.
+//
+// Durable tasks are executed at least once. Each execution is a Delivery, and the task is only removed from the
+// journal once a delivery is acked. Nacked tasks are delivered again after DurableTasks.RedeliveryDelay, and tasks
+// whose process crashes before they're acked are delivered again on replay, so handlers should be idempotent.
+func WithDurableTasks(durable DurableTasks) Option {
+	return func(o *options) {
+		o.durable = &durableTasks{DurableTasks: durable, lock: &sync.Mutex{}, pending: make(map[uint64]bool)}
+	}
+}
+
+// SubmitDurable submits a durable task of type taskType, which is journaled until its DurableHandler has executed
+// payload successfully. It returns ErrNoQueueStore for pools without durable tasks, the QueueStore's error if
+// journaling fails, and the same errors as SubmitTask if the submission is rejected.
+func SubmitDurable(p WorkerPool, taskType string, payload []byte) error {
+	return p.submitDurable(taskType, "", payload)
+}
+
+// SubmitIdempotent submits a durable task like SubmitDurable, but skips it if p has executed a task with the same
+// idempotencyKey within DurableTasks.IdempotencyWindow. A task redelivered after being executed is skipped likewise,
+// making repeated effects of at-least-once delivery unlikely.
+func SubmitIdempotent(p WorkerPool, taskType string, idempotencyKey string, payload []byte) error {
+	return p.submitDurable(taskType, idempotencyKey, payload)
+}
+
+// durableTasks tracks the durable tasks a process has queued
+type durableTasks struct {
+	DurableTasks
+	lock *sync.Mutex
+	// The IDs of the journaled tasks this process is delivering, so they aren't replayed while they're still queued or
+	// waiting to be redelivered
+	pending map[uint64]bool
+}
+
+// Delivery is a single execution of a durable task. Exactly one of Ack and Nack takes effect, whichever is called
+// first.
+type Delivery struct {
+	Task DurableTask
+	// Attempt counts the task's deliveries by this process, starting at 1
+	Attempt int
+
+	durable *durableTasks
+	pool    WorkerPool
+	clock   Clock
+	window  *idempotencyWindow
+	// Whether the delivery has been acked or nacked, accessed atomically
+	settled int32
+}
+
+// Ack removes the task from the journal, returning the QueueStore's error if that fails
+func (d *Delivery) Ack() error {
+	if !atomic.CompareAndSwapInt32(&d.settled, 0, 1) {
+		return nil
+	}
+	defer d.durable.done(d.Task.ID)
+	d.window.record(d.Task.IdempotencyKey)
+	return d.durable.Store.Ack(d.Task.ID)
+}
+
+// Nack reports err to DurableTasks.OnError, and delivers the task again after DurableTasks.RedeliveryDelay unless this
+// was its last delivery
+func (d *Delivery) Nack(err error) {
+	if !atomic.CompareAndSwapInt32(&d.settled, 0, 1) {
+		return
+	}
+	durable := d.durable
+	durable.failed(d.Task, err)
+	if durable.MaxDeliveries > 0 && d.Attempt >= durable.MaxDeliveries {
+		durable.failed(d.Task, ErrDeliveriesExhausted)
+		durable.done(d.Task.ID)
+		return
+	}
+
+	// Never enqueued from the worker itself, which could block on its own full queue
+	redeliver := func() {
+		if err := durable.enqueue(d.pool, d.clock, d.Task, d.Attempt+1); err != nil {
+			durable.failed(d.Task, err)
+		}
+	}
+	if durable.RedeliveryDelay > 0 {
+		d.clock.AfterFunc(durable.RedeliveryDelay, redeliver)
+	} else {
+		go redeliver()
+	}
+}
+
+func (p *BaseWorkerPool) submitDurable(taskType string, idempotencyKey string, payload []byte) error {
+	if p.options == nil || p.options.durable == nil {
+		return ErrNoQueueStore
+	}
+	d := p.options.durable
+	if !d.handles(taskType) {
+		return fmt.Errorf("no handler for durable tasks of type %q", taskType)
+	}
+	if p.idempotency.seen(idempotencyKey) {
+		return nil
+	}
+
+	t := DurableTask{Key: p.key, Type: taskType, IdempotencyKey: idempotencyKey, Payload: payload}
+	id, err := d.Store.Append(t)
+	if err != nil {
+		return err
+	}
+	t.ID = id
+	if err := d.enqueue(p, p.clock, t, 1); err != nil {
+		// It was never accepted, so there's nothing to replay
+		_ = d.Store.Ack(id)
+		return err
+	}
+	return nil
+}
+
+func (p *BaseWorkerPool) recentKeys() *idempotencyWindow {
+	return p.idempotency
+}
+
+func (d *durableTasks) handles(taskType string) bool {
+	return d.Handlers[taskType] != nil || d.DeliveryHandlers[taskType] != nil
+}
+
+// Queue a delivery of a journaled task on p
+func (d *durableTasks) enqueue(p WorkerPool, clock Clock, t DurableTask, attempt int) error {
+	d.lock.Lock()
+	d.pending[t.ID] = true
+	d.lock.Unlock()
+	delivery := &Delivery{Task: t, Attempt: attempt, durable: d, pool: p, clock: clock, window: p.recentKeys()}
+	info := TaskInfo{Label: t.Type, MemoryCost: int64(len(t.Payload))}
+	err := p.enqueue(task{info: info, work: func() {
+		d.deliver(delivery)
+	}})
+	if err != nil {
+		d.done(t.ID)
+	}
+	return err
+}
+
+func (d *durableTasks) deliver(delivery *Delivery) {
+	defer func() {
+		if r := recover(); r != nil {
+			delivery.Nack(fmt.Errorf("durable task panicked: %v", r))
+			panic(r)
+		}
+	}()
+
+	t := delivery.Task
+	if delivery.window.seen(t.IdempotencyKey) {
+		// Already executed, e.g. by a delivery which was acked after this one was queued
+		if err := delivery.Ack(); err != nil {
+			d.failed(t, err)
+		}
+		return
+	}
+	if handler := d.DeliveryHandlers[t.Type]; handler != nil {
+		handler(delivery)
+		return
+	}
+	handler := d.Handlers[t.Type]
+	if handler == nil {
+		// Replayed from a journal written by a process with different handlers, so it's left for one which has them
+		d.failed(t, fmt.Errorf("no handler for durable tasks of type %q", t.Type))
+		d.done(t.ID)
+		return
+	}
+	if err := handler(t.Key, t.Payload); err != nil {
+		delivery.Nack(err)
+		return
+	}
+	if err := delivery.Ack(); err != nil {
+		d.failed(t, err)
+	}
+}
+
+func (d *durableTasks) done(id uint64) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	delete(d.pending, id)
+}
+
+func (d *durableTasks) queued(id uint64) bool {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	return d.pending[id]
+}
+
+func (d *durableTasks) failed(t DurableTask, err error) {
+	if d.OnError != nil {
+		d.OnError(t, err)
+	}
+}
+
+// ReplayDurableTasks queues the journaled durable tasks which haven't been acked - typically those left unfinished by a
+// previous process, so it's called once at startup - and returns how many were queued. Tasks this process is still
+// delivering aren't queued again, so calling it later retries only the tasks which ran out of deliveries.
+//
+// Tasks which can't be queued, e.g. because their key is blocked, are reported to DurableTasks.OnError and stay
+// journaled.
+func (m *WorkerPoolManager) ReplayDurableTasks() (int, error) {
+	d := m.options.durable
+	if d == nil {
+		return 0, ErrNoQueueStore
+	}
+	tasks, err := d.Store.Replay()
+	if err != nil {
+		return 0, err
+	}
+
+	// Each key's tasks are queued on a single checkout, spawning as many workers as there are tasks
+	var keys []string
+	byKey := make(map[string][]DurableTask)
+	for _, t := range tasks {
+		if d.queued(t.ID) {
+			continue
+		}
+		if _, ok := byKey[t.Key]; !ok {
+			keys = append(keys, t.Key)
+		}
+		byKey[t.Key] = append(byKey[t.Key], t)
+	}
+
+	replayed := 0
+	for _, key := range keys {
+		pool, doneUsing, err := m.GetPoolWithFactory(key, len(byKey[key]), nil)
+		if err != nil {
+			for _, t := range byKey[key] {
+				d.failed(t, err)
+			}
+			continue
+		}
+		for _, t := range byKey[key] {
+			if err := d.enqueue(pool, m.clock, t, 1); err != nil {
+				d.failed(t, err)
+				continue
+			}
+			replayed++
+		}
+		close(doneUsing)
+	}
+	return replayed, nil
+}
@@ -0,0 +1,272 @@
+package pool
+
+import (
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// journal is a QueueStore kept in memory, as the queuestore package can't be imported here
+type journal struct {
+	lock    sync.Mutex
+	nextID  uint64
+	tasks   map[uint64]DurableTask
+	failing bool
+}
+
+func newJournal() *journal {
+	return &journal{tasks: make(map[uint64]DurableTask)}
+}
+
+func (j *journal) Append(task DurableTask) (uint64, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	if j.failing {
+		return 0, errors.New("disk full")
+	}
+	j.nextID++
+	task.ID = j.nextID
+	j.tasks[task.ID] = task
+	return task.ID, nil
+}
+
+func (j *journal) Ack(id uint64) error {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	delete(j.tasks, id)
+	return nil
+}
+
+func (j *journal) Replay() ([]DurableTask, error) {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	var tasks []DurableTask
+	for _, task := range j.tasks {
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(a, b int) bool {
+		return tasks[a].ID < tasks[b].ID
+	})
+	return tasks, nil
+}
+
+func (j *journal) len() int {
+	j.lock.Lock()
+	defer j.lock.Unlock()
+	return len(j.tasks)
+}
+
+func TestDurableTasksAreJournaledUntilExecuted(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	store := newJournal()
+	executed := make(chan string, 10)
+	release := make(chan bool)
+	var failures []error
+	var lock sync.Mutex
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10), WithDurableTasks(DurableTasks{
+		Store:         store,
+		MaxDeliveries: 2,
+		Handlers: map[string]DurableHandler{
+			"send": func(key string, payload []byte) error {
+				<-release
+				executed <- key + ":" + string(payload)
+				return nil
+			},
+			"flaky": func(string, []byte) error {
+				return errors.New("downstream unavailable")
+			},
+		},
+		OnError: func(task DurableTask, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			failures = append(failures, err)
+		},
+	}))
+	pool, doneUsing := pm.GetPool("app-42", 1)
+
+	assert.Nil(t, SubmitDurable(pool, "send", []byte("hello")))
+	assert.EqualError(t, SubmitDurable(pool, "unknown", nil), `no handler for durable tasks of type "unknown"`)
+	assert.Equal(t, 1, store.len())
+	close(release)
+	assert.Equal(t, "app-42:hello", <-executed)
+	assert.Eventually(t, func() bool {
+		return store.len() == 0
+	}, time.Second, time.Millisecond)
+
+	// Failed tasks are redelivered, then stay journaled for the next replay
+	assert.Nil(t, SubmitDurable(pool, "flaky", nil))
+	assert.Eventually(t, func() bool {
+		lock.Lock()
+		defer lock.Unlock()
+		return len(failures) == 3
+	}, time.Second, time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, ErrDeliveriesExhausted, failures[2])
+	lock.Unlock()
+	assert.Equal(t, 1, store.len())
+
+	// Rejected submissions aren't left journaled
+	pm.Block("app-42")
+	assert.Equal(t, ErrKeyBlocked, SubmitDurable(pool, "send", nil))
+	pm.Unblock("app-42")
+	store.failing = true
+	assert.EqualError(t, SubmitDurable(pool, "send", nil), "disk full")
+	assert.Equal(t, 1, store.len())
+	assert.Contains(t, pm.Config().Features, "durable tasks")
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestDurableTasksAreRedeliveredUntilAcked(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	store := newJournal()
+	deliveries := make(chan *Delivery, 10)
+	var lock sync.Mutex
+	var nacks []error
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithDurableTasks(DurableTasks{
+		Store:           store,
+		RedeliveryDelay: 20 * time.Millisecond,
+		DeliveryHandlers: map[string]DeliveryHandler{
+			// Settled by the test once the handler has returned
+			"export": func(delivery *Delivery) {
+				deliveries <- delivery
+			},
+		},
+		OnError: func(task DurableTask, err error) {
+			lock.Lock()
+			defer lock.Unlock()
+			nacks = append(nacks, err)
+		},
+	}))
+	pool, doneUsing := pm.GetPool("app-42", 1)
+	assert.Nil(t, SubmitDurable(pool, "export", []byte("report")))
+
+	first := <-deliveries
+	assert.Equal(t, 1, first.Attempt)
+	assert.Equal(t, "report", string(first.Task.Payload))
+	nacked := time.Now()
+	first.Nack(errors.New("upload failed"))
+	// Only the first of Ack and Nack takes effect
+	assert.Nil(t, first.Ack())
+	assert.Equal(t, 1, store.len())
+
+	second := <-deliveries
+	assert.Equal(t, 2, second.Attempt)
+	assert.GreaterOrEqual(t, time.Since(nacked), 20*time.Millisecond)
+	// Tasks waiting to be redelivered aren't replayed
+	replayed, err := pm.ReplayDurableTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, replayed)
+	assert.Nil(t, second.Ack())
+	assert.Equal(t, 0, store.len())
+	lock.Lock()
+	assert.Equal(t, []error{errors.New("upload failed")}, nacks)
+	lock.Unlock()
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestIdempotentTasksAreSkippedWithinTheWindow(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	store := newJournal()
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	executed := make(chan string, 10)
+	release := make(chan bool, 10)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithClock(clock), WithDurableTasks(DurableTasks{
+		Store:             store,
+		IdempotencyWindow: time.Minute,
+		Handlers: map[string]DurableHandler{
+			"charge": func(key string, payload []byte) error {
+				<-release
+				executed <- string(payload)
+				return nil
+			},
+		},
+	}))
+	pool, doneUsing := pm.GetPool("app-42", 1)
+
+	// Both are queued before either executes, so the repeat is skipped once it's delivered
+	assert.Nil(t, SubmitIdempotent(pool, "charge", "order-1", []byte("first")))
+	assert.Nil(t, SubmitIdempotent(pool, "charge", "order-1", []byte("repeat")))
+	release <- true
+	assert.Equal(t, "first", <-executed)
+	assert.Eventually(t, func() bool {
+		return store.len() == 0
+	}, time.Second, time.Millisecond)
+
+	// Once executed, repeats aren't journaled at all
+	assert.Nil(t, SubmitIdempotent(pool, "charge", "order-1", []byte("late repeat")))
+	assert.Equal(t, 0, store.len())
+	release <- true
+	assert.Nil(t, SubmitIdempotent(pool, "charge", "order-2", []byte("second")))
+	assert.Equal(t, "second", <-executed)
+
+	clock.Advance(time.Minute)
+	release <- true
+	assert.Nil(t, SubmitIdempotent(pool, "charge", "order-1", []byte("after the window")))
+	assert.Equal(t, "after the window", <-executed)
+	assert.Empty(t, executed)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestReplayDurableTasksQueuesUnfinishedTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	store := newJournal()
+	_, _ = store.Append(DurableTask{Key: "app-1", Type: "send", Payload: []byte("a")})
+	_, _ = store.Append(DurableTask{Key: "app-2", Type: "send", Payload: []byte("b")})
+	_, _ = store.Append(DurableTask{Key: "app-1", Type: "send", Payload: []byte("c")})
+
+	var lock sync.Mutex
+	executed := make(map[string][]string)
+	release := make(chan bool)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithDurableTasks(DurableTasks{
+		Store: store,
+		Handlers: map[string]DurableHandler{
+			"send": func(key string, payload []byte) error {
+				<-release
+				lock.Lock()
+				defer lock.Unlock()
+				executed[key] = append(executed[key], string(payload))
+				return nil
+			},
+		},
+	}))
+
+	replayed, err := pm.ReplayDurableTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, replayed)
+	// Tasks which are still queued aren't replayed twice
+	replayed, err = pm.ReplayDurableTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, 0, replayed)
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return store.len() == 0
+	}, time.Second, time.Millisecond)
+	lock.Lock()
+	assert.Equal(t, map[string][]string{"app-1": {"a", "c"}, "app-2": {"b"}}, executed)
+	lock.Unlock()
+	pm.Dispose()
+
+	plain := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	_, err = plain.ReplayDurableTasks()
+	assert.Equal(t, ErrNoQueueStore, err)
+	pool, doneUsing := plain.GetPool("app-1", 1)
+	assert.Equal(t, ErrNoQueueStore, SubmitDurable(pool, "send", nil))
+	close(doneUsing)
+	plain.Dispose()
+}
@@ -0,0 +1,169 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sync"
+)
+
+// Group is a drop-in replacement for golang.org/x/sync/errgroup.Group whose goroutines are tasks executed by a key's
+// pool, so code written against errgroup can adopt per-key concurrency limits by changing the line which builds its
+// group. Its methods behave like errgroup's, see ErrGroup.
+type Group struct {
+	manager *WorkerPoolManager
+	key     string
+	// Bounds the wait for the group's checkout, see GetPoolContext
+	ctx    context.Context
+	cancel func()
+	wg     *sync.WaitGroup
+	// Tokens for the tasks in flight, with SetLimit
+	sem chan bool
+
+	// Guards the rest
+	lock *sync.Mutex
+	err  error
+	// Every error, in the order they occurred, for a TaskGroup collecting them
+	collect bool
+	errs    []error
+	// The checkout the group's tasks are submitted through, from the first Go until Wait
+	pool      WorkerPool
+	doneUsing chan<- bool
+}
+
+// ErrGroup returns a Group which executes the functions passed to Go on key's pool. The pool is checked out by the
+// first call to Go and released when Wait returns, so as with errgroup, Wait must be called once the group's work has
+// been submitted. Submissions the pool rejects, e.g. because key is blocked, count as the functions failing.
+func ErrGroup(manager *WorkerPoolManager, key string) *Group {
+	return &Group{manager: manager, key: key, ctx: context.Background(), wg: &sync.WaitGroup{}, lock: &sync.Mutex{}}
+}
+
+// ErrGroupWithContext returns a Group like ErrGroup's and a context derived from ctx, which is canceled the first time
+// a function passed to Go returns an error or the first time Wait returns, whichever occurs first - as with
+// errgroup.WithContext. The group's checkout stops waiting, as with GetPoolContext, once ctx is done.
+func ErrGroupWithContext(ctx context.Context, manager *WorkerPoolManager, key string) (*Group, context.Context) {
+	g := ErrGroup(manager, key)
+	g.ctx = ctx
+	ctx, g.cancel = context.WithCancel(ctx)
+	return g, ctx
+}
+
+// Go calls f on the group's pool, blocking until there's room for it if the group has a limit. The first call to
+// return a non-nil error cancels the group's context, if it has one, and its error is returned by Wait.
+func (g *Group) Go(f func() error) {
+	if g.sem != nil {
+		g.sem <- true
+	}
+	g.submit(f)
+}
+
+// TryGo calls f on the group's pool only if the group has room for it under its limit, reporting whether it did
+func (g *Group) TryGo(f func() error) bool {
+	if g.sem != nil {
+		select {
+		case g.sem <- true:
+		default:
+			return false
+		}
+	}
+	g.submit(f)
+	return true
+}
+
+// SetLimit limits the number of functions the group has submitted but which haven't returned yet to at most n. A
+// negative value means no limit, and the key's pool size caps how many execute at once either way.
+//
+// The limit must not be modified while any functions in the group are active.
+func (g *Group) SetLimit(n int) {
+	if n < 0 {
+		g.sem = nil
+		return
+	}
+	if len(g.sem) != 0 {
+		panic(fmt.Errorf("errgroup: modify limit while %v goroutines in the group are still active", len(g.sem)))
+	}
+	g.sem = make(chan bool, n)
+}
+
+// Wait blocks until all the functions passed to Go have returned, then returns the first non-nil error from them
+func (g *Group) Wait() error {
+	g.wg.Wait()
+	if g.cancel != nil {
+		g.cancel()
+	}
+
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.doneUsing != nil {
+		close(g.doneUsing)
+		g.pool, g.doneUsing = nil, nil
+	}
+	return g.err
+}
+
+func (g *Group) submit(f func() error) {
+	g.wg.Add(1)
+	pool, err := g.checkout()
+	if err == nil {
+		err = pool.enqueue(task{work: func() {
+			defer g.done()
+			if err := f(); err != nil {
+				g.fail(err)
+			}
+		}, dropped: func() {
+			g.fail(ErrTaskPurged)
+			g.done()
+		}})
+	}
+	if err != nil {
+		g.fail(err)
+		g.done()
+	}
+}
+
+// The group's checkout of its pool, spawning workers for as many functions as its limit allows
+func (g *Group) checkout() (WorkerPool, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.pool == nil {
+		// Capped to the key's pool size by the manager
+		sendSize := math.MaxInt32
+		if g.sem != nil {
+			sendSize = cap(g.sem)
+		}
+		pool, doneUsing, err := g.manager.GetPoolContext(g.ctx, g.key, sendSize, nil)
+		if err != nil {
+			return nil, err
+		}
+		g.pool, g.doneUsing = pool, doneUsing
+	}
+	return g.pool, nil
+}
+
+func (g *Group) done() {
+	if g.sem != nil {
+		<-g.sem
+	}
+	g.wg.Done()
+}
+
+func (g *Group) fail(err error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	if g.collect {
+		g.errs = append(g.errs, err)
+	}
+	if g.err == nil {
+		g.err = err
+		if g.cancel != nil {
+			g.cancel()
+		}
+	}
+}
+
+// Every error, for a TaskGroup collecting them
+func (g *Group) errors() []error {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return append([]error(nil), g.errs...)
+}
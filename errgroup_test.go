@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestErrGroupReturnsTheFirstErrorAndCancelsItsContext(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithQueueCapacity(10))
+	g, ctx := ErrGroupWithContext(context.Background(), pm, "key")
+	failure := errors.New("failure")
+	var completed int32
+	for i := 0; i < 5; i++ {
+		i := i
+		g.Go(func() error {
+			if i == 2 {
+				return failure
+			}
+			atomic.AddInt32(&completed, 1)
+			return nil
+		})
+	}
+	assert.Equal(t, failure, g.Wait())
+	assert.Equal(t, int32(4), atomic.LoadInt32(&completed))
+	assert.Equal(t, context.Canceled, ctx.Err())
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Reservations == 0
+	}, time.Second, time.Millisecond)
+
+	pm.Block("blocked")
+	g = ErrGroup(pm, "blocked")
+	g.Go(func() error { return nil })
+	assert.Equal(t, ErrKeyBlocked, g.Wait())
+	pm.Dispose()
+}
+
+func TestErrGroupCheckoutStopsWaitingWhenItsContextIsDone(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMaxCheckouts(1))
+	_, doneUsing := pm.GetPool("key", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	g, _ := ErrGroupWithContext(ctx, pm, "key")
+	g.Go(func() error { return nil })
+	assert.Equal(t, context.DeadlineExceeded, g.Wait())
+
+	tasks := pm.TaskGroup(ctx, "key", TaskGroupConfig{})
+	tasks.Submit(func(context.Context) error { return nil })
+	assert.ErrorIs(t, tasks.Wait(), context.DeadlineExceeded)
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestErrGroupLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour)
+	g := ErrGroup(pm, "key")
+	g.SetLimit(1)
+	release := make(chan bool)
+	g.Go(func() error {
+		<-release
+		return nil
+	})
+	assert.False(t, g.TryGo(func() error { return nil }))
+	assert.Panics(t, func() { g.SetLimit(2) })
+	close(release)
+	assert.Nil(t, g.Wait())
+
+	var executing, peak int32
+	for i := 0; i < 6; i++ {
+		g.Go(func() error {
+			n := atomic.AddInt32(&executing, 1)
+			if n > atomic.LoadInt32(&peak) {
+				atomic.StoreInt32(&peak, n)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&executing, -1)
+			return nil
+		})
+	}
+	assert.Nil(t, g.Wait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+	pm.Dispose()
+}
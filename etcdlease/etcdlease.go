@@ -0,0 +1,194 @@
+// Package etcdlease provides a pool.LeaseBackend storing key ownership leases in etcd, for use with pool.WithLeases.
+// Each pool key is an etcd key holding its owner, attached to an etcd lease so it's deleted once the lease runs out.
+//
+// It talks to etcd's v3 JSON gateway over HTTP, which etcd serves on its client URLs.
+package etcdlease
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// DefaultKeyPrefix is prepended to pool keys to build etcd keys, unless changed with WithKeyPrefix
+const DefaultKeyPrefix = "/worker-pools/leases/"
+
+// Option configures a Backend
+type Option func(*Backend)
+
+// WithHTTPClient sets the client used to call etcd, e.g. one configured for TLS. http.DefaultClient is used by default.
+func WithHTTPClient(client *http.Client) Option {
+	return func(b *Backend) {
+		b.client = client
+	}
+}
+
+// WithKeyPrefix sets the prefix of etcd keys, so deployments sharing an etcd cluster don't share leases
+func WithKeyPrefix(prefix string) Option {
+	return func(b *Backend) {
+		b.prefix = prefix
+	}
+}
+
+// Backend is a pool.LeaseBackend backed by an etcd cluster. It grants an etcd lease for each pool key it acquires, and
+// keeps it alive on each renewal.
+type Backend struct {
+	endpoint string
+	client   *http.Client
+	prefix   string
+
+	// Guards leases
+	lock *sync.Mutex
+	// The ID of the etcd lease holding each acquired key
+	leases map[string]int64
+}
+
+var _ pool.LeaseBackend = (*Backend)(nil)
+
+// New builds a Backend for the etcd client URL endpoint, e.g. "http://127.0.0.1:2379"
+func New(endpoint string, opts ...Option) *Backend {
+	b := &Backend{
+		endpoint: strings.TrimSuffix(endpoint, "/"),
+		client:   http.DefaultClient,
+		prefix:   DefaultKeyPrefix,
+		lock:     &sync.Mutex{},
+		leases:   make(map[string]int64),
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	return b
+}
+
+// Acquire takes or renews owner's lease on key. etcd leases last for whole seconds, so ttl is rounded up.
+func (b *Backend) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	if id, ok := b.leaseID(key); ok {
+		alive, err := b.keepAlive(ctx, id)
+		if err != nil || alive {
+			return alive, err
+		}
+		// The etcd lease ran out, taking the key with it, so it's acquired afresh
+		b.forget(key, id)
+	}
+
+	var grant leaseGrantResponse
+	seconds := int64((ttl + time.Second - 1) / time.Second)
+	if err := b.call(ctx, "/v3/lease/grant", leaseGrantRequest{TTL: seconds}, &grant); err != nil {
+		return false, err
+	}
+	acquired, err := b.put(ctx, key, owner, grant.ID)
+	if err != nil || !acquired {
+		// The etcd lease is no use without the key, and would otherwise linger until it runs out
+		_ = b.call(ctx, "/v3/lease/revoke", leaseRequest{ID: grant.ID}, &struct{}{})
+		return false, err
+	}
+
+	b.lock.Lock()
+	b.leases[key] = grant.ID
+	b.lock.Unlock()
+	return true, nil
+}
+
+// Release gives up owner's lease on key
+func (b *Backend) Release(ctx context.Context, key string, owner string) error {
+	var txn txnResponse
+	err := b.call(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: b.etcdKey(key), Target: "VALUE", Result: "EQUAL", Value: []byte(owner)}},
+		Success: []requestOp{{DeleteRange: &rangeRequest{Key: b.etcdKey(key)}}},
+	}, &txn)
+	if err != nil {
+		return err
+	}
+	if id, ok := b.leaseID(key); ok {
+		b.forget(key, id)
+		return b.call(ctx, "/v3/lease/revoke", leaseRequest{ID: id}, &struct{}{})
+	}
+	return nil
+}
+
+// Put owner in key with the etcd lease id if key is missing, or already belongs to owner e.g. from before a restart
+func (b *Backend) put(ctx context.Context, key string, owner string, id int64) (bool, error) {
+	etcdKey := b.etcdKey(key)
+	put := []requestOp{{Put: &putRequest{Key: etcdKey, Value: []byte(owner), Lease: id}}}
+
+	var created txnResponse
+	err := b.call(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: etcdKey, Target: "CREATE", Result: "EQUAL", CreateRevision: 0}},
+		Success: put,
+		Failure: []requestOp{{Range: &rangeRequest{Key: etcdKey}}},
+	}, &created)
+	if err != nil || created.Succeeded {
+		return created.Succeeded, err
+	}
+	if !created.heldBy(owner) {
+		return false, nil
+	}
+
+	var moved txnResponse
+	err = b.call(ctx, "/v3/kv/txn", txnRequest{
+		Compare: []compare{{Key: etcdKey, Target: "VALUE", Result: "EQUAL", Value: []byte(owner)}},
+		Success: put,
+	}, &moved)
+	return moved.Succeeded, err
+}
+
+// Renew the etcd lease id, returning false if it has already run out
+func (b *Backend) keepAlive(ctx context.Context, id int64) (bool, error) {
+	var alive keepAliveResponse
+	if err := b.call(ctx, "/v3/lease/keepalive", leaseRequest{ID: id}, &alive); err != nil {
+		return false, err
+	}
+	return alive.Result.TTL > 0, nil
+}
+
+func (b *Backend) leaseID(key string) (int64, bool) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	id, ok := b.leases[key]
+	return id, ok
+}
+
+// Stop tracking key's etcd lease, unless it has been replaced since
+func (b *Backend) forget(key string, id int64) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.leases[key] == id {
+		delete(b.leases, key)
+	}
+}
+
+func (b *Backend) etcdKey(key string) []byte {
+	return []byte(b.prefix + key)
+}
+
+// POST request to the gateway's path, decoding its response into response
+func (b *Backend) call(ctx context.Context, path string, request interface{}, response interface{}) error {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, b.endpoint+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		var failure errorResponse
+		_ = json.NewDecoder(resp.Body).Decode(&failure)
+		return fmt.Errorf("etcdlease: %s returned %s: %s", path, resp.Status, failure.Message)
+	}
+	return json.NewDecoder(resp.Body).Decode(response)
+}
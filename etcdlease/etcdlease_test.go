@@ -0,0 +1,208 @@
+package etcdlease
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// fakeEtcd serves the gateway calls a Backend makes, keeping keys and leases in memory
+type fakeEtcd struct {
+	lock    sync.Mutex
+	nextID  int64
+	leases  map[int64]bool
+	values  map[string]string
+	leaseOf map[string]int64
+	paths   []string
+}
+
+func listen() (*fakeEtcd, *httptest.Server) {
+	f := &fakeEtcd{leases: make(map[int64]bool), values: make(map[string]string), leaseOf: make(map[string]int64)}
+	return f, httptest.NewServer(http.HandlerFunc(f.serve))
+}
+
+func (f *fakeEtcd) serve(w http.ResponseWriter, r *http.Request) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.paths = append(f.paths, r.URL.Path)
+
+	var response interface{}
+	switch r.URL.Path {
+	case "/v3/lease/grant":
+		f.nextID++
+		f.leases[f.nextID] = true
+		response = map[string]string{"ID": strconv.FormatInt(f.nextID, 10), "TTL": "30"}
+	case "/v3/lease/keepalive":
+		var req leaseRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		result := map[string]string{"ID": strconv.FormatInt(req.ID, 10)}
+		if f.leases[req.ID] {
+			result["TTL"] = "30"
+		}
+		response = map[string]interface{}{"result": result}
+	case "/v3/lease/revoke":
+		var req leaseRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.expire(req.ID)
+		response = struct{}{}
+	case "/v3/kv/txn":
+		var req txnRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		response = f.txn(req)
+	default:
+		w.WriteHeader(http.StatusNotFound)
+		response = map[string]string{"message": "Not Found"}
+	}
+	_ = json.NewEncoder(w).Encode(response)
+}
+
+// Revoke a lease, deleting its keys. It's not thread-safe, lock above this.
+func (f *fakeEtcd) expire(id int64) {
+	delete(f.leases, id)
+	for key, lease := range f.leaseOf {
+		if lease == id {
+			delete(f.values, key)
+			delete(f.leaseOf, key)
+		}
+	}
+}
+
+// It's not thread-safe, lock above this
+func (f *fakeEtcd) txn(req txnRequest) map[string]interface{} {
+	succeeded := true
+	for _, c := range req.Compare {
+		value, exists := f.values[string(c.Key)]
+		switch c.Target {
+		case "CREATE":
+			succeeded = succeeded && !exists
+		case "VALUE":
+			succeeded = succeeded && exists && value == string(c.Value)
+		}
+	}
+	ops := req.Failure
+	if succeeded {
+		ops = req.Success
+	}
+
+	var responses []interface{}
+	for _, op := range ops {
+		switch {
+		case op.Put != nil:
+			f.values[string(op.Put.Key)] = string(op.Put.Value)
+			f.leaseOf[string(op.Put.Key)] = op.Put.Lease
+			responses = append(responses, map[string]interface{}{"response_put": struct{}{}})
+		case op.DeleteRange != nil:
+			delete(f.values, string(op.DeleteRange.Key))
+			delete(f.leaseOf, string(op.DeleteRange.Key))
+			responses = append(responses, map[string]interface{}{"response_delete_range": struct{}{}})
+		case op.Range != nil:
+			var kvs []keyValue
+			if value, ok := f.values[string(op.Range.Key)]; ok {
+				kvs = append(kvs, keyValue{Key: op.Range.Key, Value: []byte(value)})
+			}
+			responses = append(responses, map[string]interface{}{"response_range": map[string]interface{}{"kvs": kvs}})
+		}
+	}
+	response := map[string]interface{}{"responses": responses}
+	if succeeded {
+		response["succeeded"] = true
+	}
+	return response
+}
+
+func (f *fakeEtcd) owner(key string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.values[key]
+}
+
+func TestBackendAcquiresRenewsAndReleasesLeases(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	etcd, server := listen()
+	defer server.Close()
+	ctx := context.Background()
+	first := New(server.URL+"/", WithHTTPClient(server.Client()))
+	second := New(server.URL, WithHTTPClient(server.Client()))
+
+	acquired, err := first.Acquire(ctx, "app-42", "first", 10*time.Second)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	acquired, err = first.Acquire(ctx, "app-42", "first", 10*time.Second)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	acquired, err = second.Acquire(ctx, "app-42", "second", 10*time.Second)
+	assert.Nil(t, err)
+	assert.False(t, acquired)
+	assert.Equal(t, "first", etcd.owner(DefaultKeyPrefix+"app-42"))
+
+	// Releasing a lease which isn't held leaves it alone
+	assert.Nil(t, second.Release(ctx, "app-42", "second"))
+	assert.Equal(t, "first", etcd.owner(DefaultKeyPrefix+"app-42"))
+
+	assert.Nil(t, first.Release(ctx, "app-42", "first"))
+	acquired, err = second.Acquire(ctx, "app-42", "second", 10*time.Second)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "second", etcd.owner(DefaultKeyPrefix+"app-42"))
+
+	etcd.lock.Lock()
+	defer etcd.lock.Unlock()
+	// The etcd leases granted for the failed acquisition and the released lease are revoked
+	assert.Equal(t, map[int64]bool{3: true}, etcd.leases)
+	assert.Equal(t, []string{
+		"/v3/lease/grant", "/v3/kv/txn",
+		"/v3/lease/keepalive",
+		"/v3/lease/grant", "/v3/kv/txn", "/v3/lease/revoke",
+		"/v3/kv/txn",
+		"/v3/kv/txn", "/v3/lease/revoke",
+		"/v3/lease/grant", "/v3/kv/txn",
+	}, etcd.paths)
+}
+
+func TestBackendReacquiresExpiredLeases(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	etcd, server := listen()
+	defer server.Close()
+	ctx := context.Background()
+	b := New(server.URL, WithHTTPClient(server.Client()), WithKeyPrefix("leases/"))
+
+	acquired, _ := b.Acquire(ctx, "app-42", "first", time.Second)
+	assert.True(t, acquired)
+	etcd.lock.Lock()
+	etcd.expire(1)
+	etcd.lock.Unlock()
+	acquired, err := b.Acquire(ctx, "app-42", "first", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	assert.Equal(t, "first", etcd.owner("leases/app-42"))
+
+	// Restarted instances take back keys they held under their own name
+	restarted := New(server.URL, WithHTTPClient(server.Client()), WithKeyPrefix("leases/"))
+	acquired, err = restarted.Acquire(ctx, "app-42", "first", time.Second)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	etcd.lock.Lock()
+	assert.Equal(t, int64(3), etcd.leaseOf["leases/app-42"])
+	etcd.lock.Unlock()
+}
+
+func TestBackendReportsGatewayErrors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	_, server := listen()
+	defer server.Close()
+	b := New(server.URL+"/missing", WithHTTPClient(server.Client()))
+
+	_, err := b.Acquire(context.Background(), "app-42", "first", time.Second)
+	assert.EqualError(t, err, "etcdlease: /v3/lease/grant returned 404 Not Found: Not Found")
+}
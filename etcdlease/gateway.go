@@ -0,0 +1,89 @@
+package etcdlease
+
+import "bytes"
+
+// The subset of the etcd v3 JSON gateway's messages used by Backend. The gateway encodes 64-bit integers as strings,
+// and byte strings as base64.
+
+type leaseGrantRequest struct {
+	TTL int64 `json:"TTL,string"`
+}
+
+type leaseGrantResponse struct {
+	ID  int64 `json:"ID,string"`
+	TTL int64 `json:"TTL,string"`
+}
+
+type leaseRequest struct {
+	ID int64 `json:"ID,string"`
+}
+
+type keepAliveResponse struct {
+	Result struct {
+		ID  int64 `json:"ID,string"`
+		TTL int64 `json:"TTL,string"`
+	} `json:"result"`
+}
+
+type compare struct {
+	Key            []byte `json:"key"`
+	Target         string `json:"target"`
+	Result         string `json:"result"`
+	Value          []byte `json:"value,omitempty"`
+	CreateRevision int64  `json:"create_revision,omitempty,string"`
+}
+
+type putRequest struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+	Lease int64  `json:"lease,string"`
+}
+
+type rangeRequest struct {
+	Key []byte `json:"key"`
+}
+
+type requestOp struct {
+	Put         *putRequest   `json:"request_put,omitempty"`
+	Range       *rangeRequest `json:"request_range,omitempty"`
+	DeleteRange *rangeRequest `json:"request_delete_range,omitempty"`
+}
+
+type txnRequest struct {
+	Compare []compare   `json:"compare"`
+	Success []requestOp `json:"success,omitempty"`
+	Failure []requestOp `json:"failure,omitempty"`
+}
+
+type keyValue struct {
+	Key   []byte `json:"key"`
+	Value []byte `json:"value"`
+}
+
+type txnResponse struct {
+	Succeeded bool `json:"succeeded"`
+	Responses []struct {
+		Range *struct {
+			KVs []keyValue `json:"kvs"`
+		} `json:"response_range"`
+	} `json:"responses"`
+}
+
+type errorResponse struct {
+	Message string `json:"message"`
+}
+
+// Whether the range read by a failed transaction found the key held by owner
+func (r txnResponse) heldBy(owner string) bool {
+	for _, op := range r.Responses {
+		if op.Range == nil {
+			continue
+		}
+		for _, kv := range op.Range.KVs {
+			if bytes.Equal(kv.Value, []byte(owner)) {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,165 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// SubscriptionBuffer is how many events each subscription buffers. Events for a subscriber whose buffer is full are
+// dropped, so slow subscribers can't hold up pools.
+const SubscriptionBuffer = 256
+
+// EventType identifies a kind of Event
+type EventType int
+
+// Available event types
+const (
+	// EventPoolCreated - the manager built a pool
+	EventPoolCreated EventType = iota + 1
+	// EventPoolEvicted - a pool was evicted and disposed
+	EventPoolEvicted
+	// EventTaskPanicked - a task panicked, and the panic was recovered, see WithPanicRecovery
+	EventTaskPanicked
+	// EventQueueSaturated - a submission found the pool's queue full, and had to wait for room
+	EventQueueSaturated
+	// EventPoolQuarantined - a pool was quarantined, see WithQuarantine
+	EventPoolQuarantined
+)
+
+func (e EventType) String() string {
+	switch e {
+	case EventPoolCreated:
+		return "pool created"
+	case EventPoolEvicted:
+		return "pool evicted"
+	case EventTaskPanicked:
+		return "task panicked"
+	case EventQueueSaturated:
+		return "queue saturated"
+	case EventPoolQuarantined:
+		return "pool quarantined"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a lifecycle or scheduling event of a manager's pools, delivered to subscribers
+type Event struct {
+	Type EventType
+	Key  string
+	Time time.Time
+	// Eviction is set for EventPoolEvicted
+	Eviction *PoolEviction
+	// Panic is set for EventTaskPanicked, and for EventPoolQuarantined with the panic which tipped the pool over
+	Panic *TaskPanic
+}
+
+// Subscribe returns a channel delivering the manager's events of the given types, or of every type if none are
+// given. Delivery is best-effort: see SubscriptionBuffer. The channel is closed by Unsubscribe, or when the manager
+// is disposed, so evictions caused by disposing the manager aren't delivered.
+//
+// Pools built with their own options by NewWorkerPoolWithOptions don't publish events.
+func (m *WorkerPoolManager) Subscribe(types ...EventType) <-chan Event {
+	return m.events.subscribe(types)
+}
+
+// Unsubscribe stops delivering events to a channel returned by Subscribe, and closes it
+func (m *WorkerPoolManager) Unsubscribe(events <-chan Event) {
+	m.events.unsubscribe(events)
+}
+
+// eventBus fans a manager's events out to its subscribers
+type eventBus struct {
+	clock       Clock
+	lock        *sync.RWMutex
+	subscribers map[<-chan Event]*subscription
+	closed      bool
+}
+
+type subscription struct {
+	events chan Event
+	// The types delivered, or nil for all of them
+	types map[EventType]bool
+}
+
+func newEventBus(clock Clock) *eventBus {
+	return &eventBus{
+		clock:       clock,
+		lock:        &sync.RWMutex{},
+		subscribers: make(map[<-chan Event]*subscription),
+	}
+}
+
+func (b *eventBus) subscribe(types []EventType) <-chan Event {
+	s := &subscription{events: make(chan Event, SubscriptionBuffer)}
+	if len(types) > 0 {
+		s.types = make(map[EventType]bool, len(types))
+		for _, t := range types {
+			s.types[t] = true
+		}
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.closed {
+		close(s.events)
+	} else {
+		b.subscribers[s.events] = s
+	}
+	return s.events
+}
+
+func (b *eventBus) unsubscribe(events <-chan Event) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if s, ok := b.subscribers[events]; ok {
+		delete(b.subscribers, events)
+		close(s.events)
+	}
+}
+
+func (b *eventBus) close() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.closed = true
+	for events, s := range b.subscribers {
+		delete(b.subscribers, events)
+		close(s.events)
+	}
+}
+
+func (b *eventBus) publish(event Event) {
+	event.Time = b.clock.Now()
+	b.lock.RLock()
+	defer b.lock.RUnlock()
+	for _, s := range b.subscribers {
+		if s.types != nil && !s.types[event.Type] {
+			continue
+		}
+		select {
+		case s.events <- event:
+		default:
+		}
+	}
+}
+
+// Hooks publishing the events they observe
+func (b *eventBus) hooks() Hooks {
+	return Hooks{
+		OnPoolCreated: func(key string, pool WorkerPool) {
+			b.publish(Event{Type: EventPoolCreated, Key: key})
+		},
+		OnPoolEvicted: func(eviction PoolEviction) {
+			b.publish(Event{Type: EventPoolEvicted, Key: eviction.Key, Eviction: &eviction})
+		},
+		OnTaskPanic: func(recovered TaskPanic) {
+			b.publish(Event{Type: EventTaskPanicked, Key: recovered.Key, Panic: &recovered})
+		},
+		OnQueueSaturated: func(key string) {
+			b.publish(Event{Type: EventQueueSaturated, Key: key})
+		},
+		OnPoolQuarantined: func(key string, lastPanic TaskPanic) {
+			b.publish(Event{Type: EventPoolQuarantined, Key: key, Panic: &lastPanic})
+		},
+	}
+}
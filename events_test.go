@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubscribeDeliversEvents(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(
+		1, 20*time.Millisecond, time.Hour, WithQuarantine(Quarantine{Panics: 1, Window: time.Minute}),
+	)
+	all := pm.Subscribe()
+	panics := pm.Subscribe(EventTaskPanicked)
+
+	pool, doneUsing := pm.GetPool("key", 1)
+	pool.Submit(func() {
+		panic("poisoned task")
+	})
+	close(doneUsing)
+
+	var types []EventType
+	for event := range all {
+		assert.Equal(t, "key", event.Key)
+		assert.False(t, event.Time.IsZero())
+		types = append(types, event.Type)
+		if event.Type == EventPoolEvicted {
+			assert.Equal(t, EvictionReasonExpired, event.Eviction.Reason)
+			break
+		}
+	}
+	assert.Equal(t, []EventType{EventPoolCreated, EventTaskPanicked, EventPoolQuarantined, EventPoolEvicted}, types)
+
+	event := <-panics
+	assert.Equal(t, "poisoned task", event.Panic.Value)
+	assert.Empty(t, panics)
+
+	pm.Unsubscribe(all)
+	_, open := <-all
+	assert.False(t, open)
+	pm.Dispose()
+	_, open = <-panics
+	assert.False(t, open)
+	_, open = <-pm.Subscribe()
+	assert.False(t, open)
+}
+
+func TestQueueSaturatedEvent(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Second, time.Hour)
+	saturated := pm.Subscribe(EventQueueSaturated)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	unblock := make(chan bool)
+	pool.Submit(func() {
+		<-unblock
+	})
+	// One task executing and one queued fill the pool, so the third has to wait
+	done := make(chan bool)
+	for i := 0; i < 2; i++ {
+		go pool.Submit(func() {
+			done <- true
+		})
+	}
+	assert.Equal(t, "key", (<-saturated).Key)
+	close(unblock)
+	<-done
+	<-done
+
+	close(doneUsing)
+	pm.Dispose()
+}
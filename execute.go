@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrQueueTimeout is returned by ExecuteOnPool when f is still queued after the queue timeout
+var ErrQueueTimeout = errors.New("timed out waiting for a worker")
+
+// What became of a call of ExecuteOnPool waiting in its pool's queue, which is settled once either a worker takes it up
+// or it's abandoned
+const (
+	executionWaiting int32 = iota
+	executionStarted
+	executionAbandoned
+)
+
+// ExecuteOnPool calls f on a worker of key's pool and waits for it to return, turning the manager into a per-key
+// concurrency limit for synchronous work such as request handlers. It returns ErrQueueTimeout if f is still waiting
+// for a worker after queueTimeout, or ctx's error if ctx is done first - a wait only limited by ctx if queueTimeout
+// isn't positive - and f is then never called. It returns the same errors as SubmitTask if the pool rejects f, and
+// GetPoolContext's if the pool can't be checked out.
+func ExecuteOnPool(ctx context.Context, pm *WorkerPoolManager, key string, queueTimeout time.Duration, f func()) error {
+	p, doneUsing, err := pm.GetPoolContext(ctx, key, 1, nil)
+	if err != nil {
+		return err
+	}
+	defer close(doneUsing)
+
+	state := executionWaiting
+	started := make(chan bool)
+	finished := make(chan bool)
+	// Buffered, so a submission which was waiting for room doesn't block once the call has been abandoned
+	rejected := make(chan error, 1)
+	go func() {
+		err := p.enqueue(task{work: func() {
+			if !atomic.CompareAndSwapInt32(&state, executionWaiting, executionStarted) {
+				return
+			}
+			close(started)
+			defer close(finished)
+			f()
+		}, dropped: func() {
+			rejected <- ErrTaskPurged
+		}})
+		if err != nil {
+			rejected <- err
+		}
+	}()
+
+	var timeout <-chan time.Time
+	if queueTimeout > 0 {
+		timer := time.NewTimer(queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-started:
+		<-finished
+		return nil
+	case err = <-rejected:
+		return err
+	case <-timeout:
+		err = ErrQueueTimeout
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+	if !atomic.CompareAndSwapInt32(&state, executionWaiting, executionAbandoned) {
+		// A worker took the call up just as it was abandoned
+		<-finished
+		return nil
+	}
+	return err
+}
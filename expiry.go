@@ -0,0 +1,166 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// WithoutMaxLifetime turns off max lifetime rotation, so pools are only evicted once they've gone unused for the stale
+// pool expiration, however long they've lived. The maxPoolLifetime passed to NewWorkerPoolManager and set by
+// UpdateConfig is ignored, but a Child can turn rotation back on for its pools with WithMaxPoolLifetime.
+func WithoutMaxLifetime() Option {
+	return func(o *options) {
+		o.withoutMaxLifetime = true
+		o.maxPoolLifetime = 0
+	}
+}
+
+// WithDrainBeforeRotate makes pools which outlive the max pool lifetime drain before they're replaced: checkouts of a
+// rotated pool's key wait until every caller has released it and it has no queued or executing tasks before a new
+// pool is built, so the key never has two pools working at once. It's for downstreams which can't take the combined
+// concurrency of the old and new pools while the old one finishes its work. The wait is as long as the old pool's
+// checkouts are held, up to a minute after the rotation, after which the new pool is built regardless - so a
+// caller which checks its key out again while holding the checkout which rotated it is held up rather than stuck.
+// Disposing the old pool drops its queued tasks, so once it's disposed only its executing tasks are waited for.
+func WithDrainBeforeRotate() Option {
+	return func(o *options) {
+		o.drainBeforeRotate = true
+	}
+}
+
+// How often checkouts waiting on a rotated pool check whether it has drained
+const drainPollInterval = 5 * time.Millisecond
+
+// How long after a pool's rotation checkouts of its key wait for it to drain, see WithDrainBeforeRotate
+const maxDrainWait = time.Minute
+
+// drainingPool is a rotated pool which checkouts of its key wait on, see WithDrainBeforeRotate
+type drainingPool struct {
+	pool WorkerPool
+	// When the checkouts stop waiting, on the manager's clock
+	deadline time.Time
+}
+
+// Build key's replacement for old as soon as old is rotated, rather than on the next checkout, so OnRotate hooks can
+// hand old's state over before the replacement is used - or with WithDrainBeforeRotate, leave it to the first checkout
+// after old has drained. It's not thread-safe, lock above this
+func (m *WorkerPoolManager) rotate(key string, old WorkerPool, create func() (WorkerPool, error)) {
+	if m.options.drainBeforeRotate {
+		m.draining[key] = drainingPool{pool: old, deadline: m.clock.Now().Add(maxDrainWait)}
+		return
+	}
+	if !m.options.handsOver() {
+		return
+	}
+	replacement, _, err := m.getOrCreate(key, create)
+	if err != nil {
+		// The next checkout tries again, without a handover
+		return
+	}
+	m.options.poolCreated(key, replacement)
+	m.options.poolRotated(key, old, replacement)
+}
+
+// Whether every caller has released the rotated pool old, and it has finished its work - or the checkouts waiting on
+// it have run out of patience
+func (m *WorkerPoolManager) drained(old drainingPool) bool {
+	if !m.clock.Now().Before(old.deadline) {
+		return true
+	}
+	return old.pool.reservations() == 0 && old.pool.settled()
+}
+
+// Block until the rotated pool old has drained
+func (m *WorkerPoolManager) waitForDrain(old drainingPool) {
+	for !m.drained(old) {
+		time.Sleep(drainPollInterval)
+	}
+}
+
+// Whether stale pools are expired by timers on the manager's clock rather than by the cache's janitor
+func (m *WorkerPoolManager) clockDrivenExpiry() bool {
+	_, isRealClock := m.clock.(realClock)
+	return !isRealClock
+}
+
+// Return key's cached pool, reporting that it was cached, or else cache and return the pool built by create. Cached
+// pools which the scheduler evicts or which need rebuilding are replaced. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) getOrCreate(key string, create func() (WorkerPool, error)) (WorkerPool, bool, error) {
+	item := m.workerPoolCache.Get(key)
+	if item != nil && m.options.scheduler != nil && m.options.scheduler.evict(key) {
+		item.Value().markEvicted(EvictionReasonExpired)
+		m.workerPoolCache.Delete(key)
+		item = nil
+	}
+	if item != nil && item.Value().needsRebuild() {
+		item.Value().markEvicted(EvictionReasonQuarantined)
+		m.workerPoolCache.Delete(key)
+		item = nil
+	}
+	if item != nil {
+		if ttl := m.cacheTTL(key); item.TTL() != ttl {
+			// The stale pool expiration has been changed by UpdateConfig
+			m.workerPoolCache.Set(key, item.Value(), ttl)
+		}
+		return item.Value(), true, nil
+	}
+
+	pool, err := create()
+	if err != nil {
+		return nil, false, err
+	}
+	// The cache misses on pools which have expired but haven't been evicted by its janitor yet. Setting key over one
+	// would leave it queued for eviction, and evicting it later would drop key's new pool from the cache instead, so the
+	// next checkout would build a second live pool for key. Evicting expired pools first rules that out.
+	m.workerPoolCache.DeleteExpired()
+	m.workerPoolCache.Set(key, pool, m.cacheTTL(key))
+	return pool, false, nil
+}
+
+// Record that key was just used, pushing back its expiration. It's not thread-safe, lock above this
+func (m *WorkerPoolManager) touch(key string) {
+	if !m.clockDrivenExpiry() {
+		// The cache's janitor takes care of it
+		return
+	}
+	m.lastUsed[key] = m.clock.Now()
+	if _, scheduled := m.expiryTimers[key]; !scheduled {
+		m.expiryTimers[key] = m.clock.AfterFunc(m.staleExpirationFor(key), func() {
+			m.expire(key)
+		})
+	}
+}
+
+// Evict the pool for key if it has gone unused for the stale pool expiration, otherwise check again once it might have
+func (m *WorkerPoolManager) expire(key string) {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+
+	lastUsed, ok := m.lastUsed[key]
+	if !ok {
+		return
+	}
+	if remaining := m.staleExpirationFor(key) - m.clock.Now().Sub(lastUsed); remaining > 0 {
+		m.expiryTimers[key].Reset(remaining)
+		return
+	}
+
+	delete(m.lastUsed, key)
+	delete(m.expiryTimers, key)
+	if item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]()); item != nil {
+		item.Value().markEvicted(EvictionReasonExpired)
+		m.workerPoolCache.Delete(key)
+	}
+}
+
+func (m *WorkerPoolManager) stopExpiryTimers() {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+
+	for key, timer := range m.expiryTimers {
+		timer.Stop()
+		delete(m.expiryTimers, key)
+		delete(m.lastUsed, key)
+	}
+}
@@ -0,0 +1,38 @@
+package pool
+
+// ExtendedPool is the extension point for custom pools which take part in their own lifecycle.
+//
+// WorkerPool has unexported methods, which the manager relies on to reserve, configure and dispose pools correctly,
+// so a pool from outside this package can't implement it from scratch. Custom pools embed one of this package's pools
+// instead - typically the one NewWorkerPool returns - and override its exported methods, while lifecycle behavior is
+// added by implementing the optional interfaces the manager looks for: ExtendedPool for checkouts, ErrorDisposer for
+// disposal which can fail, and io.Closer for releasing resources once the pool's workers have stopped. WrapPool does
+// the same with callbacks, without declaring a type.
+type ExtendedPool interface {
+	WorkerPool
+	// PoolConfigured is called once the manager has configured the pool for key, before handing it out for the first
+	// time
+	PoolConfigured(key string)
+	// PoolReserved is called each time the pool is checked out
+	PoolReserved()
+	// PoolReleased is called each time a checkout of the pool is released
+	PoolReleased()
+}
+
+func poolConfigured(pool WorkerPool, key string) {
+	if extended, ok := pool.(ExtendedPool); ok {
+		extended.PoolConfigured(key)
+	}
+}
+
+func poolReserved(pool WorkerPool) {
+	if extended, ok := pool.(ExtendedPool); ok {
+		extended.PoolReserved()
+	}
+}
+
+func poolReleased(pool WorkerPool) {
+	if extended, ok := pool.(ExtendedPool); ok {
+		extended.PoolReleased()
+	}
+}
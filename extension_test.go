@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// lifecyclePool records the lifecycle calls it receives as an ExtendedPool
+type lifecyclePool struct {
+	WorkerPool
+	lock  *sync.Mutex
+	calls []string
+}
+
+func (p *lifecyclePool) PoolConfigured(key string) {
+	p.record("configured " + key)
+}
+
+func (p *lifecyclePool) PoolReserved() {
+	p.record("reserved")
+}
+
+func (p *lifecyclePool) PoolReleased() {
+	p.record("released")
+}
+
+func (p *lifecyclePool) record(call string) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.calls = append(p.calls, call)
+}
+
+func (p *lifecyclePool) recorded() []string {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return append([]string(nil), p.calls...)
+}
+
+func TestManagerDrivesExtendedPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	var extended *lifecyclePool
+	assert.Nil(t, pm.RegisterFactory("*", func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		extended = &lifecyclePool{WorkerPool: basePool, lock: &sync.Mutex{}}
+		// Wrapping keeps it extended
+		return WrapPool(extended, PoolHooks{}), nil
+	}))
+
+	for i := 0; i < 2; i++ {
+		_, doneUsing := pm.GetPool("key", 1)
+		close(doneUsing)
+		assert.Eventually(t, func() bool {
+			return pm.Snapshot()["key"].Reservations == 0
+		}, time.Second, time.Millisecond)
+	}
+	assert.Equal(t, []string{"configured key", "reserved", "released", "reserved", "released"}, extended.recorded())
+	pm.Dispose()
+}
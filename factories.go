@@ -0,0 +1,69 @@
+package pool
+
+import (
+	"path"
+	"sync"
+)
+
+// registeredFactory is a Factory registered for the keys matching pattern, see RegisterFactory
+type registeredFactory struct {
+	pattern string
+	factory Factory
+}
+
+// factoryRegistry holds a manager's registered factories, in the order they were registered
+type factoryRegistry struct {
+	lock      *sync.RWMutex
+	factories []registeredFactory
+}
+
+// WithFactoryMiddleware wraps every factory the manager builds pools with - NewWorkerPool, registered factories and
+// those passed to GetPoolWithFactory alike - with middleware, so cross-cutting decorations such as instrumented pools
+// or attached resources are added in one place. It may be passed multiple times, and the first middleware passed is
+// the outermost.
+//
+// Middleware which wraps pools changes their type, so callers type asserting the pools from GetPoolWithFactory, e.g.
+// GetResourcePool, should be given pools which embed the one built by next rather than replace it.
+func WithFactoryMiddleware(middleware func(next Factory) Factory) Option {
+	return func(o *options) {
+		o.factoryMiddleware = append(o.factoryMiddleware, middleware)
+	}
+}
+
+// Wrap factory with the manager's middleware
+func (o *options) decorateFactory(factory Factory) Factory {
+	for i := len(o.factoryMiddleware) - 1; i >= 0; i-- {
+		factory = o.factoryMiddleware[i](factory)
+	}
+	return factory
+}
+
+// RegisterFactory builds the pools of keys matching keyPattern with f, so call sites needn't pass the right factory to
+// GetPoolWithFactory themselves. Patterns are matched as with path.Match, e.g. "tenant-*", and when several match a
+// key the first registered wins. It returns path.ErrBadPattern if keyPattern is malformed.
+//
+// Registered factories are used by GetPool, by GetPoolWithFactory with a nil factory, and for the pools built by
+// prewarming and durable task replay. They're only consulted when a pool is built, so cached pools keep their type
+// until they're evicted. GetPool can't return a factory's error, so it panics when a registered factory fails - use
+// GetPoolWithFactory with a nil factory for factories which can fail.
+func (m *WorkerPoolManager) RegisterFactory(keyPattern string, f Factory) error {
+	if _, err := path.Match(keyPattern, ""); err != nil {
+		return err
+	}
+	m.factories.lock.Lock()
+	defer m.factories.lock.Unlock()
+	m.factories.factories = append(m.factories.factories, registeredFactory{pattern: keyPattern, factory: f})
+	return nil
+}
+
+// The factory registered for key, NewWorkerPool if there isn't one
+func (r *factoryRegistry) factoryFor(key string) Factory {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+	for _, registered := range r.factories {
+		if matched, _ := path.Match(registered.pattern, key); matched {
+			return registered.factory
+		}
+	}
+	return NewWorkerPool
+}
@@ -0,0 +1,114 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"path"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestRegisteredFactoriesBuildMatchingKeys(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	factory := func(value int) Factory {
+		return func(maxSize int) (WorkerPool, error) {
+			basePool, _ := NewWorkerPool(maxSize)
+			return &MockWorkerPool{WorkerPool: basePool, value: value}, nil
+		}
+	}
+	assert.Nil(t, pm.RegisterFactory("tenant-*", factory(1)))
+	assert.Nil(t, pm.RegisterFactory("tenant-vip", factory(2)))
+	assert.Equal(t, path.ErrBadPattern, pm.RegisterFactory("tenant-[", factory(3)))
+
+	pool, doneUsing := pm.GetPool("tenant-vip", 1)
+	// The first registered pattern wins
+	assert.Equal(t, 1, pool.(*MockWorkerPool).value)
+	close(doneUsing)
+	pool, doneUsing, err := pm.GetPoolWithFactory("tenant-2", 1, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pool.(*MockWorkerPool).value)
+	close(doneUsing)
+	pool, doneUsing = pm.GetPool("other", 1)
+	assert.IsType(t, &BaseWorkerPool{}, pool)
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestGetPoolPanicsWhenARegisteredFactoryFails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	failure := errors.New("failure")
+	assert.Nil(t, pm.RegisterFactory("*", func(int) (WorkerPool, error) {
+		return nil, failure
+	}))
+
+	_, _, err := pm.GetPoolWithFactory("key", 1, nil)
+	assert.Equal(t, failure, err)
+	assert.PanicsWithError(t, `building the worker pool for "key": failure`, func() {
+		pm.GetPool("key", 1)
+	})
+	pm.Dispose()
+}
+
+func TestCheckoutHelpersReturnARegisteredFactorysError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	failure := errors.New("failure")
+	assert.Nil(t, pm.RegisterFactory("*", func(int) (WorkerPool, error) {
+		return nil, failure
+	}))
+
+	called := false
+	assert.Equal(t, failure, ExecuteOnPool(context.Background(), pm, "execute", 0, func() {
+		called = true
+	}))
+	bulkhead := NewBulkhead(pm, "bulkhead", BulkheadConfig{MaxConcurrent: 1})
+	assert.Equal(t, failure, bulkhead.Execute(context.Background(), func(context.Context) error {
+		called = true
+		return nil
+	}))
+	assert.Equal(t, 0, bulkhead.Executing())
+	group := ErrGroup(pm, "group")
+	group.Go(func() error {
+		called = true
+		return nil
+	})
+	assert.Equal(t, failure, group.Wait())
+	assert.False(t, called)
+	pm.Dispose()
+}
+
+func TestFactoryMiddlewareWrapsEveryFactory(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var built []string
+	middleware := func(name string) Option {
+		return WithFactoryMiddleware(func(next Factory) Factory {
+			return func(maxSize int) (WorkerPool, error) {
+				built = append(built, name)
+				return next(maxSize)
+			}
+		})
+	}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, middleware("outer"), middleware("inner"))
+	_, doneUsing := pm.GetPool("default", 1)
+	close(doneUsing)
+	pool, doneUsing, err := pm.GetPoolWithFactory("custom", 1, func(maxSize int) (WorkerPool, error) {
+		built = append(built, "custom")
+		basePool, _ := NewWorkerPool(maxSize)
+		return &MockWorkerPool{WorkerPool: basePool, value: 1}, nil
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, 1, pool.(*MockWorkerPool).value)
+	close(doneUsing)
+
+	assert.Equal(t, []string{"outer", "inner", "outer", "inner", "custom"}, built)
+	pm.Dispose()
+}
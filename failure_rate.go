@@ -0,0 +1,133 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// The window over which PoolSnapshot.FailureRate is measured, unless set by WithAutoPause
+const defaultFailureRateWindow = time.Minute
+
+// Failure rates are counted in this many buckets across their window, which expire one at a time
+const failureRateBuckets = 10
+
+// AutoPause configures when a pool automatically pauses because too many of its tasks are failing, set with
+// WithAutoPause
+type AutoPause struct {
+	// Threshold is the fraction of attempts, between 0 and 1, which have to fail within Window to pause the pool
+	Threshold float64
+	Window    time.Duration
+	// MinAttempts is how many attempts have to be made within Window before the failure rate is trusted
+	MinAttempts int
+	// Cooldown is how long the pool stays paused before resuming by itself. Zero means until it's resumed with
+	// WorkerPoolManager.ResumeKey.
+	Cooldown time.Duration
+}
+
+// WithAutoPause pauses pools whose failure rate reaches autoPause.Threshold, so that workers stop starting tasks
+// while the downstream recovers. Queued tasks are kept and carry on once the pool resumes, and the OnPoolAutoPaused
+// hook is called.
+//
+// A pool's failure rate is measured over the attempts made by tasks submitted with SubmitWithRetry, and is included in
+// PoolSnapshot.FailureRate.
+func WithAutoPause(autoPause AutoPause) Option {
+	return func(o *options) {
+		if autoPause.Window > 0 {
+			o.autoPause = &autoPause
+		}
+	}
+}
+
+func (o *options) poolAutoPaused(key string, failureRate float64) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnPoolAutoPaused != nil {
+			hooks.OnPoolAutoPaused(key, failureRate)
+		}
+	}
+}
+
+// outcomeBucket counts the attempts made within one slice of a failure rate's window
+type outcomeBucket struct {
+	// The slice of the window the counts are for
+	slice    int64
+	attempts int
+	failures int
+}
+
+// failureRate is a rolling rate of failed attempts
+type failureRate struct {
+	lock *sync.Mutex
+	// How much of the window each bucket covers
+	bucketWidth time.Duration
+	buckets     [failureRateBuckets]outcomeBucket
+}
+
+func newFailureRate(window time.Duration) *failureRate {
+	bucketWidth := window / failureRateBuckets
+	if bucketWidth < 1 {
+		// Windows shorter than a nanosecond per bucket are as fine grained as the clock gets
+		bucketWidth = 1
+	}
+	return &failureRate{lock: &sync.Mutex{}, bucketWidth: bucketWidth}
+}
+
+func (r *failureRate) slice(now time.Time) int64 {
+	return now.UnixNano() / int64(r.bucketWidth)
+}
+
+// Record an attempt, returning the failure rate and number of attempts within the window
+func (r *failureRate) record(now time.Time, failed bool) (float64, int) {
+	slice := r.slice(now)
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	bucket := &r.buckets[slice%failureRateBuckets]
+	if bucket.slice != slice {
+		*bucket = outcomeBucket{slice: slice}
+	}
+	bucket.attempts++
+	if failed {
+		bucket.failures++
+	}
+	return r.rateLocked(slice)
+}
+
+func (r *failureRate) rate(now time.Time) (float64, int) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.rateLocked(r.slice(now))
+}
+
+func (r *failureRate) rateLocked(slice int64) (float64, int) {
+	var attempts, failures int
+	for _, bucket := range r.buckets {
+		if slice-bucket.slice < failureRateBuckets {
+			attempts += bucket.attempts
+			failures += bucket.failures
+		}
+	}
+	if attempts == 0 {
+		return 0, 0
+	}
+	return float64(failures) / float64(attempts), attempts
+}
+
+func (r *failureRate) reset() {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.buckets = [failureRateBuckets]outcomeBucket{}
+}
+
+// Record the outcome of an attempt, pausing the pool if that takes its failure rate over the auto pause threshold
+func (p *BaseWorkerPool) recordOutcome(err error) {
+	rate, attempts := p.failures.record(p.clock.Now(), err != nil)
+	if err == nil || p.options == nil || p.options.autoPause == nil {
+		return
+	}
+	autoPause := p.options.autoPause
+	if attempts < autoPause.MinAttempts || rate < autoPause.Threshold || p.paused() {
+		return
+	}
+	p.failures.reset()
+	p.pause(autoPause.Cooldown)
+	p.options.poolAutoPaused(p.key, rate)
+}
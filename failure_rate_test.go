@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestFailureRateRollsOver(t *testing.T) {
+	r := newFailureRate(10 * time.Second)
+	start := time.Unix(1000, 0)
+
+	r.record(start, true)
+	r.record(start.Add(time.Second), false)
+	rate, attempts := r.record(start.Add(2*time.Second), false)
+	assert.InDelta(t, 1.0/3, rate, 0.001)
+	assert.Equal(t, 3, attempts)
+
+	// The first failure falls out of the window
+	rate, attempts = r.rate(start.Add(10 * time.Second))
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, 2, attempts)
+
+	rate, attempts = r.rate(start.Add(time.Minute))
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, 0, attempts)
+}
+
+func TestFailureRateWithTinyWindow(t *testing.T) {
+	r := newFailureRate(time.Nanosecond)
+	start := time.Unix(1000, 0)
+
+	rate, attempts := r.record(start, true)
+	assert.Equal(t, 1.0, rate)
+	assert.Equal(t, 1, attempts)
+	rate, attempts = r.rate(start.Add(time.Second))
+	assert.Equal(t, 0.0, rate)
+	assert.Equal(t, 0, attempts)
+}
+
+func TestAutoPauseHoldsTasksUntilResumed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	paused := make(chan float64, 10)
+	pm := NewWorkerPoolManager(
+		1, time.Second, time.Hour,
+		WithAutoPause(AutoPause{Threshold: 0.5, Window: time.Minute, MinAttempts: 2}),
+		WithHooks(Hooks{
+			OnPoolAutoPaused: func(key string, failureRate float64) {
+				paused <- failureRate
+			},
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	for i := 0; i < 2; i++ {
+		_ = SubmitWithRetry(pool, TaskInfo{}, 0, func() error {
+			return errors.New("downstream unavailable")
+		})
+	}
+	assert.Equal(t, 1.0, <-paused)
+	assert.True(t, pm.Snapshot()["key"].Paused)
+
+	ran := make(chan bool, 1)
+	pool.Submit(func() {
+		ran <- true
+	})
+	select {
+	case <-ran:
+		t.Fatal("paused pool started a task")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	assert.True(t, pm.ResumeKey("key"))
+	<-ran
+	assert.False(t, pm.ResumeKey("missing"))
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestAutoPauseResumesAfterCooldown(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPoolWithOptions(1, WithAutoPause(AutoPause{
+		Threshold: 1, Window: time.Minute, Cooldown: 20 * time.Millisecond,
+	}))
+	p.spawnWorkers(1)
+
+	_ = SubmitWithRetry(p, TaskInfo{}, 0, func() error {
+		return errors.New("downstream unavailable")
+	})
+	start := time.Now()
+	done := make(chan bool)
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+	assert.GreaterOrEqual(t, time.Since(start), 15*time.Millisecond)
+	assert.False(t, p.snapshot().Paused)
+	p.Dispose()
+}
@@ -0,0 +1,73 @@
+package pool
+
+import "sync/atomic"
+
+// FairShare configures how WithFairShare divides a shared fleet between keys
+type FairShare struct {
+	// Weight returns key's weight, e.g. its tenant's plan size. Keys weigh 1 if it's nil, and weights below 1 count
+	// as 1.
+	Weight func(key string) int
+	// MinWorkers is how many fleet workers each key with work may use however little it weighs, 1 if unset
+	MinWorkers int
+}
+
+// WithFairShare divides the workers of a shared fleet - see WithSharedFleet and WithMultiplexedDispatch - between the
+// keys with work in proportion to their weights, so bigger tenants get more of the fleet while small tenants keep a
+// guaranteed minimum. Shares are recomputed as keys' work comes and goes: a key with queued or executing tasks may have
+// its share of the fleet's workers, up to its own pool size, and idle keys' shares go to the rest.
+//
+// Minimums are guaranteed even when they add up to more than the fleet, in which case keys take turns at the workers.
+// Without a shared fleet there's no shared budget to divide, and WithFairShare has no effect.
+func WithFairShare(fairShare FairShare) Option {
+	return func(o *options) {
+		if fairShare.MinWorkers < 1 {
+			fairShare.MinWorkers = 1
+		}
+		o.fairShare = &fairShare
+	}
+}
+
+func (f *FairShare) weight(key string) int64 {
+	if f == nil || f.Weight == nil {
+		return 1
+	}
+	if weight := f.Weight(key); weight > 1 {
+		return int64(weight)
+	}
+	return 1
+}
+
+// The most turns the pool may have right now: its own cap, or its fair share of the fleet if that's smaller. It's not
+// thread-safe, lock above this.
+func (m *fleetMember) currentLimit() int {
+	fairShare := m.fleet.options.fairShare
+	if fairShare == nil {
+		return m.limit
+	}
+	active := atomic.LoadInt64(&m.fleet.activeWeight)
+	if m.turns == 0 {
+		// About to take its first turn, so it's counted as having work
+		active += m.weight
+	}
+	share := int(int64(m.fleet.workers) * m.weight / active)
+	if share < fairShare.MinWorkers {
+		share = fairShare.MinWorkers
+	}
+	return min(m.limit, share)
+}
+
+// Count the pool's weight towards the fleet's keys with work while it has turns. It's not thread-safe, lock above this.
+func (m *fleetMember) addTurn() {
+	m.turns++
+	if m.turns == 1 {
+		atomic.AddInt64(&m.fleet.activeWeight, m.weight)
+	}
+}
+
+// It's not thread-safe, lock above this
+func (m *fleetMember) removeTurn() {
+	m.turns--
+	if m.turns == 0 {
+		atomic.AddInt64(&m.fleet.activeWeight, -m.weight)
+	}
+}
@@ -0,0 +1,94 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestFairShareDividesTheFleetByWeight(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	weights := map[string]int{"big": 3, "small": 1}
+	pm := NewWorkerPoolManager(
+		4, time.Hour, time.Hour, WithSharedFleet(4), WithQueueCapacity(20),
+		WithFairShare(FairShare{Weight: func(key string) int {
+			return weights[key]
+		}}),
+	)
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	work := func() {
+		defer wg.Done()
+		<-release
+	}
+	executing := func(key string) int {
+		return pm.Snapshot()[key].Executing
+	}
+
+	// Alone, the big key may use the whole fleet
+	big, doneUsing := pm.GetPool("big", 4)
+	wg.Add(8)
+	for i := 0; i < 8; i++ {
+		big.Submit(work)
+	}
+	close(doneUsing)
+	assert.Eventually(t, func() bool {
+		return executing("big") == 4
+	}, time.Second, time.Millisecond)
+
+	// Once the small key has work too, the big key's turns shrink to its share as they finish
+	small, doneUsing := pm.GetPool("small", 4)
+	wg.Add(4)
+	for i := 0; i < 4; i++ {
+		small.Submit(work)
+	}
+	close(doneUsing)
+	release <- true
+	assert.Eventually(t, func() bool {
+		return executing("big") == 3 && executing("small") == 1
+	}, time.Second, time.Millisecond)
+	release <- true
+	assert.Eventually(t, func() bool {
+		return executing("big") == 3 && executing("small") == 1
+	}, time.Second, time.Millisecond)
+	assert.Contains(t, pm.Config().Features, "fair share")
+
+	close(release)
+	wg.Wait()
+	pm.Dispose()
+}
+
+func TestFairShareGuaranteesAMinimum(t *testing.T) {
+	pm := NewWorkerPoolManager(
+		8, time.Hour, time.Hour, WithSharedFleet(8),
+		WithFairShare(FairShare{MinWorkers: 2, Weight: func(key string) int {
+			if key == "big" {
+				return 100
+			}
+			return 1
+		}}),
+	)
+	defer pm.Dispose()
+	member := func(key string) *fleetMember {
+		pool, doneUsing := pm.GetPool(key, 8)
+		close(doneUsing)
+		return pool.(*BaseWorkerPool).fleet
+	}
+	big, small := member("big"), member("small")
+	// The big key has work, so the small one's share rounds down to nothing
+	big.lock.Lock()
+	big.addTurn()
+	big.lock.Unlock()
+
+	small.lock.Lock()
+	assert.Equal(t, 2, small.currentLimit())
+	small.lock.Unlock()
+	big.lock.Lock()
+	assert.Equal(t, 8, big.currentLimit())
+	big.removeTurn()
+	big.lock.Unlock()
+}
@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// ErrInjectedFault is returned by factories failed by WithFaults, and is the value injected task panics panic with
+var ErrInjectedFault = errors.New("injected fault")
+
+// Faults configures the faults WithFaults injects. Each rate is the probability, from 0 to 1, that a given submission,
+// pool build or task is faulted, and faults are off while their rate is zero.
+type Faults struct {
+	// SubmissionDelay is how long faulted submissions are held up before they're enqueued, at SubmissionDelayRate
+	SubmissionDelay     time.Duration
+	SubmissionDelayRate float64
+	// FactoryFailureRate fails pool builds with ErrInjectedFault instead of calling their factories. It's returned by
+	// GetPoolWithFactory, GetPoolWithSize and GetPoolContext, and GetPool, which can't return it, panics with it.
+	FactoryFailureRate float64
+	// TaskPanicRate panics tasks with ErrInjectedFault before their work is called, as WithPanicRecovery would recover
+	TaskPanicRate float64
+	// EvictionDelay is how long faulted evictions are held up before their pools are disposed, at EvictionDelayRate
+	EvictionDelay     time.Duration
+	EvictionDelayRate float64
+	// Seed seeds the choice of which faults are injected, the current time if unset
+	Seed int64
+}
+
+// WithFaults injects faults into the manager and its pools - delayed submissions, failing factories, panicking tasks
+// and delayed evictions - so that services can test how they behave when the pool layer misbehaves. It's meant for
+// tests, never for production.
+func WithFaults(faults Faults) Option {
+	return func(o *options) {
+		seed := faults.Seed
+		if seed == 0 {
+			seed = time.Now().UnixNano()
+		}
+		o.faults = &faultInjector{Faults: faults, lock: &sync.Mutex{}, rnd: rand.New(rand.NewSource(seed))}
+	}
+}
+
+// faultInjector decides which faults to inject. A nil faultInjector injects none.
+type faultInjector struct {
+	Faults
+	// Guards rnd, which isn't safe for concurrent use
+	lock *sync.Mutex
+	rnd  *rand.Rand
+}
+
+// The fault injector, which standalone pools built by NewWorkerPool don't have
+func (o *options) injectedFaults() *faultInjector {
+	if o == nil {
+		return nil
+	}
+	return o.faults
+}
+
+// Whether to inject a fault at rate
+func (f *faultInjector) roll(rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.rnd.Float64() < rate
+}
+
+func (f *faultInjector) delaySubmission() {
+	if f != nil && f.roll(f.SubmissionDelayRate) {
+		time.Sleep(f.SubmissionDelay)
+	}
+}
+
+func (f *faultInjector) panicTask() {
+	if f != nil && f.roll(f.TaskPanicRate) {
+		panic(ErrInjectedFault)
+	}
+}
+
+func (f *faultInjector) delayEviction() {
+	if f != nil && f.roll(f.EvictionDelayRate) {
+		time.Sleep(f.EvictionDelay)
+	}
+}
+
+// Wrap factory so that it fails at FactoryFailureRate, resolving a nil factory as a checkout of key would
+func (m *WorkerPoolManager) injectFactoryFaults(key string, factory Factory) Factory {
+	f := m.options.faults
+	if f == nil || f.FactoryFailureRate <= 0 {
+		return factory
+	}
+	return func(maxSize int) (WorkerPool, error) {
+		if f.roll(f.FactoryFailureRate) {
+			return nil, ErrInjectedFault
+		}
+		build := factory
+		if build == nil {
+			build = m.factories.factoryFor(key)
+		}
+		return build(maxSize)
+	}
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestFaultsAreInjected(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	panics := make(chan TaskPanic, 1)
+	evictions := make(chan time.Time, 1)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithPanicRecovery(), WithHooks(Hooks{
+		OnTaskPanic: func(recovered TaskPanic) {
+			panics <- recovered
+		},
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- time.Now()
+		},
+	}), WithFaults(Faults{
+		SubmissionDelay:     20 * time.Millisecond,
+		SubmissionDelayRate: 1,
+		TaskPanicRate:       1,
+		EvictionDelay:       20 * time.Millisecond,
+		EvictionDelayRate:   1,
+	}))
+	defer pm.Dispose()
+
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	submitted := time.Now()
+	pool.Submit(func() {
+		t.Error("Expected the task to panic before its work")
+	})
+	assert.GreaterOrEqual(t, time.Since(submitted), 20*time.Millisecond)
+	select {
+	case recovered := <-panics:
+		assert.Equal(t, ErrInjectedFault, recovered.Value)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the task to panic")
+	}
+	close(doneUsing)
+
+	deleted := time.Now()
+	pm.workerPoolCache.Delete("key")
+	select {
+	case evicted := <-evictions:
+		assert.GreaterOrEqual(t, evicted.Sub(deleted), 20*time.Millisecond)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the pool to be evicted")
+	}
+	assert.Contains(t, pm.Config().Features, "faults")
+}
+
+func TestFactoryFaultsAreInjected(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithFaults(Faults{FactoryFailureRate: 1}))
+	defer pm.Dispose()
+
+	_, _, err := pm.GetPoolWithFactory("key", 1, nil)
+	assert.Equal(t, ErrInjectedFault, err)
+	_, _, err = pm.GetPoolWithSize("key", 1, 1, nil)
+	assert.Equal(t, ErrInjectedFault, err)
+	_, _, err = pm.GetPoolContext(context.Background(), "key", 1, nil)
+	assert.Equal(t, ErrInjectedFault, err)
+	assert.Equal(t, ErrInjectedFault, ExecuteOnPool(context.Background(), pm, "key", 0, func() {
+		t.Error("Expected the pool's build to fail")
+	}))
+	assert.PanicsWithError(t, `building the worker pool for "key": injected fault`, func() {
+		pm.GetPool("key", 1)
+	})
+	assert.Equal(t, 0, pm.CacheStats().Pools)
+}
@@ -0,0 +1,300 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// WithSharedFleet runs the tasks of every pool the manager builds on a single fleet of workers shared by all keys,
+// instead of spawning workers for each pool. Each key still executes at most as many tasks at once as its pool would
+// have spawned workers, so the pool size caps each key's in-flight tasks, while the number of goroutines stays fixed
+// however many keys are cached. This suits deployments with tens of thousands of keys, where per-key workers add up
+// to more goroutines than the work needs.
+//
+// Fleet workers take turns between the keys with queued tasks, executing one task per turn, so a key with a deep
+// queue doesn't starve the others. Anything which makes a pool's workers wait before starting a task - pausing,
+// freezing, throttling or failure backoff - holds up the fleet worker serving it instead, so up to that key's cap of
+// fleet workers. WithWorkerInit builds the state of each fleet worker, rather than of each pool's workers.
+//
+// WithBurst and WithAutoscaling don't apply to pools served by the fleet, and pools built with their own options by
+// a custom Factory still spawn their own workers. PoolSnapshot.Workers reports each key's current cap.
+func WithSharedFleet(workers int) Option {
+	return func(o *options) {
+		o.fleetWorkers = workers
+		o.multiplexed = false
+	}
+}
+
+// fleet is a fixed set of workers shared by a manager's pools. Pools with queued tasks are handed turns, each of which
+// lets a fleet worker execute a few of their tasks.
+type fleet struct {
+	// The total weight of the pools with turns, with WithFairShare, accessed atomically. It's first to keep it 64-bit
+	// aligned.
+	activeWeight int64
+	options      *options
+	workers      int
+	// Each shard has its own workers and line of turns. New turns are spread over the shards round robin, and a turn
+	// passed on after being taken stays on its shard.
+	shards       []*fleetShard
+	nextShard    uint32
+	tasksPerTurn int
+	stopLock     *sync.Mutex
+	stopped      chan bool
+}
+
+// fleetShard is a line of turns, and the workers taking them
+type fleetShard struct {
+	lock  *sync.Mutex
+	ready *sync.Cond
+	// Pools owed a turn, in a ring buffer in the order they were handed them. A pool appears once for each turn it's
+	// owed.
+	turns   []*BaseWorkerPool
+	head    int
+	count   int
+	stopped <-chan bool
+}
+
+func newFleet(o *options, shards int, workersPerShard int, tasksPerTurn int) *fleet {
+	f := &fleet{
+		options:      o,
+		workers:      shards * workersPerShard,
+		tasksPerTurn: tasksPerTurn,
+		stopLock:     &sync.Mutex{},
+		stopped:      make(chan bool),
+	}
+	for i := 0; i < shards; i++ {
+		lock := &sync.Mutex{}
+		shard := &fleetShard{lock: lock, ready: sync.NewCond(lock), stopped: f.stopped}
+		f.shards = append(f.shards, shard)
+		for j := 0; j < workersPerShard; j++ {
+			go f.runWorker(shard)
+		}
+	}
+	return f
+}
+
+// Run a fleet worker on shard until the fleet is stopped
+func (f *fleet) runWorker(shard *fleetShard) {
+	state, ok := initWorkerState(f.options, "", f.options.clock, f.stopped)
+	if !ok {
+		return
+	}
+	defer func() {
+		if f.options.workerTeardown != nil {
+			f.options.workerTeardown(state)
+		}
+	}()
+	worker := &Worker{state: state}
+	if f.options.tracksGoroutines() {
+		worker.goroutine = currentGoroutine()
+	}
+
+	loop := func() {
+		for {
+			p, ok := shard.next()
+			if !ok {
+				return
+			}
+			p.takeTurn(worker, shard, f.tasksPerTurn)
+		}
+	}
+	if f.options.workerLoop != nil {
+		f.options.workerLoop(loop)
+	} else {
+		loop()
+	}
+}
+
+// Hand p a new turn, returning false if the fleet is stopped
+func (f *fleet) schedule(p *BaseWorkerPool) bool {
+	shard := f.shards[0]
+	if len(f.shards) > 1 {
+		shard = f.shards[atomic.AddUint32(&f.nextShard, 1)%uint32(len(f.shards))]
+	}
+	return shard.schedule(p)
+}
+
+// Stop the fleet's workers once they've finished their current turns, ending the turns they won't get to
+func (f *fleet) stop() {
+	if f == nil {
+		return
+	}
+	f.stopLock.Lock()
+	defer f.stopLock.Unlock()
+	if isClosed(f.stopped) {
+		return
+	}
+	close(f.stopped)
+
+	for _, shard := range f.shards {
+		shard.lock.Lock()
+		shard.ready.Broadcast()
+		var turns []*BaseWorkerPool
+		for shard.count > 0 {
+			turns = append(turns, shard.take())
+		}
+		shard.lock.Unlock()
+
+		// Pools take their own lock before the shard's, so turns are ended outside it
+		for _, p := range turns {
+			p.endTurn()
+		}
+	}
+}
+
+// Block until a pool is owed a turn, returning false if the fleet is stopped first
+func (s *fleetShard) next() (*BaseWorkerPool, bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for s.count == 0 && !isClosed(s.stopped) {
+		s.ready.Wait()
+	}
+	if isClosed(s.stopped) {
+		return nil, false
+	}
+	return s.take(), true
+}
+
+// Hand p a turn, returning false if the fleet is stopped
+func (s *fleetShard) schedule(p *BaseWorkerPool) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if isClosed(s.stopped) {
+		return false
+	}
+	if s.count == len(s.turns) {
+		// Full, so the ring is unrolled into a buffer twice the size
+		turns := make([]*BaseWorkerPool, 2*len(s.turns)+1)
+		for i := 0; i < s.count; i++ {
+			turns[i] = s.turns[(s.head+i)%len(s.turns)]
+		}
+		s.turns = turns
+		s.head = 0
+	}
+	s.turns[(s.head+s.count)%len(s.turns)] = p
+	s.count++
+	s.ready.Signal()
+	return true
+}
+
+// Remove the oldest turn. It's not thread-safe, lock above this.
+func (s *fleetShard) take() *BaseWorkerPool {
+	p := s.turns[s.head]
+	s.turns[s.head] = nil
+	s.head = (s.head + 1) % len(s.turns)
+	s.count--
+	return p
+}
+
+// fleetMember tracks a pool's turns on its manager's fleet
+type fleetMember struct {
+	fleet *fleet
+	lock  *sync.Mutex
+	// The most turns the pool may have at once, though its fair share may be fewer, see currentLimit
+	limit  int
+	weight int64
+	// Turns the pool has been handed, whether they're waiting for a fleet worker or being taken
+	turns int
+}
+
+// Hand the pool a turn for a task which has just been queued, if it has room for another. Each turn counts as one of
+// the pool's workers until it ends, so waitForWorkers waits for the tasks executing on the fleet.
+func (p *BaseWorkerPool) offerTurn() {
+	m := p.fleet
+	if m == nil {
+		return
+	}
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	p.offerTurnLocked()
+}
+
+// It's not thread-safe, lock above this
+func (p *BaseWorkerPool) offerTurnLocked() bool {
+	m := p.fleet
+	if m.turns >= m.currentLimit() || isClosed(p.disposed) {
+		return false
+	}
+	m.addTurn()
+	p.workers.Add(1)
+	if !m.fleet.schedule(p) {
+		m.removeTurn()
+		p.workers.Done()
+		return false
+	}
+	return true
+}
+
+// Change the most turns the pool may have at once, handing it turns for queued tasks if that makes room
+func (p *BaseWorkerPool) setFleetLimit(limit int) {
+	m := p.fleet
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.limit = limit
+	for queued := p.queue.len() - m.turns; queued > 0; queued-- {
+		if !p.offerTurnLocked() {
+			return
+		}
+	}
+}
+
+// Execute up to tasks queued tasks on worker, then pass the turn on along shard
+func (p *BaseWorkerPool) takeTurn(worker *Worker, shard *fleetShard, tasks int) {
+	// Deferred, so a task which panics into a custom worker loop's recovery doesn't use up the turn
+	defer p.passTurn(shard)
+
+	for i := 0; i < tasks; i++ {
+		t, ok := p.queue.tryPop()
+		if !ok {
+			return
+		}
+		if p.blocked() {
+			p.finish(t)
+			continue
+		}
+		if p.labels.admit(t) && !p.run(t, worker) {
+			return
+		}
+	}
+}
+
+// Send a finished turn to the back of shard's line while the pool has more queued tasks, so that keys take turns, and
+// otherwise end it
+func (p *BaseWorkerPool) passTurn(shard *fleetShard) {
+	m := p.fleet
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	// Checked under the lock, so a task queued meanwhile either is seen here or is offered a turn of its own
+	if p.queue.len() > 0 && m.turns <= m.currentLimit() && !isClosed(p.disposed) && shard.schedule(p) {
+		if m.fleet.options.fairShare != nil {
+			// The pool's fair share may have grown as other keys ran out of work
+			for queued := p.queue.len() - m.turns; queued > 0; queued-- {
+				if !p.offerTurnLocked() {
+					break
+				}
+			}
+		}
+		return
+	}
+	m.removeTurn()
+	p.workers.Done()
+}
+
+func (p *BaseWorkerPool) endTurn() {
+	m := p.fleet
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.removeTurn()
+	p.workers.Done()
+}
+
+// Wait out a turn being handed to the pool as it's disposed, so that none are once waitForWorkers may be waiting
+func (p *BaseWorkerPool) closeTurns() {
+	m := p.fleet
+	if m == nil {
+		return
+	}
+	// Taken only to wait for an offerTurnLocked in progress
+	m.lock.Lock()
+	m.lock.Unlock()
+}
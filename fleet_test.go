@@ -0,0 +1,185 @@
+package pool
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSharedFleetCapsEachKeysInFlightTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithSharedFleet(5), WithQueueCapacity(20))
+	var lock sync.Mutex
+	running := make(map[string]int)
+	peaks := make(map[string]int)
+	total, peak := 0, 0
+
+	var wg sync.WaitGroup
+	for _, key := range []string{"a", "b", "c"} {
+		key := key
+		pool, doneUsing := pm.GetPool(key, 2)
+		wg.Add(20)
+		for i := 0; i < 20; i++ {
+			pool.Submit(func() {
+				defer wg.Done()
+				lock.Lock()
+				running[key]++
+				total++
+				if running[key] > peaks[key] {
+					peaks[key] = running[key]
+				}
+				if total > peak {
+					peak = total
+				}
+				lock.Unlock()
+
+				time.Sleep(time.Millisecond)
+				lock.Lock()
+				running[key]--
+				total--
+				lock.Unlock()
+			})
+		}
+		close(doneUsing)
+	}
+	wg.Wait()
+
+	assert.Equal(t, map[string]int{"a": 2, "b": 2, "c": 2}, peaks)
+	assert.Equal(t, 5, peak)
+	assert.Equal(t, 2, pm.Snapshot()["a"].Workers)
+	pm.Dispose()
+}
+
+func TestSharedFleetTakesTurnsBetweenKeys(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithSharedFleet(1), WithQueueCapacity(10))
+	busy, doneUsingBusy := pm.GetPool("busy", 2)
+	quiet, doneUsingQuiet := pm.GetPool("quiet", 2)
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	record := func(label string) Work {
+		return func() {
+			defer wg.Done()
+			lock.Lock()
+			order = append(order, label)
+			lock.Unlock()
+		}
+	}
+	gate := make(chan bool)
+	wg.Add(6)
+	busy.Submit(func() {
+		<-gate
+		record("busy-1")()
+	})
+	for i := 2; i <= 5; i++ {
+		busy.Submit(record(fmt.Sprintf("busy-%d", i)))
+	}
+	quiet.Submit(record("quiet-1"))
+	close(gate)
+	wg.Wait()
+
+	// The busy key's second turn was handed out before the quiet key's, and its later ones after
+	assert.Equal(t, []string{"busy-1", "busy-2", "quiet-1", "busy-3", "busy-4", "busy-5"}, order)
+	close(doneUsingBusy)
+	close(doneUsingQuiet)
+	pm.Dispose()
+}
+
+func TestSharedFleetKeepsGoroutinesFixed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var inits int32
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour, WithSharedFleet(3), WithWorkerInit(func() (interface{}, error) {
+		return atomic.AddInt32(&inits, 1), nil
+	}))
+	before := runtime.NumGoroutine()
+	var wg sync.WaitGroup
+	states := make(chan interface{}, 1000)
+	for i := 0; i < 1000; i++ {
+		pool, doneUsing := pm.GetPool(fmt.Sprint(i), 4)
+		wg.Add(1)
+		SubmitWithWorkerState(pool, func(workerState interface{}) {
+			defer wg.Done()
+			states <- workerState
+		})
+		close(doneUsing)
+	}
+	wg.Wait()
+	close(states)
+
+	// Only the goroutines waiting for callers to be done using pools are left to finish
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine()-before < 10
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(3), atomic.LoadInt32(&inits))
+	for state := range states {
+		assert.Contains(t, []interface{}{int32(1), int32(2), int32(3)}, state)
+	}
+	assert.Contains(t, pm.Config().Features, "shared fleet")
+	pm.Dispose()
+}
+
+func TestSharedFleetShrinksCachedPoolsInPlace(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour, WithSharedFleet(4), WithQueueCapacity(10))
+	pool, doneUsing := pm.GetPool("key", 4)
+	pm.SetPoolSize(1)
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+
+	var running, peak int32
+	var wg sync.WaitGroup
+	wg.Add(10)
+	for i := 0; i < 10; i++ {
+		pool.Submit(func() {
+			defer wg.Done()
+			now := atomic.AddInt32(&running, 1)
+			if now > atomic.LoadInt32(&peak) {
+				atomic.StoreInt32(&peak, now)
+			}
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&running, -1)
+		})
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), atomic.LoadInt32(&peak))
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSharedFleetStopsWithQueuedTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithSharedFleet(1))
+	pool, doneUsing := pm.GetPool("key", 1)
+	started := make(chan bool)
+	release := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	pool.Submit(func() {})
+	<-started
+	close(doneUsing)
+
+	disposed := make(chan bool)
+	go func() {
+		pm.Dispose()
+		close(disposed)
+	}()
+	<-disposed
+	close(release)
+	// The queued task's turn is ended along with the running one, so the pool's workers are accounted for
+	pool.waitForWorkers()
+}
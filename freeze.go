@@ -0,0 +1,144 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+)
+
+// ErrPoolFrozen is returned when submitting to a frozen pool of a manager built with FreezeRejectSubmissions
+var ErrPoolFrozen = errors.New("pool is frozen for maintenance")
+
+// FreezePolicy decides what happens to submissions while a manager's pools are frozen with FreezeAll
+type FreezePolicy int
+
+// Available freeze policies
+const (
+	// FreezeQueueSubmissions - submissions are accepted and queued, to be executed once the pools are thawed. Once a
+	// pool's queue is full, submitting to it blocks until then.
+	FreezeQueueSubmissions FreezePolicy = iota
+	// FreezeRejectSubmissions - submissions are rejected, with ErrPoolFrozen from SubmitTask and SubmitWithRetry
+	FreezeRejectSubmissions
+)
+
+// WithFreezePolicy sets what happens to submissions while the manager's pools are frozen, FreezeQueueSubmissions by
+// default
+func WithFreezePolicy(policy FreezePolicy) Option {
+	return func(o *options) {
+		o.freezePolicy = policy
+	}
+}
+
+// freeze is a manager's current FreezeAll
+type freeze struct {
+	// Closed by ThawAll, to stop waiting for the freeze's context
+	thawed chan bool
+}
+
+// FreezeAll stops every pool, including pools built while frozen, from starting tasks until ThawAll is called or ctx
+// is done, e.g. to coordinate maintenance on a downstream without restarting the services producing work. Tasks which
+// are already executing carry on. Submissions are queued or rejected according to the manager's FreezePolicy.
+//
+// Freezing is independent of pausing individual pools with PauseKey, and thawing doesn't resume paused pools. As with
+// paused pools, frozen pools still expire once they go unused for the stale pool expiration, discarding their queued
+// tasks.
+func (m *WorkerPoolManager) FreezeAll(ctx context.Context) {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	if m.frozen != nil {
+		// Only the first freeze's context can end it
+		return
+	}
+	f := &freeze{thawed: make(chan bool)}
+	m.frozen = f
+	for _, item := range m.workerPoolCache.Items() {
+		item.Value().setFrozen(true, m.options.freezePolicy)
+	}
+
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				m.thaw(f)
+			case <-f.thawed:
+			}
+		}()
+	}
+}
+
+// ThawAll lets every pool start tasks again after FreezeAll
+func (m *WorkerPoolManager) ThawAll() {
+	m.poolReservationLock.Lock()
+	f := m.frozen
+	m.poolReservationLock.Unlock()
+	if f != nil {
+		m.thaw(f)
+	}
+}
+
+// Frozen reports whether the manager's pools are frozen
+func (m *WorkerPoolManager) Frozen() bool {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	return m.frozen != nil
+}
+
+// Thaw the pools, if f is still the current freeze
+func (m *WorkerPoolManager) thaw(f *freeze) {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	if m.frozen != f {
+		return
+	}
+	m.frozen = nil
+	close(f.thawed)
+	for _, item := range m.workerPoolCache.Items() {
+		item.Value().setFrozen(false, m.options.freezePolicy)
+	}
+}
+
+// Stop waiting on the current freeze's context once the manager is disposed, leaving its pools frozen so they don't
+// start executing their queues while they're being disposed
+func (m *WorkerPoolManager) stopFreeze() {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	if m.frozen != nil {
+		close(m.frozen.thawed)
+		m.frozen = nil
+	}
+}
+
+// Freeze or thaw the pool, see FreezeAll
+func (p *BaseWorkerPool) setFrozen(frozen bool, policy FreezePolicy) {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	switch {
+	case frozen && p.thawed == nil:
+		p.thawed = make(chan bool)
+		if policy == FreezeRejectSubmissions {
+			atomic.StoreInt32(&p.rejectWhileFrozen, 1)
+		}
+	case !frozen && p.thawed != nil:
+		close(p.thawed)
+		p.thawed = nil
+		atomic.StoreInt32(&p.rejectWhileFrozen, 0)
+	}
+}
+
+func (p *BaseWorkerPool) frozen() bool {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	return p.thawed != nil
+}
+
+// Whether submissions are being rejected because the pool is frozen
+func (p *BaseWorkerPool) rejectingWhileFrozen() bool {
+	return atomic.LoadInt32(&p.rejectWhileFrozen) == 1
+}
+
+// Freeze a newly built pool if the manager is frozen. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) freezeIfFrozen(pool WorkerPool) {
+	if m.frozen != nil {
+		pool.setFrozen(true, m.options.freezePolicy)
+	}
+}
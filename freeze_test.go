@@ -0,0 +1,93 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestFreezeAllHoldsDispatchUntilThawed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	existing, doneUsing := pm.GetPool("existing", 2)
+	defer close(doneUsing)
+
+	pm.FreezeAll(context.Background())
+	assert.True(t, pm.Frozen())
+	built, builtDone := pm.GetPool("built while frozen", 2)
+	defer close(builtDone)
+
+	var executed int32
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for _, pool := range []WorkerPool{existing, built} {
+		for i := 0; i < 2; i++ {
+			assert.NoError(t, SubmitTask(pool, TaskInfo{}, func() {
+				atomic.AddInt32(&executed, 1)
+				wg.Done()
+			}))
+		}
+	}
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, int32(0), atomic.LoadInt32(&executed))
+	assert.True(t, pm.Snapshot()["existing"].Frozen)
+
+	// Pausing is independent of freezing
+	pm.PauseKey("existing")
+	pm.ThawAll()
+	assert.False(t, pm.Frozen())
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&executed) == 2
+	}, time.Second, time.Millisecond)
+	pm.ResumeKey("existing")
+	wg.Wait()
+
+	pm.Dispose()
+}
+
+func TestFreezeAllEndsWithContext(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	pool, doneUsing := pm.GetPool("key", 1)
+	defer close(doneUsing)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	pm.FreezeAll(ctx)
+	executed := make(chan bool)
+	pool.Submit(func() {
+		close(executed)
+	})
+	cancel()
+	<-executed
+	assert.False(t, pm.Frozen())
+
+	// A later freeze isn't ended by an earlier context
+	pm.FreezeAll(context.Background())
+	assert.True(t, pm.Frozen())
+	pm.Dispose()
+}
+
+func TestFreezeRejectSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithFreezePolicy(FreezeRejectSubmissions))
+	pool, doneUsing := pm.GetPool("key", 1)
+	defer close(doneUsing)
+
+	pm.FreezeAll(context.Background())
+	assert.ErrorIs(t, SubmitTask(pool, TaskInfo{}, func() {}), ErrPoolFrozen)
+	pm.ThawAll()
+	executed := make(chan bool)
+	assert.NoError(t, SubmitTask(pool, TaskInfo{}, func() {
+		close(executed)
+	}))
+	<-executed
+	pm.Dispose()
+}
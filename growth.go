@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"errors"
+	"math"
+)
+
+// ErrNoWorkers is returned by SubmitTask for pools without workers, with ZeroSendSizeReject
+var ErrNoWorkers = errors.New("pool has no workers to execute submissions")
+
+// GrowthPolicy decides how many workers a pool spawns for the sendSize hint passed to GetPool, see WithGrowthPolicy
+type GrowthPolicy int
+
+// Available growth policies
+const (
+	// GrowthExact - a pool spawns as many workers as it's hinted, the default
+	GrowthExact GrowthPolicy = iota
+	// GrowthExponential - a pool at most doubles its workers per checkout, so a pool grows to a large hint over a few
+	// checkouts rather than all at once
+	GrowthExponential
+	// GrowthDamped - a pool spawns the square root of its hint, rounded up, so a hint of 10,000 spawns 100 workers
+	GrowthDamped
+)
+
+// ZeroSendSize decides what happens to pools checked out with a sendSize of zero, see WithZeroSendSize
+type ZeroSendSize int
+
+// Available behaviors for a sendSize of zero
+const (
+	// ZeroSendSizeAllowed - no workers are spawned, so a pool which has none leaves submissions queued until a later
+	// checkout spawns some, the default
+	ZeroSendSizeAllowed ZeroSendSize = iota
+	// ZeroSendSizeSpawnOne - a pool which has no workers spawns one
+	ZeroSendSizeSpawnOne
+	// ZeroSendSizeReject - submissions to a pool which has no workers are rejected with ErrNoWorkers, rather than
+	// queued where nothing executes them
+	ZeroSendSizeReject
+)
+
+// WithZeroSendSize changes what happens to checkouts with a sendSize of zero or less, which spawn no workers by
+// default, so that tasks submitted to a pool which has no workers yet don't sit unprocessed. Rejected submissions are
+// counted by MetricTasksRejected. It doesn't apply to autoscaled pools, which always run a worker once checked out.
+func WithZeroSendSize(behavior ZeroSendSize) Option {
+	return func(o *options) {
+		o.zeroSendSize = behavior
+	}
+}
+
+// WithGrowthPolicy changes how pools translate the sendSize hints of checkouts into workers, so chatty callers passing
+// huge hints don't spawn the whole pool size at once. Pools still never grow past the pool size nor shrink, and
+// GrowthExact, the default, spawns one worker per hinted task. Pools served by a shared fleet grow their cap of fleet
+// workers likewise, and autoscaled pools aren't affected, as their policy decides their workers.
+func WithGrowthPolicy(policy GrowthPolicy) Option {
+	return func(o *options) {
+		o.growthPolicy = policy
+	}
+}
+
+// Reject submissions to a pool without workers, with ZeroSendSizeReject
+func (p *BaseWorkerPool) admitWithoutWorkers() error {
+	if p.options == nil || p.options.zeroSendSize != ZeroSendSizeReject || p.spawnedWorkers() > 0 {
+		return nil
+	}
+	return ErrNoWorkers
+}
+
+// How many workers to spawn for a hint of sendSize tasks, in a pool which has workers already
+func (o *options) growth(sendSize int, workers int) int {
+	if o == nil {
+		return sendSize
+	}
+	if sendSize < 1 && workers == 0 && o.zeroSendSize == ZeroSendSizeSpawnOne {
+		return 1
+	}
+	switch o.growthPolicy {
+	case GrowthExponential:
+		if workers < 1 {
+			workers = 1
+		}
+		return min(sendSize, workers)
+	case GrowthDamped:
+		return int(math.Ceil(math.Sqrt(float64(sendSize))))
+	default:
+		return sendSize
+	}
+}
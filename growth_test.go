@@ -0,0 +1,80 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestGrowthPolicies(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for _, tc := range []struct {
+		name    string
+		policy  GrowthPolicy
+		workers []int
+	}{
+		{name: "exact", policy: GrowthExact, workers: []int{50, 50, 50}},
+		{name: "exponential", policy: GrowthExponential, workers: []int{1, 2, 4}},
+		{name: "damped", policy: GrowthDamped, workers: []int{8, 16, 24}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			pm := NewWorkerPoolManager(50, time.Second, time.Hour, WithGrowthPolicy(tc.policy))
+			defer pm.Dispose()
+
+			for _, workers := range tc.workers {
+				_, doneUsing := pm.GetPool("key", 64)
+				close(doneUsing)
+				assert.Equal(t, workers, pm.Snapshot()["key"].Workers)
+			}
+		})
+	}
+}
+
+func TestGrowthPolicyDoesntDampSetKeySize(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour, WithGrowthPolicy(GrowthDamped))
+	_, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+
+	assert.True(t, pm.SetKeySize("key", 9))
+	assert.Equal(t, 9, pm.Snapshot()["key"].Workers)
+	pm.Dispose()
+}
+
+func TestZeroSendSize(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	allowed := NewWorkerPoolManager(2, time.Second, time.Hour, WithQueueCapacity(1))
+	_, doneUsing := allowed.GetPool("key", 0)
+	close(doneUsing)
+	assert.Equal(t, 0, allowed.Snapshot()["key"].Workers)
+	allowed.Dispose()
+
+	spawnOne := NewWorkerPoolManager(2, time.Second, time.Hour, WithZeroSendSize(ZeroSendSizeSpawnOne))
+	pool, doneUsing := spawnOne.GetPool("key", 0)
+	assert.Equal(t, 1, spawnOne.Snapshot()["key"].Workers)
+	done := make(chan bool)
+	pool.Submit(func() {
+		close(done)
+	})
+	<-done
+	close(doneUsing)
+	// Only pools without workers spawn one
+	_, doneUsing = spawnOne.GetPool("key", 0)
+	assert.Equal(t, 1, spawnOne.Snapshot()["key"].Workers)
+	close(doneUsing)
+	spawnOne.Dispose()
+
+	reject := NewWorkerPoolManager(2, time.Second, time.Hour, WithZeroSendSize(ZeroSendSizeReject))
+	pool, doneUsing = reject.GetPool("key", 0)
+	assert.ErrorIs(t, SubmitTask(pool, TaskInfo{}, func() {}), ErrNoWorkers)
+	close(doneUsing)
+	pool, doneUsing = reject.GetPool("key", 1)
+	assert.NoError(t, SubmitTask(pool, TaskInfo{}, func() {}))
+	close(doneUsing)
+	reject.Dispose()
+}
@@ -0,0 +1,141 @@
+// Package grpcpool bounds the concurrency of gRPC handlers per tenant with a pool.WorkerPoolManager, executing each
+// call's handler on a worker of its tenant's pool. It's written against the shapes of grpc-go's server interceptors
+// rather than its package, so using it doesn't add a dependency - each interceptor is wired in with a closure, and the
+// tenant is read from the call's incoming metadata with MetadataKey:
+//
+//	limiter := grpcpool.New(poolManager, grpcpool.Config{
+//		Key: grpcpool.MetadataKey("x-tenant-id", func(ctx context.Context) map[string][]string {
+//			md, _ := metadata.FromIncomingContext(ctx)
+//			return md
+//		}),
+//		RequireKey: true,
+//		Reject: func(key string, err error) error {
+//			return status.Error(codes.ResourceExhausted, err.Error())
+//		},
+//	})
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(func(
+//			ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler,
+//		) (interface{}, error) {
+//			return limiter.Unary(ctx, req, info.FullMethod, handler)
+//		}),
+//		grpc.StreamInterceptor(func(
+//			srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler,
+//		) error {
+//			return limiter.Stream(ss.Context(), info.FullMethod, func() error {
+//				return handler(srv, ss)
+//			})
+//		}),
+//	)
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// ErrNoKey is what calls without a tenant key fail with, before Reject, when Config.RequireKey is set
+var ErrNoKey = errors.New("call has no tenant key")
+
+// Config configures a Limiter
+type Config struct {
+	// Key returns the tenant key of a call to fullMethod, e.g. from its metadata with MetadataKey. Calls it returns an
+	// empty key for aren't limited, unless RequireKey is set.
+	Key func(ctx context.Context, fullMethod string) string
+	// RequireKey rejects calls without a tenant key with ErrNoKey, rather than letting them through unlimited, so
+	// clients can't bypass their tenant's limit by leaving out its metadata
+	RequireKey bool
+	// QueueTimeout is how long a call may wait for one of its tenant's workers before it's rejected with
+	// pool.ErrQueueTimeout, as long as the call's context allows if unset
+	QueueTimeout time.Duration
+	// Reject returns the error a call fails with when it times out waiting, its pool rejects it or it has no key with
+	// RequireKey, e.g. a status error with codes.ResourceExhausted, codes.Unavailable or codes.InvalidArgument
+	// depending on err. Calls fail with err itself if it's unset.
+	Reject func(key string, err error) error
+}
+
+// MetadataKey returns a Config.Key which reads the tenant key from the first value of the call's incoming metadata
+// named name, as returned by incoming - a wrapper of metadata.FromIncomingContext. Metadata names are case
+// insensitive, as in grpc-go.
+func MetadataKey(
+	name string, incoming func(ctx context.Context) map[string][]string,
+) func(ctx context.Context, fullMethod string) string {
+	name = strings.ToLower(name)
+	return func(ctx context.Context, fullMethod string) string {
+		values := incoming(ctx)[name]
+		if len(values) == 0 {
+			return ""
+		}
+		return values[0]
+	}
+}
+
+// Limiter bounds handler concurrency per tenant, see the package documentation
+type Limiter struct {
+	manager *pool.WorkerPoolManager
+	config  Config
+}
+
+// New builds a Limiter executing handlers on the pools of manager
+func New(manager *pool.WorkerPoolManager, config Config) *Limiter {
+	return &Limiter{manager: manager, config: config}
+}
+
+// Unary executes a unary call's handler on its tenant's pool, for a grpc.UnaryServerInterceptor
+func (l *Limiter) Unary(
+	ctx context.Context, req interface{}, fullMethod string,
+	handler func(ctx context.Context, req interface{}) (interface{}, error),
+) (interface{}, error) {
+	var resp interface{}
+	var err error
+	if rejected := l.execute(ctx, fullMethod, func() {
+		resp, err = handler(ctx, req)
+	}); rejected != nil {
+		return nil, rejected
+	}
+	return resp, err
+}
+
+// Stream executes a streaming call's handler on its tenant's pool, for a grpc.StreamServerInterceptor. ctx is the
+// stream's context. The stream occupies a worker for as long as it's open, so long-lived streams count against their
+// tenant's concurrency throughout.
+func (l *Limiter) Stream(ctx context.Context, fullMethod string, handler func() error) error {
+	var err error
+	if rejected := l.execute(ctx, fullMethod, func() {
+		err = handler()
+	}); rejected != nil {
+		return rejected
+	}
+	return err
+}
+
+// Call f on the pool of its call's tenant, returning the error the call fails with if it's rejected
+func (l *Limiter) execute(ctx context.Context, fullMethod string, f func()) error {
+	key := ""
+	if l.config.Key != nil {
+		key = l.config.Key(ctx, fullMethod)
+	}
+	if key == "" && !l.config.RequireKey {
+		f()
+		return nil
+	}
+	err := ErrNoKey
+	if key != "" {
+		err = pool.ExecuteOnPool(ctx, l.manager, key, l.config.QueueTimeout, f)
+	}
+	if err == nil {
+		return nil
+	}
+	if ctx.Err() != nil {
+		// The call was canceled, or its deadline passed, while it waited
+		return ctx.Err()
+	}
+	if l.config.Reject != nil {
+		return l.config.Reject(key, err)
+	}
+	return err
+}
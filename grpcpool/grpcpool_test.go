@@ -0,0 +1,126 @@
+package grpcpool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// The shape of grpc.UnaryHandler, which Unary must accept as is
+type unaryHandler func(ctx context.Context, req interface{}) (interface{}, error)
+
+type tenantKey struct{}
+
+func TestLimiterBoundsEachTenantsCalls(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+	limiter := New(pm, Config{
+		Key: func(ctx context.Context, fullMethod string) string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant
+		},
+		QueueTimeout: 20 * time.Millisecond,
+		Reject: func(key string, err error) error {
+			return fmt.Errorf("resource exhausted for %s: %w", key, err)
+		},
+	})
+	tenant := func(name string) context.Context {
+		return context.WithValue(context.Background(), tenantKey{}, name)
+	}
+	var echo unaryHandler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		return req, nil
+	}
+
+	started := make(chan bool)
+	unblock := make(chan bool)
+	streamed := make(chan error, 1)
+	go func() {
+		streamed <- limiter.Stream(tenant("a"), "/svc/Watch", func() error {
+			started <- true
+			<-unblock
+			return errors.New("stream closed")
+		})
+	}()
+	<-started
+
+	_, err := limiter.Unary(tenant("a"), "req", "/svc/Get", echo)
+	assert.True(t, errors.Is(err, pool.ErrQueueTimeout))
+	assert.Contains(t, err.Error(), "resource exhausted for a")
+	// Other tenants, and calls without a tenant, aren't held up
+	resp, err := limiter.Unary(tenant("b"), "req", "/svc/Get", echo)
+	assert.Nil(t, err)
+	assert.Equal(t, "req", resp)
+	resp, err = limiter.Unary(context.Background(), "req", "/svc/Get", echo)
+	assert.Nil(t, err)
+	assert.Equal(t, "req", resp)
+
+	close(unblock)
+	assert.EqualError(t, <-streamed, "stream closed")
+	resp, err = limiter.Unary(tenant("a"), "req", "/svc/Get", echo)
+	assert.Nil(t, err)
+	assert.Equal(t, "req", resp)
+}
+
+func TestMetadataKeyReadsTheTenantFromIncomingMetadata(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	type incomingKey struct{}
+	key := MetadataKey("X-Tenant-ID", func(ctx context.Context) map[string][]string {
+		md, _ := ctx.Value(incomingKey{}).(map[string][]string)
+		return md
+	})
+	incoming := context.WithValue(context.Background(), incomingKey{}, map[string][]string{
+		"x-tenant-id": {"a", "b"},
+	})
+	assert.Equal(t, "a", key(incoming, "/svc/Get"))
+	assert.Equal(t, "", key(context.Background(), "/svc/Get"))
+	assert.Equal(t, "", key(context.WithValue(context.Background(), incomingKey{}, map[string][]string{
+		"x-tenant-id": {},
+	}), "/svc/Get"))
+}
+
+func TestRequireKeyRejectsCallsWithoutATenant(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+	limiter := New(pm, Config{
+		Key: func(ctx context.Context, fullMethod string) string {
+			tenant, _ := ctx.Value(tenantKey{}).(string)
+			return tenant
+		},
+		RequireKey: true,
+		Reject: func(key string, err error) error {
+			return fmt.Errorf("invalid argument for %q: %w", key, err)
+		},
+	})
+	handled := false
+	var echo unaryHandler = func(ctx context.Context, req interface{}) (interface{}, error) {
+		handled = true
+		return req, nil
+	}
+
+	_, err := limiter.Unary(context.Background(), "req", "/svc/Get", echo)
+	assert.True(t, errors.Is(err, ErrNoKey))
+	assert.Contains(t, err.Error(), `invalid argument for ""`)
+	err = limiter.Stream(context.Background(), "/svc/Watch", func() error {
+		handled = true
+		return nil
+	})
+	assert.True(t, errors.Is(err, ErrNoKey))
+	assert.False(t, handled)
+
+	resp, err := limiter.Unary(context.WithValue(context.Background(), tenantKey{}, "a"), "req", "/svc/Get", echo)
+	assert.Nil(t, err)
+	assert.Equal(t, "req", resp)
+	assert.True(t, handled)
+}
@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Hedge executes w on p and waits for it, hedging against a slow attempt: if no attempt has succeeded within delay of
+// the last one starting, a duplicate attempt is submitted to the same pool, up to maxAttempts in all, and the first to
+// succeed wins. The other attempts' contexts are canceled once there's a winner, or once ctx is done, so w should give
+// up when its context is - a loser which doesn't keeps its worker until it returns. It's for tail latency sensitive
+// keyed calls, such as reads from a replicated downstream, and w must be safe to execute more than once.
+//
+// An attempt which fails is hedged straight away, without waiting out the delay. Hedge returns nil once an attempt
+// succeeds, the last attempt's error if every attempt fails, or ctx's error if it's done first. Submissions rejected
+// by p count as failed attempts, with the same errors as SubmitTask.
+func Hedge(
+	ctx context.Context, p WorkerPool, w func(ctx context.Context) error, delay time.Duration, maxAttempts int,
+) error {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	// Buffered, so losers finishing after Hedge returns don't block their workers
+	results := make(chan error, maxAttempts)
+	attempt := func() {
+		err := SubmitTask(p, TaskInfo{}, func() {
+			results <- w(ctx)
+		})
+		if err != nil {
+			results <- err
+		}
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	attempt()
+	started, finished := 1, 0
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-timer.C:
+			if started < maxAttempts {
+				attempt()
+				started++
+				timer.Reset(delay)
+			}
+		case err := <-results:
+			finished++
+			if err == nil {
+				return nil
+			}
+			if started < maxAttempts {
+				attempt()
+				started++
+				resetTimer(timer, delay)
+			} else if finished == started {
+				return err
+			}
+		}
+	}
+}
+
+// Reset timer, which may not have fired, to fire after d
+func resetTimer(timer *time.Timer, d time.Duration) {
+	if !timer.Stop() {
+		select {
+		case <-timer.C:
+		default:
+		}
+	}
+	timer.Reset(d)
+}
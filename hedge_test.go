@@ -0,0 +1,64 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestHedgeCancelsTheSlowAttempt(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool("key", 2)
+	defer close(doneUsing)
+
+	var attempts int32
+	loserCanceled := make(chan bool, 1)
+	err := Hedge(context.Background(), pool, func(ctx context.Context) error {
+		if atomic.AddInt32(&attempts, 1) == 1 {
+			<-ctx.Done()
+			loserCanceled <- true
+			return ctx.Err()
+		}
+		return nil
+	}, 10*time.Millisecond, 3)
+	assert.Nil(t, err)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&attempts))
+	select {
+	case <-loserCanceled:
+	case <-time.After(time.Second):
+		t.Error("Expected the slow attempt to be canceled")
+	}
+}
+
+func TestHedgeReturnsTheLastErrorOnceEveryAttemptFails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(3, time.Hour, time.Hour)
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool("key", 3)
+	defer close(doneUsing)
+
+	var attempts int32
+	err := Hedge(context.Background(), pool, func(ctx context.Context) error {
+		return fmt.Errorf("attempt %d failed", atomic.AddInt32(&attempts, 1))
+	}, time.Hour, 3)
+	// Failed attempts are hedged straight away, rather than after the delay
+	assert.EqualError(t, err, "attempt 3 failed")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	err = Hedge(ctx, pool, func(ctx context.Context) error {
+		<-ctx.Done()
+		return errors.New("canceled")
+	}, time.Hour, 3)
+	assert.Equal(t, context.Canceled, err)
+}
@@ -0,0 +1,186 @@
+package pool
+
+import "time"
+
+// EvictionReason describes why a pool was removed from a manager's cache
+type EvictionReason int
+
+// Available eviction reasons
+const (
+	// EvictionReasonExpired - the pool went unused for longer than the stale pool expiration
+	EvictionReasonExpired EvictionReason = iota + 1
+	// EvictionReasonMaxLifetime - the pool outlived the max pool lifetime, and will be replaced on next use
+	EvictionReasonMaxLifetime
+	// EvictionReasonDeleted - the pool was removed explicitly, e.g. by disposing the manager
+	EvictionReasonDeleted
+	// EvictionReasonQuarantined - the pool was quarantined, and replaced because of Quarantine.Rebuild
+	EvictionReasonQuarantined
+	// EvictionReasonResized - the pool was rotated because the manager's pool size shrank, see SetPoolSize
+	EvictionReasonResized
+	// EvictionReasonLeaseLost - another instance took over the lease on the pool's key, see WithLeases
+	EvictionReasonLeaseLost
+)
+
+func (r EvictionReason) String() string {
+	switch r {
+	case EvictionReasonExpired:
+		return "expired"
+	case EvictionReasonMaxLifetime:
+		return "max lifetime"
+	case EvictionReasonDeleted:
+		return "deleted"
+	case EvictionReasonQuarantined:
+		return "quarantined"
+	case EvictionReasonResized:
+		return "resized"
+	case EvictionReasonLeaseLost:
+		return "lease lost"
+	default:
+		return "unknown"
+	}
+}
+
+// PoolEviction describes a pool which has been evicted from a manager's cache and disposed
+type PoolEviction struct {
+	Key    string
+	Pool   WorkerPool
+	Reason EvictionReason
+	// Age of the pool when it was disposed
+	Age time.Duration
+	// Err is the error returned by disposing the pool, for pools implementing ErrorDisposer
+	Err error
+}
+
+// PoolReuse describes a cached pool being handed out again, see Hooks.OnPoolReused
+type PoolReuse struct {
+	Key  string
+	Pool WorkerPool
+	// Age of the pool when it was reused
+	Age time.Duration
+	// Reservations is how many callers are using the pool, including the one it has just been handed to
+	Reservations int
+}
+
+// Hooks are callbacks for a manager's pool lifecycle events. Any of them may be nil.
+type Hooks struct {
+	// OnPoolCreated is called when the manager builds and caches a new pool for key, before it's handed to the caller
+	OnPoolCreated func(key string, pool WorkerPool)
+	// OnPoolReused is called when the manager hands out a pool it already had cached, which refreshes the pool's stale
+	// pool expiration. Comparing how often it's called with OnPoolCreated shows how well the expiration settings keep
+	// pools warm.
+	OnPoolReused func(reuse PoolReuse)
+	// OnPoolEvicted is called once an evicted pool has been disposed. Disposal waits for all callers to be done using
+	// the pool, so this may happen some time after the pool is removed from the cache.
+	OnPoolEvicted func(eviction PoolEviction)
+	// OnRotate is called when key's pool outlives the max pool lifetime, with the pool built to replace it, before the
+	// replacement is handed to any caller. old is still in use by the checkout which rotated it, and is disposed once
+	// released, so custom pools can hand over state - caches, metrics, a rate limiter's fill - to new instead of it
+	// starting cold. Registering it builds replacements as soon as pools are rotated, rather than on the next checkout,
+	// unless WithDrainBeforeRotate waits for old to drain first, in which case old has been released by then.
+	OnRotate func(key string, old, new WorkerPool)
+	// OnWorkerInitError is called when a worker for key's pool fails to start because WithWorkerInit failed
+	OnWorkerInitError func(key string, err error)
+	// OnStuckTask is called when a task runs for longer than the threshold set with WithWatchdog. It's called from a
+	// timer while the task is still running.
+	OnStuckTask func(stuck StuckTask)
+	// OnStuckDisposal is called when an evicted pool is still in use after the timeout set with WithDisposalTimeout.
+	// It's called from a timer, and the pool is still disposed once it's released.
+	OnStuckDisposal func(stuck StuckDisposal)
+	// OnLeakedCheckout is called when a checkout's doneUsing channel is still open after the threshold set with
+	// WithLeakDetection. It's called from a timer.
+	OnLeakedCheckout func(leak LeakedCheckout)
+	// OnTaskPanic is called when a panic is recovered from a task, see WithPanicRecovery
+	OnTaskPanic func(recovered TaskPanic)
+	// OnPoolQuarantined is called when key's pool is quarantined, with the panic which tipped it over the threshold
+	OnPoolQuarantined func(key string, lastPanic TaskPanic)
+	// OnTaskFailure is called when a task submitted with SubmitWithRetry has failed its last attempt
+	OnTaskFailure func(failure TaskFailure)
+	// OnPoolAutoPaused is called when key's pool is paused because its failure rate reached the WithAutoPause threshold
+	OnPoolAutoPaused func(key string, failureRate float64)
+	// OnQueueSaturated is called when a submission to key's pool finds its queue full, before waiting for room. It's
+	// called for every such submission, so it can be frequent under sustained load.
+	OnQueueSaturated func(key string)
+}
+
+// WithHooks registers lifecycle callbacks on the manager. It may be passed multiple times, and every registered hook
+// will be called.
+func WithHooks(hooks Hooks) Option {
+	return func(o *options) {
+		o.hooks = append(o.hooks, hooks)
+	}
+}
+
+// The registered hooks, which standalone pools built by NewWorkerPool don't have
+func (o *options) registeredHooks() []Hooks {
+	if o == nil {
+		return nil
+	}
+	return o.hooks
+}
+
+func (o *options) poolCreated(key string, pool WorkerPool) {
+	o.count(MetricPoolsCreated, key, 1)
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnPoolCreated != nil {
+			hooks.OnPoolCreated(key, pool)
+		}
+	}
+}
+
+func (o *options) poolReused(reuse PoolReuse) {
+	o.count(MetricPoolsReused, reuse.Key, 1)
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnPoolReused != nil {
+			hooks.OnPoolReused(reuse)
+		}
+	}
+}
+
+func (o *options) poolEvicted(eviction PoolEviction) {
+	o.count(MetricPoolsEvicted, eviction.Key, 1)
+	if eviction.Err != nil {
+		o.count(MetricDisposeErrors, eviction.Key, 1)
+	}
+	if o != nil {
+		o.metricKeys.release(eviction.Key)
+	}
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnPoolEvicted != nil {
+			hooks.OnPoolEvicted(eviction)
+		}
+	}
+}
+
+// Whether any registered hooks are waiting to hand over rotated pools
+func (o *options) handsOver() bool {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnRotate != nil {
+			return true
+		}
+	}
+	return false
+}
+
+func (o *options) poolRotated(key string, old, new WorkerPool) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnRotate != nil {
+			hooks.OnRotate(key, old, new)
+		}
+	}
+}
+
+func (o *options) workerInitFailed(key string, err error) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnWorkerInitError != nil {
+			hooks.OnWorkerInitError(key, err)
+		}
+	}
+}
+
+func (o *options) queueSaturated(key string) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnQueueSaturated != nil {
+			hooks.OnQueueSaturated(key)
+		}
+	}
+}
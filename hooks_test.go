@@ -0,0 +1,159 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestHooksObservePoolLifecycle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	created := make(chan string, 10)
+	evictions := make(chan PoolEviction, 10)
+	otherCreated := make(chan string, 10)
+	pm := NewWorkerPoolManager(
+		1, 50*time.Millisecond, time.Hour,
+		WithHooks(Hooks{
+			OnPoolCreated: func(key string, pool WorkerPool) {
+				created <- key
+			},
+			OnPoolEvicted: func(eviction PoolEviction) {
+				evictions <- eviction
+			},
+		}),
+		WithHooks(Hooks{
+			OnPoolCreated: func(key string, pool WorkerPool) {
+				otherCreated <- key
+			},
+		}),
+	)
+
+	pool, doneUsing := pm.GetPool("expires", 1)
+	close(doneUsing)
+	assert.Equal(t, "expires", <-created)
+	assert.Equal(t, "expires", <-otherCreated)
+
+	eviction := <-evictions
+	assert.Equal(t, "expires", eviction.Key)
+	assert.Equal(t, EvictionReasonExpired, eviction.Reason)
+	assert.Same(t, pool, eviction.Pool)
+	assert.GreaterOrEqual(t, eviction.Age, 50*time.Millisecond)
+
+	_, doneUsing = pm.GetPool("deleted", 1)
+	close(doneUsing)
+	assert.Equal(t, "deleted", <-created)
+	pm.Dispose()
+	assert.Equal(t, EvictionReasonDeleted, (<-evictions).Reason)
+}
+
+type failingDisposePool struct {
+	WorkerPool
+}
+
+func (p *failingDisposePool) DisposeE() error {
+	p.WorkerPool.Dispose()
+	return errors.New("couldn't close client")
+}
+
+func TestEvictionHookReceivesDisposeErrors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 10)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- eviction
+		},
+	}))
+
+	var factory Factory = func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &failingDisposePool{WorkerPool: basePool}, nil
+	}
+	pool, doneUsing, _ := pm.GetPoolWithFactory("failing", 1, factory)
+	close(doneUsing)
+	_, doneUsing = pm.GetPool("succeeding", 1)
+	close(doneUsing)
+	pm.Dispose()
+
+	errs := map[string]error{}
+	for i := 0; i < 2; i++ {
+		eviction := <-evictions
+		errs[eviction.Key] = eviction.Err
+	}
+	assert.EqualError(t, errs["failing"], "couldn't close client")
+	assert.Nil(t, errs["succeeding"])
+
+	// DisposeE must still have disposed the embedded pool
+	select {
+	case <-pool.(*failingDisposePool).WorkerPool.(*BaseWorkerPool).disposed:
+	default:
+		t.Error("Expected the embedded pool to be disposed")
+	}
+}
+
+func TestReuseHookObservesCachedPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var reuses []PoolReuse
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolReused: func(reuse PoolReuse) {
+			reuses = append(reuses, reuse)
+		},
+	}))
+
+	pool, firstDone := pm.GetPool("key", 1)
+	assert.Empty(t, reuses)
+	_, secondDone := pm.GetPool("key", 1)
+	close(firstDone)
+	close(secondDone)
+	_, doneUsing := pm.GetPool("other", 1)
+	close(doneUsing)
+
+	assert.Len(t, reuses, 1)
+	assert.Equal(t, "key", reuses[0].Key)
+	assert.Same(t, pool, reuses[0].Pool)
+	assert.Equal(t, 2, reuses[0].Reservations)
+	assert.Positive(t, reuses[0].Age)
+	pm.Dispose()
+}
+
+func TestRotateHookHandsOverToTheReplacement(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	type rotation struct {
+		key      string
+		old, new WorkerPool
+	}
+	var rotations []rotation
+	pm := NewWorkerPoolManager(1, time.Hour, time.Minute, WithClock(clock), WithHooks(Hooks{
+		OnRotate: func(key string, old, new WorkerPool) {
+			rotations = append(rotations, rotation{key: key, old: old, new: new})
+		},
+	}))
+	defer pm.Dispose()
+
+	old, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Empty(t, rotations)
+
+	clock.Advance(2 * time.Minute)
+	rotated, doneUsing := pm.GetPool("key", 1)
+	assert.Same(t, old, rotated, "the checkout which rotates a pool still gets it")
+	if assert.Len(t, rotations, 1) {
+		assert.Equal(t, "key", rotations[0].key)
+		assert.Same(t, old, rotations[0].old)
+		assert.NotSame(t, old, rotations[0].new)
+	}
+	close(doneUsing)
+
+	replacement, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Same(t, rotations[0].new, replacement)
+	assert.Len(t, rotations, 1)
+}
@@ -0,0 +1,77 @@
+package pool
+
+import (
+	"errors"
+	"net/http"
+	"time"
+)
+
+// HTTPConfig configures HTTPMiddlewareWithConfig
+type HTTPConfig struct {
+	// QueueTimeout is how long a request may wait for one of its tenant's workers before it's turned away, as long as
+	// the client is willing to wait if unset
+	QueueTimeout time.Duration
+	// TimeoutStatus is the status turned away requests are answered with, http.StatusTooManyRequests if unset. For
+	// load balancers which retry elsewhere on it, http.StatusServiceUnavailable might suit better.
+	TimeoutStatus int
+	// RejectedStatus is the status of requests whose pool rejects them, e.g. because their key is blocked,
+	// http.StatusServiceUnavailable if unset
+	RejectedStatus int
+}
+
+// HTTPMiddleware returns middleware which executes each request's handler on a worker of its tenant's pool, as picked
+// by keyFromRequest, so that the manager limits how many requests each tenant has in flight. Requests beyond the
+// pool's size wait in its queue for as long as their clients do, see HTTPMiddlewareWithConfig to turn them away.
+func HTTPMiddleware(pm *WorkerPoolManager, keyFromRequest func(*http.Request) string) func(http.Handler) http.Handler {
+	return HTTPMiddlewareWithConfig(pm, keyFromRequest, HTTPConfig{})
+}
+
+// HTTPMiddlewareWithConfig returns middleware like HTTPMiddleware's, configured by config. Requests which are still
+// queued after config.QueueTimeout are answered with config.TimeoutStatus, and never handled.
+//
+// A handler which panics does so on the goroutine serving the request rather than on the pool's worker, so net/http
+// recovers it as usual - including http.ErrAbortHandler - instead of it crashing the process.
+func HTTPMiddlewareWithConfig(
+	pm *WorkerPoolManager, keyFromRequest func(*http.Request) string, config HTTPConfig,
+) func(http.Handler) http.Handler {
+	if config.TimeoutStatus == 0 {
+		config.TimeoutStatus = http.StatusTooManyRequests
+	}
+	if config.RejectedStatus == 0 {
+		config.RejectedStatus = http.StatusServiceUnavailable
+	}
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			serveOnPool(pm, keyFromRequest(r), config, next, w, r)
+		})
+	}
+}
+
+func serveOnPool(
+	pm *WorkerPoolManager, key string, config HTTPConfig, next http.Handler, w http.ResponseWriter, r *http.Request,
+) {
+	// The handler's panic, recovered on the worker to be panicked again here
+	var panicked interface{}
+	returned := true
+	err := ExecuteOnPool(r.Context(), pm, key, config.QueueTimeout, func() {
+		returned = false
+		defer func() {
+			if !returned {
+				panicked = recover()
+			}
+		}()
+		next.ServeHTTP(w, r)
+		returned = true
+	})
+	if !returned {
+		panic(panicked)
+	}
+	switch {
+	case err == nil || r.Context().Err() != nil:
+		// Handled, or the client has gone away
+	case errors.Is(err, ErrQueueTimeout):
+		w.WriteHeader(config.TimeoutStatus)
+	default:
+		w.WriteHeader(config.RejectedStatus)
+	}
+}
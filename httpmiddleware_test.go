@@ -0,0 +1,73 @@
+package pool
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestHTTPMiddlewareLimitsEachTenantsRequests(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+	pm.Block("blocked")
+
+	started := make(chan bool)
+	unblock := make(chan bool)
+	handler := HTTPMiddlewareWithConfig(pm, func(r *http.Request) string {
+		return r.URL.Query().Get("tenant")
+	}, HTTPConfig{QueueTimeout: 20 * time.Millisecond})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("slow") != "" {
+			started <- true
+			<-unblock
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	serve := func(url string) int {
+		recorder := httptest.NewRecorder()
+		handler.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, url, nil))
+		return recorder.Code
+	}
+
+	slow := make(chan int, 1)
+	go func() {
+		slow <- serve("/?tenant=a&slow=1")
+	}()
+	<-started
+	assert.Equal(t, http.StatusTooManyRequests, serve("/?tenant=a"))
+	// Other tenants have workers of their own
+	assert.Equal(t, http.StatusNoContent, serve("/?tenant=b"))
+	assert.Equal(t, http.StatusServiceUnavailable, serve("/?tenant=blocked"))
+
+	close(unblock)
+	assert.Equal(t, http.StatusNoContent, <-slow)
+	assert.Equal(t, http.StatusNoContent, serve("/?tenant=a"))
+}
+
+func TestHTTPMiddlewarePanicsOnTheServingGoroutine(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	defer pm.Dispose()
+	handler := HTTPMiddleware(pm, func(r *http.Request) string {
+		return "key"
+	})(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("abort") != "" {
+			panic(http.ErrAbortHandler)
+		}
+		panic("handler failed")
+	}))
+	serve := func(url string) {
+		handler.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, url, nil))
+	}
+
+	assert.PanicsWithValue(t, "handler failed", func() { serve("/") })
+	assert.PanicsWithValue(t, http.ErrAbortHandler, func() { serve("/?abort=1") })
+	// The worker carries on serving requests
+	assert.PanicsWithValue(t, "handler failed", func() { serve("/") })
+}
@@ -0,0 +1,66 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// idempotencyWindow remembers the idempotency keys of the durable tasks a pool has executed recently, see
+// DurableTasks.IdempotencyWindow
+type idempotencyWindow struct {
+	lock   *sync.Mutex
+	clock  Clock
+	window time.Duration
+	// When each key was last executed
+	executed map[string]time.Time
+	// Keys in the order they were executed, for expiring them. A key executed more than once appears each time.
+	order []string
+}
+
+func newIdempotencyWindow(clock Clock, window time.Duration) *idempotencyWindow {
+	return &idempotencyWindow{
+		lock:     &sync.Mutex{},
+		clock:    clock,
+		window:   window,
+		executed: make(map[string]time.Time),
+	}
+}
+
+// Whether a task with key has executed within the window
+func (w *idempotencyWindow) seen(key string) bool {
+	if w == nil || key == "" {
+		return false
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	w.expire(w.clock.Now())
+	_, ok := w.executed[key]
+	return ok
+}
+
+// Remember that a task with key has executed
+func (w *idempotencyWindow) record(key string) {
+	if w == nil || key == "" {
+		return
+	}
+	w.lock.Lock()
+	defer w.lock.Unlock()
+	now := w.clock.Now()
+	w.expire(now)
+	w.executed[key] = now
+	w.order = append(w.order, key)
+}
+
+// Forget the keys executed before the window. It's not thread-safe, lock above this.
+func (w *idempotencyWindow) expire(now time.Time) {
+	for len(w.order) > 0 {
+		key := w.order[0]
+		if executed, ok := w.executed[key]; ok {
+			if now.Sub(executed) < w.window {
+				return
+			}
+			delete(w.executed, key)
+		}
+		w.order = w.order[1:]
+	}
+}
@@ -0,0 +1,107 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// ErrTooManyCheckouts is returned when checking out a key which already has as many unreleased checkouts as it's
+// allowed, see WithMaxCheckouts
+var ErrTooManyCheckouts = errors.New("key has too many unreleased checkouts")
+
+// WithMaxCheckouts caps how many unreleased checkouts each key may have at once, protecting a pool from a runaway
+// caller loop which reserves it thousands of times without releasing it. Beyond the limit GetPoolWithFactory and
+// GetPoolWithSize return ErrTooManyCheckouts, GetPoolContext waits for a checkout of the key to be released, and
+// GetPool, which can't return an error, waits likewise. The limit counts checkouts of the key across its pools, so a
+// pool's rotation doesn't reset it.
+func WithMaxCheckouts(limit int) Option {
+	return func(o *options) {
+		o.maxCheckouts = limit
+	}
+}
+
+// GetPoolContext returns the WorkerPool for this key like GetPoolWithFactory, but waits while the key has as many
+// checkouts as WithMaxCheckouts allows, until one is released or ctx is done, in which case the context's error is
+// returned.
+func (m *WorkerPoolManager) GetPoolContext(
+	ctx context.Context, key string, sendSize int, factory Factory,
+) (WorkerPool, chan<- bool, error) {
+	for {
+		// Fetched before checking out, so a release in between isn't missed
+		released := m.checkouts.released(key)
+		pool, doneUsing, err := m.GetPoolWithFactory(key, sendSize, factory)
+		if !errors.Is(err, ErrTooManyCheckouts) {
+			return pool, doneUsing, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, nil, ctx.Err()
+		case <-released:
+		}
+	}
+}
+
+// checkoutCounter counts each key's unreleased checkouts, with WithMaxCheckouts
+type checkoutCounter struct {
+	limit  int
+	lock   *sync.Mutex
+	counts map[string]int
+	// Closed once one of the key's checkouts is released, for GetPoolContext to wait on
+	releases map[string]chan struct{}
+}
+
+func newCheckoutCounter(limit int) *checkoutCounter {
+	return &checkoutCounter{
+		limit:    limit,
+		lock:     &sync.Mutex{},
+		counts:   make(map[string]int),
+		releases: make(map[string]chan struct{}),
+	}
+}
+
+// Count a checkout of key, returning false if key has reached the limit. It's a no-op without a limit.
+func (c *checkoutCounter) acquire(key string) bool {
+	if c == nil {
+		return true
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.counts[key] >= c.limit {
+		return false
+	}
+	c.counts[key]++
+	return true
+}
+
+// Stop counting a checkout of key, waking any callers waiting for one to be released
+func (c *checkoutCounter) release(key string) {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.counts[key]--; c.counts[key] <= 0 {
+		delete(c.counts, key)
+	}
+	if released, ok := c.releases[key]; ok {
+		close(released)
+		delete(c.releases, key)
+	}
+}
+
+// A channel which is closed once one of key's checkouts is released, or nil without a limit, which blocks forever but
+// is never waited on, as checkouts are never rejected without one
+func (c *checkoutCounter) released(key string) <-chan struct{} {
+	if c == nil {
+		return nil
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	released, ok := c.releases[key]
+	if !ok {
+		released = make(chan struct{})
+		c.releases[key] = released
+	}
+	return released
+}
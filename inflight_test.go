@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestMaxCheckoutsCapsEachKeysUnreleasedCheckouts(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMaxCheckouts(2))
+	defer pm.Dispose()
+
+	_, first, err := pm.GetPoolWithFactory("key", 1, nil)
+	assert.Nil(t, err)
+	_, second, err := pm.GetPoolWithSize("key", 1, 1, nil)
+	assert.Nil(t, err)
+	_, _, err = pm.GetPoolWithFactory("key", 1, nil)
+	assert.Equal(t, ErrTooManyCheckouts, err)
+
+	// Other keys have limits of their own
+	_, other, err := pm.GetPoolWithFactory("other", 1, nil)
+	assert.Nil(t, err)
+	close(other)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, _, err = pm.GetPoolContext(ctx, "key", 1, nil)
+	assert.Equal(t, context.DeadlineExceeded, err)
+
+	waited := make(chan chan<- bool, 1)
+	go func() {
+		_, doneUsing := pm.GetPool("key", 1)
+		waited <- doneUsing
+	}()
+	select {
+	case <-waited:
+		t.Fatal("Expected GetPool to wait for a checkout to be released")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	close(first)
+	select {
+	case doneUsing := <-waited:
+		close(doneUsing)
+	case <-time.After(time.Second):
+		t.Fatal("Expected GetPool to check out once a checkout was released")
+	}
+	close(second)
+	assert.Contains(t, pm.Config().Features, "max checkouts")
+}
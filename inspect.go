@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"errors"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// ErrTaskPurged is returned to whatever waits for a task which PurgeQueue removed before it executed
+var ErrTaskPurged = errors.New("task purged from the queue")
+
+// WithQueueInspection queues pools' tasks behind a lock, as WithCondvarDispatch does, so that PeekQueue and
+// PurgeQueue can look into the queue in place. The default channel-based queue can't be looked into without taking
+// its tasks out, which would reorder them and could block on a backed up pool, so PeekQueue returns nil and
+// PurgeQueue removes nothing from it. The queues of WithCondvarDispatch, WithPriorityQueue and WithSubQueues are
+// lock-guarded already, and can be looked into without this.
+func WithQueueInspection() Option {
+	return func(o *options) {
+		o.queueInspection = true
+	}
+}
+
+// PeekQueue returns the TaskInfo of up to n of the pool's queued tasks, in the order they'll execute in, to see what
+// a backed up pool is holding. Tasks parked by a label limit and tasks already executing aren't included. It leaves
+// the queue as it is, and returns nil for pools with the default channel-based queue, see WithQueueInspection.
+func (p *BaseWorkerPool) PeekQueue(n int) []TaskInfo {
+	var infos []TaskInfo
+	p.queue.rework(func(tasks []task) []task {
+		for _, t := range tasks {
+			if len(infos) == n {
+				break
+			}
+			if !t.wakeup {
+				infos = append(infos, t.info)
+			}
+		}
+		return tasks
+	})
+	return infos
+}
+
+// PurgeQueue removes the pool's queued tasks for which filter returns true, without executing them, and returns how
+// many it removed - e.g. to drop poison or obsolete tasks from a backed up pool. Like PeekQueue, it needs a queue it
+// can look into, see WithQueueInspection. Purged tasks never execute: the Scope, Group or TaskGroup they were
+// submitted through fails with ErrTaskPurged, as do SubmitAndWait, ExecuteOnPool and Bulkhead.Execute calls waiting
+// on them.
+func (p *BaseWorkerPool) PurgeQueue(filter func(info TaskInfo) bool) int {
+	var purged []task
+	p.queue.rework(func(tasks []task) []task {
+		kept := tasks[:0]
+		for _, t := range tasks {
+			if !t.wakeup && filter(t.info) {
+				purged = append(purged, t)
+				continue
+			}
+			kept = append(kept, t)
+		}
+		return kept
+	})
+	for _, t := range purged {
+		p.forget(t)
+	}
+	return len(purged)
+}
+
+// Account for a queued task being dropped without executing, and release whatever waits for it
+func (p *BaseWorkerPool) forget(t task) {
+	if t.coalesced != nil {
+		p.forgetCoalesced(t.coalesced)
+	}
+	p.finish(t)
+	if t.dropped != nil {
+		t.dropped()
+	}
+}
+
+// PeekQueue returns up to n of the tasks queued in key's pool, see BaseWorkerPool.PeekQueue. It returns nil if no pool
+// is cached for key.
+func (m *WorkerPoolManager) PeekQueue(key string, n int) []TaskInfo {
+	item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]())
+	if item == nil {
+		return nil
+	}
+	return item.Value().PeekQueue(n)
+}
+
+// PurgeQueue removes the tasks queued in key's pool which match filter, see BaseWorkerPool.PurgeQueue. It returns 0 if
+// no pool is cached for key.
+func (m *WorkerPoolManager) PurgeQueue(key string, filter func(info TaskInfo) bool) int {
+	item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]())
+	if item == nil {
+		return 0
+	}
+	return item.Value().PurgeQueue(filter)
+}
@@ -0,0 +1,137 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestQueueInspection(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for name, opts := range map[string][]Option{
+		"inspection": {WithQueueInspection()},
+		"condvar":    {WithCondvarDispatch()},
+		"priority":   {WithPriorityQueue(0)},
+	} {
+		t.Run(name, func(t *testing.T) {
+			pm := NewWorkerPoolManager(1, time.Hour, time.Hour, append(opts, WithQueueCapacity(10))...)
+			pool, doneUsing := pm.GetPool("key", 1)
+			release := make(chan bool)
+			started := make(chan bool)
+			pool.Submit(func() {
+				close(started)
+				<-release
+			})
+			<-started
+
+			executed := make(chan string, 10)
+			for _, label := range []string{"a", "poison", "b", "poison", "c"} {
+				label := label
+				assert.Nil(t, SubmitTask(pool, TaskInfo{Label: label}, func() { executed <- label }))
+			}
+			labels := func(infos []TaskInfo) []string {
+				var labels []string
+				for _, info := range infos {
+					labels = append(labels, info.Label)
+				}
+				return labels
+			}
+			assert.Equal(t, []string{"a", "poison", "b"}, labels(pm.PeekQueue("key", 3)))
+			assert.Equal(t, 2, pm.PurgeQueue("key", func(info TaskInfo) bool { return info.Label == "poison" }))
+			assert.Equal(t, []string{"a", "b", "c"}, labels(pool.PeekQueue(10)))
+			assert.Equal(t, 3, pm.Snapshot()["key"].QueueDepth)
+			assert.Nil(t, pm.PeekQueue("missing", 1))
+
+			close(release)
+			for _, label := range []string{"a", "b", "c"} {
+				assert.Equal(t, label, <-executed)
+			}
+			assert.Eventually(t, pool.idle, time.Second, time.Millisecond)
+			close(doneUsing)
+			pm.Dispose()
+		})
+	}
+}
+
+func TestChannelQueuesArentInspected(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(3))
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool("key", 1)
+	defer close(doneUsing)
+	release := make(chan bool)
+	started := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	executed := make(chan string, 3)
+	for _, label := range []string{"a", "b", "c"} {
+		label := label
+		assert.Nil(t, SubmitTask(pool, TaskInfo{Label: label}, func() { executed <- label }))
+	}
+	// The queue is full, so looking into it by taking its tasks out and putting them back could block
+	assert.Nil(t, pool.PeekQueue(3))
+	assert.Equal(t, 0, pool.PurgeQueue(func(TaskInfo) bool { return true }))
+	assert.Equal(t, 3, pm.Snapshot()["key"].QueueDepth)
+
+	close(release)
+	for _, label := range []string{"a", "b", "c"} {
+		assert.Equal(t, label, <-executed)
+	}
+	assert.NotContains(t, pm.Config().Features, "queue inspection")
+}
+
+func TestPurgedTasksReleaseTheirWaiters(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueInspection(), WithQueueCapacity(10))
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool("key", 1)
+	defer close(doneUsing)
+	release := make(chan bool)
+	started := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	scope := pm.Scope(context.Background(), "key", 1)
+	scope.Go(func() {
+		t.Error("Expected the scope's work to be purged")
+	})
+	group := ErrGroup(pm, "key")
+	group.Go(func() error {
+		t.Error("Expected the group's work to be purged")
+		return nil
+	})
+	waited := make(chan error, 1)
+	go func() {
+		waited <- SubmitAndWait(pool, func() {
+			t.Error("Expected the waited on work to be purged")
+		})
+	}()
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].QueueDepth == 3
+	}, time.Second, time.Millisecond)
+
+	assert.Equal(t, 3, pm.PurgeQueue("key", func(TaskInfo) bool { return true }))
+	assert.Equal(t, ErrTaskPurged, scope.Wait())
+	assert.Equal(t, ErrTaskPurged, group.Wait())
+	select {
+	case err := <-waited:
+		assert.Equal(t, ErrTaskPurged, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expected SubmitAndWait to return once its work was purged")
+	}
+	close(release)
+	assert.Eventually(t, pool.idle, time.Second, time.Millisecond)
+}
@@ -0,0 +1,45 @@
+package pool
+
+import "fmt"
+
+// VetoError is returned for submissions vetoed by a WithSubmitInterceptor interceptor. Err is the interceptor's error,
+// which errors.Is and errors.As see through to.
+type VetoError struct {
+	Key  string
+	Info TaskInfo
+	Err  error
+}
+
+func (e *VetoError) Error() string {
+	return fmt.Sprintf("submission to %q vetoed: %v", e.Key, e.Err)
+}
+
+func (e *VetoError) Unwrap() error {
+	return e.Err
+}
+
+// WithSubmitInterceptor consults interceptor before each submission is queued, with the key and TaskInfo of the task.
+// An error vetoes the submission, and is returned to the submitter wrapped in a VetoError, so policies like quotas,
+// kill switches and compliance holds can be enforced in one place. It may be passed multiple times, in which case the
+// interceptors are consulted in order until one vetoes.
+//
+// Interceptors are called on the submitting goroutine for every submission, so they should be cheap. Vetoed
+// submissions are counted in MetricTasksRejected.
+func WithSubmitInterceptor(interceptor func(key string, t TaskInfo) error) Option {
+	return func(o *options) {
+		o.interceptors = append(o.interceptors, interceptor)
+	}
+}
+
+// Consult the interceptors about a submission of t
+func (p *BaseWorkerPool) intercept(t task) error {
+	if p.options == nil {
+		return nil
+	}
+	for _, interceptor := range p.options.interceptors {
+		if err := interceptor(p.key, t.info); err != nil {
+			return &VetoError{Key: p.key, Info: t.info, Err: err}
+		}
+	}
+	return nil
+}
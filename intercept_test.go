@@ -0,0 +1,46 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubmitInterceptorsVetoSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	errOverQuota := errors.New("over quota")
+	var consulted []string
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithSubmitInterceptor(func(key string, info TaskInfo) error {
+			consulted = append(consulted, key+"/"+info.Label)
+			if info.Label == "bulk" {
+				return errOverQuota
+			}
+			return nil
+		}),
+		WithSubmitInterceptor(func(key string, info TaskInfo) error {
+			consulted = append(consulted, "second")
+			return nil
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+	executed := make(chan bool, 1)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{Label: "urgent"}, func() { executed <- true }))
+	<-executed
+
+	err := SubmitTask(pool, TaskInfo{Label: "bulk"}, func() { executed <- true })
+	var veto *VetoError
+	assert.True(t, errors.As(err, &veto))
+	assert.Equal(t, "key", veto.Key)
+	assert.Equal(t, "bulk", veto.Info.Label)
+	assert.True(t, errors.Is(err, errOverQuota))
+	assert.Equal(t, `submission to "key" vetoed: over quota`, err.Error())
+	assert.Equal(t, []string{"key/urgent", "second", "key/bulk"}, consulted)
+	close(doneUsing)
+	pm.Dispose()
+	assert.Empty(t, executed)
+}
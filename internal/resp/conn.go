@@ -0,0 +1,131 @@
+package resp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"sync"
+)
+
+// Config is where a Conn connects to, and how
+type Config struct {
+	// Name prefixes the errors the Conn returns, e.g. "redislease"
+	Name string
+	// Addr is the Redis server's address, e.g. "127.0.0.1:6379"
+	Addr string
+	// Username and Password authenticate the connection if Password isn't empty, with Username for Redis 6 ACLs if it
+	// isn't empty either
+	Username string
+	Password string
+	// DB is the numbered database to select, unless it's 0
+	DB int
+}
+
+// Conn is a single connection to a Redis server, which is opened when it's first needed and reopened after errors,
+// so it's safe to build before Redis is reachable. It sends one command at a time.
+type Conn struct {
+	config Config
+
+	// Guards the connection
+	lock   *sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// NewConn builds a Conn for config, without connecting yet
+func NewConn(config Config) *Conn {
+	return &Conn{config: config, lock: &sync.Mutex{}}
+}
+
+// Do sends a command and reads its reply, connecting first if need be. It returns ReplyErrors as errors, after which
+// the connection is kept, and discards the connection after any other error.
+func (c *Conn) Do(ctx context.Context, args ...string) (interface{}, error) {
+	reply, err := c.do(ctx, args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", c.config.Name, err)
+	}
+	return reply, nil
+}
+
+// Close closes the connection, if it's open
+func (c *Conn) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conn == nil {
+		return nil
+	}
+	err := c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Conn) do(ctx context.Context, args []string) (interface{}, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.conn == nil {
+		if err := c.connect(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	deadline, _ := ctx.Deadline()
+	if err := c.conn.SetDeadline(deadline); err != nil {
+		return nil, c.broken(err)
+	}
+	reply, err := c.roundTrip(args)
+	var replyErr ReplyError
+	if err != nil && !errors.As(err, &replyErr) {
+		// The connection is in an unknown state, so it's discarded
+		return nil, c.broken(err)
+	}
+	return reply, err
+}
+
+// Open the connection, authenticating and selecting the database. It's not thread-safe, lock above this.
+func (c *Conn) connect(ctx context.Context) error {
+	var dialer net.Dialer
+	conn, err := dialer.DialContext(ctx, "tcp", c.config.Addr)
+	if err != nil {
+		return err
+	}
+	c.conn = conn
+	c.reader = bufio.NewReader(conn)
+	if deadline, ok := ctx.Deadline(); ok {
+		if err := conn.SetDeadline(deadline); err != nil {
+			return c.broken(err)
+		}
+	}
+
+	if c.config.Password != "" {
+		args := []string{"AUTH", c.config.Password}
+		if c.config.Username != "" {
+			args = []string{"AUTH", c.config.Username, c.config.Password}
+		}
+		if _, err := c.roundTrip(args); err != nil {
+			return c.broken(err)
+		}
+	}
+	if c.config.DB != 0 {
+		if _, err := c.roundTrip([]string{"SELECT", strconv.Itoa(c.config.DB)}); err != nil {
+			return c.broken(err)
+		}
+	}
+	return nil
+}
+
+// Discard the connection after err. It's not thread-safe, lock above this.
+func (c *Conn) broken(err error) error {
+	_ = c.conn.Close()
+	c.conn = nil
+	return err
+}
+
+func (c *Conn) roundTrip(args []string) (interface{}, error) {
+	if _, err := c.conn.Write(EncodeCommand(args)); err != nil {
+		return nil, err
+	}
+	return ReadReply(c.reader)
+}
@@ -0,0 +1,91 @@
+// Package resp is the Redis client shared by the redislease and redisthrottle packages: a single connection speaking
+// RESP, the Redis serialization protocol, which is all they need of Redis.
+package resp
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ReplyError is an error reply from Redis, after which the connection can still be used
+type ReplyError string
+
+func (e ReplyError) Error() string {
+	return string(e)
+}
+
+// EncodeCommand encodes a command as a RESP array of bulk strings
+func EncodeCommand(args []string) []byte {
+	var buf []byte
+	buf = append(buf, '*')
+	buf = strconv.AppendInt(buf, int64(len(args)), 10)
+	buf = append(buf, '\r', '\n')
+	for _, arg := range args {
+		buf = append(buf, '$')
+		buf = strconv.AppendInt(buf, int64(len(arg)), 10)
+		buf = append(buf, '\r', '\n')
+		buf = append(buf, arg...)
+		buf = append(buf, '\r', '\n')
+	}
+	return buf
+}
+
+// ReadReply reads a RESP reply, as a string, an int64, nil, a []interface{} of replies, or a ReplyError
+func ReadReply(r *bufio.Reader) (interface{}, error) {
+	line, err := r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	if !strings.HasSuffix(line, "\r\n") || len(line) < 3 {
+		return nil, fmt.Errorf("malformed reply %q", line)
+	}
+	kind, value := line[0], line[1:len(line)-2]
+
+	switch kind {
+	case '+':
+		return value, nil
+	case '-':
+		return nil, ReplyError(value)
+	case ':':
+		return strconv.ParseInt(value, 10, 64)
+	case '$':
+		length, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		buf := make([]byte, length+2)
+		if _, err := io.ReadFull(r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:length]), nil
+	case '*':
+		length, err := strconv.Atoi(value)
+		if err != nil {
+			return nil, err
+		}
+		if length < 0 {
+			return nil, nil
+		}
+		replies := make([]interface{}, length)
+		for i := range replies {
+			// Errors inside arrays are elements, not failures of the whole reply
+			replies[i], err = ReadReply(r)
+			var replyErr ReplyError
+			if errors.As(err, &replyErr) {
+				replies[i] = replyErr
+			} else if err != nil {
+				return nil, err
+			}
+		}
+		return replies, nil
+	default:
+		return nil, fmt.Errorf("unknown reply type %q", kind)
+	}
+}
@@ -0,0 +1,23 @@
+package resp
+
+import (
+	"bufio"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRESPEncoding(t *testing.T) {
+	assert.Equal(t, "*2\r\n$3\r\nGET\r\n$6\r\napp-42\r\n", string(EncodeCommand([]string{"GET", "app-42"})))
+
+	r := bufio.NewReader(strings.NewReader("*4\r\n+OK\r\n:7\r\n$-1\r\n-ERR nope\r\n$5\r\nhello\r\n"))
+	reply, err := ReadReply(r)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{"OK", int64(7), nil, ReplyError("ERR nope")}, reply)
+	reply, err = ReadReply(r)
+	assert.Nil(t, err)
+	assert.Equal(t, "hello", reply)
+	_, err = ReadReply(bufio.NewReader(strings.NewReader("?\r\n")))
+	assert.NotNil(t, err)
+}
@@ -0,0 +1,183 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// TaskInfo describes a task submitted with SubmitTask
+type TaskInfo struct {
+	// Label is the class of operation the task belongs to, e.g. "export". Labels limited with WithLabelLimit are
+	// restricted to that many concurrently executing tasks per pool. Tasks without a label are never limited.
+	Label string
+	// Priority orders the task in pools built WithPriorityQueue, where higher priority tasks execute first, and decides
+	// which tasks are shed first under WithLoadShedder. It's ignored by other pools.
+	Priority int
+	// Deadline is when the task should have been executed by, which orders it in pools built WithDeadlineQueue. It's
+	// ignored by other pools, and missing it doesn't stop the task from executing.
+	Deadline time.Time
+	// MemoryCost is roughly how many bytes the task holds until it finishes executing, counted against the pool's
+	// WithMemoryBudget. It's ignored by pools without one.
+	MemoryCost int64
+	// Queue names the sub-queue the task waits in, in pools built WithSubQueues. It's ignored by other pools.
+	Queue string
+	// Baggage is the alternating keys and values copied from the task's context by WithBaggage, which log messages
+	// about the task include
+	Baggage []interface{}
+}
+
+// WithLabelLimit restricts each pool to executing at most limit tasks labeled label at once, so an expensive class of
+// operation can't occupy all of a pool's workers. It may be passed once per label.
+//
+// Over-limit tasks don't hold up a worker: a worker which picks one up parks it and moves on to the rest of the
+// queue, and parked tasks are executed in submission order as earlier tasks with the same label finish.
+func WithLabelLimit(label string, limit int) Option {
+	return func(o *options) {
+		if o.labelLimits == nil {
+			o.labelLimits = make(map[string]int)
+		}
+		o.labelLimits[label] = limit
+	}
+}
+
+// SubmitTask submits w to be executed, described by info. Unlike Submit, it reports rejected submissions, returning
+// ErrKeyBlocked if the pool's key is blocked, ErrPoolQuarantined if the pool is quarantined, a VetoError if an
+// interceptor vetoes it, ErrLoadShed if it's shed under load, ErrMemoryBudget if info.MemoryCost doesn't fit in a
+// rejecting memory budget, ErrNoWorkers if the pool has no workers, with ZeroSendSizeReject, or ErrDisposed if the
+// pool is disposed before the task is queued.
+func SubmitTask(p WorkerPool, info TaskInfo, w Work) error {
+	return p.enqueue(task{work: w, info: info})
+}
+
+// labelLimiter tracks a pool's executing and parked tasks for each limited class: the labels limited with
+// WithLabelLimit, and the traffic which isn't reserved for with WithReservedWorkers. A task may be limited under both.
+// A nil labelLimiter limits nothing.
+type labelLimiter struct {
+	lock    *sync.Mutex
+	limits  map[string]int
+	running map[string]int
+	parked  map[string][]task
+
+	// With WithReservedWorkers, tasks not labeled reservedLabel are limited together under unreservedClass
+	reserved         bool
+	reservedLabel    string
+	reservedFraction float64
+}
+
+// Labels limited with WithLabelLimit are never empty, so the empty label's class is free for the unreserved traffic
+const unreservedClass = ""
+
+func newLabelLimiter(limits map[string]int) *labelLimiter {
+	l := &labelLimiter{
+		lock:    &sync.Mutex{},
+		limits:  make(map[string]int, len(limits)),
+		running: make(map[string]int),
+		parked:  make(map[string][]task),
+	}
+	for label, limit := range limits {
+		if label != "" {
+			l.limits[label] = limit
+		}
+	}
+	return l
+}
+
+// The classes t is limited under, at most one for its label and one for the unreserved traffic
+func (l *labelLimiter) classes(t task) (classes [2]string, n int) {
+	if l == nil {
+		return classes, 0
+	}
+	label := t.info.Label
+	if _, ok := l.limits[label]; ok && label != "" {
+		classes[n] = label
+		n++
+	}
+	if l.reserved && label != l.reservedLabel {
+		classes[n] = unreservedClass
+		n++
+	}
+	return classes, n
+}
+
+// Whether t may start now, parking it if not
+func (l *labelLimiter) admit(t task) bool {
+	classes, n := l.classes(t)
+	if n == 0 {
+		return true
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, class := range classes[:n] {
+		if l.running[class] >= l.limits[class] {
+			l.parked[class] = append(l.parked[class], t)
+			return false
+		}
+	}
+	for _, class := range classes[:n] {
+		l.running[class]++
+	}
+	return true
+}
+
+// Called once t has finished, returning the next parked task which fits in the slots t freed, which takes them over
+func (l *labelLimiter) finish(t task) (task, bool) {
+	classes, n := l.classes(t)
+	if n == 0 {
+		return task{}, false
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	for _, class := range classes[:n] {
+		l.running[class]--
+	}
+	for _, class := range classes[:n] {
+		if next, ok := l.resume(class); ok {
+			return next, true
+		}
+	}
+	if l.reserved {
+		// A task parked under another class may have been waiting for one of t's too
+		for class := range l.parked {
+			if next, ok := l.resume(class); ok {
+				return next, true
+			}
+		}
+	}
+	return task{}, false
+}
+
+// Admit the oldest task parked under class if it fits now. It's not thread-safe, lock above this.
+func (l *labelLimiter) resume(class string) (task, bool) {
+	parked := l.parked[class]
+	if len(parked) == 0 {
+		return task{}, false
+	}
+	next := parked[0]
+	classes, n := l.classes(next)
+	for _, class := range classes[:n] {
+		if l.running[class] >= l.limits[class] {
+			return task{}, false
+		}
+	}
+	parked[0] = task{}
+	l.parked[class] = parked[1:]
+	for _, class := range classes[:n] {
+		l.running[class]++
+	}
+	return next, true
+}
+
+// Called when t panicked, releasing its slots and returning the next parked task which fitted in them, which has to be
+// readmitted
+func (l *labelLimiter) abandon(t task) (task, bool) {
+	next, ok := l.finish(t)
+	if ok {
+		classes, n := l.classes(next)
+		l.lock.Lock()
+		for _, class := range classes[:n] {
+			l.running[class]--
+		}
+		l.lock.Unlock()
+	}
+	return next, ok
+}
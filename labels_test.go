@@ -0,0 +1,86 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestLabelLimitCapsConcurrentTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPoolWithOptions(2, WithLabelLimit("export", 1))
+	p.spawnWorkers(2)
+
+	var running, maxRunning int32
+	var order []int
+	var orderLock sync.Mutex
+	var exports sync.WaitGroup
+	unblock := make(chan bool)
+	for i := 0; i < 3; i++ {
+		i := i
+		exports.Add(1)
+		SubmitTask(p, TaskInfo{Label: "export"}, func() {
+			defer exports.Done()
+			n := atomic.AddInt32(&running, 1)
+			if n > atomic.LoadInt32(&maxRunning) {
+				atomic.StoreInt32(&maxRunning, n)
+			}
+			orderLock.Lock()
+			order = append(order, i)
+			orderLock.Unlock()
+			<-unblock
+			atomic.AddInt32(&running, -1)
+		})
+	}
+
+	// Parked exports leave the other workers free for unlimited work
+	done := make(chan bool)
+	SubmitTask(p, TaskInfo{Label: "other"}, func() {
+		p.Submit(func() {
+			close(done)
+		})
+	})
+	<-done
+
+	close(unblock)
+	exports.Wait()
+	assert.Equal(t, int32(1), maxRunning)
+	assert.Equal(t, []int{0, 1, 2}, order)
+	p.Dispose()
+}
+
+func TestLabelLimitReadmitsParkedTasksAfterPanic(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recovering := WithWorkerLoop(func(loop func()) {
+		for stopped := false; !stopped; {
+			func() {
+				defer func() {
+					_ = recover()
+				}()
+				loop()
+				stopped = true
+			}()
+		}
+	})
+	p, _ := NewWorkerPoolWithOptions(2, WithLabelLimit("export", 1), recovering)
+
+	unblock := make(chan bool)
+	SubmitTask(p, TaskInfo{Label: "export"}, func() {
+		<-unblock
+		panic("export failed")
+	})
+	done := make(chan bool)
+	SubmitTask(p, TaskInfo{Label: "export"}, func() {
+		close(done)
+	})
+	p.spawnWorkers(2)
+
+	close(unblock)
+	<-done
+	p.Dispose()
+}
@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"runtime/debug"
+	"time"
+)
+
+// LeakedCheckout describes a checkout reported by WithLeakDetection, whose doneUsing channel is still open
+type LeakedCheckout struct {
+	Key string
+	// Held is how long the pool had been checked out when it was reported
+	Held time.Duration
+	// Site is where the pool was checked out
+	Site *CallSite
+	// Stack is the stack of the goroutine which checked the pool out, as it did
+	Stack []byte
+}
+
+// WithLeakDetection reports checkouts whose doneUsing channel hasn't been closed within threshold to the
+// OnLeakedCheckout hook, with where the pool was checked out. A forgotten close(doneUsing) otherwise keeps its pool
+// from ever being disposed with nothing to say who checked it out. Each checkout is reported at most once, and counted
+// in MetricLeakedCheckouts.
+//
+// It's meant for debugging: recording the stack of every checkout costs several microseconds per GetPool.
+func WithLeakDetection(threshold time.Duration) Option {
+	return func(o *options) {
+		o.leakThreshold = threshold
+	}
+}
+
+// Watch a checkout of key's pool being made by the caller, returning a function to call once it's released
+func (m *WorkerPoolManager) watchCheckout(key string) func() {
+	threshold := m.options.leakThreshold
+	if threshold <= 0 {
+		return func() {}
+	}
+	leak := LeakedCheckout{Key: key, Site: submitSite(), Stack: debug.Stack()}
+	checkedOut := m.clock.Now()
+	timer := m.clock.AfterFunc(threshold, func() {
+		leak.Held = m.clock.Now().Sub(checkedOut)
+		m.options.count(MetricLeakedCheckouts, key, 1)
+		m.options.checkoutLeaked(leak)
+	})
+	return func() {
+		timer.Stop()
+	}
+}
+
+func (o *options) checkoutLeaked(leak LeakedCheckout) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnLeakedCheckout != nil {
+			hooks.OnLeakedCheckout(leak)
+		}
+	}
+}
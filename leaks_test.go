@@ -0,0 +1,38 @@
+package pool
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestLeakDetectionReportsUnreleasedCheckouts(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	leaks := make(chan LeakedCheckout, 2)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithLeakDetection(20*time.Millisecond),
+		WithHooks(Hooks{
+			OnLeakedCheckout: func(leak LeakedCheckout) {
+				leaks <- leak
+			},
+		}),
+	)
+	_, released := pm.GetPool("released", 1)
+	close(released)
+	_, leaked := pm.GetPool("leaked", 1)
+
+	leak := <-leaks
+	assert.Equal(t, "leaked", leak.Key)
+	assert.GreaterOrEqual(t, leak.Held, 20*time.Millisecond)
+	assert.Equal(t, "leaks_test.go", filepath.Base(leak.Site.File))
+	assert.Contains(t, string(leak.Stack), "TestLeakDetectionReportsUnreleasedCheckouts")
+	time.Sleep(20 * time.Millisecond)
+	assert.Empty(t, leaks)
+
+	close(leaked)
+	pm.Dispose()
+}
@@ -0,0 +1,259 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// ErrKeyNotOwned is returned when submitting to the pool of a key whose lease is held by another instance, see
+// WithLeases
+var ErrKeyNotOwned = errors.New("key is leased to another instance")
+
+// LeaseBackend stores the key ownership leases shared by the instances of a deployment. The redislease and etcdlease
+// packages implement it.
+type LeaseBackend interface {
+	// Acquire takes the lease on key for owner, or renews it if owner already holds it, so that it lasts for ttl. It
+	// returns false if another owner holds the lease.
+	Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error)
+	// Release gives up owner's lease on key, doing nothing if owner doesn't hold it
+	Release(ctx context.Context, key string, owner string) error
+}
+
+// Leases configures key ownership leases, see WithLeases
+type Leases struct {
+	Backend LeaseBackend
+	// Owner identifies this instance to the other instances, e.g. its hostname
+	Owner string
+	// TTL is how long leases last unless renewed, 30 seconds if unset. Leases are renewed every third of TTL while
+	// their pools are cached, and a crashed instance's keys can be taken over once its leases run out.
+	TTL time.Duration
+	// Timeout bounds each call to Backend, a second if unset
+	Timeout time.Duration
+	// OnError is called when a call to Backend fails. It may be nil.
+	OnError func(key string, err error)
+}
+
+// Defaults for Leases
+const (
+	defaultLeaseTTL     = 30 * time.Second
+	defaultLeaseTimeout = time.Second
+)
+
+// WithLeases coordinates the manager with the other instances of a deployment, so only the instance holding a key's
+// lease spawns workers for it - e.g. to stop every instance opening a pool's worth of connections to the same tenant.
+//
+// The manager tries to acquire a key's lease when a pool is first checked out for it, and every third of the lease
+// TTL while it isn't the owner. Pools for keys leased to another instance are still handed out, but don't spawn
+// workers, and reject submissions with ErrKeyNotOwned. Callers should route work for those keys to the owning
+// instance. If the lease on a cached pool's key is lost, the pool is evicted with EvictionReasonLeaseLost.
+//
+// Backend failures are treated as another instance holding the lease, so an outage of the backend stops work for keys
+// this instance doesn't already hold leases on, rather than risking every instance working on them at once.
+func WithLeases(leases Leases) Option {
+	return func(o *options) {
+		if leases.TTL <= 0 {
+			leases.TTL = defaultLeaseTTL
+		}
+		if leases.Timeout <= 0 {
+			leases.Timeout = defaultLeaseTimeout
+		}
+		o.leases = &leases
+	}
+}
+
+// leaseCoordinator tracks the leases a manager holds, and renews them
+type leaseCoordinator struct {
+	Leases
+	manager *WorkerPoolManager
+	lock    *sync.Mutex
+	// When each held lease runs out, unless it's renewed
+	held map[string]time.Time
+	// When acquiring each lease held by another instance was last attempted
+	attempted map[string]time.Time
+	timer     Timer
+	stopped   bool
+}
+
+func newLeaseCoordinator(m *WorkerPoolManager, leases Leases) *leaseCoordinator {
+	c := &leaseCoordinator{
+		Leases:    leases,
+		manager:   m,
+		lock:      &sync.Mutex{},
+		held:      make(map[string]time.Time),
+		attempted: make(map[string]time.Time),
+	}
+	c.timer = m.clock.AfterFunc(c.renewInterval(), c.renew)
+	return c
+}
+
+func (c *leaseCoordinator) renewInterval() time.Duration {
+	return c.TTL / 3
+}
+
+// Whether this instance owns key, acquiring its lease if need be. A nil coordinator owns everything.
+func (c *leaseCoordinator) owns(key string) bool {
+	if c == nil {
+		return true
+	}
+	now := c.manager.clock.Now()
+	c.lock.Lock()
+	if expiry, ok := c.held[key]; ok && now.Before(expiry) {
+		c.lock.Unlock()
+		return true
+	}
+	if attempted, ok := c.attempted[key]; ok && now.Sub(attempted) < c.renewInterval() {
+		c.lock.Unlock()
+		return false
+	}
+	c.lock.Unlock()
+
+	acquired, _ := c.acquire(key)
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if c.stopped {
+		return false
+	}
+	if acquired {
+		delete(c.attempted, key)
+		c.held[key] = now.Add(c.TTL)
+	} else {
+		delete(c.held, key)
+		c.attempted[key] = now
+	}
+	return acquired
+}
+
+func (c *leaseCoordinator) acquire(key string) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	acquired, err := c.Backend.Acquire(ctx, key, c.Owner, c.TTL)
+	if err != nil {
+		c.failed(key, err)
+		return false, err
+	}
+	return acquired, nil
+}
+
+func (c *leaseCoordinator) release(key string) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Timeout)
+	defer cancel()
+	if err := c.Backend.Release(ctx, key, c.Owner); err != nil {
+		c.failed(key, err)
+	}
+}
+
+func (c *leaseCoordinator) failed(key string, err error) {
+	if c.OnError != nil {
+		c.OnError(key, err)
+	}
+}
+
+// Renew the leases of cached pools, and release the rest
+func (c *leaseCoordinator) renew() {
+	c.lock.Lock()
+	if c.stopped {
+		c.lock.Unlock()
+		return
+	}
+	keys := make([]string, 0, len(c.held))
+	for key := range c.held {
+		keys = append(keys, key)
+	}
+	// Forget failed attempts once they're due to be retried anyway, so the map doesn't grow with every key seen
+	now := c.manager.clock.Now()
+	for key, attempted := range c.attempted {
+		if now.Sub(attempted) >= c.renewInterval() {
+			delete(c.attempted, key)
+		}
+	}
+	c.lock.Unlock()
+
+	for _, key := range keys {
+		c.lock.Lock()
+		stopped := c.stopped
+		c.lock.Unlock()
+		if stopped {
+			return
+		}
+		if !c.manager.cached(key) {
+			c.release(key)
+			c.forget(key)
+			continue
+		}
+		renewedAt := c.manager.clock.Now()
+		renewed, err := c.acquire(key)
+		c.lock.Lock()
+		expiry, ok := c.held[key]
+		if renewed && ok {
+			c.held[key] = renewedAt.Add(c.TTL)
+		}
+		// The lease might still be held after a failure to renew it, until it runs out
+		lost := ok && !renewed && (err == nil || !c.manager.clock.Now().Before(expiry))
+		c.lock.Unlock()
+		if lost {
+			c.forget(key)
+			c.manager.leaseLost(key)
+		}
+	}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if !c.stopped {
+		c.timer = c.manager.clock.AfterFunc(c.renewInterval(), c.renew)
+	}
+}
+
+func (c *leaseCoordinator) forget(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.held, key)
+}
+
+// Stop renewing leases, and release those still held, once the manager is disposed
+func (c *leaseCoordinator) stop() {
+	if c == nil {
+		return
+	}
+	c.lock.Lock()
+	c.stopped = true
+	c.timer.Stop()
+	held := c.held
+	c.held = make(map[string]time.Time)
+	c.lock.Unlock()
+
+	for key := range held {
+		c.release(key)
+	}
+}
+
+// Whether a pool is cached for key
+func (m *WorkerPoolManager) cached(key string) bool {
+	return m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]()) != nil
+}
+
+// Evict key's pool once its lease has gone to another instance
+func (m *WorkerPoolManager) leaseLost(key string) {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	if item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]()); item != nil {
+		item.Value().markEvicted(EvictionReasonLeaseLost)
+		m.workerPoolCache.Delete(key)
+	}
+}
+
+func (p *BaseWorkerPool) setOwned(owned bool) {
+	var flag int32
+	if !owned {
+		flag = 1
+	}
+	atomic.StoreInt32(&p.unownedFlag, flag)
+}
+
+func (p *BaseWorkerPool) owned() bool {
+	return atomic.LoadInt32(&p.unownedFlag) == 0
+}
@@ -0,0 +1,145 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// memoryLeases is a LeaseBackend shared by managers in the same process. Leases never run out by themselves.
+type memoryLeases struct {
+	lock    sync.Mutex
+	owners  map[string]string
+	failing bool
+}
+
+func newMemoryLeases() *memoryLeases {
+	return &memoryLeases{owners: make(map[string]string)}
+}
+
+func (l *memoryLeases) Acquire(_ context.Context, key string, owner string, _ time.Duration) (bool, error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.failing {
+		return false, errors.New("backend unavailable")
+	}
+	if current, ok := l.owners[key]; ok && current != owner {
+		return false, nil
+	}
+	l.owners[key] = owner
+	return true, nil
+}
+
+func (l *memoryLeases) Release(_ context.Context, key string, owner string) error {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.owners[key] == owner {
+		delete(l.owners, key)
+	}
+	return nil
+}
+
+func (l *memoryLeases) owner(key string) string {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return l.owners[key]
+}
+
+func TestOnlyLeaseHolderSpawnsWorkers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	backend := newMemoryLeases()
+	first := NewWorkerPoolManager(2, time.Hour, time.Hour, WithLeases(Leases{Backend: backend, Owner: "first"}))
+	second := NewWorkerPoolManager(2, time.Hour, time.Hour, WithLeases(Leases{Backend: backend, Owner: "second"}))
+
+	owned, doneUsing := first.GetPool("tenant", 2)
+	executed := make(chan bool)
+	assert.NoError(t, SubmitTask(owned, TaskInfo{}, func() {
+		close(executed)
+	}))
+	<-executed
+	close(doneUsing)
+	assert.Equal(t, 2, first.Snapshot()["tenant"].Workers)
+
+	notOwned, doneUsing := second.GetPool("tenant", 2)
+	assert.ErrorIs(t, SubmitTask(notOwned, TaskInfo{}, func() {}), ErrKeyNotOwned)
+	close(doneUsing)
+	assert.Equal(t, 0, second.Snapshot()["tenant"].Workers)
+	assert.Equal(t, "first", backend.owner("tenant"))
+
+	// Disposing the owner releases its leases
+	first.Dispose()
+	assert.Equal(t, "", backend.owner("tenant"))
+	second.Dispose()
+}
+
+func TestLeaseCoordinatorRetriesAndRenews(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	backend := newMemoryLeases()
+	backend.owners["tenant"] = "other"
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	var errs []string
+	// The renewal timer runs on the real clock, and is too slow to interfere
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithClock(clock), WithLeases(Leases{
+		Backend: backend,
+		Owner:   "self",
+		TTL:     time.Hour,
+		OnError: func(key string, err error) {
+			errs = append(errs, key+": "+err.Error())
+		},
+	}))
+	coordinator := pm.leases
+
+	assert.False(t, coordinator.owns("tenant"))
+	// Acquisition isn't retried until a third of the TTL has passed
+	assert.NoError(t, backend.Release(context.Background(), "tenant", "other"))
+	assert.False(t, coordinator.owns("tenant"))
+	clock.Advance(20 * time.Minute)
+	assert.True(t, coordinator.owns("tenant"))
+
+	_, doneUsing := pm.GetPool("tenant", 1)
+	close(doneUsing)
+
+	// Renewal failures are tolerated until the lease runs out
+	backend.lock.Lock()
+	backend.failing = true
+	backend.lock.Unlock()
+	clock.Advance(30 * time.Minute)
+	coordinator.renew()
+	assert.True(t, pm.cached("tenant"))
+	clock.Advance(31 * time.Minute)
+	coordinator.renew()
+	assert.False(t, pm.cached("tenant"))
+	assert.Equal(t, []string{"tenant: backend unavailable", "tenant: backend unavailable"}, errs)
+
+	pm.Dispose()
+}
+
+func TestLostLeasesEvictPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	backend := newMemoryLeases()
+	evictions := make(chan PoolEviction, 1)
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithLeases(Leases{Backend: backend, Owner: "self"}),
+		WithHooks(Hooks{
+			OnPoolEvicted: func(eviction PoolEviction) {
+				evictions <- eviction
+			},
+		}),
+	)
+	_, doneUsing := pm.GetPool("tenant", 1)
+	close(doneUsing)
+
+	backend.lock.Lock()
+	backend.owners["tenant"] = "other"
+	backend.lock.Unlock()
+	pm.leases.renew()
+	assert.Equal(t, EvictionReasonLeaseLost, (<-evictions).Reason)
+	pm.Dispose()
+}
@@ -0,0 +1,83 @@
+package pool
+
+import (
+	"errors"
+	"sync/atomic"
+	"time"
+)
+
+// ErrLoadShed is returned for submissions rejected by the WithLoadShedder shedder
+var ErrLoadShed = errors.New("submission shed while the service is overloaded")
+
+// ShedLevel is how overloaded the service is, as reported to WithLoadShedder. Each level sheds more than the one
+// before it, going by TaskInfo.Priority.
+type ShedLevel int
+
+// Available shed levels
+const (
+	// ShedNone - the service is healthy, and nothing is shed
+	ShedNone ShedLevel = iota
+	// ShedDelayLow - low priority submissions, with a negative priority, wait for the level to drop before being queued
+	ShedDelayLow
+	// ShedRejectLow - low priority submissions are rejected with ErrLoadShed
+	ShedRejectLow
+	// ShedRejectNormal - submissions without a positive priority are rejected with ErrLoadShed, so only high priority
+	// work is accepted
+	ShedRejectNormal
+)
+
+// How often the shed level is polled
+const loadShedPollInterval = 100 * time.Millisecond
+
+// WithLoadShedder sheds submissions by priority according to the level shedder reports, tying the pools into the
+// service's overall overload protection. At ShedDelayLow low priority submitters block until the level drops, as if
+// the queue were full, and at higher levels submissions are rejected with ErrLoadShed, counted in
+// MetricTasksRejected.
+//
+// The level is polled at most every 100ms, by whichever submission comes along, so shedder may be as expensive as
+// reading a health signal but shouldn't block. Submissions without a TaskInfo have priority 0.
+func WithLoadShedder(shedder func() ShedLevel) Option {
+	return func(o *options) {
+		o.loadShedder = &loadShedder{shedder: shedder}
+	}
+}
+
+// loadShedder caches the level reported by a WithLoadShedder shedder
+type loadShedder struct {
+	// When the level was last polled, in nanoseconds since the epoch, accessed atomically. It's first to keep it
+	// 64-bit aligned.
+	polled  int64
+	level   int32
+	shedder func() ShedLevel
+}
+
+// The current level, polling shedder if it's due
+func (s *loadShedder) current(clock Clock) ShedLevel {
+	now := clock.Now().UnixNano()
+	polled := atomic.LoadInt64(&s.polled)
+	if now-polled >= int64(loadShedPollInterval) && atomic.CompareAndSwapInt64(&s.polled, polled, now) {
+		atomic.StoreInt32(&s.level, int32(s.shedder()))
+	}
+	return ShedLevel(atomic.LoadInt32(&s.level))
+}
+
+// Shed t if the current level calls for it, delaying it until the level drops or rejecting it with ErrLoadShed
+func (p *BaseWorkerPool) shedLoad(t task) error {
+	if p.options == nil || p.options.loadShedder == nil {
+		return nil
+	}
+	priority := t.info.Priority
+	for {
+		level := p.options.loadShedder.current(p.clock)
+		switch {
+		case level >= ShedRejectNormal && priority <= 0, level >= ShedRejectLow && priority < 0:
+			return ErrLoadShed
+		case level >= ShedDelayLow && priority < 0:
+			if !sleep(p.clock, loadShedPollInterval, p.disposed) {
+				return ErrLoadShed
+			}
+		default:
+			return nil
+		}
+	}
+}
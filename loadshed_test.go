@@ -0,0 +1,56 @@
+package pool
+
+import (
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestLoadShedderShedsByPriority(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	var level int32
+	setLevel := func(l ShedLevel) {
+		atomic.StoreInt32(&level, int32(l))
+		// Due for another poll
+		clock.Advance(time.Second)
+	}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithClock(clock), WithQueueCapacity(10),
+		WithLoadShedder(func() ShedLevel {
+			return ShedLevel(atomic.LoadInt32(&level))
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+	submit := func(priority int) error {
+		return SubmitTask(pool, TaskInfo{Priority: priority}, func() {})
+	}
+
+	setLevel(ShedRejectLow)
+	assert.Equal(t, ErrLoadShed, submit(-1))
+	assert.Nil(t, submit(0))
+	setLevel(ShedRejectNormal)
+	assert.Equal(t, ErrLoadShed, submit(0))
+	assert.Nil(t, submit(1))
+
+	setLevel(ShedDelayLow)
+	assert.Nil(t, submit(0))
+	submitted := make(chan error)
+	go func() {
+		submitted <- submit(-1)
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("low priority submission wasn't delayed")
+	case <-time.After(20 * time.Millisecond):
+	}
+	setLevel(ShedNone)
+	assert.Nil(t, <-submitted)
+
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -0,0 +1,104 @@
+package pool
+
+// LogLevel is the severity of a message logged by a manager
+type LogLevel int
+
+// Available log levels
+const (
+	// LogDebug - routine pool lifecycle events, which are frequent with a high cardinality of keys
+	LogDebug LogLevel = iota + 1
+	// LogInfo - notable but expected events
+	LogInfo
+	// LogWarn - tasks misbehaving, e.g. stuck or failing
+	LogWarn
+	// LogError - panics, and pools being quarantined or failing to dispose
+	LogError
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogDebug:
+		return "debug"
+	case LogInfo:
+		return "info"
+	case LogWarn:
+		return "warn"
+	case LogError:
+		return "error"
+	default:
+		return "unknown"
+	}
+}
+
+// Logger receives a manager's log messages, with structured context as alternating keys and values, as in logr and
+// slog. Errors are logged under the "error" key. The poollog package adapts zap, logr and slog loggers to it.
+type Logger interface {
+	Log(level LogLevel, msg string, keysAndValues ...interface{})
+}
+
+// WithLogger logs the manager's pool lifecycle events and task problems to logger. It's built on Hooks, so it may be
+// combined with WithHooks. Queue saturation isn't logged, as it's reported for every affected submission - see
+// Hooks.OnQueueSaturated.
+func WithLogger(logger Logger) Option {
+	return WithHooks(loggingHooks(logger))
+}
+
+func loggingHooks(logger Logger) Hooks {
+	return Hooks{
+		OnPoolCreated: func(key string, pool WorkerPool) {
+			logger.Log(LogDebug, "worker pool created", "key", key)
+		},
+		OnPoolEvicted: func(eviction PoolEviction) {
+			if eviction.Err != nil {
+				logger.Log(LogError, "worker pool disposal failed",
+					"key", eviction.Key, "reason", eviction.Reason.String(), "error", eviction.Err)
+				return
+			}
+			logger.Log(LogDebug, "worker pool evicted",
+				"key", eviction.Key, "reason", eviction.Reason.String(), "age", eviction.Age)
+		},
+		OnWorkerInitError: func(key string, err error) {
+			logger.Log(LogError, "worker init failed", "key", key, "error", err)
+		},
+		OnStuckTask: func(stuck StuckTask) {
+			logger.Log(LogWarn, "task stuck", withTaskContext(stuck.Info, stuck.SubmitSite,
+				"key", stuck.Key, "running", stuck.Running)...)
+		},
+		OnLeakedCheckout: func(leak LeakedCheckout) {
+			keysAndValues := []interface{}{"key", leak.Key, "held", leak.Held, "stack", string(leak.Stack)}
+			if leak.Site != nil {
+				keysAndValues = append(keysAndValues, "checked_out_at", leak.Site.String())
+			}
+			logger.Log(LogWarn, "checkout leaked", keysAndValues...)
+		},
+		OnTaskPanic: func(recovered TaskPanic) {
+			keysAndValues := withTaskContext(recovered.Info, recovered.SubmitSite,
+				"key", recovered.Key, "panic", recovered.Value)
+			if len(recovered.Stack) > 0 {
+				keysAndValues = append(keysAndValues, "stack", string(recovered.Stack))
+			}
+			logger.Log(LogError, "task panicked", keysAndValues...)
+		},
+		OnPoolQuarantined: func(key string, lastPanic TaskPanic) {
+			logger.Log(LogError, "worker pool quarantined", "key", key, "panic", lastPanic.Value)
+		},
+		OnTaskFailure: func(failure TaskFailure) {
+			logger.Log(LogWarn, "task failed", withTaskContext(failure.Info, nil,
+				"key", failure.Key, "attempts", failure.Attempts, "error", failure.Err)...)
+		},
+		OnPoolAutoPaused: func(key string, failureRate float64) {
+			logger.Log(LogWarn, "worker pool auto-paused", "key", key, "failure_rate", failureRate)
+		},
+	}
+}
+
+// Add what's known about a task to a message's context
+func withTaskContext(info TaskInfo, site *CallSite, keysAndValues ...interface{}) []interface{} {
+	if info.Label != "" {
+		keysAndValues = append(keysAndValues, "label", info.Label)
+	}
+	if site != nil {
+		keysAndValues = append(keysAndValues, "submitted_at", site.String())
+	}
+	return append(keysAndValues, info.Baggage...)
+}
@@ -0,0 +1,67 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type logEntry struct {
+	level         LogLevel
+	msg           string
+	keysAndValues []interface{}
+}
+
+type recordingLogger struct {
+	lock    sync.Mutex
+	entries []logEntry
+}
+
+func (l *recordingLogger) Log(level LogLevel, msg string, keysAndValues ...interface{}) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.entries = append(l.entries, logEntry{level: level, msg: msg, keysAndValues: keysAndValues})
+}
+
+func (l *recordingLogger) logged() []logEntry {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	return append([]logEntry(nil), l.entries...)
+}
+
+func TestLoggerReceivesLifecycleEventsAndPanics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	logger := &recordingLogger{}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithLogger(logger), WithPanicRecovery())
+	pool, doneUsing := pm.GetPool("key", 1)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	assert.NoError(t, SubmitTask(pool, TaskInfo{Label: "export"}, func() {
+		defer wg.Done()
+		panic("boom")
+	}))
+	wg.Wait()
+	close(doneUsing)
+	// The panic is logged once it has unwound past the task
+	assert.Eventually(t, func() bool {
+		return len(logger.logged()) == 2
+	}, time.Second, time.Millisecond)
+	pm.Dispose()
+
+	entries := logger.logged()
+	assert.Equal(t, logEntry{level: LogDebug, msg: "worker pool created", keysAndValues: []interface{}{"key", "key"}},
+		entries[0])
+	assert.Equal(t, LogError, entries[1].level)
+	assert.Equal(t, "task panicked", entries[1].msg)
+	assert.Equal(t, []interface{}{"key", "key", "panic", "boom", "label", "export"}, entries[1].keysAndValues)
+}
+
+func TestLogLevelStrings(t *testing.T) {
+	assert.Equal(t, "debug", LogDebug.String())
+	assert.Equal(t, "error", LogError.String())
+	assert.Equal(t, "unknown", LogLevel(0).String())
+}
@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrMemoryBudget is returned when a submission's TaskInfo.MemoryCost doesn't fit in its pool's memory budget, see
+// WithMemoryBudget
+var ErrMemoryBudget = errors.New("submission would exceed the pool's memory budget")
+
+// MemoryBudget configures the per-key memory budget enabled by WithMemoryBudget
+type MemoryBudget struct {
+	// Bytes is the most memory each pool's unfinished tasks may hold at once, by their TaskInfo.MemoryCost
+	Bytes int64
+	// Reject makes submissions which don't fit fail with ErrMemoryBudget, instead of blocking until earlier tasks
+	// finish and free up room
+	Reject bool
+}
+
+// WithMemoryBudget caps how much memory each pool's unfinished tasks - queued or executing - may hold at once, for
+// tasks which carry large payloads such as rendered message bodies. Tasks declare what they hold with
+// TaskInfo.MemoryCost, and tasks without one aren't counted.
+//
+// A submission which doesn't fit blocks until enough earlier tasks finish, or is rejected with budget.Reject. A task
+// costing more than the whole budget is let in once the pool holds nothing else, so it can't wait forever.
+func WithMemoryBudget(budget MemoryBudget) Option {
+	return func(o *options) {
+		if budget.Bytes > 0 {
+			o.memoryBudget = &budget
+		}
+	}
+}
+
+// memoryBudget tracks the memory held by a pool's unfinished tasks
+type memoryBudget struct {
+	MemoryBudget
+	lock  *sync.Mutex
+	freed *sync.Cond
+	held  int64
+	// Closed once the pool is disposed, so blocked submitters stop waiting for room
+	disposed <-chan bool
+}
+
+func newMemoryBudget(budget MemoryBudget, disposed <-chan bool) *memoryBudget {
+	lock := &sync.Mutex{}
+	return &memoryBudget{MemoryBudget: budget, lock: lock, freed: sync.NewCond(lock), disposed: disposed}
+}
+
+// Reserve cost bytes for a task being submitted, waiting for room unless submissions which don't fit are rejected
+func (b *memoryBudget) acquire(cost int64) error {
+	if b == nil || cost <= 0 {
+		return nil
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	for b.held > 0 && b.held+cost > b.Bytes && !isClosed(b.disposed) {
+		if b.Reject {
+			return ErrMemoryBudget
+		}
+		b.freed.Wait()
+	}
+	b.held += cost
+	return nil
+}
+
+// Free the cost bytes held by a task which has finished
+func (b *memoryBudget) release(cost int64) {
+	if b == nil || cost <= 0 {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.held -= cost
+	b.freed.Broadcast()
+}
+
+// Wake submitters waiting for room once the pool is disposed
+func (b *memoryBudget) close() {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.freed.Broadcast()
+}
+
+func (b *memoryBudget) inUse() int64 {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.held
+}
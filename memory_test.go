@@ -0,0 +1,91 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestMemoryBudgetRejectsSubmissionsWhichDontFit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(
+		1, time.Hour, time.Hour, WithQueueCapacity(10),
+		WithMemoryBudget(MemoryBudget{Bytes: 100, Reject: true}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+	release := make(chan bool)
+	block := func() {
+		<-release
+	}
+
+	assert.Nil(t, SubmitTask(pool, TaskInfo{MemoryCost: 60}, block))
+	assert.Equal(t, ErrMemoryBudget, SubmitTask(pool, TaskInfo{MemoryCost: 60}, block))
+	assert.Nil(t, SubmitTask(pool, TaskInfo{MemoryCost: 40}, block))
+	// Tasks without a cost aren't counted
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, block))
+	assert.Equal(t, int64(100), pm.Snapshot()["key"].MemoryInUse)
+	assert.Contains(t, pm.Config().Features, "memory budget")
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].MemoryInUse == 0
+	}, time.Second, time.Millisecond)
+	// A task costing more than the whole budget gets in once nothing else is held
+	assert.Nil(t, SubmitTask(pool, TaskInfo{MemoryCost: 500}, block))
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestMemoryBudgetBlocksSubmissionsUntilThereIsRoom(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMemoryBudget(MemoryBudget{Bytes: 100}))
+	pool, doneUsing := pm.GetPool("key", 1)
+	release := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{MemoryCost: 80}, func() {
+		<-release
+	}))
+
+	submitted := make(chan error)
+	go func() {
+		submitted <- SubmitTask(pool, TaskInfo{MemoryCost: 80}, func() {})
+	}()
+	select {
+	case <-submitted:
+		t.Fatal("submitted beyond the memory budget")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(release)
+	assert.Nil(t, <-submitted)
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestMemoryBudgetReleasesRejectedSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPoolWithOptions(1, WithMemoryBudget(MemoryBudget{Bytes: 100}))
+	base := p.(*BaseWorkerPool)
+
+	// Without workers, the first task fills the queue, and the second waits for room until the pool is disposed
+	assert.Nil(t, SubmitTask(p, TaskInfo{MemoryCost: 10}, func() {}))
+	rejected := make(chan error)
+	go func() {
+		rejected <- SubmitTask(p, TaskInfo{MemoryCost: 20}, func() {})
+	}()
+	assert.Eventually(t, func() bool {
+		return base.memory.inUse() == 30
+	}, time.Second, time.Millisecond)
+	p.Dispose()
+	select {
+	case err := <-rejected:
+		assert.Equal(t, ErrDisposed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting submission to be rejected")
+	}
+	assert.Equal(t, int64(10), base.memory.inUse())
+}
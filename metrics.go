@@ -0,0 +1,163 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// MetricsCollector receives the metrics of a manager and its pools, to be bridged to a metrics backend. Every metric
+// is labeled with the key of the pool it's for, and the names are the Metric constants.
+//
+// Collectors are called from hot paths, including every task submission and execution, so they should be cheap and
+// must be safe for concurrent use.
+type MetricsCollector interface {
+	// Count adds delta to a counter
+	Count(name string, key string, delta int64)
+	// Gauge sets a gauge's current value
+	Gauge(name string, key string, value float64)
+	// Histogram records a value into a distribution. Durations are recorded in seconds.
+	Histogram(name string, key string, value float64)
+}
+
+// Names of the metrics reported to a MetricsCollector
+const (
+	// MetricTasksSubmitted counts tasks accepted into a pool's queue
+	MetricTasksSubmitted = "tasks_submitted"
+	// MetricTasksRejected counts submissions, including streams, which a pool rejected for any reason - because it's
+	// disposed, frozen or quarantined, its key is blocked or leased to another instance, it has no workers with
+	// ZeroSendSizeReject, an interceptor vetoed them, load shedding turned them away, they'd exceed its memory budget
+	// or their checkout's WithCheckoutLimit
+	MetricTasksRejected = "tasks_rejected"
+	// MetricTasksCompleted counts tasks which finished executing without panicking
+	MetricTasksCompleted = "tasks_completed"
+	// MetricTaskPanics counts task panics recovered with WithPanicRecovery
+	MetricTaskPanics = "task_panics"
+	// MetricTaskFailures counts failed attempts by tasks submitted with SubmitWithRetry
+	MetricTaskFailures = "task_failures"
+	// MetricThrottleStoreErrors counts task starts paced locally because the ThrottleStore failed, see
+	// WithThrottleStore
+	MetricThrottleStoreErrors = "throttle_store_errors"
+	// MetricStuckTasks counts tasks reported by the watchdog, see WithWatchdog
+	MetricStuckTasks = "stuck_tasks"
+	// MetricQueueWait is a histogram of how long tasks waited in the queue before being picked up by a worker
+	MetricQueueWait = "queue_wait_seconds"
+	// MetricExecution is a histogram of how long tasks took to execute
+	MetricExecution = "execution_seconds"
+	// MetricQueueDepth is a gauge of how many tasks are waiting in a pool's queue
+	MetricQueueDepth = "queue_depth"
+	// MetricThroughput is a gauge of how many tasks a pool has completed per second over the last minute
+	MetricThroughput = "throughput"
+	// MetricWorkers is a gauge of how many workers a pool has spawned
+	MetricWorkers = "workers"
+	// MetricStreams is a gauge of how many streams a pool is running, see SubmitStream
+	MetricStreams = "streams"
+	// MetricPoolsCreated counts pools built by the manager
+	MetricPoolsCreated = "pools_created"
+	// MetricPoolsReused counts cached pools handed out again by the manager
+	MetricPoolsReused = "pools_reused"
+	// MetricPoolsEvicted counts pools evicted from the manager and disposed
+	MetricPoolsEvicted = "pools_evicted"
+	// MetricStuckDisposals counts evicted pools still in use after the disposal timeout, see WithDisposalTimeout
+	MetricStuckDisposals = "stuck_disposals"
+	// MetricDisposeErrors counts evicted pools whose disposal failed, see ErrorDisposer and PoolEviction.Err
+	MetricDisposeErrors = "dispose_errors"
+	// MetricLeakedCheckouts counts checkouts still unreleased after the threshold set with WithLeakDetection
+	MetricLeakedCheckouts = "leaked_checkouts"
+	// MetricCacheHits, MetricCacheMisses and MetricCacheEvictions count the lookups and evictions of the manager's
+	// cache, and MetricCachedPools is a gauge of the pools it holds, see CacheStats. They're reported with
+	// MetricKeyManager.
+	MetricCacheHits      = "cache_hits"
+	MetricCacheMisses    = "cache_misses"
+	MetricCacheEvictions = "cache_evictions"
+	MetricCachedPools    = "cached_pools"
+)
+
+// WithMetrics reports the manager's and its pools' metrics to collector
+func WithMetrics(collector MetricsCollector) Option {
+	return func(o *options) {
+		o.metrics = collector
+	}
+}
+
+// MetricKeyOther is the key reported for pools whose own key is over the WithMetricKeyLimit
+const MetricKeyOther = "other"
+
+// MetricKeyManager is the key reported for metrics of the manager as a whole, rather than of any one pool. It's never
+// limited by WithMetricKeyLimit.
+const MetricKeyManager = "manager"
+
+// WithMetricKeyLimit caps how many distinct keys metrics are reported with, so a manager with millions of keys can't
+// create millions of series in the metrics backend. Zero reports every metric with MetricKeyOther, leaving key out
+// of metrics altogether.
+//
+// Keys are labeled on a first come, first served basis, and a key keeps its label until its pool is evicted, so busy
+// long-lived pools hold on to theirs. Metrics for keys over the limit are reported with MetricKeyOther instead, and as
+// every such pool reports to the same series, their gauges are only meaningful when aggregated by the backend.
+func WithMetricKeyLimit(limit int) Option {
+	return func(o *options) {
+		o.metricKeys = newMetricKeyLimiter(limit)
+	}
+}
+
+// metricKeyLimiter hands out metric labels to at most limit keys
+type metricKeyLimiter struct {
+	lock    *sync.RWMutex
+	limit   int
+	labeled map[string]bool
+}
+
+func newMetricKeyLimiter(limit int) *metricKeyLimiter {
+	return &metricKeyLimiter{lock: &sync.RWMutex{}, limit: limit, labeled: make(map[string]bool)}
+}
+
+// The key to report key's metrics with
+func (l *metricKeyLimiter) label(key string) string {
+	if l == nil {
+		return key
+	}
+	l.lock.RLock()
+	labeled := l.labeled[key]
+	l.lock.RUnlock()
+	if labeled {
+		return key
+	}
+
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	if l.labeled[key] {
+		return key
+	}
+	if len(l.labeled) >= l.limit {
+		return MetricKeyOther
+	}
+	l.labeled[key] = true
+	return key
+}
+
+// Free up key's label once its pool is gone
+func (l *metricKeyLimiter) release(key string) {
+	if l == nil {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	delete(l.labeled, key)
+}
+
+func (o *options) count(name string, key string, delta int64) {
+	if o != nil && o.metrics != nil {
+		o.metrics.Count(name, o.metricKeys.label(key), delta)
+	}
+}
+
+func (o *options) gauge(name string, key string, value float64) {
+	if o != nil && o.metrics != nil {
+		o.metrics.Gauge(name, o.metricKeys.label(key), value)
+	}
+}
+
+func (o *options) observe(name string, key string, d time.Duration) {
+	if o != nil && o.metrics != nil {
+		o.metrics.Histogram(name, o.metricKeys.label(key), d.Seconds())
+	}
+}
@@ -0,0 +1,187 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+// recordingCollector keeps every metric it's given, by name and key
+type recordingCollector struct {
+	lock       sync.Mutex
+	counts     map[string]int64
+	gauges     map[string]float64
+	histograms map[string][]float64
+}
+
+func newRecordingCollector() *recordingCollector {
+	return &recordingCollector{
+		counts:     make(map[string]int64),
+		gauges:     make(map[string]float64),
+		histograms: make(map[string][]float64),
+	}
+}
+
+func (c *recordingCollector) Count(name string, key string, delta int64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.counts[name+"/"+key] += delta
+}
+
+func (c *recordingCollector) Gauge(name string, key string, value float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.gauges[name+"/"+key] = value
+}
+
+func (c *recordingCollector) Histogram(name string, key string, value float64) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.histograms[name+"/"+key] = append(c.histograms[name+"/"+key], value)
+}
+
+func (c *recordingCollector) count(name string, key string) int64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.counts[name+"/"+key]
+}
+
+func TestMetricsInstrumentPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour, WithMetrics(collector), WithPanicRecovery())
+	pool, doneUsing := pm.GetPool("key", 2)
+
+	var wg sync.WaitGroup
+	wg.Add(4)
+	for i := 0; i < 3; i++ {
+		pool.Submit(wg.Done)
+	}
+	_ = SubmitWithRetry(pool, TaskInfo{}, 0, func() error {
+		defer wg.Done()
+		return errors.New("downstream unavailable")
+	})
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		return collector.count(MetricTasksCompleted, "key") == 4
+	}, time.Second, time.Millisecond)
+
+	pm.Block("key")
+	pool.Submit(func() {})
+	close(doneUsing)
+	pm.Dispose()
+	assert.Eventually(t, func() bool {
+		return collector.count(MetricPoolsEvicted, "key") == 1
+	}, time.Second, time.Millisecond)
+
+	collector.lock.Lock()
+	defer collector.lock.Unlock()
+	assert.Equal(t, map[string]int64{
+		"pools_created/key":    1,
+		"pools_evicted/key":    1,
+		"tasks_submitted/key":  4,
+		"tasks_rejected/key":   1,
+		"tasks_completed/key":  4,
+		"task_failures/key":    1,
+		"cache_misses/manager": 1,
+	}, collector.counts)
+	assert.Equal(t, 2.0, collector.gauges["workers/key"])
+	assert.Positive(t, collector.gauges["throughput/key"])
+	assert.Len(t, collector.histograms["queue_wait_seconds/key"], 4)
+	assert.Len(t, collector.histograms["execution_seconds/key"], 4)
+}
+
+func TestMetricsCountDisposeErrors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMetrics(collector))
+	_, doneUsing, _ := pm.GetPoolWithFactory("failing", 1, func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &failingDisposePool{WorkerPool: basePool}, nil
+	})
+	close(doneUsing)
+	_, doneUsing = pm.GetPool("succeeding", 1)
+	close(doneUsing)
+	pm.Dispose()
+	assert.Eventually(t, func() bool {
+		return collector.count(MetricPoolsEvicted, "failing") == 1 && collector.count(MetricPoolsEvicted, "succeeding") == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int64(1), collector.count(MetricDisposeErrors, "failing"))
+	assert.Equal(t, int64(0), collector.count(MetricDisposeErrors, "succeeding"))
+}
+
+func TestMetricKeyLimitBucketsExcessKeys(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMetrics(collector), WithMetricKeyLimit(2))
+	for _, key := range []string{"a", "b", "c", "d", "a"} {
+		_, doneUsing := pm.GetPool(key, 1)
+		close(doneUsing)
+	}
+	assert.Equal(t, int64(1), collector.count(MetricPoolsCreated, "a"))
+	assert.Equal(t, int64(1), collector.count(MetricPoolsReused, "a"))
+	assert.Equal(t, int64(1), collector.count(MetricPoolsCreated, "b"))
+	assert.Equal(t, int64(0), collector.count(MetricPoolsCreated, "c"))
+	assert.Equal(t, int64(2), collector.count(MetricPoolsCreated, MetricKeyOther))
+
+	// Evicting a pool frees up its key's label for the next key
+	events := pm.Subscribe(EventPoolEvicted)
+	pm.workerPoolCache.Delete("b")
+	assert.Equal(t, "b", (<-events).Key)
+	_, doneUsing := pm.GetPool("e", 1)
+	close(doneUsing)
+	assert.Equal(t, int64(1), collector.count(MetricPoolsCreated, "e"))
+	pm.Dispose()
+}
+
+func TestMetricKeyLimitOfZeroOmitsKeys(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMetrics(collector), WithMetricKeyLimit(0))
+	for _, key := range []string{"a", "b"} {
+		_, doneUsing := pm.GetPool(key, 1)
+		close(doneUsing)
+	}
+	assert.Equal(t, int64(2), collector.count(MetricPoolsCreated, MetricKeyOther))
+	pm.Dispose()
+}
+
+func TestCacheMetricsAreSampled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	collector := newRecordingCollector()
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	pm := NewWorkerPoolManager(1, time.Hour, 10*time.Hour, WithMetrics(collector), WithClock(clock))
+	_, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Equal(t, int64(1), collector.count(MetricCacheMisses, MetricKeyManager))
+	assert.Equal(t, int64(0), collector.count(MetricCacheHits, MetricKeyManager))
+
+	// Not sampled again within the interval
+	_, doneUsing = pm.GetPool("key", 1)
+	close(doneUsing)
+	_, doneUsing = pm.GetPool("other", 1)
+	close(doneUsing)
+	assert.Equal(t, int64(0), collector.count(MetricCacheHits, MetricKeyManager))
+
+	clock.Advance(cacheSampleInterval)
+	_, doneUsing = pm.GetPool("key", 1)
+	close(doneUsing)
+	assert.Equal(t, int64(2), collector.count(MetricCacheHits, MetricKeyManager))
+	assert.Equal(t, int64(2), collector.count(MetricCacheMisses, MetricKeyManager))
+	collector.lock.Lock()
+	assert.Equal(t, 2.0, collector.gauges[MetricCachedPools+"/"+MetricKeyManager])
+	collector.lock.Unlock()
+
+	stats := pm.CacheStats()
+	assert.Equal(t, CacheStats{Pools: 2, Hits: 2, Misses: 2, Insertions: 2}, stats)
+	pm.Dispose()
+}
@@ -0,0 +1,20 @@
+package pool
+
+// How many tasks a multiplexing worker executes from a key's queue before moving on to the next key's
+const multiplexedTasksPerTurn = 32
+
+// WithMultiplexedDispatch is an alternative to WithSharedFleet for workloads of sub-microsecond tasks, where handing
+// each task to a parked worker costs more than executing it. A single goroutine per P (see runtime.GOMAXPROCS)
+// multiplexes the queues of every key, working through several of a key's queued tasks per turn before moving on to
+// the next key, so busy goroutines rarely park and the scheduler wakes far fewer of them.
+//
+// Each key's in-flight tasks are capped by the pool size as with WithSharedFleet, and the same caveats apply. Since a
+// key's turns are spread over a handful of goroutines, a slow task also holds up the other keys queued behind it on
+// its goroutine, so this only suits tasks which don't block. See BenchmarkTinyTasks to compare the dispatch models
+// on a given machine.
+func WithMultiplexedDispatch() Option {
+	return func(o *options) {
+		o.multiplexed = true
+		o.fleetWorkers = 0
+	}
+}
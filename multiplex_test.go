@@ -0,0 +1,87 @@
+package pool
+
+import (
+	"fmt"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestMultiplexedDispatchRunsEveryKeysTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMultiplexedDispatch(), WithQueueCapacity(100))
+	before := runtime.NumGoroutine()
+	var peak, running int32
+	var wg sync.WaitGroup
+	for i := 0; i < 100; i++ {
+		pool, doneUsing := pm.GetPool(fmt.Sprint(i), 1)
+		wg.Add(100)
+		for j := 0; j < 100; j++ {
+			pool.Submit(func() {
+				defer wg.Done()
+				now := atomic.AddInt32(&running, 1)
+				if now > atomic.LoadInt32(&peak) {
+					atomic.StoreInt32(&peak, now)
+				}
+				atomic.AddInt32(&running, -1)
+			})
+		}
+		close(doneUsing)
+	}
+	wg.Wait()
+
+	// A goroutine per P serves every key
+	assert.LessOrEqual(t, int(atomic.LoadInt32(&peak)), runtime.GOMAXPROCS(0))
+	assert.Eventually(t, func() bool {
+		return runtime.NumGoroutine()-before < 10
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, uint64(100), pm.Snapshot()["42"].Completed)
+	assert.Contains(t, pm.Config().Features, "multiplexed dispatch")
+	pm.Dispose()
+}
+
+func TestMultiplexedDispatchWorksThroughSeveralTasksPerTurn(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMultiplexedDispatch(), WithQueueCapacity(100))
+	busy, doneUsingBusy := pm.GetPool("busy", 1)
+	quiet, doneUsingQuiet := pm.GetPool("quiet", 1)
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	record := func(label string) Work {
+		return func() {
+			defer wg.Done()
+			lock.Lock()
+			order = append(order, label)
+			lock.Unlock()
+		}
+	}
+	gate := make(chan bool)
+	wg.Add(1 + multiplexedTasksPerTurn + 1)
+	busy.Submit(func() {
+		<-gate
+		record("busy")()
+	})
+	for i := 0; i < multiplexedTasksPerTurn; i++ {
+		busy.Submit(record("busy"))
+	}
+	quiet.Submit(record("quiet"))
+	close(gate)
+	wg.Wait()
+
+	// With a single P, the quiet key waits for the busy key's first turn, and then gets the next one
+	if runtime.GOMAXPROCS(0) == 1 {
+		assert.Equal(t, "quiet", order[multiplexedTasksPerTurn])
+	}
+	close(doneUsingBusy)
+	close(doneUsingQuiet)
+	pm.Dispose()
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"context"
+	"runtime/pprof"
+)
+
+// Profiler labels set on the workers of pools with a name or description, see WithName
+const (
+	ProfileLabelManager     = "worker_pool_manager"
+	ProfileLabelKey         = "worker_pool_key"
+	ProfileLabelDescription = "worker_pool"
+)
+
+// WithName names the manager, e.g. "push sends", to tell it apart from other managers in the same process. The name
+// is included in PoolSnapshot, and set as a profiler label on the workers of every pool the manager builds, so CPU
+// profiles can be broken down by manager and key.
+func WithName(name string) Option {
+	return func(o *options) {
+		o.name = name
+	}
+}
+
+// WithPoolDescription gives each pool a human-readable description built from its key, e.g. "sends for app 42". Like
+// the manager's name, it's included in PoolSnapshot and set as a profiler label on the pool's workers.
+func WithPoolDescription(describe func(key string) string) Option {
+	return func(o *options) {
+		o.describe = describe
+	}
+}
+
+// Name returns the name the manager was given with WithName
+func (m *WorkerPoolManager) Name() string {
+	return m.options.name
+}
+
+// Profiler labels for this pool's workers, or nil if it's neither named nor described
+func (p *BaseWorkerPool) profileLabels() []string {
+	if p.options == nil || p.options.name == "" && p.description == "" {
+		return nil
+	}
+	labels := []string{ProfileLabelKey, p.key}
+	if p.options.name != "" {
+		labels = append(labels, ProfileLabelManager, p.options.name)
+	}
+	if p.description != "" {
+		labels = append(labels, ProfileLabelDescription, p.description)
+	}
+	return labels
+}
+
+// Run f with the pool's profiler labels set on the calling goroutine
+func (p *BaseWorkerPool) withProfileLabels(f func()) {
+	labels := p.profileLabels()
+	if labels == nil {
+		f()
+		return
+	}
+	pprof.Do(context.Background(), pprof.Labels(labels...), func(context.Context) {
+		f()
+	})
+}
@@ -0,0 +1,47 @@
+package pool
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestNamedManagersDescribePools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(
+		1, time.Second, time.Hour,
+		WithName("push sends"),
+		WithPoolDescription(func(key string) string {
+			return fmt.Sprintf("sends for app %s", key)
+		}),
+	)
+	assert.Equal(t, "push sends", pm.Name())
+
+	pool, doneUsing := pm.GetPool("42", 1)
+	done := make(chan bool)
+	pool.Submit(func() {
+		close(done)
+	})
+	<-done
+
+	snapshot := pm.Snapshot()["42"]
+	assert.Equal(t, "push sends", snapshot.Manager)
+	assert.Equal(t, "sends for app 42", snapshot.Description)
+	assert.Equal(t, []string{
+		ProfileLabelKey, "42", ProfileLabelManager, "push sends", ProfileLabelDescription, "sends for app 42",
+	}, pool.(*BaseWorkerPool).profileLabels())
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestUnnamedPoolsHaveNoProfileLabels(t *testing.T) {
+	p, _ := NewWorkerPoolWithOptions(1)
+	assert.Nil(t, p.(*BaseWorkerPool).profileLabels())
+	standalone, _ := NewWorkerPool(1)
+	assert.Nil(t, standalone.(*BaseWorkerPool).profileLabels())
+}
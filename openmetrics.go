@@ -0,0 +1,186 @@
+package pool
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// OpenMetricsContentType is the Content-Type to serve WriteMetrics' output with
+const OpenMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// Prepended to the Metric constants to name the metrics WriteMetrics writes
+const openMetricsPrefix = "worker_pools_"
+
+// openMetricsFamily is a metric WriteMetrics reads from each pool's snapshot
+type openMetricsFamily struct {
+	name  string
+	kind  string
+	help  string
+	value func(s PoolSnapshot) float64
+}
+
+var poolMetricFamilies = []openMetricsFamily{
+	{MetricWorkers, "gauge", "Workers the pool is running", func(s PoolSnapshot) float64 {
+		return float64(s.Workers)
+	}},
+	{MetricStreams, "gauge", "Streams the pool is running", func(s PoolSnapshot) float64 {
+		return float64(s.Streams)
+	}},
+	{"executing", "gauge", "Tasks executing right now", func(s PoolSnapshot) float64 {
+		return float64(s.Executing)
+	}},
+	{MetricQueueDepth, "gauge", "Tasks waiting for a worker", func(s PoolSnapshot) float64 {
+		return float64(s.QueueDepth)
+	}},
+	{"checkouts", "gauge", "Callers which have the pool checked out", func(s PoolSnapshot) float64 {
+		return float64(s.Reservations)
+	}},
+	{"memory_in_use_bytes", "gauge", "Memory held by unfinished tasks", func(s PoolSnapshot) float64 {
+		return float64(s.MemoryInUse)
+	}},
+	{MetricThroughput, "gauge", "Tasks completed per second over the last minute", func(s PoolSnapshot) float64 {
+		return s.ThroughputLastMinute
+	}},
+	{MetricTasksCompleted, "counter", "Tasks which finished executing", func(s PoolSnapshot) float64 {
+		return float64(s.Completed)
+	}},
+	{MetricStuckTasks, "counter", "Tasks reported stuck by the watchdog", func(s PoolSnapshot) float64 {
+		return float64(s.StuckTasks)
+	}},
+	{MetricTaskPanics, "counter", "Task panics recovered", func(s PoolSnapshot) float64 {
+		return float64(s.Panics)
+	}},
+	{"paused", "gauge", "Whether the pool's workers have stopped starting tasks", func(s PoolSnapshot) float64 {
+		return boolMetric(s.Paused)
+	}},
+	{"quarantined", "gauge", "Whether the pool is rejecting submissions", func(s PoolSnapshot) float64 {
+		return boolMetric(s.Quarantined)
+	}},
+}
+
+var poolLatencyFamilies = []struct {
+	name    string
+	help    string
+	latency func(s PoolSnapshot) LatencyPercentiles
+}{
+	{MetricExecution, "How long tasks took to execute", func(s PoolSnapshot) LatencyPercentiles {
+		return s.ExecutionLatency
+	}},
+	{MetricQueueWait, "How long tasks waited for a worker", func(s PoolSnapshot) LatencyPercentiles {
+		return s.QueueWaitLatency
+	}},
+}
+
+// WriteMetrics writes the metrics of the manager and its cached pools to w in the OpenMetrics text format, which
+// Prometheus scrapes, so services without a metrics library can still expose them by mounting a handler:
+//
+//	http.HandleFunc("/metrics", func(w http.ResponseWriter, r *http.Request) {
+//		w.Header().Set("Content-Type", pool.OpenMetricsContentType)
+//		_ = poolManager.WriteMetrics(w)
+//	})
+//
+// Metrics are named after the Metric constants, prefixed with worker_pools_, and labeled with their pool's key and
+// the manager's name, if it has one - see WithName. They're read from Snapshot and CacheStats as they're written, so
+// they don't need WithMetrics. WithMetricKeyLimit still applies: pools over the limit are summed into the series of
+// MetricKeyOther, which has no latency quantiles.
+func (m *WorkerPoolManager) WriteMetrics(w io.Writer) error {
+	// Each label's snapshots, most often just its key's
+	groups := make(map[string][]PoolSnapshot)
+	for key, snapshot := range m.Snapshot() {
+		label := m.options.metricKeys.label(key)
+		groups[label] = append(groups[label], snapshot)
+	}
+	labels := make([]string, 0, len(groups))
+	for label := range groups {
+		labels = append(labels, label)
+	}
+	sort.Strings(labels)
+
+	out := bufio.NewWriter(w)
+	for _, family := range poolMetricFamilies {
+		name := writeMetricFamily(out, family.name, family.kind, family.help)
+		for _, label := range labels {
+			sum := 0.0
+			for _, snapshot := range groups[label] {
+				sum += family.value(snapshot)
+			}
+			m.writeMetric(out, name, label, "", sum)
+		}
+	}
+	for _, family := range poolLatencyFamilies {
+		name := writeMetricFamily(out, family.name, "summary", family.help)
+		for _, label := range labels {
+			if label == MetricKeyOther || len(groups[label]) != 1 {
+				continue
+			}
+			latency := family.latency(groups[label][0])
+			for _, quantile := range []struct {
+				label string
+				value float64
+			}{{"0.5", latency.P50.Seconds()}, {"0.95", latency.P95.Seconds()}, {"0.99", latency.P99.Seconds()}} {
+				m.writeMetric(out, name, label, quantile.label, quantile.value)
+			}
+		}
+	}
+
+	stats := m.CacheStats()
+	m.writeMetric(out, writeMetricFamily(out, MetricCachedPools, "gauge", "Pools cached by the manager"), "", "",
+		float64(stats.Pools))
+	for _, counter := range []struct {
+		name  string
+		help  string
+		value uint64
+	}{
+		{MetricCacheHits, "Cache lookups which found a pool", stats.Hits},
+		{MetricCacheMisses, "Cache lookups which didn't find a pool", stats.Misses},
+		{MetricCacheEvictions, "Pools removed from the cache", stats.Evictions},
+	} {
+		m.writeMetric(out, writeMetricFamily(out, counter.name, "counter", counter.help), "", "", float64(counter.value))
+	}
+
+	fmt.Fprint(out, "# EOF\n")
+	return out.Flush()
+}
+
+// Write a family's metadata, returning the name of its samples, which counters suffix with _total
+func writeMetricFamily(out *bufio.Writer, name string, kind string, help string) string {
+	name = openMetricsPrefix + name
+	fmt.Fprintf(out, "# TYPE %s %s\n# HELP %s %s.\n", name, kind, name, help)
+	if kind == "counter" {
+		return name + "_total"
+	}
+	return name
+}
+
+// Write a sample, labeled with key and quantile unless they're empty. The bufio.Writer holds on to any write error.
+func (m *WorkerPoolManager) writeMetric(out *bufio.Writer, name string, key string, quantile string, value float64) {
+	var labels []string
+	if m.options.name != "" {
+		labels = append(labels, `manager="`+openMetricsEscaper.Replace(m.options.name)+`"`)
+	}
+	if key != "" {
+		labels = append(labels, `key="`+openMetricsEscaper.Replace(key)+`"`)
+	}
+	if quantile != "" {
+		labels = append(labels, `quantile="`+quantile+`"`)
+	}
+	fmt.Fprint(out, name)
+	if len(labels) > 0 {
+		fmt.Fprint(out, "{"+strings.Join(labels, ",")+"}")
+	}
+	fmt.Fprint(out, " "+strconv.FormatFloat(value, 'g', -1, 64)+"\n")
+}
+
+// Escapes label values as OpenMetrics requires
+var openMetricsEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func boolMetric(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
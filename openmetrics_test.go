@@ -0,0 +1,68 @@
+package pool
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestWriteMetricsWritesOpenMetrics(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithName("sends"))
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool(`app "42"`, 2)
+	done := make(chan bool)
+	for i := 0; i < 3; i++ {
+		pool.Submit(func() {
+			done <- true
+		})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected the tasks to execute")
+		}
+	}
+	close(doneUsing)
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()[`app "42"`].Completed == 3
+	}, time.Second, time.Millisecond)
+
+	var out bytes.Buffer
+	assert.Nil(t, pm.WriteMetrics(&out))
+	text := out.String()
+	assert.Contains(t, text, "# TYPE worker_pools_workers gauge\n"+
+		"# HELP worker_pools_workers Workers the pool is running.\n"+
+		`worker_pools_workers{manager="sends",key="app \"42\""} 2`+"\n")
+	assert.Contains(t, text, "# TYPE worker_pools_tasks_completed counter\n")
+	assert.Contains(t, text, `worker_pools_tasks_completed_total{manager="sends",key="app \"42\""} 3`+"\n")
+	assert.Contains(t, text, `worker_pools_execution_seconds{manager="sends",key="app \"42\"",quantile="0.99"} `)
+	assert.Contains(t, text, `worker_pools_cached_pools{manager="sends"} 1`+"\n")
+	assert.Contains(t, text, `worker_pools_cache_misses_total{manager="sends"} `)
+	assert.True(t, strings.HasSuffix(text, "# EOF\n"))
+}
+
+func TestWriteMetricsSumsKeysOverTheLimit(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithMetricKeyLimit(1))
+	defer pm.Dispose()
+	for _, key := range []string{"a", "b", "c"} {
+		_, doneUsing := pm.GetPool(key, 2)
+		close(doneUsing)
+	}
+
+	var out bytes.Buffer
+	assert.Nil(t, pm.WriteMetrics(&out))
+	text := out.String()
+	// Whichever key is labeled first keeps its own series, and the other two share one
+	assert.Contains(t, text, `worker_pools_workers{key="other"} 4`+"\n")
+	assert.NotContains(t, text, `key="other",quantile`)
+	assert.Equal(t, 2, strings.Count(text, "worker_pools_workers{"))
+}
@@ -0,0 +1,102 @@
+package pool
+
+import (
+	"context"
+	"time"
+)
+
+// Option configures optional behavior of a WorkerPoolManager, and of the pools it builds.
+//
+// Options are passed to NewWorkerPoolManager, and are applied to every pool the manager builds - including pools
+// built by custom Factory functions - unless that pool was built with its own options via NewWorkerPoolWithOptions.
+type Option func(*options)
+
+// options holds the configuration built up from a set of Option
+type options struct {
+	throttleStarts   int
+	throttleInterval time.Duration
+	queueCapacity    int
+	metrics          MetricsCollector
+	metricKeys       *metricKeyLimiter
+	name             string
+	describe         func(key string) string
+	debug            bool
+	panicStacks      bool
+	condvarDispatch  bool
+	rankTasks        func(t task) int64
+	subQueues        map[string]int
+	queueOrder       string
+	clock            Clock
+	hooks            []Hooks
+	scheduler        scheduler
+	workerInit       func() (interface{}, error)
+	workerTeardown   func(interface{})
+	workerLoop       func(loop func())
+	labelLimits      map[string]int
+	watchdog         *Watchdog
+	recoverPanics    bool
+	quarantine       *Quarantine
+	disposal         *Disposal
+	disposalTimeout  time.Duration
+	leakThreshold    time.Duration
+	freezePolicy     FreezePolicy
+	leases           *Leases
+	fleetWorkers     int
+	multiplexed      bool
+	checkoutLimit    int
+	durable          *durableTasks
+	memoryBudget     *MemoryBudget
+	sampling         *TaskSampling
+	reservedLabel    string
+	reservedFraction float64
+	fairShare        *FairShare
+	// The manager's fleet, built from fleetWorkers or multiplexed
+	fleet *fleet
+	// The dependencies between the manager's pools
+	dependencies *dependencyGraph
+	// Wraps the factories pools are built with, outermost first
+	factoryMiddleware []func(next Factory) Factory
+	// Consulted in order, see WithSubmitInterceptor
+	interceptors []func(key string, t TaskInfo) error
+	// Shared by every pool, so the level is polled once for all of them
+	loadShedder *loadShedder
+	// Sizes each key's pool, see WithSizeResolver
+	sizeResolver func(key string) int
+	// Copies values from the contexts of tasks, see WithBaggage
+	baggage func(ctx context.Context) []interface{}
+	// Turns checkouts' hints into workers, see WithGrowthPolicy
+	growthPolicy GrowthPolicy
+	// What checkouts with a sendSize of zero do, see WithZeroSendSize
+	zeroSendSize ZeroSendSize
+	// A Child's overrides of the manager's configuration
+	poolSize            int
+	stalePoolExpiration time.Duration
+	maxPoolLifetime     time.Duration
+	withoutMaxLifetime  bool
+	// Rotated pools drain before they're replaced, see WithDrainBeforeRotate
+	drainBeforeRotate bool
+	// Caps each key's unreleased checkouts, see WithMaxCheckouts
+	maxCheckouts int
+	// Injected into the manager and its pools, see WithFaults
+	faults *faultInjector
+	// Shares the throttle's state across replicas, see WithThrottleStore
+	throttleStore ThrottleStore
+	// Pools queue tasks where they can be looked into, see WithQueueInspection
+	queueInspection bool
+
+	failureBackoffMin time.Duration
+	failureBackoffMax time.Duration
+	autoPause         *AutoPause
+	burstWorkers      int
+	burstDuration     time.Duration
+	autoscaleInterval time.Duration
+	autoscalePolicy   AutoscalePolicy
+}
+
+func newOptions(opts []Option) *options {
+	o := &options{clock: realClock{}}
+	for _, opt := range opts {
+		opt(o)
+	}
+	return o
+}
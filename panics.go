@@ -0,0 +1,148 @@
+package pool
+
+import (
+	"errors"
+	"math"
+	"runtime/debug"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ErrPoolQuarantined is returned when submitting to a pool which has been quarantined, see WithQuarantine
+var ErrPoolQuarantined = errors.New("pool is quarantined after repeated task panics")
+
+// TaskPanic describes a task panic recovered by a pool with WithPanicRecovery
+type TaskPanic struct {
+	Key  string
+	Info TaskInfo
+	// Value is the value the task panicked with
+	Value interface{}
+	// Stack is the panicking goroutine's stack, if captured with WithPanicStacks
+	Stack []byte
+	// SubmitSite is where the task was submitted, in debug mode - see WithDebug
+	SubmitSite *CallSite
+}
+
+// Quarantine configures when a pool is quarantined because its tasks keep panicking, set with WithQuarantine
+type Quarantine struct {
+	// Panics is how many panics within Window quarantine a pool
+	Panics int
+	Window time.Duration
+	// Duration is how long a quarantined pool rejects submissions for. Zero means for as long as the pool lives.
+	Duration time.Duration
+	// Rebuild makes the manager replace a quarantined pool with a fresh one the next time it's requested. Callers
+	// still holding the quarantined pool keep being rejected.
+	Rebuild bool
+}
+
+// WithPanicRecovery recovers panics in tasks, so they don't crash the process. Recovered panics are reported to the
+// OnTaskPanic hook and counted in PoolSnapshot.Panics, and the worker carries on with the next task.
+func WithPanicRecovery() Option {
+	return func(o *options) {
+		o.recoverPanics = true
+	}
+}
+
+// WithQuarantine quarantines pools whose tasks panic quarantine.Panics times within quarantine.Window, so a poisoned
+// pattern of tasks can't keep failing unnoticed. Quarantined pools reject new submissions with ErrPoolQuarantined,
+// while tasks already queued are still executed, and the OnPoolQuarantined hook is called.
+//
+// Quarantine implies WithPanicRecovery.
+func WithQuarantine(quarantine Quarantine) Option {
+	return func(o *options) {
+		if quarantine.Panics > 0 {
+			o.recoverPanics = true
+			o.quarantine = &quarantine
+		}
+	}
+}
+
+func (o *options) taskPanicked(recovered TaskPanic) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnTaskPanic != nil {
+			hooks.OnTaskPanic(recovered)
+		}
+	}
+}
+
+func (o *options) poolQuarantined(key string, lastPanic TaskPanic) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnPoolQuarantined != nil {
+			hooks.OnPoolQuarantined(key, lastPanic)
+		}
+	}
+}
+
+// quarantineState tracks a pool's recent panics
+type quarantineState struct {
+	// When the quarantine ends as a UnixNano timestamp, or 0 if the pool isn't quarantined. Accessed atomically.
+	until int64
+
+	lock   *sync.Mutex
+	panics []time.Time
+}
+
+func (p *BaseWorkerPool) recoversPanics() bool {
+	return p.options != nil && p.options.recoverPanics
+}
+
+// Deferred around tasks when panics are recovered
+func (p *BaseWorkerPool) recoverPanic(t task) {
+	recovered := recover()
+	if recovered == nil {
+		return
+	}
+	atomic.AddUint64(&p.stats.panics, 1)
+	p.options.count(MetricTaskPanics, p.key, 1)
+	report := TaskPanic{Key: p.key, Info: t.info, Value: recovered, SubmitSite: t.site}
+	if p.options.panicStacks {
+		report.Stack = debug.Stack()
+	}
+	p.options.taskPanicked(report)
+
+	if p.quarantine != nil && p.countPanic() {
+		p.options.poolQuarantined(p.key, report)
+	}
+}
+
+// Record a panic for quarantine, returning true if it quarantines the pool
+func (p *BaseWorkerPool) countPanic() bool {
+	q := p.quarantine
+	config := p.options.quarantine
+	now := p.clock.Now()
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	recent := q.panics[:0]
+	for _, at := range q.panics {
+		if now.Sub(at) < config.Window {
+			recent = append(recent, at)
+		}
+	}
+	q.panics = append(recent, now)
+	if len(q.panics) < config.Panics || p.quarantined() {
+		return false
+	}
+
+	q.panics = q.panics[:0]
+	until := int64(math.MaxInt64)
+	if config.Duration > 0 {
+		until = now.Add(config.Duration).UnixNano()
+	}
+	atomic.StoreInt64(&q.until, until)
+	return true
+}
+
+func (p *BaseWorkerPool) quarantined() bool {
+	if p.quarantine == nil {
+		return false
+	}
+	until := atomic.LoadInt64(&p.quarantine.until)
+	return until != 0 && p.clock.Now().UnixNano() < until
+}
+
+// Whether the manager should replace this pool with a fresh one
+func (p *BaseWorkerPool) needsRebuild() bool {
+	return p.quarantined() && p.options.quarantine.Rebuild
+}
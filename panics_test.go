@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestPanicRecoveryKeepsWorkersRunning(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	panics := make(chan TaskPanic, 10)
+	p, _ := NewWorkerPoolWithOptions(1, WithPanicRecovery(), WithHooks(Hooks{
+		OnTaskPanic: func(recovered TaskPanic) {
+			panics <- recovered
+		},
+	}))
+	p.spawnWorkers(1)
+
+	assert.Nil(t, SubmitTask(p, TaskInfo{Label: "export"}, func() {
+		panic("poisoned task")
+	}))
+	done := make(chan bool)
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+
+	recovered := <-panics
+	assert.Equal(t, "poisoned task", recovered.Value)
+	assert.Equal(t, "export", recovered.Info.Label)
+	snapshot := p.snapshot()
+	assert.Equal(t, uint64(1), snapshot.Panics)
+	assert.Equal(t, uint64(1), snapshot.Completed)
+	p.Dispose()
+}
+
+func TestQuarantineRejectsSubmissionsAndRebuilds(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	quarantined := make(chan string, 10)
+	evictions := make(chan PoolEviction, 10)
+	pm := NewWorkerPoolManager(
+		1, time.Second, time.Hour,
+		WithQuarantine(Quarantine{Panics: 2, Window: time.Minute, Rebuild: true}),
+		WithHooks(Hooks{
+			OnPoolQuarantined: func(key string, lastPanic TaskPanic) {
+				quarantined <- key
+			},
+			OnPoolEvicted: func(eviction PoolEviction) {
+				evictions <- eviction
+			},
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() {
+			panic("poisoned task")
+		}))
+	}
+	assert.Equal(t, "key", <-quarantined)
+	assert.True(t, pm.Snapshot()["key"].Quarantined)
+	assert.Equal(t, ErrPoolQuarantined, SubmitTask(pool, TaskInfo{}, func() {}))
+	close(doneUsing)
+
+	rebuilt, doneUsing := pm.GetPool("key", 1)
+	assert.NotSame(t, pool, rebuilt)
+	assert.Nil(t, SubmitTask(rebuilt, TaskInfo{}, func() {}))
+	eviction := <-evictions
+	assert.Same(t, pool, eviction.Pool)
+	assert.Equal(t, EvictionReasonQuarantined, eviction.Reason)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestQuarantineEndsAfterDuration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPoolWithOptions(1, WithQuarantine(Quarantine{
+		Panics: 1, Window: time.Minute, Duration: 20 * time.Millisecond,
+	}))
+	p.spawnWorkers(1)
+	p.Submit(func() {
+		panic("poisoned task")
+	})
+	assert.Eventually(t, func() bool {
+		return SubmitTask(p, TaskInfo{}, func() {}) == ErrPoolQuarantined
+	}, time.Second, time.Millisecond)
+	assert.Eventually(t, func() bool {
+		return SubmitTask(p, TaskInfo{}, func() {}) == nil
+	}, time.Second, 5*time.Millisecond)
+	p.Dispose()
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"time"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// Pause stops workers from starting new tasks until the pool is resumed, e.g. for a maintenance window on the
+// downstream system. Tasks which are already executing carry on, and submissions are still accepted and queued.
+func (p *BaseWorkerPool) Pause() {
+	p.pause(0)
+}
+
+// Resume lets the pool's workers start tasks again after Pause, or after an automatic pause
+func (p *BaseWorkerPool) Resume() {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	if p.resumed != nil {
+		close(p.resumed)
+		p.resumed = nil
+	}
+	if p.resumeTimer != nil {
+		p.resumeTimer.Stop()
+		p.resumeTimer = nil
+	}
+}
+
+// Stop workers from starting tasks until the pool is resumed, or until cooldown elapses if it's positive. Each worker
+// holds on to the task it picks up next until then, and the rest stay queued.
+func (p *BaseWorkerPool) pause(cooldown time.Duration) {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	if p.resumed == nil {
+		p.resumed = make(chan bool)
+	}
+	if p.resumeTimer != nil {
+		p.resumeTimer.Stop()
+		p.resumeTimer = nil
+	}
+	if cooldown > 0 {
+		p.resumeTimer = p.clock.AfterFunc(cooldown, p.Resume)
+	}
+}
+
+// Stop a pending automatic resume, leaving paused workers to notice the pool has been disposed
+func (p *BaseWorkerPool) cancelResume() {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	if p.resumeTimer != nil {
+		p.resumeTimer.Stop()
+		p.resumeTimer = nil
+	}
+}
+
+func (p *BaseWorkerPool) paused() bool {
+	p.pauseLock.Lock()
+	defer p.pauseLock.Unlock()
+	return p.resumed != nil
+}
+
+// Block while the pool is paused or frozen, returning false if it's disposed first
+func (p *BaseWorkerPool) waitWhilePaused() bool {
+	for {
+		p.pauseLock.Lock()
+		waitFor := p.resumed
+		if waitFor == nil {
+			waitFor = p.thawed
+		}
+		p.pauseLock.Unlock()
+		if waitFor == nil {
+			return true
+		}
+		select {
+		case <-waitFor:
+		case <-p.disposed:
+			return false
+		}
+	}
+}
+
+// PauseKey pauses key's pool, see WorkerPool.Pause, returning false if no pool is cached for key. Paused pools still
+// expire once they go unused for the stale pool expiration, discarding their queued tasks.
+func (m *WorkerPoolManager) PauseKey(key string) bool {
+	item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]())
+	if item == nil {
+		return false
+	}
+	item.Value().Pause()
+	return true
+}
+
+// ResumeKey resumes key's pool if it's paused, returning false if no pool is cached for key
+func (m *WorkerPoolManager) ResumeKey(key string) bool {
+	item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]())
+	if item == nil {
+		return false
+	}
+	item.Value().Resume()
+	return true
+}
@@ -0,0 +1,55 @@
+package pool
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestPauseKeepsQueueUntilResumed(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour)
+	pool, doneUsing := pm.GetPool("key", 2)
+	assert.True(t, pm.PauseKey("key"))
+	assert.False(t, pm.PauseKey("missing"))
+
+	ran := make(chan int, 10)
+	for i := 0; i < 4; i++ {
+		i := i
+		pool.Submit(func() {
+			ran <- i
+		})
+	}
+	select {
+	case <-ran:
+		t.Fatal("paused pool started a task")
+	case <-time.After(20 * time.Millisecond):
+	}
+	assert.True(t, pm.Snapshot()["key"].Paused)
+
+	pool.Resume()
+	seen := map[int]bool{}
+	for i := 0; i < 4; i++ {
+		seen[<-ran] = true
+	}
+	assert.Len(t, seen, 4)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestDisposeReleasesPausedWorkers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPool(1)
+	p.spawnWorkers(1)
+	p.Pause()
+	p.Submit(func() {
+		t.Error("paused pool started a task")
+	})
+	p.Dispose()
+	p.waitForWorkers()
+}
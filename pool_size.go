@@ -0,0 +1,181 @@
+package pool
+
+import (
+	"sync/atomic"
+
+	"github.com/jellydator/ttlcache/v3"
+)
+
+// WithSizeResolver sizes each key's pool with resolve, so per-tenant limits kept in a config service or a database
+// decide each key's max number of workers instead of the manager's pool size. It's consulted whenever a pool is built,
+// including when a rotated pool is rebuilt, and by SetPoolSize. Results below 1 fall back to the pool size.
+//
+// resolve is called with the manager locked, so it should answer from memory, e.g. from a cache of the config service,
+// rather than make a request of its own.
+func WithSizeResolver(resolve func(key string) int) Option {
+	return func(o *options) {
+		o.sizeResolver = resolve
+	}
+}
+
+// SetPoolSize changes the max number of workers for each key, or for keys the WithSizeResolver resolver doesn't size.
+// Pools built from now on get the new size, and cached pools are reconciled straight away:
+//
+// * when the size grows, cached pools are allowed to spawn workers up to the new size as they're next used, but keep
+// their original queue capacity
+// * when the size shrinks, cached pools are rotated, to be rebuilt at the new size - evicted with
+// EvictionReasonResized, and disposed once their callers are done with them - except for autoscaled pools, which stop
+// their extra workers instead, see WithAutoscaling, and pools served by a shared fleet, whose cap is lowered, see
+// WithSharedFleet. To shrink a key's pool in place instead, retiring its extra workers as they finish their tasks,
+// use SetKeySize.
+//
+// Sizes below 1 are treated as 1, as with SetKeySize, since a pool needs a worker to ever execute its queue.
+func (m *WorkerPoolManager) SetPoolSize(poolSize int) {
+	if poolSize < 1 {
+		poolSize = 1
+	}
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	m.workerPoolMaxSize = poolSize
+	for key, item := range m.workerPoolCache.Items() {
+		pool := item.Value()
+		if !pool.resize(m.sizeFor(key)) {
+			pool.markEvicted(EvictionReasonResized)
+			m.workerPoolCache.Delete(key)
+		}
+	}
+}
+
+// SetKeySize changes the max number of workers for key's cached pool, returning false if key has no cached pool. A
+// pool which grows spawns its new workers straight away, and a pool which shrinks retires workers as they finish the
+// tasks they're executing, so a tenant's new limit takes effect without waiting for the pool to be rotated. The size
+// only lasts as long as the pool, so pair it with WithSizeResolver for pools built later to get it too.
+func (m *WorkerPoolManager) SetKeySize(key string, n int) bool {
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	item := m.workerPoolCache.Get(key, ttlcache.WithDisableTouchOnHit[string, WorkerPool]())
+	if item == nil {
+		return false
+	}
+	item.Value().setSize(n)
+	return true
+}
+
+// Resize the pool to maxSize workers, retiring workers if need be, and spawn any it's short of. It's not thread-safe,
+// lock above this.
+func (p *BaseWorkerPool) setSize(maxSize int) {
+	if maxSize < 1 {
+		maxSize = 1
+	}
+	resized := p.resize(maxSize)
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	if !resized {
+		p.maxSize = maxSize
+		p.labels.resize(maxSize)
+		p.retireWorkers(p.workerCount - maxSize)
+	}
+	if p.owned() && p.autoscale == nil {
+		// Regardless of the growth policy, which is about checkouts' hints
+		p.addWorkers(maxSize)
+	}
+}
+
+// Grow spawns up to n more workers straight away, without growing past the pool's size, and returns how many it
+// spawned. With Shrink, it lets applications with their own scaling signals drive a pool's workers directly, rather
+// than through the sendSize hints of checkouts. Autoscaled pools don't grow, as their policy decides their workers,
+// nor do disposed pools or pools whose key is leased to another instance. For pools served by a shared fleet, it
+// raises the pool's cap of fleet workers instead.
+func (p *BaseWorkerPool) Grow(n int) int {
+	if n <= 0 || p.autoscale != nil || !p.owned() || isClosed(p.disposed) {
+		return 0
+	}
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	return p.addWorkers(n)
+}
+
+// Shrink retires up to n of the pool's workers and returns how many it retired. Idle workers retire straight away,
+// and busy ones once they've finished the tasks they're executing, but the pool always keeps one worker so its queue
+// is never stranded. The pool's size is unchanged, so later checkouts or Grow may spawn the workers again. Autoscaled
+// pools don't shrink, and for pools served by a shared fleet, it lowers the pool's cap of fleet workers instead.
+func (p *BaseWorkerPool) Shrink(n int) int {
+	if n <= 0 || p.autoscale != nil {
+		return 0
+	}
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	retiring := min(n, p.workerCount-1)
+	if retiring <= 0 {
+		return 0
+	}
+	if p.fleet != nil {
+		p.workerCount -= retiring
+		p.setFleetLimit(p.workerCount)
+		p.options.gauge(MetricWorkers, p.key, float64(p.workerCount))
+		return retiring
+	}
+	p.retireWorkers(retiring)
+	return retiring
+}
+
+// Retire n of the pool's own workers, if n is positive. It's not thread-safe, lock sizeLock above this.
+func (p *BaseWorkerPool) retireWorkers(n int) {
+	if n <= 0 {
+		return
+	}
+	p.workerCount -= n
+	atomic.AddInt32(&p.retiring, int32(n))
+	// Idle workers are waiting on the queue, and busy ones retire once they're done, so a full queue needs no wakeups
+	for i := 0; i < n; i++ {
+		if !p.queue.tryPush(task{wakeup: true}) {
+			break
+		}
+	}
+	p.options.gauge(MetricWorkers, p.key, float64(p.workerCount))
+}
+
+// Claim one of the retirements owed after the pool shrank, if there are any
+func (p *BaseWorkerPool) retire() bool {
+	for {
+		retiring := atomic.LoadInt32(&p.retiring)
+		if retiring == 0 {
+			return false
+		}
+		if atomic.CompareAndSwapInt32(&p.retiring, retiring, retiring-1) {
+			return true
+		}
+	}
+}
+
+// The max number of workers for key's pool. It's not thread-safe, lock above this.
+func (m *WorkerPoolManager) sizeFor(key string) int {
+	o := m.poolOptionsFor(key)
+	if resolve := o.sizeResolver; resolve != nil {
+		if size := resolve(key); size > 0 {
+			return size
+		}
+	}
+	if o.poolSize > 0 {
+		return o.poolSize
+	}
+	return m.workerPoolMaxSize
+}
+
+// Resize the pool to maxSize workers, returning false if that would shrink a pool which SetPoolSize rotates instead.
+// It's not thread-safe, lock above this.
+func (p *BaseWorkerPool) resize(maxSize int) bool {
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	if maxSize < p.maxSize && p.autoscale == nil && p.fleet == nil {
+		return false
+	}
+	p.maxSize = maxSize
+	p.setAutoscaleLimit(maxSize)
+	p.labels.resize(maxSize)
+	if p.fleet != nil && p.workerCount > maxSize {
+		p.workerCount = maxSize
+		p.setFleetLimit(maxSize)
+	}
+	return true
+}
@@ -0,0 +1,200 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSetPoolSizeGrowsCachedPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour)
+	pool, doneUsing := pm.GetPool("key", 4)
+	close(doneUsing)
+	assert.Equal(t, 2, pm.Snapshot()["key"].Workers)
+
+	pm.SetPoolSize(4)
+	grown, doneUsing := pm.GetPool("key", 4)
+	assert.Same(t, pool, grown)
+	assert.Equal(t, 4, pm.Snapshot()["key"].Workers)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSetPoolSizeRotatesShrunkPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 10)
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- eviction
+		},
+	}))
+	pool, doneUsing := pm.GetPool("key", 4)
+	close(doneUsing)
+
+	pm.SetPoolSize(1)
+	eviction := <-evictions
+	assert.Same(t, pool, eviction.Pool)
+	assert.Equal(t, EvictionReasonResized, eviction.Reason)
+
+	rotated, doneUsing := pm.GetPool("key", 4)
+	assert.NotSame(t, pool, rotated)
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSetPoolSizeKeepsAWorker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour)
+	pm.SetPoolSize(0)
+	pool, doneUsing := pm.GetPool("key", 2)
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+	executed := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{}, func() { close(executed) }))
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		t.Fatal("the pool never executed its task")
+	}
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSizeResolverSizesEachKey(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	sizes := map[string]int{"big": 6, "small": 1}
+	pm := NewWorkerPoolManager(3, time.Second, time.Hour, WithSizeResolver(func(key string) int {
+		return sizes[key]
+	}))
+	for _, key := range []string{"big", "small", "other"} {
+		_, doneUsing := pm.GetPool(key, 10)
+		close(doneUsing)
+	}
+	snapshots := pm.Snapshot()
+	assert.Equal(t, 6, snapshots["big"].Workers)
+	assert.Equal(t, 1, snapshots["small"].Workers)
+	assert.Equal(t, 3, snapshots["other"].Workers)
+
+	// Resizing grows the keys it resolves to more workers, and falls back to the pool size for the rest
+	sizes["small"] = 2
+	pm.SetPoolSize(4)
+	for _, key := range []string{"small", "other"} {
+		_, doneUsing := pm.GetPool(key, 10)
+		close(doneUsing)
+	}
+	snapshots = pm.Snapshot()
+	assert.Equal(t, 2, snapshots["small"].Workers)
+	assert.Equal(t, 4, snapshots["other"].Workers)
+	pm.Dispose()
+}
+
+func TestSetKeySizeResizesTheCachedPool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour, WithQueueCapacity(10))
+	pool, doneUsing := pm.GetPool("key", 4)
+	assert.False(t, pm.SetKeySize("missing", 1))
+
+	peakConcurrency := func() int32 {
+		var executing, peak int32
+		done := make(chan bool, 6)
+		for i := 0; i < 6; i++ {
+			pool.Submit(func() {
+				n := atomic.AddInt32(&executing, 1)
+				for {
+					p := atomic.LoadInt32(&peak)
+					if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+						break
+					}
+				}
+				time.Sleep(5 * time.Millisecond)
+				atomic.AddInt32(&executing, -1)
+				done <- true
+			})
+		}
+		for i := 0; i < 6; i++ {
+			<-done
+		}
+		return atomic.LoadInt32(&peak)
+	}
+
+	assert.True(t, pm.SetKeySize("key", 1))
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+	assert.Equal(t, int32(1), peakConcurrency())
+
+	// Growing spawns the new workers without waiting for another checkout
+	assert.True(t, pm.SetKeySize("key", 3))
+	assert.Equal(t, 3, pm.Snapshot()["key"].Workers)
+	assert.Equal(t, int32(3), peakConcurrency())
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestGrowAndShrinkDriveWorkersDirectly(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour, WithQueueCapacity(10))
+	pool, doneUsing := pm.GetPool("key", 1)
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+
+	assert.Equal(t, 2, pool.Grow(2))
+	assert.Equal(t, 3, pm.Snapshot()["key"].Workers)
+	// Never past the pool's size
+	assert.Equal(t, 1, pool.Grow(5))
+	assert.Equal(t, 0, pool.Grow(1))
+
+	// Always keeping a worker
+	assert.Equal(t, 3, pool.Shrink(10))
+	assert.Equal(t, 1, pm.Snapshot()["key"].Workers)
+	assert.Equal(t, 0, pool.Shrink(1))
+
+	done := make(chan bool)
+	pool.Submit(func() {
+		close(done)
+	})
+	<-done
+
+	// The size is unchanged, so checkouts spawn workers again
+	_, again := pm.GetPool("key", 4)
+	assert.Equal(t, 4, pm.Snapshot()["key"].Workers)
+
+	close(again)
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestGetPoolWithSizeBuildsSmallerPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour)
+	small, doneSmall, err := pm.GetPoolWithSize("small", 8, 2, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, pm.Snapshot()["small"].Workers)
+
+	// Capped by the manager's size
+	_, doneLarge, err := pm.GetPoolWithSize("large", 8, 10, nil)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, pm.Snapshot()["large"].Workers)
+
+	// The cached pool keeps its size
+	cached, doneCached := pm.GetPool("small", 8)
+	assert.Same(t, small, cached)
+	assert.Equal(t, 2, pm.Snapshot()["small"].Workers)
+
+	close(doneCached)
+	close(doneLarge)
+	close(doneSmall)
+	pm.Dispose()
+}
@@ -0,0 +1,207 @@
+package poolbench
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// Report summarizes how a manager coped with a Workload
+type Report struct {
+	// Submitted is the number of tasks submitted, which is less than the workload's Tasks if the run was cancelled
+	Submitted int
+	// Completed is the number of tasks which finished executing
+	Completed int
+	Elapsed   time.Duration
+	// Throughput is the average number of tasks completed per second
+	Throughput float64
+	// Latency is the distribution of times from submitting tasks to them finishing
+	Latency Percentiles
+	// Keys is the number of distinct keys tasks were submitted for
+	Keys int
+	// HottestKeyShare is the fraction of tasks submitted for the busiest key
+	HottestKeyShare float64
+}
+
+// Percentiles summarizes a latency distribution
+type Percentiles struct {
+	P50 time.Duration
+	P90 time.Duration
+	P99 time.Duration
+	Max time.Duration
+}
+
+func (r Report) String() string {
+	return fmt.Sprintf(
+		"%d/%d tasks in %s (%.0f/s), latency p50 %s p90 %s p99 %s max %s, %d keys (hottest %.1f%%)",
+		r.Completed, r.Submitted, r.Elapsed.Round(time.Millisecond), r.Throughput,
+		r.Latency.P50, r.Latency.P90, r.Latency.P99, r.Latency.Max, r.Keys, 100*r.HottestKeyShare,
+	)
+}
+
+// Run submits workload to pm's pools, keyed "0", "1" and so on, and waits for it to finish or for ctx to be done.
+// Pools are checked out with GetPool, so pm's pool size, options and expiration all apply as they would in production.
+func Run(ctx context.Context, pm *pool.WorkerPoolManager, workload Workload) Report {
+	submitters := workload.Submitters
+	if submitters < 1 {
+		submitters = 1
+	}
+	batchSize := workload.BatchSize
+	if batchSize < 1 {
+		batchSize = 1
+	}
+	duration := workload.Duration
+	if duration == nil {
+		duration = Constant(0)
+	}
+
+	var pacing <-chan time.Time
+	if workload.Rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / workload.Rate * float64(batchSize)))
+		defer ticker.Stop()
+		pacing = ticker.C
+	}
+
+	var remaining int64 = int64(workload.Tasks)
+	var lock sync.Mutex
+	var latencies []time.Duration
+	perKey := make(map[int]int)
+	var running sync.WaitGroup
+	var submitting sync.WaitGroup
+
+	start := time.Now()
+	for i := 0; i < submitters; i++ {
+		// Each submitter has its own source, as rand.Rand isn't safe for concurrent use
+		r := rand.New(rand.NewSource(workload.Seed + int64(i)))
+		pickKey := workload.keyPicker(r)
+		submitting.Add(1)
+		go func() {
+			defer submitting.Done()
+			for {
+				if pacing != nil {
+					select {
+					case <-pacing:
+					case <-ctx.Done():
+						return
+					}
+				} else if ctx.Err() != nil {
+					return
+				}
+
+				batch := int(atomic.AddInt64(&remaining, -int64(batchSize)) + int64(batchSize))
+				if batch <= 0 {
+					return
+				}
+				if batch > batchSize {
+					batch = batchSize
+				}
+
+				key := pickKey()
+				lock.Lock()
+				perKey[key] += batch
+				lock.Unlock()
+
+				p, doneUsing := pm.GetPool(strconv.Itoa(key), batch)
+				for j := 0; j < batch; j++ {
+					d := duration.Sample(r)
+					submitted := time.Now()
+					running.Add(1)
+					p.Submit(func() {
+						defer running.Done()
+						workload.work(d)
+						latency := time.Since(submitted)
+						lock.Lock()
+						latencies = append(latencies, latency)
+						lock.Unlock()
+					})
+				}
+				close(doneUsing)
+			}
+		}()
+	}
+	submitting.Wait()
+
+	finished := make(chan bool)
+	go func() {
+		running.Wait()
+		close(finished)
+	}()
+	select {
+	case <-finished:
+	case <-ctx.Done():
+	}
+	elapsed := time.Since(start)
+
+	lock.Lock()
+	defer lock.Unlock()
+	report := Report{
+		Completed: len(latencies),
+		Elapsed:   elapsed,
+		Latency:   percentiles(latencies),
+		Keys:      len(perKey),
+	}
+	hottest := 0
+	for _, count := range perKey {
+		report.Submitted += count
+		if count > hottest {
+			hottest = count
+		}
+	}
+	if report.Submitted > 0 {
+		report.HottestKeyShare = float64(hottest) / float64(report.Submitted)
+	}
+	if elapsed > 0 {
+		report.Throughput = float64(report.Completed) / elapsed.Seconds()
+	}
+	return report
+}
+
+// Compare runs workload against a fresh manager from each of newManagers in turn, reporting on each by name, to
+// compare pool sizes or options like pool.WithCondvarDispatch against the same load
+func Compare(
+	ctx context.Context, workload Workload, newManagers map[string]func() *pool.WorkerPoolManager,
+) map[string]Report {
+	reports := make(map[string]Report, len(newManagers))
+	for name, newManager := range newManagers {
+		pm := newManager()
+		reports[name] = Run(ctx, pm, workload)
+		pm.Dispose()
+	}
+	return reports
+}
+
+// FormatComparison lays out reports from Compare, one line per manager in name order
+func FormatComparison(reports map[string]Report) string {
+	names := make([]string, 0, len(reports))
+	for name := range reports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&out, "%s: %s\n", name, reports[name])
+	}
+	return out.String()
+}
+
+func percentiles(latencies []time.Duration) Percentiles {
+	if len(latencies) == 0 {
+		return Percentiles{}
+	}
+	sort.Slice(latencies, func(i, j int) bool {
+		return latencies[i] < latencies[j]
+	})
+	at := func(quantile float64) time.Duration {
+		return latencies[int(quantile*float64(len(latencies)-1))]
+	}
+	return Percentiles{P50: at(0.5), P90: at(0.9), P99: at(0.99), Max: latencies[len(latencies)-1]}
+}
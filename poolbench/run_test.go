@@ -0,0 +1,88 @@
+package poolbench
+
+import (
+	"context"
+	"math/rand"
+	"strings"
+	"testing"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestRunReportsOnWorkload(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(4, time.Second, time.Hour)
+	report := Run(context.Background(), pm, Workload{
+		Tasks:      200,
+		Duration:   Uniform(0, 100*time.Microsecond),
+		Keys:       20,
+		KeySkew:    2,
+		Submitters: 4,
+		BatchSize:  3,
+	})
+	pm.Dispose()
+
+	assert.Equal(t, 200, report.Submitted)
+	assert.Equal(t, 200, report.Completed)
+	assert.Positive(t, report.Throughput)
+	assert.LessOrEqual(t, report.Latency.P50, report.Latency.P99)
+	assert.LessOrEqual(t, report.Keys, 20)
+	// Skew piles most of the tasks onto the first key
+	assert.Greater(t, report.HottestKeyShare, 0.4)
+	assert.Contains(t, report.String(), "200/200 tasks")
+}
+
+func TestRunPacesSubmissions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(4, time.Second, time.Hour)
+	report := Run(context.Background(), pm, Workload{
+		Tasks: 10, Rate: 500, Busy: true, Duration: Constant(10 * time.Microsecond),
+	})
+	pm.Dispose()
+	assert.Equal(t, 10, report.Completed)
+	assert.GreaterOrEqual(t, report.Elapsed, 15*time.Millisecond)
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := pool.NewWorkerPoolManager(4, time.Second, time.Hour)
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report := Run(ctx, pm, Workload{Tasks: 1000000, Rate: 1000})
+	pm.Dispose()
+	assert.Less(t, report.Submitted, 1000000)
+}
+
+func TestCompareDispatchers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	reports := Compare(context.Background(), Workload{Tasks: 50, Keys: 2}, map[string]func() *pool.WorkerPoolManager{
+		"channel": func() *pool.WorkerPoolManager {
+			return pool.NewWorkerPoolManager(2, time.Second, time.Hour)
+		},
+		"condvar": func() *pool.WorkerPoolManager {
+			return pool.NewWorkerPoolManager(2, time.Second, time.Hour, pool.WithCondvarDispatch())
+		},
+	})
+	assert.Equal(t, 50, reports["condvar"].Completed)
+	lines := strings.Split(strings.TrimSpace(FormatComparison(reports)), "\n")
+	assert.Len(t, lines, 2)
+	assert.True(t, strings.HasPrefix(lines[0], "channel: 50/50 tasks"))
+}
+
+func TestDistributions(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	assert.Equal(t, time.Millisecond, Constant(time.Millisecond).Sample(r))
+	for i := 0; i < 100; i++ {
+		d := Uniform(time.Millisecond, 2*time.Millisecond).Sample(r)
+		assert.True(t, d >= time.Millisecond && d < 2*time.Millisecond)
+		assert.GreaterOrEqual(t, Exponential(time.Millisecond).Sample(r), time.Duration(0))
+		assert.Positive(t, LogNormal(time.Millisecond, 0.5).Sample(r))
+	}
+}
@@ -0,0 +1,121 @@
+// Package poolbench generates synthetic load against a WorkerPoolManager and reports how it coped, for evaluating pool
+// sizing and dispatcher options against the shape of a real workload.
+package poolbench
+
+import (
+	"math"
+	"math/rand"
+	"time"
+)
+
+// Distribution is a distribution of task durations
+type Distribution interface {
+	Sample(r *rand.Rand) time.Duration
+}
+
+type constant time.Duration
+
+// Constant is a distribution where every task takes d
+func Constant(d time.Duration) Distribution {
+	return constant(d)
+}
+
+func (c constant) Sample(*rand.Rand) time.Duration {
+	return time.Duration(c)
+}
+
+type uniform struct {
+	min, max time.Duration
+}
+
+// Uniform is a distribution of durations spread evenly between min and max
+func Uniform(min, max time.Duration) Distribution {
+	return uniform{min: min, max: max}
+}
+
+func (u uniform) Sample(r *rand.Rand) time.Duration {
+	if u.max <= u.min {
+		return u.min
+	}
+	return u.min + time.Duration(r.Int63n(int64(u.max-u.min)))
+}
+
+type exponential time.Duration
+
+// Exponential is a distribution of durations averaging mean, with a long tail of slow tasks
+func Exponential(mean time.Duration) Distribution {
+	return exponential(mean)
+}
+
+func (e exponential) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(r.ExpFloat64() * float64(e))
+}
+
+type logNormal struct {
+	mu, sigma float64
+}
+
+// LogNormal is a distribution of durations with the given median, where sigma controls the spread - around 0.5 is
+// typical of network calls
+func LogNormal(median time.Duration, sigma float64) Distribution {
+	return logNormal{mu: math.Log(float64(median)), sigma: sigma}
+}
+
+func (l logNormal) Sample(r *rand.Rand) time.Duration {
+	return time.Duration(math.Exp(l.mu + l.sigma*r.NormFloat64()))
+}
+
+// Workload describes the load to generate
+type Workload struct {
+	// Tasks is how many tasks to submit in total
+	Tasks int
+	// Duration is how long each task takes
+	Duration Distribution
+	// Busy makes tasks spin on the CPU for their duration, rather than sleeping as if waiting on a downstream
+	Busy bool
+
+	// Keys is how many distinct pool keys tasks are spread across
+	Keys int
+	// KeySkew concentrates tasks on the first keys, following a Zipf distribution with this exponent, which must be
+	// greater than 1. Zero spreads tasks evenly across keys.
+	KeySkew float64
+
+	// Submitters is how many goroutines submit tasks concurrently, 1 if unset
+	Submitters int
+	// Rate caps the total submissions per second across all submitters. Zero submits as fast as pools accept tasks.
+	Rate float64
+	// BatchSize is how many tasks each submitter submits per pool checkout, 1 if unset
+	BatchSize int
+
+	// Seed makes the generated keys and durations reproducible
+	Seed int64
+}
+
+// keyPicker chooses the key of each batch
+type keyPicker func() int
+
+func (w Workload) keyPicker(r *rand.Rand) keyPicker {
+	keys := w.Keys
+	if keys < 1 {
+		keys = 1
+	}
+	if w.KeySkew > 1 {
+		zipf := rand.NewZipf(r, w.KeySkew, 1, uint64(keys-1))
+		return func() int {
+			return int(zipf.Uint64())
+		}
+	}
+	return func() int {
+		return r.Intn(keys)
+	}
+}
+
+// Run the task for a duration
+func (w Workload) work(d time.Duration) {
+	if !w.Busy {
+		time.Sleep(d)
+		return
+	}
+	for start := time.Now(); time.Since(start) < d; {
+	}
+}
@@ -0,0 +1,80 @@
+// Package poollog adapts popular structured loggers to pool.Logger, for use with pool.WithLogger. The adapters are
+// written against the loggers' method sets rather than their packages, so using them doesn't add dependencies.
+package poollog
+
+import (
+	pool "github.com/Appboy/worker-pools"
+)
+
+// ZapLogger is the part of zap's *SugaredLogger used by Zap
+type ZapLogger interface {
+	Debugw(msg string, keysAndValues ...interface{})
+	Infow(msg string, keysAndValues ...interface{})
+	Warnw(msg string, keysAndValues ...interface{})
+	Errorw(msg string, keysAndValues ...interface{})
+}
+
+// Zap logs to a zap SugaredLogger, e.g. poollog.Zap(zapLogger.Sugar())
+func Zap(logger ZapLogger) pool.Logger {
+	return zapLogger{logger}
+}
+
+type zapLogger struct {
+	logger ZapLogger
+}
+
+func (l zapLogger) Log(level pool.LogLevel, msg string, keysAndValues ...interface{}) {
+	switch level {
+	case pool.LogDebug:
+		l.logger.Debugw(msg, keysAndValues...)
+	case pool.LogInfo:
+		l.logger.Infow(msg, keysAndValues...)
+	case pool.LogWarn:
+		l.logger.Warnw(msg, keysAndValues...)
+	default:
+		l.logger.Errorw(msg, keysAndValues...)
+	}
+}
+
+// LogrLogger is the part of logr's Logger used by Logr
+type LogrLogger interface {
+	Info(msg string, keysAndValues ...interface{})
+	Error(err error, msg string, keysAndValues ...interface{})
+}
+
+// Logr logs to a logr Logger. logr has no warning level, so warnings are logged with Info, and debug messages are
+// logged to verbose - pass logger.V(1), or nil to drop them.
+func Logr(logger LogrLogger, verbose LogrLogger) pool.Logger {
+	return logrLogger{logger: logger, verbose: verbose}
+}
+
+type logrLogger struct {
+	logger  LogrLogger
+	verbose LogrLogger
+}
+
+func (l logrLogger) Log(level pool.LogLevel, msg string, keysAndValues ...interface{}) {
+	switch level {
+	case pool.LogDebug:
+		if l.verbose != nil {
+			l.verbose.Info(msg, keysAndValues...)
+		}
+	case pool.LogInfo, pool.LogWarn:
+		l.logger.Info(msg, keysAndValues...)
+	default:
+		err, rest := extractError(keysAndValues)
+		l.logger.Error(err, msg, rest...)
+	}
+}
+
+// Split the "error" value out of keysAndValues, as logr takes it separately
+func extractError(keysAndValues []interface{}) (error, []interface{}) {
+	for i := 0; i+1 < len(keysAndValues); i += 2 {
+		if err, ok := keysAndValues[i+1].(error); ok && keysAndValues[i] == "error" {
+			rest := make([]interface{}, 0, len(keysAndValues)-2)
+			rest = append(rest, keysAndValues[:i]...)
+			return err, append(rest, keysAndValues[i+2:]...)
+		}
+	}
+	return nil, keysAndValues
+}
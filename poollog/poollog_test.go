@@ -0,0 +1,82 @@
+package poollog
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/stretchr/testify/assert"
+)
+
+// Stand-ins for zap's SugaredLogger and logr's Logger, recording what they're called with
+type fakeZap struct {
+	lines []string
+}
+
+func (z *fakeZap) Debugw(msg string, keysAndValues ...interface{}) {
+	z.log("debug", msg, keysAndValues)
+}
+
+func (z *fakeZap) Infow(msg string, keysAndValues ...interface{}) {
+	z.log("info", msg, keysAndValues)
+}
+
+func (z *fakeZap) Warnw(msg string, keysAndValues ...interface{}) {
+	z.log("warn", msg, keysAndValues)
+}
+
+func (z *fakeZap) Errorw(msg string, keysAndValues ...interface{}) {
+	z.log("error", msg, keysAndValues)
+}
+
+func (z *fakeZap) log(level string, msg string, keysAndValues []interface{}) {
+	z.lines = append(z.lines, fmt.Sprint(level, " ", msg, " ", keysAndValues))
+}
+
+type fakeLogr struct {
+	name  string
+	lines *[]string
+}
+
+func (l fakeLogr) Info(msg string, keysAndValues ...interface{}) {
+	*l.lines = append(*l.lines, fmt.Sprint(l.name, " info ", msg, " ", keysAndValues))
+}
+
+func (l fakeLogr) Error(err error, msg string, keysAndValues ...interface{}) {
+	*l.lines = append(*l.lines, fmt.Sprint(l.name, " error ", err, " ", msg, " ", keysAndValues))
+}
+
+func TestZap(t *testing.T) {
+	z := &fakeZap{}
+	logger := Zap(z)
+	logger.Log(pool.LogDebug, "created", "key", "a")
+	logger.Log(pool.LogInfo, "note", "key", "a")
+	logger.Log(pool.LogWarn, "stuck", "key", "a")
+	logger.Log(pool.LogError, "panicked", "key", "a", "panic", "boom")
+	assert.Equal(t, []string{
+		"debug created [key a]",
+		"info note [key a]",
+		"warn stuck [key a]",
+		"error panicked [key a panic boom]",
+	}, z.lines)
+}
+
+func TestLogr(t *testing.T) {
+	var lines []string
+	logger := Logr(fakeLogr{name: "main", lines: &lines}, fakeLogr{name: "verbose", lines: &lines})
+	logger.Log(pool.LogDebug, "created", "key", "a")
+	logger.Log(pool.LogWarn, "stuck", "key", "a")
+	logger.Log(pool.LogError, "disposal failed", "key", "a", "error", errors.New("closed"), "reason", "expired")
+	logger.Log(pool.LogError, "panicked", "key", "a")
+	assert.Equal(t, []string{
+		"verbose info created [key a]",
+		"main info stuck [key a]",
+		"main error closed disposal failed [key a reason expired]",
+		"main error <nil> panicked [key a]",
+	}, lines)
+
+	lines = nil
+	Logr(fakeLogr{name: "main", lines: &lines}, nil).Log(pool.LogDebug, "created", "key", "a")
+	assert.Empty(t, lines)
+}
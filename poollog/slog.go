@@ -0,0 +1,36 @@
+//go:build go1.21
+
+package poollog
+
+import (
+	"context"
+	"log/slog"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// Slog logs to an slog Logger
+func Slog(logger *slog.Logger) pool.Logger {
+	return slogLogger{logger}
+}
+
+type slogLogger struct {
+	logger *slog.Logger
+}
+
+func (l slogLogger) Log(level pool.LogLevel, msg string, keysAndValues ...interface{}) {
+	l.logger.Log(context.Background(), slogLevel(level), msg, keysAndValues...)
+}
+
+func slogLevel(level pool.LogLevel) slog.Level {
+	switch level {
+	case pool.LogDebug:
+		return slog.LevelDebug
+	case pool.LogInfo:
+		return slog.LevelInfo
+	case pool.LogWarn:
+		return slog.LevelWarn
+	default:
+		return slog.LevelError
+	}
+}
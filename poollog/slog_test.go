@@ -0,0 +1,33 @@
+//go:build go1.21
+
+package poollog
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlog(t *testing.T) {
+	var out bytes.Buffer
+	handler := slog.NewTextHandler(&out, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	})
+	logger := Slog(slog.New(handler))
+	logger.Log(pool.LogDebug, "created", "key", "a")
+	logger.Log(pool.LogWarn, "stuck", "key", "a")
+	assert.Equal(t, []string{
+		`level=DEBUG msg=created key=a`,
+		`level=WARN msg=stuck key=a`,
+	}, strings.Split(strings.TrimSpace(out.String()), "\n"))
+}
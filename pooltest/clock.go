@@ -0,0 +1,126 @@
+package pooltest
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// FakeClock is a pool.Clock which only moves when Advance is called. Calls scheduled with AfterFunc are made
+// synchronously by Advance, in the order they're due.
+type FakeClock struct {
+	lock   *sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFakeClock builds a FakeClock reading start
+func NewFakeClock(start time.Time) *FakeClock {
+	return &FakeClock{
+		lock: &sync.Mutex{},
+		now:  start,
+	}
+}
+
+// Now returns the clock's current time
+func (c *FakeClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+// AfterFunc schedules f to be called once the clock has been advanced by d
+func (c *FakeClock) AfterFunc(d time.Duration, f func()) pool.Timer {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	t := &fakeTimer{clock: c, f: f}
+	c.schedule(t, d)
+	return t
+}
+
+// Advance moves the clock forward by d, making every scheduled call which falls due along the way. Each call is made
+// with the clock reading the time it was due, so calls which schedule further calls within d are made too.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	target := c.now.Add(d)
+	for {
+		t := c.nextDue(target)
+		if t == nil {
+			break
+		}
+		c.now = t.due
+		c.unschedule(t)
+
+		// Calls may use the clock, so they're made without holding its lock
+		c.lock.Unlock()
+		t.f()
+		c.lock.Lock()
+	}
+	c.now = target
+	c.lock.Unlock()
+}
+
+// Pending returns the number of scheduled calls which haven't been made yet
+func (c *FakeClock) Pending() int {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return len(c.timers)
+}
+
+// The earliest scheduled timer due no later than target. It's not thread-safe, lock above this
+func (c *FakeClock) nextDue(target time.Time) *fakeTimer {
+	if len(c.timers) == 0 || c.timers[0].due.After(target) {
+		return nil
+	}
+	return c.timers[0]
+}
+
+// It's not thread-safe, lock above this
+func (c *FakeClock) schedule(t *fakeTimer, d time.Duration) {
+	t.due = c.now.Add(d)
+	t.scheduled = true
+	c.timers = append(c.timers, t)
+	// Stable, so timers due at the same time fire in the order they were scheduled
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].due.Before(c.timers[j].due)
+	})
+}
+
+// It's not thread-safe, lock above this
+func (c *FakeClock) unschedule(t *fakeTimer) bool {
+	if !t.scheduled {
+		return false
+	}
+	t.scheduled = false
+	for i, scheduled := range c.timers {
+		if scheduled == t {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			break
+		}
+	}
+	return true
+}
+
+type fakeTimer struct {
+	clock     *FakeClock
+	f         func()
+	due       time.Time
+	scheduled bool
+}
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	return t.clock.unschedule(t)
+}
+
+func (t *fakeTimer) Reset(d time.Duration) bool {
+	t.clock.lock.Lock()
+	defer t.clock.lock.Unlock()
+	wasScheduled := t.clock.unschedule(t)
+	t.clock.schedule(t, d)
+	return wasScheduled
+}
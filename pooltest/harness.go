@@ -0,0 +1,180 @@
+// Package pooltest provides utilities for testing code built on worker pools without depending on real time.
+package pooltest
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// EventTimeout is how long a Harness waits for an expected lifecycle event. Pool disposal happens asynchronously, on
+// real time, once callers release the pool, so events may trail the clock advance which caused them.
+var EventTimeout = time.Second
+
+// EventType identifies a kind of pool lifecycle Event
+type EventType int
+
+// Available event types
+const (
+	PoolCreated EventType = iota + 1
+	PoolEvicted
+)
+
+func (e EventType) String() string {
+	switch e {
+	case PoolCreated:
+		return "created"
+	case PoolEvicted:
+		return "evicted"
+	default:
+		return "unknown"
+	}
+}
+
+// Event is a pool lifecycle event recorded by a Harness
+type Event struct {
+	Type EventType
+	Key  string
+	// Reason is only set for PoolEvicted events
+	Reason pool.EvictionReason
+}
+
+func (e Event) String() string {
+	if e.Type == PoolEvicted {
+		return fmt.Sprintf("%s %q (%s)", e.Type, e.Key, e.Reason)
+	}
+	return fmt.Sprintf("%s %q", e.Type, e.Key)
+}
+
+// Harness wires a FakeClock into a WorkerPoolManager, so tests can trigger stale pool expiry and max lifetime
+// rotation deterministically by advancing the clock, and assert on the resulting lifecycle events.
+type Harness struct {
+	Manager *pool.WorkerPoolManager
+	Clock   *FakeClock
+
+	t         testing.TB
+	events    chan Event
+	created   int64
+	evicted   int64
+	closeOnce *sync.Once
+}
+
+// NewHarness builds a manager with the given configuration and options, reading time from a new FakeClock. The
+// manager is disposed when the test finishes, unless Close is called earlier.
+func NewHarness(
+	t testing.TB, poolSize int, stalePoolExpiration time.Duration, maxPoolLifetime time.Duration, opts ...pool.Option,
+) *Harness {
+	h := &Harness{
+		Clock:     NewFakeClock(time.Date(2020, time.January, 1, 0, 0, 0, 0, time.UTC)),
+		t:         t,
+		events:    make(chan Event, 1024),
+		closeOnce: &sync.Once{},
+	}
+	opts = append(opts, pool.WithClock(h.Clock), pool.WithHooks(pool.Hooks{
+		OnPoolCreated: func(key string, _ pool.WorkerPool) {
+			atomic.AddInt64(&h.created, 1)
+			h.record(Event{Type: PoolCreated, Key: key})
+		},
+		OnPoolEvicted: func(eviction pool.PoolEviction) {
+			atomic.AddInt64(&h.evicted, 1)
+			h.record(Event{Type: PoolEvicted, Key: eviction.Key, Reason: eviction.Reason})
+		},
+	}))
+	h.Manager = pool.NewWorkerPoolManager(poolSize, stalePoolExpiration, maxPoolLifetime, opts...)
+	t.Cleanup(h.Close)
+	return h
+}
+
+func (h *Harness) record(e Event) {
+	select {
+	case h.events <- e:
+	default:
+		h.t.Errorf("pooltest: dropped %s event, too many unchecked events", e)
+	}
+}
+
+// Advance moves the harness's clock forward by d, triggering any expiry which falls due
+func (h *Harness) Advance(d time.Duration) {
+	h.Clock.Advance(d)
+}
+
+// Use checks out the pool for key, and immediately releases it, refreshing its expiration
+func (h *Harness) Use(key string, sendSize int) pool.WorkerPool {
+	p, doneUsing := h.Manager.GetPool(key, sendSize)
+	close(doneUsing)
+	return p
+}
+
+// ExpectEvent fails the test unless the next lifecycle event is want
+func (h *Harness) ExpectEvent(want Event) {
+	h.t.Helper()
+	select {
+	case got := <-h.events:
+		if got != want {
+			h.t.Errorf("pooltest: expected %s event, got %s", want, got)
+		}
+	case <-time.After(EventTimeout):
+		h.t.Errorf("pooltest: expected %s event, got none", want)
+	}
+}
+
+// ExpectCreated fails the test unless the next lifecycle event is the creation of key's pool
+func (h *Harness) ExpectCreated(key string) {
+	h.t.Helper()
+	h.ExpectEvent(Event{Type: PoolCreated, Key: key})
+}
+
+// ExpectEvicted fails the test unless the next lifecycle event is the eviction of key's pool for reason
+func (h *Harness) ExpectEvicted(key string, reason pool.EvictionReason) {
+	h.t.Helper()
+	h.ExpectEvent(Event{Type: PoolEvicted, Key: key, Reason: reason})
+}
+
+// ExpectNoEvents fails the test if there are lifecycle events which haven't been checked. Pools which have been
+// removed from the cache are given up to EventTimeout to finish disposal first.
+func (h *Harness) ExpectNoEvents() {
+	h.t.Helper()
+	h.settle()
+	select {
+	case got := <-h.events:
+		h.t.Errorf("pooltest: expected no events, got %s", got)
+	default:
+	}
+}
+
+// ExpectCached fails the test unless the manager currently has a pool cached for key
+func (h *Harness) ExpectCached(key string) {
+	h.t.Helper()
+	if _, ok := h.Manager.Snapshot()[key]; !ok {
+		h.t.Errorf("pooltest: expected a pool to be cached for %q", key)
+	}
+}
+
+// ExpectNotCached fails the test if the manager currently has a pool cached for key
+func (h *Harness) ExpectNotCached(key string) {
+	h.t.Helper()
+	if _, ok := h.Manager.Snapshot()[key]; ok {
+		h.t.Errorf("pooltest: expected no pool to be cached for %q", key)
+	}
+}
+
+// Close disposes the manager. It's safe to call more than once.
+func (h *Harness) Close() {
+	h.closeOnce.Do(h.Manager.Dispose)
+}
+
+// Wait, for up to EventTimeout, until every pool that has been removed from the cache has finished disposal
+func (h *Harness) settle() {
+	deadline := time.Now().Add(EventTimeout)
+	for time.Now().Before(deadline) {
+		cached := int64(len(h.Manager.Snapshot()))
+		if atomic.LoadInt64(&h.created)-atomic.LoadInt64(&h.evicted) <= cached {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
@@ -0,0 +1,144 @@
+package pooltest
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestHarnessExpiresStalePools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	h := NewHarness(t, 10, time.Minute, time.Hour)
+
+	h.Use("key", 1)
+	h.ExpectCreated("key")
+
+	h.Advance(59 * time.Second)
+	h.ExpectCached("key")
+	h.ExpectNoEvents()
+
+	h.Advance(time.Second)
+	h.ExpectNotCached("key")
+	h.ExpectEvicted("key", pool.EvictionReasonExpired)
+
+	h.Close()
+}
+
+func TestHarnessUseExtendsExpiration(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	h := NewHarness(t, 10, time.Minute, time.Hour)
+
+	h.Use("key", 1)
+	h.ExpectCreated("key")
+	for i := 0; i < 5; i++ {
+		h.Advance(45 * time.Second)
+		h.Use("key", 1)
+	}
+	h.ExpectCached("key")
+	h.ExpectNoEvents()
+
+	h.Advance(time.Minute)
+	h.ExpectEvicted("key", pool.EvictionReasonExpired)
+
+	h.Close()
+}
+
+func TestHarnessRotatesPoolsPastMaxLifetime(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	h := NewHarness(t, 10, time.Minute, 2*time.Minute)
+
+	original := h.Use("key", 1)
+	h.ExpectCreated("key")
+	for i := 0; i < 4; i++ {
+		h.Advance(40 * time.Second)
+		h.Use("key", 1)
+	}
+	// The checkout past max lifetime still gets the old pool, but evicts it
+	h.ExpectEvicted("key", pool.EvictionReasonMaxLifetime)
+	h.ExpectNotCached("key")
+
+	replacement := h.Use("key", 1)
+	h.ExpectCreated("key")
+	assert.NotSame(t, original, replacement)
+
+	h.Close()
+	h.ExpectEvicted("key", pool.EvictionReasonDeleted)
+}
+
+func TestHarnessDrivesThrottledPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	h := NewHarness(t, 10, time.Hour, time.Hour, pool.WithThrottle(1, time.Second))
+
+	p, doneUsing := h.Manager.GetPool("key", 2)
+	var wg sync.WaitGroup
+	started := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		wg.Add(1)
+		p.Submit(func() {
+			started <- true
+			wg.Done()
+		})
+	}
+	<-started
+
+	// The second start is paced for a second later, and only happens once the clock gets there. Wait for its timer to
+	// be scheduled alongside the pool's expiry timer.
+	waitForTimers(h.Clock, 2)
+	assert.Len(t, started, 0)
+	h.Advance(time.Second)
+	wg.Wait()
+
+	close(doneUsing)
+	h.Close()
+}
+
+// Wait for goroutines to schedule n calls on the clock
+func waitForTimers(clock *FakeClock, n int) {
+	for clock.Pending() < n {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestHarnessDrivesPrewarming(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// The harness clock starts at midnight
+	h := NewHarness(t, 10, time.Hour, 24*time.Hour)
+
+	remove, err := h.Manager.AddPrewarm(pool.Prewarm{
+		Schedule: "0 9 * * *",
+		Lead:     5 * time.Minute,
+		Keys:     []string{"campaigns"},
+		Workers:  4,
+	})
+	assert.NoError(t, err)
+
+	h.Advance(8*time.Hour + 54*time.Minute)
+	h.ExpectNoEvents()
+	h.Advance(time.Minute)
+	h.ExpectCreated("campaigns")
+	assert.Equal(t, 4, h.Manager.Snapshot()["campaigns"].Workers)
+
+	// The pool expires as usual after the burst, and is prewarmed again for the next day
+	h.Advance(time.Hour)
+	h.ExpectEvicted("campaigns", pool.EvictionReasonExpired)
+	h.Advance(23 * time.Hour)
+	h.ExpectCreated("campaigns")
+
+	remove()
+	h.Advance(24 * time.Hour)
+	h.ExpectEvicted("campaigns", pool.EvictionReasonExpired)
+	h.ExpectNoEvents()
+	h.Close()
+}
+
+func TestPrewarmRejectsInvalidSchedules(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	h := NewHarness(t, 10, time.Hour, 24*time.Hour)
+	_, err := h.Manager.AddPrewarm(pool.Prewarm{Schedule: "every morning"})
+	assert.Error(t, err)
+	h.Close()
+}
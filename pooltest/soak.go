@@ -0,0 +1,220 @@
+package pooltest
+
+import (
+	"fmt"
+	"math/rand"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"go.uber.org/goleak"
+)
+
+// SoakConfig configures Soak. Zero fields take the defaults noted, which are picked so that pools expire and are
+// rotated many times over during a soak.
+type SoakConfig struct {
+	// Duration is how long to soak for, a second if unset
+	Duration time.Duration
+	// Callers is how many goroutines check pools out concurrently, 8 if unset
+	Callers int
+	// Keys is how many keys the callers spread their checkouts over, 3 if unset
+	Keys int
+	// PoolSize, StalePoolExpiration and MaxPoolLifetime configure the manager, 10, 20ms and 50ms if unset
+	PoolSize            int
+	StalePoolExpiration time.Duration
+	MaxPoolLifetime     time.Duration
+	// Options are passed to the manager, e.g. to soak the options an application is deployed with
+	Options []pool.Option
+	// Seed seeds the callers' random choices, the current time if unset. It's logged, so a failing soak can be repeated
+	// with the same seed, though the interleavings also depend on scheduling.
+	Seed int64
+}
+
+// SoakReport counts what happened during a soak
+type SoakReport struct {
+	Checkouts    int64
+	Tasks        int64
+	PoolsCreated int64
+	PoolsEvicted int64
+}
+
+// Soak runs randomized interleavings of checkouts, submissions, stale pool expiry, pool rotation and disposal against a
+// manager built from cfg, using real time, and fails the test if anything leaks:
+//
+// * tasks which never execute, or pools whose queues aren't empty once every caller is done
+// * checkouts which are never released, leaving pools reserved
+// * evicted pools which are never disposed
+// * goroutines left running once the manager is disposed
+//
+// Soak returns once the manager has been disposed. Goroutines running when it's called aren't counted as leaks.
+func Soak(t testing.TB, cfg SoakConfig) SoakReport {
+	t.Helper()
+	cfg = cfg.withDefaults()
+	ignore := goleak.IgnoreCurrent()
+	t.Logf("pooltest: soaking with seed %d", cfg.Seed)
+
+	s := &soak{t: t, cfg: cfg}
+	opts := append(append([]pool.Option{}, cfg.Options...), pool.WithHooks(pool.Hooks{
+		OnPoolCreated: func(string, pool.WorkerPool) {
+			atomic.AddInt64(&s.report.PoolsCreated, 1)
+		},
+		OnPoolEvicted: func(pool.PoolEviction) {
+			atomic.AddInt64(&s.report.PoolsEvicted, 1)
+		},
+	}))
+	s.manager = pool.NewWorkerPoolManager(cfg.PoolSize, cfg.StalePoolExpiration, cfg.MaxPoolLifetime, opts...)
+
+	deadline := time.Now().Add(cfg.Duration)
+	var callers sync.WaitGroup
+	for i := 0; i < cfg.Callers; i++ {
+		callers.Add(1)
+		go func(rnd *rand.Rand) {
+			defer callers.Done()
+			for time.Now().Before(deadline) {
+				s.step(rnd)
+			}
+		}(rand.New(rand.NewSource(cfg.Seed + int64(i))))
+	}
+	callers.Wait()
+
+	s.checkSettled()
+	s.manager.Dispose()
+	if err := goleak.Find(ignore); err != nil {
+		t.Errorf("pooltest: goroutines leaked by the soak: %v", err)
+	}
+	return s.report
+}
+
+func (cfg SoakConfig) withDefaults() SoakConfig {
+	if cfg.Duration <= 0 {
+		cfg.Duration = time.Second
+	}
+	if cfg.Callers <= 0 {
+		cfg.Callers = 8
+	}
+	if cfg.Keys <= 0 {
+		cfg.Keys = 3
+	}
+	if cfg.PoolSize <= 0 {
+		cfg.PoolSize = 10
+	}
+	if cfg.StalePoolExpiration <= 0 {
+		cfg.StalePoolExpiration = 20 * time.Millisecond
+	}
+	if cfg.MaxPoolLifetime <= 0 {
+		cfg.MaxPoolLifetime = 50 * time.Millisecond
+	}
+	if cfg.Seed == 0 {
+		cfg.Seed = time.Now().UnixNano()
+	}
+	return cfg
+}
+
+// soak is the state shared by a soak's callers
+type soak struct {
+	t       testing.TB
+	cfg     SoakConfig
+	manager *pool.WorkerPoolManager
+	// Accessed atomically
+	report SoakReport
+}
+
+// Make one randomly chosen move
+func (s *soak) step(rnd *rand.Rand) {
+	switch n := rnd.Intn(10); {
+	case n < 8:
+		s.checkout(rnd)
+	case n < 9:
+		// Long enough, some of the time, for the caller's pools to expire
+		time.Sleep(time.Duration(rnd.Int63n(int64(2 * s.cfg.StalePoolExpiration))))
+	default:
+		// Shrinking rotates the cached pools, disposing them once they're released
+		s.manager.SetPoolSize(1 + rnd.Intn(s.cfg.PoolSize))
+	}
+}
+
+// Check out a random key's pool, submit some tasks and wait for them, then release it
+func (s *soak) checkout(rnd *rand.Rand) {
+	key := fmt.Sprint(rnd.Intn(s.cfg.Keys))
+	sendSize := 1 + rnd.Intn(s.cfg.PoolSize)
+	p, doneUsing := s.manager.GetPool(key, sendSize)
+	atomic.AddInt64(&s.report.Checkouts, 1)
+
+	tasks := 1 + rnd.Intn(2*sendSize)
+	var executed sync.WaitGroup
+	executed.Add(tasks)
+	for i := 0; i < tasks; i++ {
+		// Drawn here, as the caller's source isn't safe to use from the workers
+		work := time.Duration(rnd.Int63n(int64(time.Millisecond)))
+		p.Submit(func() {
+			time.Sleep(work)
+			executed.Done()
+		})
+	}
+	atomic.AddInt64(&s.report.Tasks, int64(tasks))
+	if !waitTimeout(&executed, EventTimeout) {
+		s.t.Errorf("pooltest: tasks submitted to %q didn't execute within %v", key, EventTimeout)
+	}
+
+	if rnd.Intn(4) == 0 {
+		// Releasing twice must be harmless
+		pool.Release(doneUsing)
+		pool.Release(doneUsing)
+	} else {
+		close(doneUsing)
+	}
+}
+
+// Fail the test unless, now that every caller is done, the cached pools are idle and unreserved, and every pool is
+// disposed within EventTimeout of expiring
+func (s *soak) checkSettled() {
+	// Checkouts are released asynchronously
+	deadline := time.Now().Add(EventTimeout)
+	for key, snapshot := range s.manager.Snapshot() {
+		for snapshot.Reservations > 0 && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+			snapshot = s.manager.Snapshot()[key]
+		}
+		if snapshot.Reservations > 0 {
+			s.t.Errorf("pooltest: %q's pool is still reserved %d times once every checkout was released", key,
+				snapshot.Reservations)
+		}
+		if snapshot.QueueDepth > 0 || snapshot.Executing > 0 {
+			s.t.Errorf("pooltest: %q's pool still has %d queued and %d executing tasks once every caller is done", key,
+				snapshot.QueueDepth, snapshot.Executing)
+		}
+	}
+
+	deadline = time.Now().Add(s.cfg.StalePoolExpiration + EventTimeout)
+	for time.Now().Before(deadline) && !s.disposed() {
+		time.Sleep(time.Millisecond)
+	}
+	if !s.disposed() {
+		s.t.Errorf("pooltest: %d pools were created but only %d disposed once they expired, with %d pending disposal",
+			atomic.LoadInt64(&s.report.PoolsCreated), atomic.LoadInt64(&s.report.PoolsEvicted),
+			s.manager.PendingDisposals())
+	}
+}
+
+// Whether every pool has expired and been disposed
+func (s *soak) disposed() bool {
+	return len(s.manager.Snapshot()) == 0 && s.manager.PendingDisposals() == 0 &&
+		atomic.LoadInt64(&s.report.PoolsCreated) == atomic.LoadInt64(&s.report.PoolsEvicted)
+}
+
+// Wait for wg for up to timeout, returning whether it finished
+func waitTimeout(wg *sync.WaitGroup, timeout time.Duration) bool {
+	done := make(chan bool)
+	go func() {
+		wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(timeout):
+		return false
+	}
+}
@@ -0,0 +1,57 @@
+package pooltest
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSoakFindsNoLeaks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	report := Soak(t, SoakConfig{Duration: 300 * time.Millisecond, Seed: 1})
+	assert.Greater(t, report.Checkouts, int64(0))
+	assert.Greater(t, report.Tasks, report.Checkouts-1)
+	// Pools expired or were rotated along the way
+	assert.Greater(t, report.PoolsCreated, int64(1))
+	assert.Equal(t, report.PoolsCreated, report.PoolsEvicted)
+}
+
+// errorRecorder is a testing.TB which records the errors reported to it
+type errorRecorder struct {
+	testing.TB
+	lock   *sync.Mutex
+	errors []string
+}
+
+func (r *errorRecorder) Errorf(format string, args ...interface{}) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.errors = append(r.errors, fmt.Sprintf(format, args...))
+}
+
+func TestSoakReportsLeakedGoroutines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	leaked := make(chan bool)
+	recorder := &errorRecorder{TB: t, lock: &sync.Mutex{}}
+	Soak(recorder, SoakConfig{Duration: 50 * time.Millisecond, Options: []pool.Option{
+		pool.WithHooks(pool.Hooks{
+			OnPoolCreated: func(string, pool.WorkerPool) {
+				go func() {
+					<-leaked
+				}()
+			},
+		}),
+	}})
+	close(leaked)
+
+	if assert.Len(t, recorder.errors, 1) {
+		assert.Contains(t, recorder.errors[0], "goroutines leaked by the soak")
+	}
+}
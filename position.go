@@ -0,0 +1,36 @@
+package pool
+
+import "time"
+
+// QueuePosition describes where a submission landed in its pool's queue, see SubmitWithPosition
+type QueuePosition struct {
+	// Ahead is how many tasks were queued ahead of the submission once it was enqueued. Pools which order their queues,
+	// e.g. WithPriorityQueue, may execute it before some of them.
+	Ahead int
+	// Throughput is how many tasks per second the pool has completed over the last minute
+	Throughput float64
+	// EstimatedWait is how long the tasks ahead of the submission should take to get through at that throughput. It's
+	// zero if nothing is ahead of it, or if the pool hasn't completed any tasks to estimate from.
+	EstimatedWait time.Duration
+}
+
+// SubmitWithPosition submits w like SubmitTask, and returns where it landed in the pool's queue, so that producers can
+// display progress, or give up on latency-sensitive requests which are unlikely to execute in time. Like SubmitTask, it
+// waits for room if the queue is full, and the position is taken once it's enqueued.
+func SubmitWithPosition(p WorkerPool, info TaskInfo, w Work) (QueuePosition, error) {
+	position := &QueuePosition{}
+	err := p.enqueue(task{work: w, info: info, position: position})
+	return *position, err
+}
+
+// The position of a task which has just been enqueued, which may already have been popped by a worker
+func (p *BaseWorkerPool) queuePosition(now time.Time) QueuePosition {
+	position := QueuePosition{Throughput: p.stats.completions.rate(now, p.creationTime, shortThroughputWindow)}
+	if queued := p.queue.len(); queued > 1 {
+		position.Ahead = queued - 1
+	}
+	if position.Throughput > 0 {
+		position.EstimatedWait = time.Duration(float64(position.Ahead) / position.Throughput * float64(time.Second))
+	}
+	return position
+}
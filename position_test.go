@@ -0,0 +1,61 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubmitWithPositionReportsTheTasksAhead(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	defer pm.Dispose()
+	pool, doneUsing := pm.GetPool("key", 1)
+	defer close(doneUsing)
+
+	started := make(chan bool)
+	unblock := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(4)
+	position, err := SubmitWithPosition(pool, TaskInfo{}, func() {
+		defer wg.Done()
+		started <- true
+		<-unblock
+	})
+	assert.Nil(t, err)
+	assert.Zero(t, position.EstimatedWait)
+	<-started
+
+	for ahead := 0; ahead < 3; ahead++ {
+		position, err := SubmitWithPosition(pool, TaskInfo{}, wg.Done)
+		assert.Nil(t, err)
+		assert.Equal(t, ahead, position.Ahead)
+		// Nothing has completed to estimate from
+		assert.Zero(t, position.Throughput)
+		assert.Zero(t, position.EstimatedWait)
+	}
+	close(unblock)
+	wg.Wait()
+
+	// Now the pool's throughput gives an estimate
+	unblock = make(chan bool)
+	wg.Add(3)
+	pool.Submit(func() {
+		defer wg.Done()
+		started <- true
+		<-unblock
+	})
+	<-started
+	pool.Submit(wg.Done)
+	position, err = SubmitWithPosition(pool, TaskInfo{}, wg.Done)
+	assert.Nil(t, err)
+	assert.Equal(t, 1, position.Ahead)
+	assert.Positive(t, position.Throughput)
+	assert.Positive(t, position.EstimatedWait)
+	close(unblock)
+	wg.Wait()
+}
@@ -0,0 +1,120 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// Prewarm describes pools to build ahead of a recurring burst of traffic, such as a campaign sent at 9am every
+// weekday, see AddPrewarm
+type Prewarm struct {
+	// Schedule is when the traffic arrives, as a five field cron expression - minute, hour, day of month, month and
+	// day of week - e.g. "0 9 * * 1-5". The @hourly, @daily, @weekly, @monthly and @yearly shorthands are accepted.
+	Schedule string
+	// Location is the time zone of Schedule, that of the manager's clock if unset - the local time zone for the real
+	// clock
+	Location *time.Location
+	// Lead is how long before each scheduled time the pools are built. It should be shorter than the stale pool
+	// expiration, or the pools expire before the traffic arrives.
+	Lead time.Duration
+	// Keys are the keys to build pools for
+	Keys []string
+	// Workers is how many workers to spawn in each pool, up to the pool size
+	Workers int
+	// Factory builds the pools, the factory registered for each key if unset - see RegisterFactory
+	Factory Factory
+	// OnError is called when Factory fails to build a pool. It may be nil.
+	OnError func(key string, err error)
+}
+
+// prewarmer schedules a Prewarm on the manager's clock
+type prewarmer struct {
+	manager  *WorkerPoolManager
+	prewarm  Prewarm
+	schedule *cronSchedule
+	lock     *sync.Mutex
+	timer    Timer
+	stopped  bool
+}
+
+// AddPrewarm registers prewarm with the manager, which builds its pools and spawns their workers shortly before each
+// scheduled time, and refreshes their expiration, so the first tasks of a burst don't wait for pools to start up. It
+// returns an error if prewarm's schedule can't be parsed, and otherwise a function which unregisters it.
+func (m *WorkerPoolManager) AddPrewarm(prewarm Prewarm) (remove func(), err error) {
+	schedule, err := parseCron(prewarm.Schedule)
+	if err != nil {
+		return nil, err
+	}
+	p := &prewarmer{manager: m, prewarm: prewarm, schedule: schedule, lock: &sync.Mutex{}}
+
+	m.poolReservationLock.Lock()
+	m.prewarmers[p] = true
+	m.poolReservationLock.Unlock()
+	p.lock.Lock()
+	p.scheduleAfter(m.clock.Now().Add(prewarm.Lead))
+	p.lock.Unlock()
+
+	return func() {
+		m.poolReservationLock.Lock()
+		delete(m.prewarmers, p)
+		m.poolReservationLock.Unlock()
+		p.stop()
+	}, nil
+}
+
+// Schedule the next prewarm, ahead of the first scheduled time after t. It's not thread-safe, lock above this.
+func (p *prewarmer) scheduleAfter(t time.Time) {
+	if p.stopped {
+		return
+	}
+	if p.prewarm.Location != nil {
+		t = t.In(p.prewarm.Location)
+	}
+	next := p.schedule.next(t)
+	if next.IsZero() {
+		return
+	}
+	at := next.Add(-p.prewarm.Lead)
+	p.timer = p.manager.clock.AfterFunc(at.Sub(p.manager.clock.Now()), func() {
+		// Holding the lock while building pools stops the manager from being disposed halfway through
+		p.lock.Lock()
+		defer p.lock.Unlock()
+		if !p.stopped {
+			p.run()
+			p.scheduleAfter(next)
+		}
+	})
+}
+
+func (p *prewarmer) run() {
+	for _, key := range p.prewarm.Keys {
+		_, doneUsing, err := p.manager.GetPoolWithFactory(key, p.prewarm.Workers, p.prewarm.Factory)
+		if err != nil {
+			if p.prewarm.OnError != nil {
+				p.prewarm.OnError(key, err)
+			}
+			continue
+		}
+		close(doneUsing)
+	}
+}
+
+func (p *prewarmer) stop() {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	p.stopped = true
+	if p.timer != nil {
+		p.timer.Stop()
+	}
+}
+
+// Stop every registered prewarm once the manager is disposed
+func (m *WorkerPoolManager) stopPrewarms() {
+	m.poolReservationLock.Lock()
+	prewarmers := m.prewarmers
+	m.prewarmers = make(map[*prewarmer]bool)
+	m.poolReservationLock.Unlock()
+	for p := range prewarmers {
+		p.stop()
+	}
+}
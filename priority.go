@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"container/heap"
+	"time"
+)
+
+// WithPriorityQueue makes pools execute queued tasks in order of TaskInfo.Priority, highest first, instead of in
+// submission order. Tasks of equal priority execute in submission order.
+//
+// So that a constant stream of high priority work can't starve the rest of the queue, a waiting task is boosted by one
+// priority level for every aging interval it spends queued: a task submitted at priority 0 is ranked alongside fresh
+// priority 3 submissions after waiting three intervals, and ahead of them after that. Zero aging disables boosting,
+// letting lower priority tasks wait for as long as higher priority ones keep arriving.
+//
+// It replaces WithDeadlineQueue and WithSubQueues if they're passed too, and is replaced by them if they're passed
+// after it.
+func WithPriorityQueue(aging time.Duration) Option {
+	return func(o *options) {
+		o.rankTasks = priorityRank(aging)
+		o.subQueues = nil
+		o.queueOrder = "priority queue"
+	}
+}
+
+// A task's aged priority is Priority + (now - enqueued) / aging, and now is the same for every task being compared, so
+// ranking by enqueued - Priority * aging orders tasks the same way without having to re-rank them as they wait
+func priorityRank(aging time.Duration) func(t task) int64 {
+	if aging <= 0 {
+		return func(t task) int64 {
+			return -int64(t.info.Priority)
+		}
+	}
+	return func(t task) int64 {
+		return t.enqueued.UnixNano() - int64(t.info.Priority)*int64(aging)
+	}
+}
+
+func newRankedQueue(capacity int, rank func(t task) int64) *condQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return newBufferedCondQueue(capacity, &rankedBuffer{rank: rank})
+}
+
+// rankedBuffer is a taskBuffer which pops the lowest ranked task first
+type rankedBuffer struct {
+	rank    func(t task) int64
+	entries []rankedTask
+	// Submission order, breaking ties between equally ranked tasks
+	seq uint64
+}
+
+type rankedTask struct {
+	t task
+	// Lower ranks are popped first
+	rank int64
+	seq  uint64
+}
+
+func (b *rankedBuffer) put(t task) {
+	b.seq++
+	heap.Push(b, rankedTask{t: t, rank: b.rank(t), seq: b.seq})
+}
+
+func (b *rankedBuffer) take() task {
+	return heap.Pop(b).(rankedTask).t
+}
+
+func (b *rankedBuffer) len() int {
+	return len(b.entries)
+}
+
+// heap.Interface
+
+func (b *rankedBuffer) Len() int {
+	return len(b.entries)
+}
+
+func (b *rankedBuffer) Less(i, j int) bool {
+	if b.entries[i].rank != b.entries[j].rank {
+		return b.entries[i].rank < b.entries[j].rank
+	}
+	return b.entries[i].seq < b.entries[j].seq
+}
+
+func (b *rankedBuffer) Swap(i, j int) {
+	b.entries[i], b.entries[j] = b.entries[j], b.entries[i]
+}
+
+func (b *rankedBuffer) Push(x interface{}) {
+	b.entries = append(b.entries, x.(rankedTask))
+}
+
+func (b *rankedBuffer) Pop() interface{} {
+	last := len(b.entries) - 1
+	entry := b.entries[last]
+	b.entries[last] = rankedTask{}
+	b.entries = b.entries[:last]
+	return entry
+}
@@ -0,0 +1,105 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func popLabels(q taskQueue) []string {
+	var labels []string
+	for q.len() > 0 {
+		t, _ := q.pop(nil)
+		labels = append(labels, t.info.Label)
+	}
+	return labels
+}
+
+func TestPriorityQueueOrdersByPriority(t *testing.T) {
+	q := newRankedQueue(10, priorityRank(0))
+	now := time.Now()
+	for i, priority := range []int{0, 2, 1, 2, 0} {
+		label := string(rune('a' + i))
+		q.push(task{info: TaskInfo{Label: label, Priority: priority}, enqueued: now.Add(time.Duration(i))})
+	}
+	assert.Equal(t, []string{"b", "d", "c", "a", "e"}, popLabels(q))
+}
+
+func TestPriorityQueueAgesWaitingTasks(t *testing.T) {
+	q := newRankedQueue(10, priorityRank(time.Second))
+	start := time.Now()
+	q.push(task{info: TaskInfo{Label: "old low", Priority: 0}, enqueued: start})
+	// Three intervals later, the old task ranks alongside fresh priority 3 tasks, and behind anything higher
+	q.push(task{info: TaskInfo{Label: "new 3", Priority: 3}, enqueued: start.Add(3 * time.Second)})
+	q.push(task{info: TaskInfo{Label: "new 4", Priority: 4}, enqueued: start.Add(3 * time.Second)})
+	q.push(task{info: TaskInfo{Label: "new 2", Priority: 2}, enqueued: start.Add(3 * time.Second)})
+	assert.Equal(t, []string{"new 4", "old low", "new 3", "new 2"}, popLabels(q))
+}
+
+// steppedClock reads a manually advanced time, for controlling task ages
+type steppedClock struct {
+	realClock
+	lock *sync.Mutex
+	now  time.Time
+}
+
+func (c *steppedClock) Now() time.Time {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.now
+}
+
+func (c *steppedClock) Advance(d time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.now = c.now.Add(d)
+}
+
+func TestPriorityQueuePreventsStarvation(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	pm := NewWorkerPoolManager(1, time.Hour, 10*time.Hour, WithClock(clock), WithPriorityQueue(time.Second),
+		WithQueueCapacity(10),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	// Hold the only worker while the queue fills up
+	release := make(chan bool)
+	started := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	submit := func(label string, priority int) {
+		wg.Add(1)
+		assert.NoError(t, SubmitTask(pool, TaskInfo{Label: label, Priority: priority}, func() {
+			lock.Lock()
+			order = append(order, label)
+			lock.Unlock()
+			wg.Done()
+		}))
+	}
+	submit("low", 0)
+	for i := 0; i < 5; i++ {
+		clock.Advance(time.Second)
+		submit("high", 2)
+	}
+	close(release)
+	wg.Wait()
+
+	// The low priority task has aged to priority 2 by the time the second high priority task arrives, so it goes ahead
+	// of that one and everything after it
+	assert.Equal(t, []string{"high", "low", "high", "high", "high", "high"}, order)
+
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -0,0 +1,274 @@
+package pool
+
+import "sync"
+
+// taskQueue holds a pool's submitted tasks until workers pick them up
+type taskQueue interface {
+	// push adds t, blocking while the queue is full, and returns false if the queue is closed first
+	push(t task) bool
+	// tryPush adds t if there's room for it without blocking
+	tryPush(t task) bool
+	// pop removes the oldest task, blocking while the queue is empty, and returns false if the queue is closed or stop
+	// is closed first
+	pop(stop <-chan bool) (task, bool)
+	// tryPop removes the oldest task if there is one, without blocking, and returns false if the queue is empty or
+	// closed
+	tryPop() (task, bool)
+	len() int
+	cap() int
+	// rework passes the queued tasks to f in the order they'd be popped, and replaces them with those f returns, unless
+	// the queue can't be looked into, in which case f isn't called
+	rework(f func(tasks []task) []task)
+	// close wakes up everything blocked on the queue, once the pool is disposed
+	close()
+}
+
+// chanQueue is the default taskQueue, a buffered channel which closes along with the pool's disposed channel
+type chanQueue struct {
+	tasks  chan task
+	closed <-chan bool
+}
+
+func newChanQueue(capacity int, closed <-chan bool) *chanQueue {
+	return &chanQueue{tasks: make(chan task, capacity), closed: closed}
+}
+
+func (q *chanQueue) push(t task) bool {
+	select {
+	case q.tasks <- t:
+		return true
+	case <-q.closed:
+		return false
+	}
+}
+
+func (q *chanQueue) tryPush(t task) bool {
+	select {
+	case q.tasks <- t:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *chanQueue) pop(stop <-chan bool) (task, bool) {
+	// Once closed, a select would pick between closing and queued tasks at random
+	if isClosed(q.closed) {
+		return task{}, false
+	}
+	select {
+	case t := <-q.tasks:
+		return t, true
+	case <-stop:
+		return task{}, false
+	case <-q.closed:
+		return task{}, false
+	}
+}
+
+func (q *chanQueue) tryPop() (task, bool) {
+	if isClosed(q.closed) {
+		return task{}, false
+	}
+	select {
+	case t := <-q.tasks:
+		return t, true
+	default:
+		return task{}, false
+	}
+}
+
+func (q *chanQueue) len() int {
+	return len(q.tasks)
+}
+
+func (q *chanQueue) cap() int {
+	return cap(q.tasks)
+}
+
+// Channels can't be looked into without taking their tasks out, which would reorder them against tasks pushed
+// meanwhile, and putting them back could block on a full channel, so a chanQueue is never reworked
+func (q *chanQueue) rework(func(tasks []task) []task) {}
+
+func (q *chanQueue) close() {}
+
+// WithCondvarDispatch is an experimental alternative to the default channel-based dispatch of tasks to workers,
+// where workers wait on a condition variable instead. Handing over a task takes a single mutex round trip rather than
+// a channel select, which can lower dispatch latency for microsecond-scale tasks - see BenchmarkDispatchLatency to
+// compare the two on a given machine.
+func WithCondvarDispatch() Option {
+	return func(o *options) {
+		o.condvarDispatch = true
+	}
+}
+
+// condQueue is a taskQueue guarded by a mutex and condition variables, with its tasks stored in a taskBuffer
+type condQueue struct {
+	lock     *sync.Mutex
+	notEmpty *sync.Cond
+	notFull  *sync.Cond
+	tasks    taskBuffer
+	capacity int
+	closed   bool
+}
+
+// taskBuffer stores a condQueue's tasks, deciding the order they're popped in
+type taskBuffer interface {
+	put(t task)
+	take() task
+	len() int
+}
+
+func newCondQueue(capacity int) *condQueue {
+	if capacity < 1 {
+		// Unlike channels, the buffer needs room for at least one task to hand it over
+		capacity = 1
+	}
+	return newBufferedCondQueue(capacity, &ringBuffer{tasks: make([]task, capacity)})
+}
+
+func newBufferedCondQueue(capacity int, tasks taskBuffer) *condQueue {
+	lock := &sync.Mutex{}
+	return &condQueue{
+		lock:     lock,
+		notEmpty: sync.NewCond(lock),
+		notFull:  sync.NewCond(lock),
+		tasks:    tasks,
+		capacity: capacity,
+	}
+}
+
+func (q *condQueue) push(t task) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for q.tasks.len() == q.capacity && !q.closed {
+		q.notFull.Wait()
+	}
+	if q.closed {
+		return false
+	}
+	q.pushLocked(t)
+	return true
+}
+
+func (q *condQueue) tryPush(t task) bool {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.tasks.len() == q.capacity || q.closed {
+		return false
+	}
+	q.pushLocked(t)
+	return true
+}
+
+func (q *condQueue) pushLocked(t task) {
+	q.tasks.put(t)
+	q.notEmpty.Signal()
+}
+
+func (q *condQueue) pop(stop <-chan bool) (task, bool) {
+	if stop != nil {
+		// Condition variables can't wait on a channel, so wake everyone up once stop closes
+		popped := make(chan bool)
+		defer close(popped)
+		go func() {
+			select {
+			case <-stop:
+				q.lock.Lock()
+				q.notEmpty.Broadcast()
+				q.lock.Unlock()
+			case <-popped:
+			}
+		}()
+	}
+
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	for q.tasks.len() == 0 && !q.closed && !isClosed(stop) {
+		q.notEmpty.Wait()
+	}
+	if q.closed || q.tasks.len() == 0 {
+		return task{}, false
+	}
+	t := q.tasks.take()
+	q.notFull.Signal()
+	return t, true
+}
+
+func (q *condQueue) tryPop() (task, bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	if q.closed || q.tasks.len() == 0 {
+		return task{}, false
+	}
+	t := q.tasks.take()
+	q.notFull.Signal()
+	return t, true
+}
+
+func (q *condQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.tasks.len()
+}
+
+func (q *condQueue) cap() int {
+	return q.capacity
+}
+
+func (q *condQueue) rework(f func(tasks []task) []task) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	tasks := make([]task, 0, q.tasks.len())
+	for q.tasks.len() > 0 {
+		tasks = append(tasks, q.tasks.take())
+	}
+	kept := f(tasks)
+	for _, t := range kept {
+		q.tasks.put(t)
+	}
+	if len(kept) < len(tasks) {
+		q.notFull.Broadcast()
+	}
+}
+
+func (q *condQueue) close() {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	q.closed = true
+	q.notEmpty.Broadcast()
+	q.notFull.Broadcast()
+}
+
+// ringBuffer is a fixed size FIFO taskBuffer
+type ringBuffer struct {
+	tasks []task
+	head  int
+	count int
+}
+
+func (b *ringBuffer) put(t task) {
+	b.tasks[(b.head+b.count)%len(b.tasks)] = t
+	b.count++
+}
+
+func (b *ringBuffer) take() task {
+	t := b.tasks[b.head]
+	b.tasks[b.head] = task{}
+	b.head = (b.head + 1) % len(b.tasks)
+	b.count--
+	return t
+}
+
+func (b *ringBuffer) len() int {
+	return b.count
+}
+
+func isClosed(c <-chan bool) bool {
+	select {
+	case <-c:
+		return true
+	default:
+		return false
+	}
+}
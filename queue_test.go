@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func testQueues() map[string]func(capacity int, closed chan bool) taskQueue {
+	return map[string]func(int, chan bool) taskQueue{
+		"channel": func(capacity int, closed chan bool) taskQueue {
+			return newChanQueue(capacity, closed)
+		},
+		"condvar": func(capacity int, _ chan bool) taskQueue {
+			return newCondQueue(capacity)
+		},
+		"priority": func(capacity int, _ chan bool) taskQueue {
+			return newRankedQueue(capacity, priorityRank(time.Second))
+		},
+	}
+}
+
+func TestQueuesAreFIFOAndBounded(t *testing.T) {
+	for name, newQueue := range testQueues() {
+		t.Run(name, func(t *testing.T) {
+			q := newQueue(2, make(chan bool))
+			assert.True(t, q.tryPush(task{info: TaskInfo{Label: "first"}}))
+			assert.True(t, q.push(task{info: TaskInfo{Label: "second"}}))
+			assert.False(t, q.tryPush(task{}))
+			assert.Equal(t, 2, q.len())
+			assert.Equal(t, 2, q.cap())
+
+			first, ok := q.pop(nil)
+			assert.True(t, ok)
+			assert.Equal(t, "first", first.info.Label)
+			second, _ := q.pop(nil)
+			assert.Equal(t, "second", second.info.Label)
+			assert.Equal(t, 0, q.len())
+		})
+	}
+}
+
+func TestQueuesWakeWaitersOnStopAndClose(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	for name, newQueue := range testQueues() {
+		t.Run(name, func(t *testing.T) {
+			closed := make(chan bool)
+			q := newQueue(1, closed)
+
+			stop := make(chan bool)
+			stopped := make(chan bool)
+			go func() {
+				_, ok := q.pop(stop)
+				stopped <- ok
+			}()
+			time.Sleep(5 * time.Millisecond)
+			close(stop)
+			assert.False(t, <-stopped)
+
+			assert.True(t, q.push(task{}))
+			pushed := make(chan bool)
+			go func() {
+				pushed <- q.push(task{})
+			}()
+			time.Sleep(5 * time.Millisecond)
+			close(closed)
+			q.close()
+			assert.False(t, <-pushed)
+			_, ok := q.pop(nil)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestCondvarDispatchRunsTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(4, time.Second, time.Hour, WithCondvarDispatch())
+	pool, doneUsing := pm.GetPool("key", 4)
+
+	var wg sync.WaitGroup
+	wg.Add(100)
+	for i := 0; i < 100; i++ {
+		pool.Submit(wg.Done)
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(100), pm.Snapshot()["key"].Completed)
+
+	close(doneUsing)
+	pm.Dispose()
+}
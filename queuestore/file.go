@@ -0,0 +1,234 @@
+package queuestore
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"sync"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// The journal is compacted once it holds at least this many acknowledged tasks, and more of them than pending tasks
+const minCompactAcks = 1024
+
+// Option configures a File store
+type Option func(*File)
+
+// WithoutSync stops File syncing each append to disk. Appends are faster, but tasks journaled just before the machine
+// crashes may be lost.
+func WithoutSync() Option {
+	return func(f *File) {
+		f.noSync = true
+	}
+}
+
+// File is a pool.QueueStore which journals tasks to a file, one JSON record per line. The journal is rewritten
+// without the acknowledged tasks when it's opened, and again whenever they come to outnumber the pending ones.
+//
+// Each append is synced to disk before it returns, unless WithoutSync is used. Acknowledgements aren't, as losing
+// one in a crash only means its task is executed again.
+type File struct {
+	path   string
+	noSync bool
+
+	lock   *sync.Mutex
+	file   *os.File
+	nextID uint64
+	tasks  map[uint64]pool.DurableTask
+	acked  int
+}
+
+var _ pool.QueueStore = (*File)(nil)
+
+// A line of the journal
+type record struct {
+	Ack            bool   `json:"ack,omitempty"`
+	ID             uint64 `json:"id"`
+	Key            string `json:"key,omitempty"`
+	Type           string `json:"type,omitempty"`
+	IdempotencyKey string `json:"idempotency_key,omitempty"`
+	Payload        []byte `json:"payload,omitempty"`
+}
+
+func taskRecord(task pool.DurableTask) record {
+	return record{
+		ID: task.ID, Key: task.Key, Type: task.Type, IdempotencyKey: task.IdempotencyKey, Payload: task.Payload,
+	}
+}
+
+// OpenFile opens the journal at path, creating it if it doesn't exist yet
+func OpenFile(path string, opts ...Option) (*File, error) {
+	f := &File{path: path, lock: &sync.Mutex{}, tasks: make(map[uint64]pool.DurableTask)}
+	for _, opt := range opts {
+		opt(f)
+	}
+	if err := f.load(); err != nil {
+		return nil, err
+	}
+	if err := f.compact(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// Append journals task under the next ID
+func (f *File) Append(task pool.DurableTask) (uint64, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	task.ID = f.nextID + 1
+	err := f.write(taskRecord(task))
+	if err == nil && !f.noSync {
+		err = f.file.Sync()
+	}
+	if err != nil {
+		return 0, err
+	}
+	f.nextID = task.ID
+	f.tasks[task.ID] = task
+	return task.ID, nil
+}
+
+// Ack journals that the task stored under id has executed
+func (f *File) Ack(id uint64) error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return os.ErrClosed
+	}
+	if _, ok := f.tasks[id]; !ok {
+		return nil
+	}
+	if err := f.write(record{Ack: true, ID: id}); err != nil {
+		return err
+	}
+	delete(f.tasks, id)
+	f.acked++
+	if f.acked >= minCompactAcks && f.acked > len(f.tasks) {
+		return f.compactLocked()
+	}
+	return nil
+}
+
+// Replay returns the journaled tasks which haven't been acknowledged, oldest first
+func (f *File) Replay() ([]pool.DurableTask, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return sortedTasks(f.tasks), nil
+}
+
+// Close closes the journal
+func (f *File) Close() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// Read the journal into tasks
+func (f *File) load() error {
+	file, err := os.Open(f.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// An unterminated last line was torn by a crash part way through appending it, so was never acknowledged
+			// as journaled
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var r record
+		if err := json.Unmarshal(line, &r); err != nil {
+			return err
+		}
+		if r.ID > f.nextID {
+			f.nextID = r.ID
+		}
+		if r.Ack {
+			delete(f.tasks, r.ID)
+		} else {
+			f.tasks[r.ID] = pool.DurableTask{
+				ID: r.ID, Key: r.Key, Type: r.Type, IdempotencyKey: r.IdempotencyKey, Payload: r.Payload,
+			}
+		}
+	}
+}
+
+func (f *File) compact() error {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return f.compactLocked()
+}
+
+// Rewrite the journal with only the pending tasks, replacing the old one atomically. It's not thread-safe, lock above
+// this.
+func (f *File) compactLocked() error {
+	tmp := f.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	writer := bufio.NewWriter(file)
+	encoder := json.NewEncoder(writer)
+	for _, task := range sortedTasks(f.tasks) {
+		if err := encoder.Encode(taskRecord(task)); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	// The last ID is kept even once its task is acknowledged, so that IDs aren't reused
+	if _, ok := f.tasks[f.nextID]; !ok && f.nextID > 0 {
+		if err := encoder.Encode(record{Ack: true, ID: f.nextID}); err != nil {
+			_ = file.Close()
+			return err
+		}
+	}
+	if err := writer.Flush(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := file.Sync(); err != nil {
+		_ = file.Close()
+		return err
+	}
+	if err := os.Rename(tmp, f.path); err != nil {
+		_ = file.Close()
+		return err
+	}
+
+	if f.file != nil {
+		_ = f.file.Close()
+	}
+	f.file = file
+	f.acked = 0
+	return nil
+}
+
+// Append a record to the journal. It's not thread-safe, lock above this.
+func (f *File) write(r record) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = f.file.Write(append(line, '\n'))
+	return err
+}
@@ -0,0 +1,60 @@
+// Package queuestore provides reference implementations of pool.QueueStore, for use with pool.WithDurableTasks:
+// Memory, which only survives restarts of the pools within a process, and File, which journals tasks to disk.
+package queuestore
+
+import (
+	"sort"
+	"sync"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// Memory is a pool.QueueStore which keeps tasks in memory, e.g. for tests
+type Memory struct {
+	lock   *sync.Mutex
+	nextID uint64
+	tasks  map[uint64]pool.DurableTask
+}
+
+var _ pool.QueueStore = (*Memory)(nil)
+
+// NewMemory builds an empty Memory store
+func NewMemory() *Memory {
+	return &Memory{lock: &sync.Mutex{}, tasks: make(map[uint64]pool.DurableTask)}
+}
+
+// Append stores task under the next ID
+func (m *Memory) Append(task pool.DurableTask) (uint64, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.nextID++
+	task.ID = m.nextID
+	m.tasks[task.ID] = task
+	return task.ID, nil
+}
+
+// Ack removes the task stored under id
+func (m *Memory) Ack(id uint64) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	delete(m.tasks, id)
+	return nil
+}
+
+// Replay returns the stored tasks, oldest first
+func (m *Memory) Replay() ([]pool.DurableTask, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return sortedTasks(m.tasks), nil
+}
+
+func sortedTasks(tasks map[uint64]pool.DurableTask) []pool.DurableTask {
+	sorted := make([]pool.DurableTask, 0, len(tasks))
+	for _, task := range tasks {
+		sorted = append(sorted, task)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		return sorted[i].ID < sorted[j].ID
+	})
+	return sorted
+}
@@ -0,0 +1,134 @@
+package queuestore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+func TestStoresReplayUnacknowledgedTasksInOrder(t *testing.T) {
+	file, err := OpenFile(filepath.Join(t.TempDir(), "journal"), WithoutSync())
+	assert.Nil(t, err)
+	defer file.Close()
+
+	for name, store := range map[string]pool.QueueStore{"memory": NewMemory(), "file": file} {
+		t.Run(name, func(t *testing.T) {
+			first, err := store.Append(pool.DurableTask{Key: "app-1", Type: "send", Payload: []byte("a")})
+			assert.Nil(t, err)
+			second, _ := store.Append(pool.DurableTask{Key: "app-2", Type: "send", Payload: []byte("b")})
+			third, _ := store.Append(pool.DurableTask{Key: "app-1", Type: "send"})
+			assert.Equal(t, []uint64{1, 2, 3}, []uint64{first, second, third})
+			assert.Nil(t, store.Ack(second))
+			assert.Nil(t, store.Ack(second))
+
+			tasks, err := store.Replay()
+			assert.Nil(t, err)
+			assert.Equal(t, []pool.DurableTask{
+				{ID: 1, Key: "app-1", Type: "send", Payload: []byte("a")},
+				{ID: 3, Key: "app-1", Type: "send"},
+			}, tasks)
+		})
+	}
+}
+
+func TestFileRecoversItsJournal(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	file, err := OpenFile(path)
+	assert.Nil(t, err)
+	_, _ = file.Append(pool.DurableTask{Key: "app-1", Type: "send", IdempotencyKey: "order-7", Payload: []byte("a")})
+	acked, _ := file.Append(pool.DurableTask{Key: "app-1", Type: "send", Payload: []byte("b")})
+	assert.Nil(t, file.Ack(acked))
+	assert.Nil(t, file.Close())
+	_, err = file.Append(pool.DurableTask{})
+	assert.Equal(t, os.ErrClosed, err)
+
+	// A crash part way through appending leaves a torn last line
+	journal, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0)
+	assert.Nil(t, err)
+	_, _ = journal.WriteString(`{"id":3,"key":"app-1","ty`)
+	assert.Nil(t, journal.Close())
+
+	file, err = OpenFile(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	tasks, _ := file.Replay()
+	assert.Equal(t, []pool.DurableTask{
+		{ID: 1, Key: "app-1", Type: "send", IdempotencyKey: "order-7", Payload: []byte("a")},
+	}, tasks)
+	// IDs carry on from before the restart, and the journal was compacted
+	id, _ := file.Append(pool.DurableTask{Key: "app-2", Type: "send"})
+	assert.Equal(t, uint64(3), id)
+	contents, _ := os.ReadFile(path)
+	assert.Equal(t, `{"id":1,"key":"app-1","type":"send","idempotency_key":"order-7","payload":"YQ=="}
+{"ack":true,"id":2}
+{"id":3,"key":"app-2","type":"send"}
+`, string(contents))
+}
+
+func TestFileCompactsOnceAcknowledgementsPileUp(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "journal")
+	file, err := OpenFile(path, WithoutSync())
+	assert.Nil(t, err)
+	defer file.Close()
+
+	for i := 0; i < minCompactAcks; i++ {
+		id, _ := file.Append(pool.DurableTask{Key: "app-1", Type: "send"})
+		assert.Nil(t, file.Ack(id))
+	}
+	contents, _ := os.ReadFile(path)
+	assert.Equal(t, "{\"ack\":true,\"id\":1024}\n", string(contents))
+}
+
+func TestFileLetsRestartedManagersFinishDurableTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	path := filepath.Join(t.TempDir(), "journal")
+	file, err := OpenFile(path)
+	assert.Nil(t, err)
+	stuck := make(chan bool)
+	crashed := pool.NewWorkerPoolManager(1, time.Hour, time.Hour, pool.WithDurableTasks(pool.DurableTasks{
+		Store: file,
+		Handlers: map[string]pool.DurableHandler{
+			"send": func(string, []byte) error {
+				<-stuck
+				return nil
+			},
+		},
+	}))
+	p, doneUsing := crashed.GetPool("app-42", 1)
+	assert.Nil(t, pool.SubmitDurable(p, "send", []byte("hello")))
+	assert.Nil(t, file.Close())
+
+	file, err = OpenFile(path)
+	assert.Nil(t, err)
+	defer file.Close()
+	executed := make(chan string, 1)
+	restarted := pool.NewWorkerPoolManager(1, time.Hour, time.Hour, pool.WithDurableTasks(pool.DurableTasks{
+		Store: file,
+		Handlers: map[string]pool.DurableHandler{
+			"send": func(key string, payload []byte) error {
+				executed <- key + ":" + string(payload)
+				return nil
+			},
+		},
+	}))
+	replayed, err := restarted.ReplayDurableTasks()
+	assert.Nil(t, err)
+	assert.Equal(t, 1, replayed)
+	assert.Equal(t, "app-42:hello", <-executed)
+	assert.Eventually(t, func() bool {
+		tasks, _ := file.Replay()
+		return len(tasks) == 0
+	}, time.Second, time.Millisecond)
+	restarted.Dispose()
+
+	close(stuck)
+	close(doneUsing)
+	crashed.Dispose()
+}
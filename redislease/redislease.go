@@ -0,0 +1,107 @@
+// Package redislease provides a pool.LeaseBackend storing key ownership leases in Redis, for use with pool.WithLeases.
+// Each lease is a Redis key holding its owner, which expires along with the lease.
+package redislease
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/Appboy/worker-pools/internal/resp"
+)
+
+// DefaultKeyPrefix is prepended to pool keys to build lease keys, unless changed with WithKeyPrefix
+const DefaultKeyPrefix = "worker-pools:lease:"
+
+// Renews the lease if the owner already holds it, and otherwise takes it if it's free
+const acquireScript = `local current = redis.call('GET', KEYS[1])
+if current == ARGV[1] then
+  redis.call('PEXPIRE', KEYS[1], ARGV[2])
+  return 1
+end
+if not current then
+  redis.call('SET', KEYS[1], ARGV[1], 'PX', ARGV[2])
+  return 1
+end
+return 0`
+
+// Deletes the lease only if the owner holds it
+const releaseScript = `if redis.call('GET', KEYS[1]) == ARGV[1] then
+  return redis.call('DEL', KEYS[1])
+end
+return 0`
+
+// Option configures a Backend
+type Option func(*Backend)
+
+// WithPassword authenticates with password when connecting, plus username for Redis 6 ACLs if it isn't empty
+func WithPassword(username string, password string) Option {
+	return func(b *Backend) {
+		b.config.Username = username
+		b.config.Password = password
+	}
+}
+
+// WithDatabase selects the numbered database to store leases in
+func WithDatabase(db int) Option {
+	return func(b *Backend) {
+		b.config.DB = db
+	}
+}
+
+// WithKeyPrefix sets the prefix of lease keys, so deployments sharing a Redis server don't share leases
+func WithKeyPrefix(prefix string) Option {
+	return func(b *Backend) {
+		b.prefix = prefix
+	}
+}
+
+// Backend is a pool.LeaseBackend backed by a Redis server. It keeps a single connection, which is opened when it's
+// first needed and reopened after errors, so it's safe to build before Redis is reachable.
+type Backend struct {
+	config resp.Config
+	prefix string
+	conn   *resp.Conn
+}
+
+var _ pool.LeaseBackend = (*Backend)(nil)
+
+// New builds a Backend for the Redis server at addr, e.g. "127.0.0.1:6379"
+func New(addr string, opts ...Option) *Backend {
+	b := &Backend{
+		config: resp.Config{Name: "redislease", Addr: addr},
+		prefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(b)
+	}
+	b.conn = resp.NewConn(b.config)
+	return b
+}
+
+// Acquire takes or renews owner's lease on key
+func (b *Backend) Acquire(ctx context.Context, key string, owner string, ttl time.Duration) (bool, error) {
+	millis := strconv.FormatInt(ttl.Milliseconds(), 10)
+	reply, err := b.conn.Do(ctx, "EVAL", acquireScript, "1", b.prefix+key, owner, millis)
+	if err != nil {
+		return false, err
+	}
+	acquired, ok := reply.(int64)
+	if !ok {
+		return false, fmt.Errorf("redislease: unexpected reply %v", reply)
+	}
+	return acquired == 1, nil
+}
+
+// Release gives up owner's lease on key
+func (b *Backend) Release(ctx context.Context, key string, owner string) error {
+	_, err := b.conn.Do(ctx, "EVAL", releaseScript, "1", b.prefix+key, owner)
+	return err
+}
+
+// Close closes the connection to Redis, if it's open
+func (b *Backend) Close() error {
+	return b.conn.Close()
+}
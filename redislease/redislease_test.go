@@ -0,0 +1,193 @@
+package redislease
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+
+	"github.com/Appboy/worker-pools/internal/resp"
+)
+
+// fakeRedis serves the commands a Backend sends, keeping leases in memory
+type fakeRedis struct {
+	listener net.Listener
+	lock     sync.Mutex
+	owners   map[string]string
+	expiry   map[string]time.Time
+	commands []string
+	wg       sync.WaitGroup
+}
+
+func listen(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeRedis{listener: listener, owners: make(map[string]string), expiry: make(map[string]time.Time)}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.wg.Add(1)
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// Stop listening, and wait for clients to disconnect
+func (f *fakeRedis) close() {
+	_ = f.listener.Close()
+	f.wg.Wait()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer f.wg.Done()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		request, err := resp.ReadReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range request.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		if _, err := conn.Write([]byte(f.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commands = append(f.commands, args[0])
+	switch args[0] {
+	case "AUTH":
+		if args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "EVAL":
+		key, owner := args[3], args[4]
+		if time.Now().After(f.expiry[key]) {
+			delete(f.owners, key)
+		}
+		current, held := f.owners[key]
+		if args[1] == releaseScript {
+			if current != owner {
+				return ":0\r\n"
+			}
+			delete(f.owners, key)
+			return ":1\r\n"
+		}
+		if held && current != owner {
+			return ":0\r\n"
+		}
+		millis, _ := strconv.Atoi(args[5])
+		f.owners[key] = owner
+		f.expiry[key] = time.Now().Add(time.Duration(millis) * time.Millisecond)
+		return ":1\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (f *fakeRedis) received() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestBackendAcquiresRenewsAndReleasesLeases(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+	ctx := context.Background()
+	first := New(server.listener.Addr().String(), WithPassword("", "secret"), WithDatabase(2))
+	second := New(server.listener.Addr().String(), WithPassword("", "secret"), WithDatabase(2))
+	defer first.Close()
+	defer second.Close()
+
+	acquired, err := first.Acquire(ctx, "app-42", "first", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	acquired, err = first.Acquire(ctx, "app-42", "first", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+	acquired, err = second.Acquire(ctx, "app-42", "second", time.Minute)
+	assert.Nil(t, err)
+	assert.False(t, acquired)
+
+	// Releasing a lease which isn't held leaves it alone
+	assert.Nil(t, second.Release(ctx, "app-42", "second"))
+	acquired, _ = second.Acquire(ctx, "app-42", "second", time.Minute)
+	assert.False(t, acquired)
+
+	assert.Nil(t, first.Release(ctx, "app-42", "first"))
+	acquired, err = second.Acquire(ctx, "app-42", "second", time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, acquired)
+
+	assert.Equal(t, []string{"AUTH", "SELECT", "EVAL", "EVAL", "AUTH", "SELECT", "EVAL"}, server.received()[:7])
+	server.lock.Lock()
+	assert.Equal(t, "second", server.owners[DefaultKeyPrefix+"app-42"])
+	server.lock.Unlock()
+}
+
+func TestBackendLeasesExpire(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+	ctx := context.Background()
+	b := New(server.listener.Addr().String(), WithKeyPrefix("leases:"))
+	defer b.Close()
+
+	acquired, _ := b.Acquire(ctx, "app-42", "first", 10*time.Millisecond)
+	assert.True(t, acquired)
+	acquired, _ = b.Acquire(ctx, "app-42", "second", time.Minute)
+	assert.False(t, acquired)
+	time.Sleep(20 * time.Millisecond)
+	acquired, _ = b.Acquire(ctx, "app-42", "second", time.Minute)
+	assert.True(t, acquired)
+
+	server.lock.Lock()
+	assert.Equal(t, "second", server.owners["leases:app-42"])
+	server.lock.Unlock()
+}
+
+func TestBackendReportsErrorsAndReconnects(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+	ctx := context.Background()
+	b := New(server.listener.Addr().String(), WithPassword("admin", "wrong"))
+	defer b.Close()
+
+	_, err := b.Acquire(ctx, "app-42", "first", time.Minute)
+	assert.EqualError(t, err, "redislease: WRONGPASS invalid password")
+	_, err = b.Acquire(ctx, "app-42", "first", time.Minute)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"AUTH", "AUTH"}, server.received())
+
+	unreachable := New("127.0.0.1:1")
+	_, err = unreachable.Acquire(ctx, "app-42", "first", time.Minute)
+	assert.NotNil(t, err)
+	assert.Nil(t, unreachable.Close())
+}
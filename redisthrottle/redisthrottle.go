@@ -0,0 +1,97 @@
+// Package redisthrottle provides a pool.ThrottleStore keeping throttle state in Redis, for use with
+// pool.WithThrottleStore, so replicas share each key's rate limit. Each key's throttle is a Redis key holding its next
+// start slot, which expires shortly after the slot passes.
+package redisthrottle
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/Appboy/worker-pools/internal/resp"
+)
+
+// DefaultKeyPrefix is prepended to pool keys to build throttle keys, unless changed with WithKeyPrefix
+const DefaultKeyPrefix = "worker-pools:throttle:"
+
+// Reserves the next start slot, in microseconds, and advances the key past it. Idle time isn't banked, so the slot is
+// no earlier than now. The key outlives its slot by a second, so it isn't dropped while starts are paced off it.
+const takeScript = `local slot = tonumber(redis.call('GET', KEYS[1]) or '0')
+local now = tonumber(ARGV[1])
+if slot < now then
+  slot = now
+end
+local next = slot + tonumber(ARGV[2])
+redis.call('SET', KEYS[1], next, 'PX', math.ceil((next - now) / 1000) + 1000)
+return slot`
+
+// Option configures a Store
+type Option func(*Store)
+
+// WithPassword authenticates with password when connecting, plus username for Redis 6 ACLs if it isn't empty
+func WithPassword(username string, password string) Option {
+	return func(s *Store) {
+		s.config.Username = username
+		s.config.Password = password
+	}
+}
+
+// WithDatabase selects the numbered database to store throttles in
+func WithDatabase(db int) Option {
+	return func(s *Store) {
+		s.config.DB = db
+	}
+}
+
+// WithKeyPrefix sets the prefix of throttle keys, so deployments sharing a Redis server don't share rate limits
+func WithKeyPrefix(prefix string) Option {
+	return func(s *Store) {
+		s.prefix = prefix
+	}
+}
+
+// Store is a pool.ThrottleStore backed by a Redis server. It keeps a single connection, which is opened when it's
+// first needed and reopened after errors, so it's safe to build before Redis is reachable.
+type Store struct {
+	config resp.Config
+	prefix string
+	conn   *resp.Conn
+}
+
+var _ pool.ThrottleStore = (*Store)(nil)
+
+// New builds a Store for the Redis server at addr, e.g. "127.0.0.1:6379"
+func New(addr string, opts ...Option) *Store {
+	s := &Store{
+		config: resp.Config{Name: "redisthrottle", Addr: addr},
+		prefix: DefaultKeyPrefix,
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.conn = resp.NewConn(s.config)
+	return s
+}
+
+// Take reserves key's next start slot, with starts spaced spacing apart. Slots are kept to the microsecond, in the
+// callers' clocks, so replicas' clocks should be in sync to well within spacing.
+func (s *Store) Take(ctx context.Context, key string, now time.Time, spacing time.Duration) (time.Time, error) {
+	micros := now.UnixMicro()
+	reply, err := s.conn.Do(ctx, "EVAL", takeScript, "1", s.prefix+key,
+		strconv.FormatInt(micros, 10), strconv.FormatInt(spacing.Microseconds(), 10))
+	if err != nil {
+		return time.Time{}, err
+	}
+	slot, ok := reply.(int64)
+	if !ok {
+		return time.Time{}, fmt.Errorf("redisthrottle: unexpected reply %v", reply)
+	}
+	return now.Add(time.Duration(slot-micros) * time.Microsecond), nil
+}
+
+// Close closes the connection to Redis, if it's open
+func (s *Store) Close() error {
+	return s.conn.Close()
+}
@@ -0,0 +1,202 @@
+package redisthrottle
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+
+	pool "github.com/Appboy/worker-pools"
+	"github.com/Appboy/worker-pools/internal/resp"
+)
+
+// fakeRedis serves the commands a Store sends, keeping slots in memory
+type fakeRedis struct {
+	listener net.Listener
+	lock     sync.Mutex
+	slots    map[string]int64
+	commands []string
+	wg       sync.WaitGroup
+}
+
+func listen(t *testing.T) *fakeRedis {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	f := &fakeRedis{listener: listener, slots: make(map[string]int64)}
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			f.wg.Add(1)
+			go f.serve(conn)
+		}
+	}()
+	return f
+}
+
+// Stop listening, and wait for clients to disconnect
+func (f *fakeRedis) close() {
+	_ = f.listener.Close()
+	f.wg.Wait()
+}
+
+func (f *fakeRedis) serve(conn net.Conn) {
+	defer f.wg.Done()
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	for {
+		request, err := resp.ReadReply(r)
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range request.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		if _, err := conn.Write([]byte(f.handle(args))); err != nil {
+			return
+		}
+	}
+}
+
+func (f *fakeRedis) handle(args []string) string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.commands = append(f.commands, args[0])
+	switch args[0] {
+	case "AUTH":
+		if args[len(args)-1] != "secret" {
+			return "-WRONGPASS invalid password\r\n"
+		}
+		return "+OK\r\n"
+	case "SELECT":
+		return "+OK\r\n"
+	case "EVAL":
+		if args[1] != takeScript {
+			return "-ERR unknown script\r\n"
+		}
+		key := args[3]
+		now, _ := strconv.ParseInt(args[4], 10, 64)
+		spacing, _ := strconv.ParseInt(args[5], 10, 64)
+		slot := f.slots[key]
+		if slot < now {
+			slot = now
+		}
+		f.slots[key] = slot + spacing
+		return ":" + strconv.FormatInt(slot, 10) + "\r\n"
+	default:
+		return "-ERR unknown command\r\n"
+	}
+}
+
+func (f *fakeRedis) received() []string {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return append([]string(nil), f.commands...)
+}
+
+func TestStoreSharesSlotsBetweenReplicas(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+	ctx := context.Background()
+	first := New(server.listener.Addr().String(), WithPassword("", "secret"), WithDatabase(2))
+	second := New(server.listener.Addr().String())
+	defer first.Close()
+	defer second.Close()
+
+	now := time.Now()
+	slot, err := first.Take(ctx, "app-42", now, 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.True(t, slot.Equal(now))
+	slot, err = second.Take(ctx, "app-42", now.Add(time.Millisecond), 10*time.Millisecond)
+	assert.Nil(t, err)
+	assert.Equal(t, 9*time.Millisecond, slot.Sub(now.Add(time.Millisecond)))
+
+	// Idle time isn't banked
+	later := now.Add(time.Second)
+	slot, _ = first.Take(ctx, "app-42", later, 10*time.Millisecond)
+	assert.True(t, slot.Equal(later))
+
+	isolated := New(server.listener.Addr().String(), WithKeyPrefix("other:"))
+	defer isolated.Close()
+	slot, _ = isolated.Take(ctx, "app-42", now, 10*time.Millisecond)
+	assert.True(t, slot.Equal(now))
+
+	assert.Equal(t, []string{"AUTH", "SELECT", "EVAL", "EVAL", "EVAL"}, server.received()[:5])
+	server.lock.Lock()
+	assert.Contains(t, server.slots, DefaultKeyPrefix+"app-42")
+	assert.Contains(t, server.slots, "other:app-42")
+	server.lock.Unlock()
+}
+
+func TestStoreSharesRateLimitsBetweenManagers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var starts []time.Time
+	for replica := 0; replica < 2; replica++ {
+		store := New(server.listener.Addr().String())
+		defer store.Close()
+		pm := pool.NewWorkerPoolManager(10, time.Second, time.Hour,
+			pool.WithThrottle(100, time.Second), pool.WithThrottleStore(store))
+		defer pm.Dispose()
+		p, doneUsing := pm.GetPool("app-42", 10)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			p.Submit(func() {
+				lock.Lock()
+				starts = append(starts, time.Now())
+				lock.Unlock()
+				wg.Done()
+			})
+		}
+		close(doneUsing)
+	}
+	wg.Wait()
+
+	// Each manager only starts 3 tasks, but the 6 between them share one 10ms spacing
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	assert.GreaterOrEqual(t, starts[len(starts)-1].Sub(starts[0]), 45*time.Millisecond)
+	server.lock.Lock()
+	assert.Len(t, server.slots, 1)
+	assert.Contains(t, server.slots, DefaultKeyPrefix+"app-42")
+	server.lock.Unlock()
+}
+
+func TestStoreReportsErrorsAndReconnects(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	defer server.close()
+	ctx := context.Background()
+	s := New(server.listener.Addr().String(), WithPassword("admin", "wrong"))
+	defer s.Close()
+
+	_, err := s.Take(ctx, "app-42", time.Now(), time.Millisecond)
+	assert.EqualError(t, err, "redisthrottle: WRONGPASS invalid password")
+	_, err = s.Take(ctx, "app-42", time.Now(), time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Equal(t, []string{"AUTH", "AUTH"}, server.received())
+
+	unreachable := New("127.0.0.1:1")
+	_, err = unreachable.Take(ctx, "app-42", time.Now(), time.Millisecond)
+	assert.NotNil(t, err)
+	assert.Nil(t, unreachable.Close())
+}
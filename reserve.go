@@ -0,0 +1,46 @@
+package pool
+
+import "math"
+
+// WithReservedWorkers reserves fraction of each pool's workers for tasks labeled label, e.g. transactional sends, so
+// that bulk traffic on the same key can never occupy every worker. Tasks with any other label, or none, may execute on
+// at most the rest of the pool's size at once - though always on at least one worker - and are parked like tasks over
+// a WithLabelLimit until earlier ones finish, so they don't hold up the workers kept free.
+//
+// The reservation is a cap on the other traffic rather than a set of dedicated workers: reserved tasks may use every
+// worker when there's room, and a pool which hasn't spawned all its workers yet has fewer to spare.
+func WithReservedWorkers(label string, fraction float64) Option {
+	return func(o *options) {
+		o.reservedLabel = label
+		o.reservedFraction = fraction
+	}
+}
+
+// Reserve a fraction of a pool of maxSize workers for label's tasks
+func (l *labelLimiter) reserve(label string, fraction float64, maxSize int) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.reserved = true
+	l.reservedLabel = label
+	l.reservedFraction = fraction
+	l.limits[unreservedClass] = unreservedLimit(fraction, maxSize)
+}
+
+// Adjust the reservation to the pool's new size
+func (l *labelLimiter) resize(maxSize int) {
+	if l == nil || !l.reserved {
+		return
+	}
+	l.lock.Lock()
+	defer l.lock.Unlock()
+	l.limits[unreservedClass] = unreservedLimit(l.reservedFraction, maxSize)
+}
+
+// How many of maxSize workers the unreserved traffic may use
+func unreservedLimit(fraction float64, maxSize int) int {
+	limit := maxSize - int(math.Ceil(fraction*float64(maxSize)))
+	if limit < 1 {
+		return 1
+	}
+	return limit
+}
@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestReservedWorkersAreKeptFreeOfOtherTraffic(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(
+		4, time.Hour, time.Hour, WithQueueCapacity(20), WithReservedWorkers("transactional", 0.25),
+		WithLabelLimit("export", 1),
+	)
+	pool, doneUsing := pm.GetPool("app-42", 4)
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	bulk := func() {
+		defer wg.Done()
+		<-release
+	}
+	wg.Add(7)
+	for i := 0; i < 5; i++ {
+		pool.Submit(bulk)
+	}
+	// Limited by its label as well as the reservation
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, SubmitTask(pool, TaskInfo{Label: "export"}, bulk))
+	}
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["app-42"].Executing == 3
+	}, time.Second, time.Millisecond)
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, 3, pm.Snapshot()["app-42"].PeakExecuting)
+
+	sent := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{Label: "transactional"}, func() {
+		close(sent)
+	}))
+	select {
+	case <-sent:
+	case <-time.After(time.Second):
+		t.Fatal("transactional task wasn't executed on the reserved worker")
+	}
+	assert.Contains(t, pm.Config().Features, "reserved workers")
+
+	close(release)
+	wg.Wait()
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestUnreservedLimitLeavesAtLeastOneWorker(t *testing.T) {
+	assert.Equal(t, 3, unreservedLimit(0.25, 4))
+	assert.Equal(t, 7, unreservedLimit(0.25, 10))
+	assert.Equal(t, 1, unreservedLimit(0.5, 1))
+	assert.Equal(t, 1, unreservedLimit(1, 8))
+}
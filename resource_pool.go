@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrPoolTypeMismatch is returned when a cached pool isn't of the type the caller asked for, because it was built by
+// a different Factory
+var ErrPoolTypeMismatch = errors.New("cached pool was built by a different factory")
+
+// ResourcePool is a WorkerPool bundled with a resource shared by all its tasks, such as a tenant-scoped API client.
+// The resource is built along with the pool, and closed when the pool is disposed.
+type ResourcePool[T any] struct {
+	WorkerPool
+
+	// Resource is the resource shared by tasks in this pool
+	Resource T
+
+	closeResource func(T) error
+	closeOnce     *sync.Once
+	closeErr      error
+}
+
+// NewResourcePool builds a ResourcePool around a BaseWorkerPool, whose resource is built by newResource and closed by
+// closeResource. closeResource may be nil if the resource doesn't need closing.
+func NewResourcePool[T any](
+	maxSize int, newResource func() (T, error), closeResource func(T) error,
+) (*ResourcePool[T], error) {
+	resource, err := newResource()
+	if err != nil {
+		return nil, err
+	}
+	workerPool, _ := NewWorkerPool(maxSize)
+	return &ResourcePool[T]{
+		WorkerPool:    workerPool,
+		Resource:      resource,
+		closeResource: closeResource,
+		closeOnce:     &sync.Once{},
+	}, nil
+}
+
+// ResourceFactory returns a Factory building ResourcePools with NewResourcePool. Any error building the resource is
+// returned by GetPoolWithFactory.
+func ResourceFactory[T any](newResource func() (T, error), closeResource func(T) error) Factory {
+	return func(maxSize int) (WorkerPool, error) {
+		return NewResourcePool(maxSize, newResource, closeResource)
+	}
+}
+
+// GetResourcePool checks out key's ResourcePool from m, building it and its resource with newResource if necessary.
+// It returns ErrPoolTypeMismatch if key's cached pool isn't a ResourcePool of this resource type.
+func GetResourcePool[T any](
+	m *WorkerPoolManager, key string, sendSize int, newResource func(key string) (T, error), closeResource func(T) error,
+) (*ResourcePool[T], chan<- bool, error) {
+	factory := ResourceFactory(func() (T, error) {
+		return newResource(key)
+	}, closeResource)
+	pool, doneUsing, err := m.GetPoolWithFactory(key, sendSize, factory)
+	if err != nil {
+		return nil, nil, err
+	}
+	resourcePool, ok := UnwrapCheckout(pool).(*ResourcePool[T])
+	if !ok {
+		close(doneUsing)
+		return nil, nil, ErrPoolTypeMismatch
+	}
+	return resourcePool, doneUsing, nil
+}
+
+// SubmitWithResource submits w to be executed with the pool's resource
+func (p *ResourcePool[T]) SubmitWithResource(w func(resource T)) {
+	p.Submit(func() {
+		w(p.Resource)
+	})
+}
+
+// Dispose the pool, closing down the workers and then the resource
+func (p *ResourcePool[T]) Dispose() {
+	_ = p.DisposeE()
+}
+
+// DisposeE disposes the pool like Dispose, returning any error from closing the resource. The resource is only
+// closed once, however many times the pool is disposed, and only once the pool's workers have stopped, so tasks
+// executing at the time never see it closed. It mustn't be called from one of the pool's own tasks.
+func (p *ResourcePool[T]) DisposeE() error {
+	p.WorkerPool.Dispose()
+	p.waitForWorkers()
+	p.closeOnce.Do(func() {
+		if p.closeResource != nil {
+			p.closeErr = p.closeResource(p.Resource)
+		}
+	})
+	return p.closeErr
+}
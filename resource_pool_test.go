@@ -0,0 +1,116 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type tenantClient struct {
+	tenant string
+	closed bool
+}
+
+func TestResourcePoolSharesAndClosesResource(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 1)
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- eviction
+		},
+	}))
+	builds := 0
+	newClient := func(key string) (*tenantClient, error) {
+		builds++
+		return &tenantClient{tenant: key}, nil
+	}
+	closeClient := func(client *tenantClient) error {
+		client.closed = true
+		return errors.New("close failed")
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < 3; i++ {
+		pool, doneUsing, err := GetResourcePool(pm, "tenant 1", 2, newClient, closeClient)
+		assert.Nil(t, err)
+		wg.Add(1)
+		pool.SubmitWithResource(func(client *tenantClient) {
+			assert.Equal(t, "tenant 1", client.tenant)
+			wg.Done()
+		})
+		close(doneUsing)
+	}
+	wg.Wait()
+	assert.Equal(t, 1, builds)
+
+	pool, doneUsing, _ := GetResourcePool(pm, "tenant 1", 2, newClient, closeClient)
+	close(doneUsing)
+	pm.Dispose()
+	assert.EqualError(t, (<-evictions).Err, "close failed")
+	assert.True(t, pool.Resource.closed)
+}
+
+func TestGetResourcePoolReturnsResourceErrors(t *testing.T) {
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	pool, doneUsing, err := GetResourcePool(pm, "tenant", 1, func(key string) (string, error) {
+		return "", errors.New("no credentials")
+	}, nil)
+	assert.EqualError(t, err, "no credentials")
+	assert.Nil(t, pool)
+	assert.Nil(t, doneUsing)
+	pm.Dispose()
+}
+
+func TestGetResourcePoolRejectsMismatchedPools(t *testing.T) {
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	_, doneUsing := pm.GetPool("tenant", 1)
+	close(doneUsing)
+
+	_, _, err := GetResourcePool(pm, "tenant", 1, func(key string) (string, error) {
+		return key, nil
+	}, nil)
+	assert.Equal(t, ErrPoolTypeMismatch, err)
+	pm.Dispose()
+}
+
+func TestResourcePoolClosesResourceOnce(t *testing.T) {
+	closes := 0
+	pool, _ := NewResourcePool(1, func() (string, error) {
+		return "resource", nil
+	}, func(string) error {
+		closes++
+		return nil
+	})
+	pool.Dispose()
+	pool.Dispose()
+	assert.Equal(t, 1, closes)
+}
+
+func TestResourcePoolClosesResourceOnceTasksFinish(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	client := &tenantClient{tenant: "tenant"}
+	pool, _ := NewResourcePool(1, func() (*tenantClient, error) {
+		return client, nil
+	}, func(client *tenantClient) error {
+		client.closed = true
+		return nil
+	})
+	pool.spawnWorkers(1)
+	started := make(chan bool)
+	sawClosed := make(chan bool, 1)
+	pool.SubmitWithResource(func(client *tenantClient) {
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		sawClosed <- client.closed
+	})
+	<-started
+	pool.Dispose()
+	assert.False(t, <-sawClosed)
+	assert.True(t, client.closed)
+}
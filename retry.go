@@ -0,0 +1,141 @@
+package pool
+
+import (
+	"sync"
+	"time"
+)
+
+// TaskFailure describes a task submitted with SubmitWithRetry which still failed after its last attempt
+type TaskFailure struct {
+	Key  string
+	Info TaskInfo
+	// Err is the error returned by the last attempt
+	Err      error
+	Attempts int
+}
+
+// SubmitWithRetry submits w to be executed, retrying it up to retries more times while it returns an error. Retries
+// happen on the same worker, so they're still bound by the pool's concurrency limit. Tasks which fail every attempt
+// are reported to the OnTaskFailure hook.
+//
+// Like SubmitTask, it returns ErrKeyBlocked or ErrPoolQuarantined when the submission is rejected.
+func SubmitWithRetry(p WorkerPool, info TaskInfo, retries int, w func() error) error {
+	return p.submitRetry(info, retries, w)
+}
+
+// WithFailureBackoff slows the rate at which each pool starts tasks while tasks submitted with SubmitWithRetry are
+// failing, protecting a struggling downstream. After each failure, workers wait before starting their next task or
+// retry, starting at minDelay and doubling up to maxDelay with consecutive failures. Each success halves the delay,
+// ramping dispatch back up to full speed.
+func WithFailureBackoff(minDelay, maxDelay time.Duration) Option {
+	return func(o *options) {
+		o.failureBackoffMin = minDelay
+		o.failureBackoffMax = maxDelay
+	}
+}
+
+func (o *options) taskFailed(failure TaskFailure) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnTaskFailure != nil {
+			hooks.OnTaskFailure(failure)
+		}
+	}
+}
+
+// retryJob is the bookkeeping of a task submitted with SubmitWithRetry. Jobs are recycled through retryJobs once
+// they've run, so high-throughput callers don't allocate one per submission.
+type retryJob struct {
+	pool    *BaseWorkerPool
+	info    TaskInfo
+	retries int
+	work    func() error
+}
+
+var retryJobs = sync.Pool{
+	New: func() interface{} {
+		return &retryJob{}
+	},
+}
+
+func (p *BaseWorkerPool) submitRetry(info TaskInfo, retries int, w func() error) error {
+	job := retryJobs.Get().(*retryJob)
+	*job = retryJob{pool: p, info: info, retries: retries, work: w}
+	err := p.enqueue(task{info: info, runner: job})
+	if err != nil {
+		job.recycle()
+	}
+	return err
+}
+
+func (j *retryJob) Run() {
+	defer j.recycle()
+	p := j.pool
+	for attempt := 1; ; attempt++ {
+		err := j.work()
+		p.backoff.record(err)
+		p.recordOutcome(err)
+		if err == nil {
+			return
+		}
+		p.options.count(MetricTaskFailures, p.key, 1)
+		if attempt > j.retries {
+			p.options.taskFailed(TaskFailure{Key: p.key, Info: j.info, Err: err, Attempts: attempt})
+			return
+		}
+		if !sleep(p.clock, p.backoff.wait(), p.disposed) {
+			return
+		}
+	}
+}
+
+func (j *retryJob) recycle() {
+	*j = retryJob{}
+	retryJobs.Put(j)
+}
+
+// failureBackoff is a delay which adapts to the outcomes of a pool's tasks. A nil failureBackoff never delays.
+type failureBackoff struct {
+	lock       *sync.Mutex
+	minDelay   time.Duration
+	maxDelay   time.Duration
+	delay      time.Duration
+	delayUntil time.Time
+	clock      Clock
+}
+
+func newFailureBackoff(clock Clock, minDelay, maxDelay time.Duration) *failureBackoff {
+	return &failureBackoff{lock: &sync.Mutex{}, minDelay: minDelay, maxDelay: maxDelay, clock: clock}
+}
+
+func (b *failureBackoff) record(err error) {
+	if b == nil {
+		return
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if err == nil {
+		b.delay /= 2
+		if b.delay < b.minDelay {
+			b.delay = 0
+		}
+	} else {
+		b.delay *= 2
+		if b.delay < b.minDelay {
+			b.delay = b.minDelay
+		}
+		if b.delay > b.maxDelay {
+			b.delay = b.maxDelay
+		}
+	}
+	b.delayUntil = b.clock.Now().Add(b.delay)
+}
+
+// How long to wait before starting the next task
+func (b *failureBackoff) wait() time.Duration {
+	if b == nil {
+		return 0
+	}
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	return b.delayUntil.Sub(b.clock.Now())
+}
@@ -0,0 +1,95 @@
+package pool
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubmitWithRetry(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	failures := make(chan TaskFailure, 10)
+	p, _ := NewWorkerPoolWithOptions(1, WithHooks(Hooks{
+		OnTaskFailure: func(failure TaskFailure) {
+			failures <- failure
+		},
+	}))
+	p.spawnWorkers(1)
+
+	attempts := 0
+	done := make(chan bool)
+	assert.Nil(t, SubmitWithRetry(p, TaskInfo{}, 3, func() error {
+		attempts++
+		if attempts < 3 {
+			return errors.New("downstream unavailable")
+		}
+		close(done)
+		return nil
+	}))
+	<-done
+	assert.Equal(t, 3, attempts)
+
+	assert.Nil(t, SubmitWithRetry(p, TaskInfo{Label: "export"}, 1, func() error {
+		return errors.New("downstream unavailable")
+	}))
+	failure := <-failures
+	assert.Equal(t, 2, failure.Attempts)
+	assert.Equal(t, "export", failure.Info.Label)
+	assert.EqualError(t, failure.Err, "downstream unavailable")
+	p.Dispose()
+}
+
+func TestFailureBackoffAdapts(t *testing.T) {
+	b := newFailureBackoff(realClock{}, 10*time.Millisecond, 35*time.Millisecond)
+	failed := errors.New("failed")
+
+	var delays []time.Duration
+	for _, err := range []error{failed, failed, failed, failed, nil, nil, nil} {
+		b.record(err)
+		delays = append(delays, b.delay)
+	}
+	assert.Equal(t, []time.Duration{
+		10 * time.Millisecond, 20 * time.Millisecond, 35 * time.Millisecond, 35 * time.Millisecond,
+		17500 * time.Microsecond, 0, 0,
+	}, delays)
+	assert.Zero(t, (*failureBackoff)(nil).wait())
+}
+
+func TestFailureBackoffSlowsDispatch(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPoolWithOptions(1, WithFailureBackoff(30*time.Millisecond, time.Second))
+	p.spawnWorkers(1)
+
+	_ = SubmitWithRetry(p, TaskInfo{}, 0, func() error {
+		return errors.New("downstream unavailable")
+	})
+	start := time.Now()
+	done := make(chan bool)
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+	assert.GreaterOrEqual(t, time.Since(start), 25*time.Millisecond)
+	p.Dispose()
+}
+
+func TestSubmitWithRetryOnStandalonePool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	p, _ := NewWorkerPool(1)
+	p.spawnWorkers(1)
+	done := make(chan bool)
+	_ = SubmitWithRetry(p, TaskInfo{}, 0, func() error {
+		return errors.New("downstream unavailable")
+	})
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+	p.Dispose()
+}
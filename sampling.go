@@ -0,0 +1,85 @@
+package pool
+
+import (
+	"context"
+	"math"
+	"runtime/pprof"
+	"runtime/trace"
+	"sync/atomic"
+)
+
+// ProfileLabelTask is the profiler label set to the TaskInfo.Label of executions sampled by WithTaskSampling
+const ProfileLabelTask = "worker_pool_task"
+
+// Name of the runtime/trace tasks which sampled executions are recorded as
+const traceTaskType = "worker pool task"
+
+// TaskSampling configures which task executions WithTaskSampling instruments, and how
+type TaskSampling struct {
+	// Rate is the fraction of each pool's task executions which are sampled, between 0 and 1. Samples are spread
+	// evenly, e.g. every tenth execution for 0.1.
+	Rate float64
+	// Profile sets the pool's key and the task's label as profiler labels on sampled executions, so their CPU samples
+	// can be attributed in profiles
+	Profile bool
+	// Trace records sampled executions as runtime/trace tasks, with a region named by the pool's key and the task's
+	// label, for viewing in go tool trace. Nothing is recorded unless a trace is being collected.
+	Trace bool
+}
+
+// WithTaskSampling instruments a sample of task executions with profiler labels or execution tracing, so hotspots
+// inside tenants' workloads can be attributed without paying for the instrumentation on every task.
+func WithTaskSampling(sampling TaskSampling) Option {
+	return func(o *options) {
+		if sampling.Rate > 0 && (sampling.Profile || sampling.Trace) {
+			o.sampling = &sampling
+		}
+	}
+}
+
+// taskSampler picks out a pool's sampled executions
+type taskSampler struct {
+	// Executions so far, accessed atomically. It's first to keep it 64-bit aligned.
+	executions uint64
+	TaskSampling
+}
+
+// Whether the next execution is sampled, which is every time the running count of executions times the rate passes a
+// whole number
+func (s *taskSampler) sample() bool {
+	if s == nil {
+		return false
+	}
+	n := float64(atomic.AddUint64(&s.executions, 1))
+	return math.Floor(n*s.Rate) > math.Floor((n-1)*s.Rate)
+}
+
+// Run f, which executes t, instrumented as a sampled execution
+func (p *BaseWorkerPool) sampleExecution(t task, f func()) {
+	label := t.info.Label
+	if label == "" {
+		label = "unlabeled"
+	}
+	sampling := p.sampling.TaskSampling
+	if sampling.Trace && trace.IsEnabled() {
+		inner := f
+		f = func() {
+			ctx, traceTask := trace.NewTask(context.Background(), traceTaskType)
+			defer traceTask.End()
+			trace.WithRegion(ctx, p.key+"/"+label, inner)
+		}
+	}
+	if !sampling.Profile {
+		f()
+		return
+	}
+
+	// The labels are put back to the worker's own once f returns, and fleet workers serving many pools have none
+	ctx := context.Background()
+	if labels := p.profileLabels(); labels != nil && p.fleet == nil {
+		ctx = pprof.WithLabels(ctx, pprof.Labels(labels...))
+	}
+	pprof.Do(ctx, pprof.Labels(ProfileLabelKey, p.key, ProfileLabelTask, label), func(context.Context) {
+		f()
+	})
+}
@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"bytes"
+	"runtime/pprof"
+	"runtime/trace"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestTaskSamplerSpreadsSamplesEvenly(t *testing.T) {
+	sampler := &taskSampler{TaskSampling: TaskSampling{Rate: 0.25}}
+	var sampled []int
+	for i := 1; i <= 8; i++ {
+		if sampler.sample() {
+			sampled = append(sampled, i)
+		}
+	}
+	assert.Equal(t, []int{4, 8}, sampled)
+	assert.False(t, (*taskSampler)(nil).sample())
+}
+
+func TestTaskSamplingSetsProfilerLabels(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(
+		1, time.Hour, time.Hour, WithName("sends"), WithTaskSampling(TaskSampling{Rate: 0.5, Profile: true}),
+	)
+	pool, doneUsing := pm.GetPool("app-42", 1)
+
+	profiles := make(chan string, 2)
+	for i := 0; i < 2; i++ {
+		assert.Nil(t, SubmitTask(pool, TaskInfo{Label: "export"}, func() {
+			var profile bytes.Buffer
+			_ = pprof.Lookup("goroutine").WriteTo(&profile, 1)
+			profiles <- profile.String()
+		}))
+	}
+	assert.NotContains(t, <-profiles, `"worker_pool_task":"export"`)
+	sampled := <-profiles
+	assert.Contains(t, sampled, `"worker_pool_task":"export"`)
+	// The worker's own labels are kept
+	assert.Contains(t, sampled, `"worker_pool_manager":"sends"`)
+	assert.Contains(t, pm.Config().Features, "task sampling")
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestTaskSamplingTracesExecutions(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithTaskSampling(TaskSampling{Rate: 1, Trace: true}))
+	pool, doneUsing := pm.GetPool("app-42", 1)
+
+	var recorded bytes.Buffer
+	assert.Nil(t, trace.Start(&recorded))
+	done := make(chan bool)
+	assert.Nil(t, SubmitTask(pool, TaskInfo{Label: "export"}, func() {
+		close(done)
+	}))
+	<-done
+	pool.Submit(func() {})
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["app-42"].Completed == 2
+	}, time.Second, time.Millisecond)
+	trace.Stop()
+	assert.Contains(t, recorded.String(), "app-42/export")
+	assert.Contains(t, recorded.String(), "app-42/unlabeled")
+
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -0,0 +1,78 @@
+package pool
+
+import (
+	"math/rand"
+	"runtime"
+	"sync"
+)
+
+// schedulePoint identifies a place where goroutines using a pool race with each other
+type schedulePoint int
+
+const (
+	// A worker is about to execute a task it has picked up
+	schedulePickup schedulePoint = iota
+	// A caller is about to reserve a pool it has found or built
+	scheduleReserve
+	// A caller is about to release its reservation of a pool
+	scheduleRelease
+	// A pool is about to wait for its reservations to be released so it can be disposed
+	scheduleDispose
+)
+
+// scheduler perturbs how pool goroutines interleave, and decides when pools are evicted, so that stress tests can
+// explore the reserve/dispose race paths systematically rather than relying on sleeps. It's for internal testing only.
+type scheduler interface {
+	// yield is called at each schedulePoint, and may delay the calling goroutine
+	yield(point schedulePoint)
+	// evict reports whether the cached pool for key should be evicted before it's checked out
+	evict(key string) bool
+}
+
+// withScheduler drives the manager and its pools with s
+func withScheduler(s scheduler) Option {
+	return func(o *options) {
+		o.scheduler = s
+	}
+}
+
+func (o *options) yield(point schedulePoint) {
+	if o != nil && o.scheduler != nil {
+		o.scheduler.yield(point)
+	}
+}
+
+// seededScheduler makes its decisions from a seeded random source, so a seed always produces the same sequence of
+// decisions. Which goroutine each decision is applied to still depends on the Go scheduler, so a failing seed narrows
+// down, but doesn't guarantee, the interleaving that reproduces a failure.
+type seededScheduler struct {
+	lock             *sync.Mutex
+	random           *rand.Rand
+	maxYields        int
+	evictProbability float64
+}
+
+func newSeededScheduler(seed int64, maxYields int, evictProbability float64) *seededScheduler {
+	return &seededScheduler{
+		lock:             &sync.Mutex{},
+		random:           rand.New(rand.NewSource(seed)),
+		maxYields:        maxYields,
+		evictProbability: evictProbability,
+	}
+}
+
+func (s *seededScheduler) yield(point schedulePoint) {
+	s.lock.Lock()
+	yields := s.random.Intn(s.maxYields + 1)
+	s.lock.Unlock()
+
+	for i := 0; i < yields; i++ {
+		runtime.Gosched()
+	}
+}
+
+func (s *seededScheduler) evict(key string) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.random.Float64() < s.evictProbability
+}
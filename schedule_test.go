@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSeededSchedulerIsReproducible(t *testing.T) {
+	decisions := func(seed int64) []bool {
+		s := newSeededScheduler(seed, 3, 0.5)
+		var evictions []bool
+		for i := 0; i < 50; i++ {
+			evictions = append(evictions, s.evict("key"))
+		}
+		return evictions
+	}
+	assert.Equal(t, decisions(7), decisions(7))
+	assert.NotEqual(t, decisions(7), decisions(8))
+}
+
+func TestSeededReserveDisposeStress(t *testing.T) {
+	for seed := int64(1); seed <= 4; seed++ {
+		seed := seed
+		t.Run(fmt.Sprint("seed ", seed), func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			pm := NewWorkerPoolManager(
+				4, time.Hour, 20*time.Millisecond, withScheduler(newSeededScheduler(seed, 3, 0.2)),
+			)
+
+			var callers sync.WaitGroup
+			var executed int64
+			for i := 0; i < 60; i++ {
+				callers.Add(1)
+				go func(i int) {
+					defer callers.Done()
+					pool, doneUsing := pm.GetPool(fmt.Sprint(i%3), 2)
+
+					// Work submitted while the pool is reserved must execute, as the pool can't be disposed before
+					// the reservation is released
+					var tasks sync.WaitGroup
+					for j := 0; j < 3; j++ {
+						tasks.Add(1)
+						pool.Submit(func() {
+							atomic.AddInt64(&executed, 1)
+							tasks.Done()
+						})
+					}
+					tasks.Wait()
+					close(doneUsing)
+				}(i)
+				if i%10 == 0 {
+					time.Sleep(5 * time.Millisecond)
+				}
+			}
+			callers.Wait()
+
+			assert.Equal(t, int64(180), atomic.LoadInt64(&executed))
+			for key, snapshot := range pm.Snapshot() {
+				assert.Eventually(t, func() bool {
+					return pm.Snapshot()[key].Reservations == 0
+				}, time.Second, time.Millisecond, "key %s had %d reservations", key, snapshot.Reservations)
+			}
+			pm.Dispose()
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package pool
+
+import (
+	"context"
+	"sync"
+)
+
+// Scope is a checkout of a key's pool which tracks the work submitted through it, see WorkerPoolManager.Scope
+type Scope struct {
+	ctx       context.Context
+	pool      WorkerPool
+	doneUsing chan<- bool
+	release   *sync.Once
+	// Closed once the checkout is released, to stop watching ctx
+	released chan bool
+
+	// Guards the rest
+	lock *sync.Mutex
+	// Work submitted with Go which hasn't finished executing or been skipped
+	pending int
+	// Whether Wait has been called, after which Go submits nothing
+	waiting bool
+	// Closed once Wait has been called and there's nothing pending
+	drained chan bool
+	err     error
+}
+
+// Scope checks out key's pool, as with GetPool, for submitting a batch of work and waiting for all of it - in the
+// manner of a sync.WaitGroup, but which also releases the checkout by itself. The checkout is released once Wait
+// returns, or as soon as ctx is done, so there's no doneUsing channel to forget to close. Work which hasn't started
+// by the time ctx is done is skipped.
+//
+// The pool is checked out as with GetPoolContext. If that fails the scope submits nothing, and Wait returns the error.
+func (m *WorkerPoolManager) Scope(ctx context.Context, key string, sendSize int) *Scope {
+	pool, doneUsing, err := m.GetPoolContext(ctx, key, sendSize, nil)
+	s := &Scope{
+		ctx:       ctx,
+		pool:      pool,
+		doneUsing: doneUsing,
+		release:   &sync.Once{},
+		released:  make(chan bool),
+		lock:      &sync.Mutex{},
+		drained:   make(chan bool),
+		err:       err,
+	}
+	if err == nil && ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				s.releaseCheckout()
+			case <-s.released:
+			}
+		}()
+	}
+	return s
+}
+
+// Go submits w to the scope's pool. It submits nothing once Wait has been called or the scope's context is done, or
+// if the pool couldn't be checked out.
+func (s *Scope) Go(w Work) {
+	s.lock.Lock()
+	if s.waiting || s.ctx.Err() != nil || s.pool == nil {
+		s.lock.Unlock()
+		return
+	}
+	s.pending++
+	s.lock.Unlock()
+
+	err := s.pool.enqueue(task{work: func() {
+		defer s.done()
+		if err := s.ctx.Err(); err != nil {
+			s.fail(err)
+			return
+		}
+		w()
+	}, dropped: func() {
+		s.fail(ErrTaskPurged)
+		s.done()
+	}})
+	if err != nil {
+		s.fail(err)
+		s.done()
+	}
+}
+
+// Wait blocks until all the work submitted with Go has executed, then releases the scope's checkout. It returns the
+// error checking out the pool, the first rejected submission's error, as with SubmitTask, ErrTaskPurged if work was
+// purged from the queue before it executed, or the context's error if the scope's context is done first.
+func (s *Scope) Wait() error {
+	s.lock.Lock()
+	s.waiting = true
+	if s.pending == 0 {
+		s.closeDrained()
+	}
+	s.lock.Unlock()
+
+	var err error
+	select {
+	case <-s.drained:
+	case <-s.ctx.Done():
+		err = s.ctx.Err()
+	}
+	s.releaseCheckout()
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return s.err
+	}
+	return err
+}
+
+// Record the first error Wait returns
+func (s *Scope) fail(err error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err == nil {
+		s.err = err
+	}
+}
+
+// Note that submitted work has finished executing, been skipped or been rejected
+func (s *Scope) done() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.pending--
+	if s.pending == 0 && s.waiting {
+		s.closeDrained()
+	}
+}
+
+// It's not thread-safe, lock above this
+func (s *Scope) closeDrained() {
+	if !isClosed(s.drained) {
+		close(s.drained)
+	}
+}
+
+func (s *Scope) releaseCheckout() {
+	s.release.Do(func() {
+		if s.doneUsing != nil {
+			close(s.doneUsing)
+		}
+		close(s.released)
+	})
+}
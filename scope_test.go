@@ -0,0 +1,107 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestScopeWaitsForItsWorkAndReleasesTheCheckout(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	scope := pm.Scope(context.Background(), "key", 2)
+	var completed int32
+	for i := 0; i < 5; i++ {
+		scope.Go(func() {
+			time.Sleep(time.Millisecond)
+			atomic.AddInt32(&completed, 1)
+		})
+	}
+	assert.Equal(t, 1, pm.Snapshot()["key"].Reservations)
+	assert.Nil(t, scope.Wait())
+	assert.Equal(t, int32(5), atomic.LoadInt32(&completed))
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Reservations == 0
+	}, time.Second, time.Millisecond)
+
+	// Nothing is submitted once it has been waited on
+	scope.Go(func() {
+		atomic.AddInt32(&completed, 1)
+	})
+	assert.Nil(t, scope.Wait())
+	assert.Equal(t, int32(5), atomic.LoadInt32(&completed))
+
+	pm.Block("blocked")
+	scope = pm.Scope(context.Background(), "blocked", 1)
+	scope.Go(func() {})
+	assert.Equal(t, ErrKeyBlocked, scope.Wait())
+	pm.Dispose()
+}
+
+func TestScopeIsReleasedWhenItsContextIsDone(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	ctx, cancel := context.WithCancel(context.Background())
+	scope := pm.Scope(ctx, "key", 1)
+	started := make(chan bool)
+	release := make(chan bool)
+	var skipped int32 = 1
+	scope.Go(func() {
+		close(started)
+		<-release
+	})
+	scope.Go(func() {
+		atomic.StoreInt32(&skipped, 0)
+	})
+	<-started
+
+	cancel()
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Reservations == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, context.Canceled, scope.Wait())
+
+	close(release)
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].QueueDepth == 0 && pm.Snapshot()["key"].Executing == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&skipped))
+	pm.Dispose()
+}
+
+func TestScopeReturnsTheCheckoutsError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithMaxCheckouts(1))
+	failure := errors.New("failure")
+	assert.Nil(t, pm.RegisterFactory("broken", func(int) (WorkerPool, error) {
+		return nil, failure
+	}))
+	scope := pm.Scope(context.Background(), "broken", 1)
+	var executed int32
+	scope.Go(func() {
+		atomic.StoreInt32(&executed, 1)
+	})
+	assert.Equal(t, failure, scope.Wait())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&executed))
+
+	// The checkout stops waiting for the key's other checkout once the scope's context is done
+	_, doneUsing := pm.GetPool("key", 1)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	scope = pm.Scope(ctx, "key", 1)
+	scope.Go(func() {
+		atomic.StoreInt32(&executed, 1)
+	})
+	assert.Equal(t, context.DeadlineExceeded, scope.Wait())
+	assert.Equal(t, int32(0), atomic.LoadInt32(&executed))
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -0,0 +1,54 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ShutdownReport describes the work abandoned by Shutdown
+type ShutdownReport struct {
+	// Unfinished is the work each key's pool still had when the deadline passed. Keys which drained in time are left
+	// out, so it's empty after a graceful shutdown.
+	Unfinished map[string]UnfinishedWork
+}
+
+// UnfinishedWork is the work a pool hadn't finished when it was shut down
+type UnfinishedWork struct {
+	// Queued is the number of tasks which were waiting for a worker, and are dropped without executing
+	Queued int
+	// Executing is the number of tasks which were executing. Tasks can't be interrupted, so they carry on in the
+	// background, but nothing waits for them to finish.
+	Executing int
+}
+
+// Shutdown waits for every pool to finish its work, as with Quiesce, then disposes the manager. If ctx is done first
+// the manager is disposed anyway, abandoning the work the pools still had, and the context's error is returned along
+// with a report of that work by key - so operators know what was dropped by a deploy which couldn't wait.
+//
+// As with Quiesce, callers should stop producing work before shutting down.
+func (m *WorkerPoolManager) Shutdown(ctx context.Context) (ShutdownReport, error) {
+	report := ShutdownReport{Unfinished: make(map[string]UnfinishedWork)}
+	err := m.Quiesce(ctx)
+	if err != nil {
+		for key, item := range m.workerPoolCache.Items() {
+			pool := item.Value()
+			if work := pool.unfinishedWork(); work.Queued > 0 || work.Executing > 0 {
+				report.Unfinished[key] = work
+			}
+			// Disposal waits for callers to be done using the pool, so its queued tasks are dropped by blocking it
+			pool.setBlocked(true)
+		}
+	}
+	m.Dispose()
+	return report, err
+}
+
+func (p *BaseWorkerPool) unfinishedWork() UnfinishedWork {
+	queued := p.queue.len()
+	// Read one after the other, so a task picked up in between can skew the split slightly
+	executing := int(atomic.LoadInt64(&p.stats.unfinished)) - queued
+	if executing < 0 {
+		executing = 0
+	}
+	return UnfinishedWork{Queued: queued, Executing: executing}
+}
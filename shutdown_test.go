@@ -0,0 +1,65 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestShutdownDrainsPools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Hour, time.Hour)
+	var completed int32
+	pool, doneUsing := pm.GetPool("key", 2)
+	for i := 0; i < 4; i++ {
+		pool.Submit(func() {
+			time.Sleep(5 * time.Millisecond)
+			atomic.AddInt32(&completed, 1)
+		})
+	}
+	close(doneUsing)
+
+	report, err := pm.Shutdown(context.Background())
+	assert.Nil(t, err)
+	assert.Empty(t, report.Unfinished)
+	assert.Equal(t, int32(4), atomic.LoadInt32(&completed))
+}
+
+func TestShutdownReportsAbandonedWork(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	blocker := make(chan bool)
+	started := make(chan bool)
+	var dropped int32
+	stuck, doneUsing := pm.GetPool("stuck", 1)
+	stuck.Submit(func() {
+		close(started)
+		<-blocker
+	})
+	for i := 0; i < 3; i++ {
+		stuck.Submit(func() {
+			atomic.AddInt32(&dropped, 1)
+		})
+	}
+	close(doneUsing)
+	<-started
+	idle, doneUsing := pm.GetPool("idle", 1)
+	idle.Submit(func() {})
+	close(doneUsing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	report, err := pm.Shutdown(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+	assert.Equal(t, map[string]UnfinishedWork{"stuck": {Queued: 3, Executing: 1}}, report.Unfinished)
+
+	close(blocker)
+	stuck.waitForWorkers()
+	assert.Equal(t, int32(0), atomic.LoadInt32(&dropped))
+}
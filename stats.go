@@ -0,0 +1,253 @@
+package pool
+
+import (
+	"math"
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// PoolSnapshot is a point-in-time view of a pool's state and performance
+type PoolSnapshot struct {
+	// Manager is the name of the manager which built the pool, see WithName
+	Manager string
+	// Description is the pool's human-readable description, see WithPoolDescription
+	Description string
+	// Workers is the number of workers the pool is running. Workers which exit, e.g. after a custom worker loop
+	// recovers a panic, no longer count, so later checkouts replace them.
+	Workers int
+	// SpawnedWorkers is the number of worker goroutines the pool has ever spawned, including temporary ones for bursts
+	// or autoscaling, and AliveWorkers the number still running, including retiring workers finishing their last tasks
+	SpawnedWorkers uint64
+	AliveWorkers   int
+	// Streams is the number of streams the pool is running, which aren't counted among its workers, see SubmitStream
+	Streams int
+	// Executing is the number of tasks executing right now, and PeakExecuting the most that have ever executed at once,
+	// showing how close the key gets to its pool size
+	Executing     int
+	PeakExecuting int
+	// QueueDepth is the number of submitted tasks waiting for a worker
+	QueueDepth int
+	// MemoryInUse is how many bytes the pool's unfinished tasks hold, by their TaskInfo.MemoryCost
+	MemoryInUse int64
+	// Reservations is the number of callers which currently have the pool checked out
+	Reservations int
+	// Age is how long ago the pool was built
+	Age time.Duration
+	// Completed is the number of tasks the pool has finished executing
+	Completed uint64
+	// Throughput is the average number of tasks completed per second over the pool's lifetime
+	Throughput float64
+	// ThroughputLastMinute and ThroughputLast5Minutes are the number of tasks completed per second over a sliding
+	// window, or since the pool was built if it's younger, so a downstream slowing down shows up quickly
+	ThroughputLastMinute   float64
+	ThroughputLast5Minutes float64
+	// ExecutionLatency is how long tasks took to execute once picked up by a worker
+	ExecutionLatency LatencyPercentiles
+	// QueueWaitLatency is how long tasks waited in the queue before being picked up by a worker
+	QueueWaitLatency LatencyPercentiles
+	// QueueWaitBySite breaks QueueWaitLatency down by where tasks were submitted, keyed by CallSite.String(). It's only
+	// recorded in debug mode - see WithDebug.
+	QueueWaitBySite map[string]LatencyPercentiles
+	// StuckTasks is the number of tasks the pool's watchdog has reported as stuck, see WithWatchdog
+	StuckTasks uint64
+	// Panics is the number of task panics the pool has recovered, see WithPanicRecovery
+	Panics uint64
+	// Quarantined is whether the pool is currently rejecting submissions, see WithQuarantine
+	Quarantined bool
+	// FailureRate is the fraction of recent attempts by tasks submitted with SubmitWithRetry which failed, see
+	// WithAutoPause
+	FailureRate float64
+	// Paused is whether the pool's workers have stopped starting tasks
+	Paused bool
+	// Frozen is whether the pool's manager has frozen it, see FreezeAll
+	Frozen bool
+	// Bursting is whether the pool is running extra workers, see WithBurst
+	Bursting bool
+}
+
+// LatencyPercentiles summarizes a latency distribution. Values are approximate, accurate to within 25%.
+type LatencyPercentiles struct {
+	P50 time.Duration
+	P95 time.Duration
+	P99 time.Duration
+}
+
+// Snapshot returns a PoolSnapshot of every cached pool, by key
+func (m *WorkerPoolManager) Snapshot() map[string]PoolSnapshot {
+	items := m.workerPoolCache.Items()
+	snapshots := make(map[string]PoolSnapshot, len(items))
+
+	// Worker counts are only safe to read under the reservation lock
+	m.poolReservationLock.Lock()
+	defer m.poolReservationLock.Unlock()
+	for key, item := range items {
+		snapshots[key] = item.Value().snapshot()
+	}
+	return snapshots
+}
+
+// poolStats holds a pool's counters and histograms, which are all accessed atomically. It's allocated separately from
+// the pool to keep the 64-bit counters aligned.
+type poolStats struct {
+	// Work which has been submitted but hasn't finished executing yet
+	unfinished   int64
+	reservations int64
+	executing    int64
+	// The most tasks which have executed at once
+	peakExecuting int64
+	completed     uint64
+	stuck         uint64
+	panics        uint64
+	// Every worker goroutine the pool has spawned, and those still running
+	spawnedWorkers uint64
+	aliveWorkers   int64
+	// Streams running on the pool, see SubmitStream
+	streams int64
+
+	executionLatency latencyHistogram
+	queueWaitLatency latencyHistogram
+	completions      completionRate
+}
+
+// Count a task starting to execute, raising the high-water mark if need be
+func (s *poolStats) startExecuting() {
+	executing := atomic.AddInt64(&s.executing, 1)
+	for {
+		peak := atomic.LoadInt64(&s.peakExecuting)
+		if executing <= peak || atomic.CompareAndSwapInt64(&s.peakExecuting, peak, executing) {
+			return
+		}
+	}
+}
+
+// It's not thread-safe, lock above this
+func (p *BaseWorkerPool) snapshot() PoolSnapshot {
+	age := p.age()
+	now := p.clock.Now()
+	completed := atomic.LoadUint64(&p.stats.completed)
+	throughput := 0.0
+	if age > 0 {
+		throughput = float64(completed) / age.Seconds()
+	}
+	failureRate, _ := p.failures.rate(now)
+	var manager string
+	if p.options != nil {
+		manager = p.options.name
+	}
+	return PoolSnapshot{
+		Manager:                manager,
+		Description:            p.description,
+		Workers:                p.spawnedWorkers(),
+		SpawnedWorkers:         atomic.LoadUint64(&p.stats.spawnedWorkers),
+		AliveWorkers:           int(atomic.LoadInt64(&p.stats.aliveWorkers)),
+		Streams:                int(atomic.LoadInt64(&p.stats.streams)),
+		Executing:              int(atomic.LoadInt64(&p.stats.executing)),
+		PeakExecuting:          int(atomic.LoadInt64(&p.stats.peakExecuting)),
+		QueueDepth:             p.queue.len(),
+		MemoryInUse:            p.memory.inUse(),
+		Reservations:           p.reservations(),
+		Age:                    age,
+		Completed:              completed,
+		Throughput:             throughput,
+		ThroughputLastMinute:   p.stats.completions.rate(now, p.creationTime, shortThroughputWindow),
+		ThroughputLast5Minutes: p.stats.completions.rate(now, p.creationTime, longThroughputWindow),
+		ExecutionLatency:       p.stats.executionLatency.percentiles(),
+		QueueWaitLatency:       p.stats.queueWaitLatency.percentiles(),
+		QueueWaitBySite:        p.siteQueueWaitPercentiles(),
+		StuckTasks:             atomic.LoadUint64(&p.stats.stuck),
+		Panics:                 atomic.LoadUint64(&p.stats.panics),
+		Quarantined:            p.quarantined(),
+		FailureRate:            failureRate,
+		Paused:                 p.paused(),
+		Frozen:                 p.frozen(),
+		Bursting:               p.bursting(),
+	}
+}
+
+// Histograms split each power of two microseconds into 2^histogramSubBucketBits linear sub-buckets, and stop growing
+// at 2^histogramMaxExponent microseconds (around 19 hours), beyond which all durations share the last bucket.
+const (
+	histogramSubBucketBits = 2
+	histogramSubBuckets    = 1 << histogramSubBucketBits
+	histogramMaxExponent   = 36
+	histogramBuckets       = histogramSubBuckets * (histogramMaxExponent - histogramSubBucketBits + 2)
+)
+
+// latencyHistogram is a lock-free histogram of durations, with logarithmically sized buckets
+type latencyHistogram struct {
+	buckets [histogramBuckets]uint64
+}
+
+func (h *latencyHistogram) record(d time.Duration) {
+	atomic.AddUint64(&h.buckets[histogramBucket(d)], 1)
+}
+
+func (h *latencyHistogram) percentiles() LatencyPercentiles {
+	counts := h.counts()
+	return histogramPercentiles(&counts)
+}
+
+// A copy of the histogram's bucket counts
+func (h *latencyHistogram) counts() [histogramBuckets]uint64 {
+	var counts [histogramBuckets]uint64
+	for i := range h.buckets {
+		counts[i] = atomic.LoadUint64(&h.buckets[i])
+	}
+	return counts
+}
+
+func histogramPercentiles(counts *[histogramBuckets]uint64) LatencyPercentiles {
+	var total uint64
+	for _, count := range counts {
+		total += count
+	}
+	return LatencyPercentiles{
+		P50: histogramPercentile(counts, total, 0.5),
+		P95: histogramPercentile(counts, total, 0.95),
+		P99: histogramPercentile(counts, total, 0.99),
+	}
+}
+
+func histogramBucket(d time.Duration) int {
+	us := uint64(0)
+	if d > 0 {
+		us = uint64(d / time.Microsecond)
+	}
+	if us < histogramSubBuckets {
+		return int(us)
+	}
+	exponent := bits.Len64(us) - 1
+	if exponent > histogramMaxExponent {
+		return histogramBuckets - 1
+	}
+	shift := exponent - histogramSubBucketBits
+	subBucket := int(us>>shift) & (histogramSubBuckets - 1)
+	return histogramSubBuckets*(shift+1) + subBucket
+}
+
+// The midpoint of the durations which fall into a bucket
+func histogramBucketValue(bucket int) time.Duration {
+	if bucket < histogramSubBuckets {
+		return time.Duration(bucket) * time.Microsecond
+	}
+	shift := bucket/histogramSubBuckets - 1
+	lower := uint64(histogramSubBuckets+bucket%histogramSubBuckets) << shift
+	width := uint64(1) << shift
+	return time.Duration(lower+width/2) * time.Microsecond
+}
+
+func histogramPercentile(counts *[histogramBuckets]uint64, total uint64, quantile float64) time.Duration {
+	if total == 0 {
+		return 0
+	}
+	rank := uint64(math.Ceil(quantile * float64(total)))
+	var seen uint64
+	for i, count := range counts {
+		seen += count
+		if seen >= rank {
+			return histogramBucketValue(i)
+		}
+	}
+	return histogramBucketValue(histogramBuckets - 1)
+}
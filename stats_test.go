@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHistogramBucketsRoundTripWithinTolerance(t *testing.T) {
+	for _, d := range []time.Duration{
+		0, time.Microsecond, 3 * time.Microsecond, 17 * time.Microsecond, time.Millisecond, 42 * time.Millisecond,
+		1500 * time.Millisecond, time.Minute, 10 * time.Hour,
+	} {
+		value := histogramBucketValue(histogramBucket(d))
+		assert.InDelta(t, float64(d), float64(value), float64(d)/4+float64(time.Microsecond), "duration %s", d)
+	}
+}
+
+func TestHistogramPercentiles(t *testing.T) {
+	var h latencyHistogram
+	for i := 0; i < 90; i++ {
+		h.record(time.Millisecond)
+	}
+	for i := 0; i < 9; i++ {
+		h.record(100 * time.Millisecond)
+	}
+	h.record(time.Second)
+
+	percentiles := h.percentiles()
+	assert.InDelta(t, float64(time.Millisecond), float64(percentiles.P50), float64(time.Millisecond)/4)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(percentiles.P95), float64(100*time.Millisecond)/4)
+	assert.InDelta(t, float64(100*time.Millisecond), float64(percentiles.P99), float64(100*time.Millisecond)/4)
+
+	assert.Equal(t, LatencyPercentiles{}, (&latencyHistogram{}).percentiles())
+}
+
+func TestCompletionRateSlidesOverItsWindows(t *testing.T) {
+	built := time.Unix(1_000_000, 0)
+	var rate completionRate
+	for i := 0; i < 60; i++ {
+		rate.record(built.Add(5 * time.Second))
+	}
+
+	// Young pools are measured since they were built
+	now := built.Add(10 * time.Second)
+	assert.Equal(t, 6.0, rate.rate(now, built, shortThroughputWindow))
+	assert.Equal(t, 6.0, rate.rate(now, built, longThroughputWindow))
+
+	now = built.Add(2 * time.Minute)
+	assert.Equal(t, 0.0, rate.rate(now, built, shortThroughputWindow))
+	assert.Equal(t, 0.5, rate.rate(now, built, longThroughputWindow))
+
+	// Buckets are reused once their slice has slid out of every window
+	now = built.Add(5*time.Minute + 5*time.Second)
+	rate.record(now)
+	assert.Equal(t, 1/50.0, rate.rate(now.Add(5*time.Second), built, shortThroughputWindow))
+	assert.Equal(t, 1/290.0, rate.rate(now.Add(5*time.Second), built, longThroughputWindow))
+}
+
+func TestManagerSnapshot(t *testing.T) {
+	pm := NewWorkerPoolManager(4, time.Second, 5*time.Second)
+
+	var wg sync.WaitGroup
+	pool, doneUsing := pm.GetPool("key", 2)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		pool.Submit(func() {
+			time.Sleep(time.Millisecond)
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	snapshots := pm.Snapshot()
+	assert.Len(t, snapshots, 1)
+	snapshot := snapshots["key"]
+	assert.Equal(t, 2, snapshot.Workers)
+	assert.Equal(t, 1, snapshot.Reservations)
+	assert.Equal(t, uint64(10), snapshot.Completed)
+	assert.Positive(t, snapshot.Throughput)
+	assert.Positive(t, snapshot.ThroughputLastMinute)
+	assert.Positive(t, snapshot.ThroughputLast5Minutes)
+	assert.Positive(t, snapshot.Age)
+	assert.GreaterOrEqual(t, snapshot.ExecutionLatency.P50, 750*time.Microsecond)
+	assert.GreaterOrEqual(t, snapshot.QueueWaitLatency.P99, snapshot.QueueWaitLatency.P50)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSnapshotTracksExecutingTasks(t *testing.T) {
+	pm := NewWorkerPoolManager(4, time.Hour, time.Hour)
+	pool, doneUsing := pm.GetPool("key", 3)
+	release := make(chan bool)
+	var wg sync.WaitGroup
+	wg.Add(3)
+	for i := 0; i < 3; i++ {
+		pool.Submit(func() {
+			defer wg.Done()
+			<-release
+		})
+	}
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Executing == 3
+	}, time.Second, time.Millisecond)
+
+	close(release)
+	wg.Wait()
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Executing == 0
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 3, pm.Snapshot()["key"].PeakExecuting)
+
+	close(doneUsing)
+	pm.Dispose()
+}
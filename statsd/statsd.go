@@ -0,0 +1,192 @@
+// Package statsd provides a pool.MetricsCollector which emits metrics over UDP in the DogStatsD flavor of the StatsD
+// protocol, as understood by the Datadog agent.
+package statsd
+
+import (
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	pool "github.com/Appboy/worker-pools"
+)
+
+// Metrics are batched into packets of up to this many bytes, which fits in a typical MTU once UDP headers are added
+const maxPacketSize = 1432
+
+// DefaultFlushInterval is how often metrics are sent, unless changed with WithFlushInterval
+const DefaultFlushInterval = 100 * time.Millisecond
+
+// Characters which delimit the parts of a DogStatsD line, and so can't appear in tags
+var tagEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_")
+
+// Tag values can't contain colons either, which would split them into a different tag name and value
+var tagValueEscaper = strings.NewReplacer(",", "_", "|", "_", "#", "_", "\n", "_", ":", "_")
+
+// Option configures an Emitter
+type Option func(*Emitter)
+
+// WithNamespace prefixes every metric name with namespace, followed by a dot
+func WithNamespace(namespace string) Option {
+	return func(e *Emitter) {
+		e.namespace = strings.TrimSuffix(namespace, ".") + "."
+	}
+}
+
+// WithTags adds tags, such as "env:prod", to every metric, in addition to the pool key tag. Commas, pipes and hashes,
+// which would corrupt the lines metrics are sent as, are replaced with underscores, as they are in pool keys.
+func WithTags(tags ...string) Option {
+	return func(e *Emitter) {
+		e.tags = append(e.tags, tags...)
+	}
+}
+
+// WithKeyTag sets the name of the tag each metric's pool key is sent as, "key" by default
+func WithKeyTag(name string) Option {
+	return func(e *Emitter) {
+		e.keyTag = name
+	}
+}
+
+// WithFlushInterval sets how often batched metrics are sent
+func WithFlushInterval(interval time.Duration) Option {
+	return func(e *Emitter) {
+		if interval > 0 {
+			e.flushInterval = interval
+		}
+	}
+}
+
+// Emitter is a pool.MetricsCollector which sends metrics to a StatsD server. Counters are sent with type "c", gauges
+// with "g" and histograms with "h".
+//
+// Metrics are batched and sent periodically, so the Emitter should be closed once it's no longer used, which sends
+// anything still batched. Sending is best-effort, like StatsD itself, so metrics which can't be sent are dropped.
+type Emitter struct {
+	conn          net.Conn
+	namespace     string
+	tags          []string
+	keyTag        string
+	flushInterval time.Duration
+
+	lock   *sync.Mutex
+	buffer []byte
+
+	closeOnce *sync.Once
+	done      chan bool
+	stopped   chan bool
+}
+
+var _ pool.MetricsCollector = (*Emitter)(nil)
+
+// New builds an Emitter sending to the StatsD server at addr, e.g. "127.0.0.1:8125"
+func New(addr string, opts ...Option) (*Emitter, error) {
+	conn, err := net.Dial("udp", addr)
+	if err != nil {
+		return nil, err
+	}
+	e := &Emitter{
+		conn:          conn,
+		keyTag:        "key",
+		flushInterval: DefaultFlushInterval,
+		lock:          &sync.Mutex{},
+		buffer:        make([]byte, 0, maxPacketSize),
+		closeOnce:     &sync.Once{},
+		done:          make(chan bool),
+		stopped:       make(chan bool),
+	}
+	for _, opt := range opts {
+		opt(e)
+	}
+	e.keyTag = tagValueEscaper.Replace(e.keyTag)
+	for i, tag := range e.tags {
+		e.tags[i] = tagEscaper.Replace(tag)
+	}
+	go e.flushPeriodically()
+	return e, nil
+}
+
+// Count sends a counter increment
+func (e *Emitter) Count(name string, key string, delta int64) {
+	e.emit(name, key, strconv.FormatInt(delta, 10), "c")
+}
+
+// Gauge sends a gauge value
+func (e *Emitter) Gauge(name string, key string, value float64) {
+	e.emit(name, key, strconv.FormatFloat(value, 'f', -1, 64), "g")
+}
+
+// Histogram sends a histogram sample
+func (e *Emitter) Histogram(name string, key string, value float64) {
+	e.emit(name, key, strconv.FormatFloat(value, 'f', -1, 64), "h")
+}
+
+// Close sends any batched metrics and stops the Emitter
+func (e *Emitter) Close() error {
+	var err error
+	e.closeOnce.Do(func() {
+		close(e.done)
+		<-e.stopped
+		e.flush()
+		err = e.conn.Close()
+	})
+	return err
+}
+
+func (e *Emitter) emit(name string, key string, value string, metricType string) {
+	var line strings.Builder
+	line.WriteString(e.namespace)
+	line.WriteString(name)
+	line.WriteByte(':')
+	line.WriteString(value)
+	line.WriteByte('|')
+	line.WriteString(metricType)
+	line.WriteString("|#")
+	line.WriteString(e.keyTag)
+	line.WriteByte(':')
+	// Keys are arbitrary strings, so delimiters in them are replaced with underscores rather than corrupt the line
+	line.WriteString(tagValueEscaper.Replace(key))
+	for _, tag := range e.tags {
+		line.WriteByte(',')
+		line.WriteString(tag)
+	}
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if len(e.buffer) > 0 && len(e.buffer)+1+line.Len() > maxPacketSize {
+		e.flushLocked()
+	}
+	if len(e.buffer) > 0 {
+		e.buffer = append(e.buffer, '\n')
+	}
+	e.buffer = append(e.buffer, line.String()...)
+}
+
+func (e *Emitter) flushPeriodically() {
+	defer close(e.stopped)
+	ticker := time.NewTicker(e.flushInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			e.flush()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *Emitter) flush() {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.flushLocked()
+}
+
+func (e *Emitter) flushLocked() {
+	if len(e.buffer) == 0 {
+		return
+	}
+	_, _ = e.conn.Write(e.buffer)
+	e.buffer = e.buffer[:0]
+}
@@ -0,0 +1,82 @@
+package statsd
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func listen(t *testing.T) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	assert.Nil(t, err)
+	t.Cleanup(func() {
+		_ = conn.Close()
+	})
+	return conn
+}
+
+func receive(t *testing.T, conn *net.UDPConn) []string {
+	buf := make([]byte, 2*maxPacketSize)
+	_ = conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	assert.Nil(t, err)
+	return strings.Split(string(buf[:n]), "\n")
+}
+
+func TestEmitterSendsDogStatsDLines(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	e, err := New(server.LocalAddr().String(), WithNamespace("sends"), WithTags("env:test"), WithFlushInterval(time.Hour))
+	assert.Nil(t, err)
+
+	e.Count("tasks_completed", "app-42", 3)
+	e.Gauge("queue_depth", "app-42", 7)
+	e.Histogram("execution_seconds", "app-42", 0.25)
+	assert.Nil(t, e.Close())
+	assert.Nil(t, e.Close())
+
+	assert.Equal(t, []string{
+		"sends.tasks_completed:3|c|#key:app-42,env:test",
+		"sends.queue_depth:7|g|#key:app-42,env:test",
+		"sends.execution_seconds:0.25|h|#key:app-42,env:test",
+	}, receive(t, server))
+}
+
+func TestEmitterEscapesTagDelimiters(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	e, err := New(server.LocalAddr().String(), WithTags("team:a,b"), WithFlushInterval(time.Hour))
+	assert.Nil(t, err)
+
+	e.Count("tasks_completed", "app:42,env:prod|#x\ny", 1)
+	assert.Nil(t, e.Close())
+
+	assert.Equal(t, []string{"tasks_completed:1|c|#key:app_42_env_prod__x_y,team:a_b"}, receive(t, server))
+}
+
+func TestEmitterSplitsPacketsAndFlushesPeriodically(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	server := listen(t)
+	e, err := New(server.LocalAddr().String(), WithKeyTag("tenant"), WithFlushInterval(10*time.Millisecond))
+	assert.Nil(t, err)
+	defer e.Close()
+
+	line := "tasks_submitted:1|c|#tenant:" + strings.Repeat("k", 100)
+	lines := 0
+	for lines*(len(line)+1) < maxPacketSize {
+		e.Count("tasks_submitted", strings.Repeat("k", 100), 1)
+		lines++
+	}
+
+	first := receive(t, server)
+	second := receive(t, server)
+	assert.Equal(t, lines, len(first)+len(second))
+	assert.Equal(t, line, first[0])
+}
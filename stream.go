@@ -0,0 +1,52 @@
+package pool
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// SubmitStream runs stream on p until ctx is canceled - a long-lived consumer, such as a reader of one of a tenant's
+// Kafka partitions, rather than the short tasks pools otherwise execute. Each stream occupies a worker spawned for it,
+// which exits once stream returns, so streams don't count against the pool's size and a pool can mix consumers with
+// bursts of short work without the consumers starving it. Nor are they queued or executing tasks, so Quiesce and
+// disposal don't wait on them: PoolSnapshot.Streams counts them instead.
+//
+// stream is passed a context which is canceled once ctx is or the pool is disposed, and it should return promptly
+// once it is. Like SubmitTask, SubmitStream returns ErrKeyBlocked, ErrPoolQuarantined and the like when the stream is
+// rejected, but a stream is never queued, so it doesn't wait for room.
+func SubmitStream(ctx context.Context, p WorkerPool, stream func(ctx context.Context)) error {
+	return p.submitStream(ctx, stream)
+}
+
+func (p *BaseWorkerPool) submitStream(ctx context.Context, stream func(ctx context.Context)) error {
+	if err := p.admitSubmission(); err != nil {
+		p.options.count(MetricTasksRejected, p.key, 1)
+		return err
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	atomic.AddInt64(&p.stats.streams, 1)
+	p.options.gauge(MetricStreams, p.key, float64(atomic.LoadInt64(&p.stats.streams)))
+
+	// Counted among the pool's workers, so closing an io.Closer pool waits for its streams to return
+	p.workers.Add(2)
+	go func() {
+		defer p.workers.Done()
+		select {
+		case <-p.disposed:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	go func() {
+		defer p.workers.Done()
+		defer func() {
+			cancel()
+			p.options.gauge(MetricStreams, p.key, float64(atomic.AddInt64(&p.stats.streams, -1)))
+		}()
+		if p.recoversPanics() {
+			defer p.recoverPanic(task{})
+		}
+		stream(ctx)
+	}()
+	return nil
+}
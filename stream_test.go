@@ -0,0 +1,56 @@
+package pool
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestStreamsDontTakeShortTasksWorkers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stopped := make(chan bool, 2)
+	consume := func(ctx context.Context) {
+		<-ctx.Done()
+		stopped <- true
+	}
+	assert.Nil(t, SubmitStream(ctx, pool, consume))
+	assert.Nil(t, SubmitStream(context.Background(), pool, consume))
+	assert.Equal(t, 2, pm.Snapshot()["key"].Streams)
+
+	// The pool's only worker is still free for short tasks, and streams aren't waited on
+	executed := make(chan bool)
+	pool.Submit(func() {
+		executed <- true
+	})
+	select {
+	case <-executed:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the task to execute alongside the streams")
+	}
+	quiesceCtx, cancelQuiesce := context.WithTimeout(context.Background(), time.Second)
+	defer cancelQuiesce()
+	assert.Nil(t, pm.Quiesce(quiesceCtx))
+
+	cancel()
+	<-stopped
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].Streams == 1
+	}, time.Second, time.Millisecond)
+
+	// Disposing the pool stops the rest
+	close(doneUsing)
+	pm.Dispose()
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("Expected disposal to cancel the stream")
+	}
+}
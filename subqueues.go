@@ -0,0 +1,124 @@
+package pool
+
+import "sort"
+
+// Weight of the sub-queue tasks without a configured sub-queue wait in, unless WithSubQueues gives it another
+const defaultSubQueueWeight = 1
+
+// WithSubQueues splits each pool's queue into named sub-queues, which share the pool's workers in proportion to
+// weights rather than in submission order - so a tenant's "bulk" backlog can't hold up its "transactional" tasks
+// however deep it gets. Tasks wait in the sub-queue named by TaskInfo.Queue, in submission order, and tasks naming a
+// sub-queue which isn't in weights wait in the unnamed sub-queue, of weight 1 unless weights gives "" another.
+//
+// Workers take from the sub-queues with waiting tasks by smooth weighted round robin: with weights of 3 and 1, and
+// both sub-queues backed up, three of every four tasks started come from the first, evenly interleaved. Weights below
+// 1 count as 1. The queue capacity is shared by the sub-queues.
+//
+// It replaces WithPriorityQueue and WithDeadlineQueue if they're passed too, and is replaced by them if they're passed
+// after it.
+func WithSubQueues(weights map[string]int) Option {
+	return func(o *options) {
+		o.subQueues = make(map[string]int, len(weights))
+		for name, weight := range weights {
+			o.subQueues[name] = weight
+		}
+		o.rankTasks = nil
+		o.queueOrder = "sub-queues"
+	}
+}
+
+func newSubQueues(capacity int, weights map[string]int) *condQueue {
+	if capacity < 1 {
+		capacity = 1
+	}
+	b := &weightedBuffer{byName: make(map[string]*subQueue)}
+	add := func(name string, weight int) {
+		if weight < 1 {
+			weight = 1
+		}
+		q := &subQueue{weight: weight}
+		b.byName[name] = q
+		b.queues = append(b.queues, q)
+	}
+	if _, ok := weights[""]; !ok {
+		add("", defaultSubQueueWeight)
+	}
+	names := make([]string, 0, len(weights))
+	for name := range weights {
+		names = append(names, name)
+	}
+	// Sorted, so ties go the same way every time
+	sort.Strings(names)
+	for _, name := range names {
+		add(name, weights[name])
+	}
+	return newBufferedCondQueue(capacity, b)
+}
+
+// weightedBuffer is a taskBuffer made up of FIFO sub-queues, taken from by smooth weighted round robin
+type weightedBuffer struct {
+	queues []*subQueue
+	byName map[string]*subQueue
+	count  int
+}
+
+type subQueue struct {
+	tasks  []task
+	head   int
+	weight int
+	// The sub-queue's standing in the round robin, see take
+	current int
+}
+
+func (b *weightedBuffer) put(t task) {
+	q, ok := b.byName[t.info.Queue]
+	if !ok {
+		q = b.byName[""]
+	}
+	q.tasks = append(q.tasks, t)
+	b.count++
+}
+
+// Take from the sub-queue furthest ahead once every waiting sub-queue has gained its weight, then set it back by the
+// total weight, as in nginx's smooth weighted round robin
+func (b *weightedBuffer) take() task {
+	var next *subQueue
+	total := 0
+	for _, q := range b.queues {
+		if q.len() == 0 {
+			continue
+		}
+		q.current += q.weight
+		total += q.weight
+		if next == nil || q.current > next.current {
+			next = q
+		}
+	}
+	next.current -= total
+
+	t := next.tasks[next.head]
+	next.tasks[next.head] = task{}
+	next.head++
+	if next.head == len(next.tasks) {
+		// Drained, so the backing array is reused from the start
+		next.tasks, next.head = next.tasks[:0], 0
+		next.current = 0
+	} else if next.head > len(next.tasks)/2 {
+		// Mostly taken, so the rest move to the start, or a sub-queue which never drains would grow without bound
+		waiting := copy(next.tasks, next.tasks[next.head:])
+		for i := waiting; i < len(next.tasks); i++ {
+			next.tasks[i] = task{}
+		}
+		next.tasks, next.head = next.tasks[:waiting], 0
+	}
+	b.count--
+	return t
+}
+
+func (b *weightedBuffer) len() int {
+	return b.count
+}
+
+func (q *subQueue) len() int {
+	return len(q.tasks) - q.head
+}
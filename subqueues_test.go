@@ -0,0 +1,76 @@
+package pool
+
+import (
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestSubQueuesShareWorkersByWeight(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(20),
+		WithSubQueues(map[string]int{"transactional": 3, "bulk": 1}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	// Hold the only worker while the queue fills up
+	release := make(chan bool)
+	started := make(chan bool)
+	pool.Submit(func() {
+		close(started)
+		<-release
+	})
+	<-started
+
+	var lock sync.Mutex
+	var order []string
+	var wg sync.WaitGroup
+	submit := func(queue string) {
+		wg.Add(1)
+		assert.NoError(t, SubmitTask(pool, TaskInfo{Queue: queue}, func() {
+			lock.Lock()
+			order = append(order, queue)
+			lock.Unlock()
+			wg.Done()
+		}))
+	}
+	for i := 0; i < 4; i++ {
+		submit("bulk")
+	}
+	for i := 0; i < 6; i++ {
+		submit("transactional")
+	}
+	// Waits in the unnamed sub-queue, of weight 1
+	submit("unknown")
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, []string{
+		"transactional", "unknown", "transactional", "bulk", "transactional",
+		"transactional", "transactional", "bulk", "transactional", "bulk", "bulk",
+	}, order)
+
+	close(doneUsing)
+	pm.Dispose()
+}
+
+func TestSubQueuesWhichNeverDrainStayBounded(t *testing.T) {
+	q := newSubQueues(4, map[string]int{"bulk": 1})
+	b := q.tasks.(*weightedBuffer)
+	for i := 0; i < 4; i++ {
+		assert.True(t, q.tryPush(task{info: TaskInfo{Queue: "bulk", Label: strconv.Itoa(i)}}))
+	}
+	for i := 4; i < 10000; i++ {
+		taken, ok := q.tryPop()
+		assert.True(t, ok)
+		assert.Equal(t, strconv.Itoa(i-4), taken.info.Label)
+		assert.True(t, q.tryPush(task{info: TaskInfo{Queue: "bulk", Label: strconv.Itoa(i)}}))
+	}
+	assert.Equal(t, 4, q.len())
+	assert.LessOrEqual(t, cap(b.byName["bulk"].tasks), 16)
+}
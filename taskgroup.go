@@ -0,0 +1,90 @@
+package pool
+
+import (
+	"context"
+	"strings"
+	"sync/atomic"
+)
+
+// TaskGroupConfig configures a TaskGroup, see WorkerPoolManager.TaskGroup
+type TaskGroupConfig struct {
+	// CollectErrors makes Wait return the errors of every task which failed, as TaskErrors, rather than the first
+	CollectErrors bool
+	// ContinueOnError keeps executing the group's tasks after one fails. Otherwise the first failure cancels the
+	// context passed to the tasks, and those which haven't started yet are skipped.
+	ContinueOnError bool
+	// MaxConcurrency caps how many of the group's tasks are submitted but unfinished at once, blocking Submit until
+	// there's room, with no cap if it's unset. The key's pool size caps how many execute at once either way.
+	MaxConcurrency int
+}
+
+// TaskGroup is a batch of tasks which can fail, submitted to a key's pool and waited on together, in the manner of
+// the task groups of pool libraries like pond and conc
+type TaskGroup struct {
+	group  *Group
+	parent context.Context
+	ctx    context.Context
+	// Whether a task has been skipped because the group's context was done, accessed atomically
+	skipped int32
+}
+
+// TaskErrors is the errors of a TaskGroup's failed tasks, in the order they failed
+type TaskErrors []error
+
+func (e TaskErrors) Error() string {
+	messages := make([]string, len(e))
+	for i, err := range e {
+		messages[i] = err.Error()
+	}
+	return strings.Join(messages, "; ")
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As match any of them
+func (e TaskErrors) Unwrap() []error {
+	return e
+}
+
+// TaskGroup returns a TaskGroup which executes its tasks on key's pool. Each task is passed a context derived from
+// ctx, and tasks which haven't started by the time it's done are skipped. The pool is checked out by the first
+// Submit and released when Wait returns.
+func (m *WorkerPoolManager) TaskGroup(ctx context.Context, key string, config TaskGroupConfig) *TaskGroup {
+	g := &TaskGroup{group: ErrGroup(m, key), parent: ctx, ctx: ctx}
+	g.group.ctx = ctx
+	g.group.collect = config.CollectErrors
+	if !config.ContinueOnError {
+		g.ctx, g.group.cancel = context.WithCancel(ctx)
+	}
+	if config.MaxConcurrency > 0 {
+		g.group.SetLimit(config.MaxConcurrency)
+	}
+	return g
+}
+
+// Submit executes f on the group's pool, unless the group's context is done before it starts
+func (g *TaskGroup) Submit(f func(ctx context.Context) error) {
+	g.group.Go(func() error {
+		if g.ctx.Err() != nil {
+			atomic.StoreInt32(&g.skipped, 1)
+			return nil
+		}
+		return f(g.ctx)
+	})
+}
+
+// Wait blocks until every submitted task has returned or been skipped, then returns the first task's error - or all
+// of them with TaskGroupConfig.CollectErrors. Rejected submissions count as failed tasks, as with SubmitTask. If no
+// task failed but some were skipped because the context passed to WorkerPoolManager.TaskGroup is done, its error is
+// returned.
+func (g *TaskGroup) Wait() error {
+	err := g.group.Wait()
+	if err == nil {
+		if atomic.LoadInt32(&g.skipped) == 1 {
+			return g.parent.Err()
+		}
+		return nil
+	}
+	if g.group.collect {
+		return TaskErrors(g.group.errors())
+	}
+	return err
+}
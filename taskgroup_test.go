@@ -0,0 +1,84 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestTaskGroupStopsOnTheFirstError(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	g := pm.TaskGroup(context.Background(), "key", TaskGroupConfig{})
+	failure := errors.New("failure")
+	var executed int32
+	g.Submit(func(ctx context.Context) error {
+		atomic.AddInt32(&executed, 1)
+		return failure
+	})
+	for i := 0; i < 5; i++ {
+		g.Submit(func(ctx context.Context) error {
+			atomic.AddInt32(&executed, 1)
+			return nil
+		})
+	}
+	assert.Equal(t, failure, g.Wait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&executed))
+	pm.Dispose()
+}
+
+func TestTaskGroupCollectsErrors(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	g := pm.TaskGroup(context.Background(), "key", TaskGroupConfig{CollectErrors: true, ContinueOnError: true})
+	first, second := errors.New("first"), errors.New("second")
+	var executed int32
+	for _, err := range []error{first, nil, second, nil} {
+		err := err
+		g.Submit(func(ctx context.Context) error {
+			atomic.AddInt32(&executed, 1)
+			return err
+		})
+	}
+	err := g.Wait()
+	assert.Equal(t, TaskErrors{first, second}, err)
+	assert.Equal(t, "first; second", err.Error())
+	assert.True(t, errors.Is(err, second))
+	assert.Equal(t, int32(4), atomic.LoadInt32(&executed))
+
+	g = pm.TaskGroup(context.Background(), "key", TaskGroupConfig{CollectErrors: true})
+	g.Submit(func(ctx context.Context) error { return nil })
+	assert.Nil(t, g.Wait())
+	pm.Dispose()
+}
+
+func TestTaskGroupSkipsTasksOnceItsContextIsDone(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithQueueCapacity(10))
+	ctx, cancel := context.WithCancel(context.Background())
+	g := pm.TaskGroup(ctx, "key", TaskGroupConfig{MaxConcurrency: 2})
+	started := make(chan bool)
+	var skipped int32 = 1
+	g.Submit(func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		return nil
+	})
+	g.Submit(func(ctx context.Context) error {
+		atomic.StoreInt32(&skipped, 0)
+		return nil
+	})
+	<-started
+	cancel()
+	assert.Equal(t, context.Canceled, g.Wait())
+	assert.Equal(t, int32(1), atomic.LoadInt32(&skipped))
+	pm.Dispose()
+}
@@ -0,0 +1,96 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// throttleStoreTimeout bounds each call to a ThrottleStore, so a slow store holds up starts no longer than this
+const throttleStoreTimeout = time.Second
+
+// WithThrottle caps each pool to maxStarts task starts per interval, independently of how many workers are available.
+//
+// Starts are paced smoothly rather than allowed in bursts: consecutive tasks in a pool start at least
+// interval/maxStarts apart. This suits downstreams which enforce request rate limits rather than concurrency limits.
+func WithThrottle(maxStarts int, interval time.Duration) Option {
+	return func(o *options) {
+		o.throttleStarts = maxStarts
+		o.throttleInterval = interval
+	}
+}
+
+// ThrottleStore holds the throttle's state outside the process, so that replicas sharing it share each key's rate
+// limit, see WithThrottleStore. Redis is a natural fit, see the redisthrottle package.
+type ThrottleStore interface {
+	// Take reserves the next start slot of key, whose starts are spaced spacing apart, and returns it. The slot is
+	// the later of now and the slot after the last one reserved, so the store only has to hold one time per key, and
+	// Take must read and advance it atomically across replicas.
+	Take(ctx context.Context, key string, now time.Time, spacing time.Duration) (time.Time, error)
+}
+
+// WithThrottleStore keeps the state of WithThrottle's rate limits in store rather than in each pool, so that replicas
+// sharing store pace their starts for each key together: with N replicas, a key gets maxStarts per interval in total
+// rather than per replica. Concurrency stays local, each replica's pool still caps its own workers.
+//
+// Starts which can't reach store are paced locally instead, and counted as MetricThrottleStoreErrors, so an outage of
+// the store loosens the limit to per replica rather than stalling pools.
+func WithThrottleStore(store ThrottleStore) Option {
+	return func(o *options) {
+		o.throttleStore = store
+	}
+}
+
+// pacer hands out evenly spaced start times to the workers of a pool
+type pacer struct {
+	clock   Clock
+	lock    *sync.Mutex
+	spacing time.Duration
+	next    time.Time
+	// Shares the slots of the pool's key with other replicas, if set
+	store   ThrottleStore
+	key     string
+	options *options
+}
+
+func newPacer(clock Clock, maxStarts int, interval time.Duration) *pacer {
+	return &pacer{
+		clock:   clock,
+		lock:    &sync.Mutex{},
+		spacing: interval / time.Duration(maxStarts),
+	}
+}
+
+// wait blocks until the caller's start slot arrives, returning false if done is closed first
+func (t *pacer) wait(done <-chan bool) bool {
+	if t.store != nil {
+		now := t.clock.Now()
+		if slot, ok := t.take(now); ok {
+			return sleep(t.clock, slot.Sub(now), done)
+		}
+	}
+
+	t.lock.Lock()
+	now := t.clock.Now()
+	// Idle time isn't banked - a pool that has been quiet gets one immediate start, not a burst
+	slot := t.next
+	if slot.Before(now) {
+		slot = now
+	}
+	t.next = slot.Add(t.spacing)
+	t.lock.Unlock()
+
+	return sleep(t.clock, slot.Sub(now), done)
+}
+
+// take reserves a slot from the store, returning false if it failed
+func (t *pacer) take(now time.Time) (time.Time, bool) {
+	ctx, cancel := context.WithTimeout(context.Background(), throttleStoreTimeout)
+	defer cancel()
+	slot, err := t.store.Take(ctx, t.key, now, t.spacing)
+	if err != nil {
+		t.options.count(MetricThrottleStoreErrors, t.key, 1)
+		return time.Time{}, false
+	}
+	return slot, true
+}
@@ -0,0 +1,157 @@
+package pool
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestThrottlePacesTaskStarts(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPoolWithOptions(10, WithThrottle(100, time.Second))
+	p.spawnWorkers(10)
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var starts []time.Time
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		p.Submit(func() {
+			lock.Lock()
+			starts = append(starts, time.Now())
+			lock.Unlock()
+			wg.Done()
+		})
+	}
+	wg.Wait()
+
+	// 6 starts spaced 10ms apart should span at least 50ms, despite there being enough workers to run them all at once
+	assert.GreaterOrEqual(t, starts[len(starts)-1].Sub(starts[0]), 45*time.Millisecond)
+	p.Dispose()
+}
+
+func TestDisposeInterruptsThrottledWorkers(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPoolWithOptions(2, WithThrottle(1, time.Hour))
+	p.spawnWorkers(2)
+
+	started := make(chan bool, 2)
+	for i := 0; i < 2; i++ {
+		p.Submit(func() {
+			started <- true
+		})
+	}
+	<-started
+
+	// The second task is waiting an hour for its slot, and should be abandoned on dispose
+	p.Dispose()
+	assert.Len(t, started, 0)
+}
+
+func TestManagerAppliesThrottleToCustomFactoryPools(t *testing.T) {
+	pm := NewWorkerPoolManager(10, time.Second, 5*time.Second, WithThrottle(1, time.Hour))
+	var factory Factory = func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &MockWorkerPool{WorkerPool: basePool}, nil
+	}
+	pool, doneUsing, _ := pm.GetPoolWithFactory("key", 1, factory)
+	assert.NotNil(t, pool.(*MockWorkerPool).WorkerPool.(*BaseWorkerPool).pacer)
+	close(doneUsing)
+
+	unthrottled, _ := NewWorkerPoolWithOptions(10)
+	pool, doneUsing, _ = pm.GetPoolWithFactory("other key", 1, func(int) (WorkerPool, error) {
+		return unthrottled, nil
+	})
+	assert.Nil(t, pool.(*BaseWorkerPool).pacer, "Expected the pool's own options to take precedence")
+	close(doneUsing)
+
+	pm.Dispose()
+}
+
+// memoryThrottleStore is a ThrottleStore shared in memory, standing in for Redis between replicas
+type memoryThrottleStore struct {
+	lock  sync.Mutex
+	slots map[string]time.Time
+	err   error
+}
+
+func (s *memoryThrottleStore) Take(
+	ctx context.Context, key string, now time.Time, spacing time.Duration,
+) (time.Time, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if s.err != nil {
+		return time.Time{}, s.err
+	}
+	slot := s.slots[key]
+	if slot.Before(now) {
+		slot = now
+	}
+	s.slots[key] = slot.Add(spacing)
+	return slot, nil
+}
+
+func TestThrottleStoreSharesRateLimitsBetweenReplicas(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	store := &memoryThrottleStore{slots: make(map[string]time.Time)}
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	var starts []time.Time
+	for replica := 0; replica < 2; replica++ {
+		pm := NewWorkerPoolManager(10, time.Second, time.Hour, WithThrottle(100, time.Second), WithThrottleStore(store))
+		defer pm.Dispose()
+		pool, doneUsing := pm.GetPool("key", 10)
+		for i := 0; i < 3; i++ {
+			wg.Add(1)
+			pool.Submit(func() {
+				lock.Lock()
+				starts = append(starts, time.Now())
+				lock.Unlock()
+				wg.Done()
+			})
+		}
+		close(doneUsing)
+	}
+	wg.Wait()
+
+	// Each replica only starts 3 tasks, but the 6 between them share one 10ms spacing
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	assert.GreaterOrEqual(t, starts[len(starts)-1].Sub(starts[0]), 45*time.Millisecond)
+	store.lock.Lock()
+	assert.Len(t, store.slots, 1)
+	store.lock.Unlock()
+}
+
+func TestThrottlePacesLocallyWhenTheStoreFails(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	store := &memoryThrottleStore{err: errors.New("store unavailable")}
+	collector := newRecordingCollector()
+	pm := NewWorkerPoolManager(10, time.Second, time.Hour,
+		WithThrottle(100, time.Second), WithThrottleStore(store), WithMetrics(collector))
+	defer pm.Dispose()
+	assert.Contains(t, pm.Config().Features, "throttle store")
+
+	pool, doneUsing := pm.GetPool("key", 2)
+	defer close(doneUsing)
+	done := make(chan bool, 3)
+	for i := 0; i < 3; i++ {
+		pool.Submit(func() {
+			done <- true
+		})
+	}
+	for i := 0; i < 3; i++ {
+		select {
+		case <-done:
+		case <-time.After(time.Second):
+			t.Fatal("Expected tasks to start despite the store failing")
+		}
+	}
+	assert.Equal(t, int64(3), collector.count(MetricThrottleStoreErrors, "key"))
+}
@@ -0,0 +1,72 @@
+package pool
+
+import (
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// Completions are counted in buckets this wide, enough of them to cover the longest window they're reported over
+const (
+	completionBucketWidth = 10 * time.Second
+	completionBuckets     = 30
+)
+
+// The windows PoolSnapshot.ThroughputLastMinute and ThroughputLast5Minutes are measured over
+const (
+	shortThroughputWindow = time.Minute
+	longThroughputWindow  = 5 * time.Minute
+)
+
+// completionRate is a lock-free rolling count of completed tasks. Each bucket packs the slice of time it's counting,
+// truncated to 32 bits, above the number of tasks completed during it.
+type completionRate struct {
+	buckets [completionBuckets]uint64
+}
+
+func (r *completionRate) record(now time.Time) {
+	slice := uint64(now.UnixNano() / int64(completionBucketWidth))
+	bucket := &r.buckets[slice%completionBuckets]
+	for {
+		old := atomic.LoadUint64(bucket)
+		next := slice<<32 | 1
+		if old>>32 == slice&math.MaxUint32 {
+			next = old + 1
+		}
+		if atomic.CompareAndSwapUint64(bucket, old, next) {
+			return
+		}
+	}
+}
+
+// The number of tasks completed per second over the window before now, or since built if that's more recent
+func (r *completionRate) rate(now time.Time, built time.Time, window time.Duration) float64 {
+	slice := now.UnixNano() / int64(completionBucketWidth)
+	slices := int64(window / completionBucketWidth)
+	var completed uint64
+	for i := int64(0); i < slices; i++ {
+		s := uint64(slice - i)
+		if bucket := atomic.LoadUint64(&r.buckets[s%completionBuckets]); bucket>>32 == s&math.MaxUint32 {
+			completed += bucket & math.MaxUint32
+		}
+	}
+
+	// The current slice is only part way through
+	start := time.Unix(0, (slice-slices+1)*int64(completionBucketWidth))
+	if built.After(start) {
+		start = built
+	}
+	elapsed := now.Sub(start)
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(completed) / elapsed.Seconds()
+}
+
+// Report the pool's throughput over the last minute, which is cheap but not free, so only with WithMetrics
+func (p *BaseWorkerPool) reportThroughput(now time.Time) {
+	if p.options == nil || p.options.metrics == nil {
+		return
+	}
+	p.options.gauge(MetricThroughput, p.key, p.stats.completions.rate(now, p.creationTime, shortThroughputWindow))
+}
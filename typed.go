@@ -0,0 +1,205 @@
+package pool
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// TypedManager is a WorkerPoolManager keyed by any comparable type, such as a struct of an app ID and a channel, so
+// callers don't need to build keys by concatenating strings and parse them back apart.
+//
+// Each key is given a name - its value formatted with %+v, made unique if two keys format the same - which is what
+// the underlying manager, metrics, hooks and events see. Name and Key convert between the two.
+type TypedManager[K comparable] struct {
+	manager *WorkerPoolManager
+	// Guards names and keys. Checkouts hold it for reading, so names aren't forgotten while pools are being built.
+	lock  *sync.RWMutex
+	names map[K]string
+	keys  map[string]K
+}
+
+// NewTypedManager builds a TypedManager, see NewWorkerPoolManager for its arguments
+func NewTypedManager[K comparable](
+	poolSize int, stalePoolExpiration time.Duration, maxPoolLifetime time.Duration, opts ...Option,
+) *TypedManager[K] {
+	m := &TypedManager[K]{
+		lock:  &sync.RWMutex{},
+		names: make(map[K]string),
+		keys:  make(map[string]K),
+	}
+	opts = append(opts, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			m.forget(eviction.Key)
+		},
+	}))
+	m.manager = NewWorkerPoolManager(poolSize, stalePoolExpiration, maxPoolLifetime, opts...)
+	return m
+}
+
+// GetPool returns the WorkerPool for key, see WorkerPoolManager.GetPool
+func (m *TypedManager[K]) GetPool(key K, sendSize int) (pool WorkerPool, doneUsing chan<- bool) {
+	m.withName(key, func(name string) {
+		pool, doneUsing = m.manager.GetPool(name, sendSize)
+	})
+	return pool, doneUsing
+}
+
+// GetPoolWithFactory returns the WorkerPool for key, building it with factory if need be, see
+// WorkerPoolManager.GetPoolWithFactory
+func (m *TypedManager[K]) GetPoolWithFactory(
+	key K, sendSize int, factory Factory,
+) (pool WorkerPool, doneUsing chan<- bool, err error) {
+	m.withName(key, func(name string) {
+		pool, doneUsing, err = m.manager.GetPoolWithFactory(name, sendSize, factory)
+	})
+	return pool, doneUsing, err
+}
+
+// GetPoolWithSize returns the WorkerPool for key, building it with at most size workers if need be, see
+// WorkerPoolManager.GetPoolWithSize
+func (m *TypedManager[K]) GetPoolWithSize(
+	key K, sendSize int, size int, factory Factory,
+) (pool WorkerPool, doneUsing chan<- bool, err error) {
+	m.withName(key, func(name string) {
+		pool, doneUsing, err = m.manager.GetPoolWithSize(name, sendSize, size, factory)
+	})
+	return pool, doneUsing, err
+}
+
+// GetPoolWithStats returns the WorkerPool for key, and a release func returning the checkout's stats, see
+// WorkerPoolManager.GetPoolWithStats. The stats' key is key's name.
+func (m *TypedManager[K]) GetPoolWithStats(key K, sendSize int) (pool WorkerPool, release func() CheckoutStats) {
+	m.withName(key, func(name string) {
+		pool, release = m.manager.GetPoolWithStats(name, sendSize)
+	})
+	return pool, release
+}
+
+// GetPoolContext returns the WorkerPool for key, waiting for a checkout to be released if need be, see
+// WorkerPoolManager.GetPoolContext
+func (m *TypedManager[K]) GetPoolContext(
+	ctx context.Context, key K, sendSize int, factory Factory,
+) (pool WorkerPool, doneUsing chan<- bool, err error) {
+	m.withName(key, func(name string) {
+		pool, doneUsing, err = m.manager.GetPoolContext(ctx, name, sendSize, factory)
+	})
+	return pool, doneUsing, err
+}
+
+// Snapshot returns a PoolSnapshot of every cached pool, by key
+func (m *TypedManager[K]) Snapshot() map[K]PoolSnapshot {
+	snapshots := m.manager.Snapshot()
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	typed := make(map[K]PoolSnapshot, len(snapshots))
+	for name, snapshot := range snapshots {
+		if key, ok := m.keys[name]; ok {
+			typed[key] = snapshot
+		}
+	}
+	return typed
+}
+
+// PauseKey pauses key's pool, see WorkerPoolManager.PauseKey
+func (m *TypedManager[K]) PauseKey(key K) bool {
+	name, ok := m.Name(key)
+	return ok && m.manager.PauseKey(name)
+}
+
+// ResumeKey resumes key's pool, see WorkerPoolManager.ResumeKey
+func (m *TypedManager[K]) ResumeKey(key K) bool {
+	name, ok := m.Name(key)
+	return ok && m.manager.ResumeKey(name)
+}
+
+// Block suspends key, see WorkerPoolManager.Block
+func (m *TypedManager[K]) Block(key K) {
+	m.withName(key, m.manager.Block)
+}
+
+// Unblock lifts a Block on key
+func (m *TypedManager[K]) Unblock(key K) {
+	name, ok := m.Name(key)
+	if !ok {
+		return
+	}
+	m.manager.Unblock(name)
+	m.forget(name)
+}
+
+// Blocked returns whether key is blocked
+func (m *TypedManager[K]) Blocked(key K) bool {
+	name, ok := m.Name(key)
+	return ok && m.manager.Blocked(name)
+}
+
+// Name returns the name key is known by to the underlying manager, or false if it doesn't have a pool or a block
+func (m *TypedManager[K]) Name(key K) (string, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	name, ok := m.names[key]
+	return name, ok
+}
+
+// Key returns the key known by name to the underlying manager, e.g. to interpret the keys passed to hooks
+func (m *TypedManager[K]) Key(name string) (K, bool) {
+	m.lock.RLock()
+	defer m.lock.RUnlock()
+	key, ok := m.keys[name]
+	return key, ok
+}
+
+// Manager returns the underlying manager, which is keyed by name
+func (m *TypedManager[K]) Manager() *WorkerPoolManager {
+	return m.manager
+}
+
+// Dispose disposes the underlying manager, see WorkerPoolManager.Dispose
+func (m *TypedManager[K]) Dispose() {
+	m.manager.Dispose()
+}
+
+// Call f with key's name, giving key a name if it doesn't have one yet. The name can't be forgotten until f returns.
+func (m *TypedManager[K]) withName(key K, f func(name string)) {
+	for {
+		m.lock.RLock()
+		if name, ok := m.names[key]; ok {
+			defer m.lock.RUnlock()
+			f(name)
+			return
+		}
+		m.lock.RUnlock()
+		m.assignName(key)
+	}
+}
+
+func (m *TypedManager[K]) assignName(key K) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if _, ok := m.names[key]; ok {
+		return
+	}
+	name := fmt.Sprintf("%+v", key)
+	for i := 2; ; i++ {
+		if _, taken := m.keys[name]; !taken {
+			break
+		}
+		name = fmt.Sprintf("%+v#%d", key, i)
+	}
+	m.names[key] = name
+	m.keys[name] = key
+}
+
+// Forget name once its key has neither a pool nor a block, so names don't pile up as keys come and go
+func (m *TypedManager[K]) forget(name string) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	key, ok := m.keys[name]
+	if !ok || m.manager.cached(name) || m.manager.Blocked(name) {
+		return
+	}
+	delete(m.names, key)
+	delete(m.keys, name)
+}
@@ -0,0 +1,104 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type appChannel struct {
+	AppID   int
+	Channel string
+}
+
+// formatsAlike keys all format the same, so their names have to be made unique
+type formatsAlike struct {
+	id int
+}
+
+func (formatsAlike) String() string {
+	return "alike"
+}
+
+func TestTypedManagerKeysPoolsByValue(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evicted := make(chan string, 1)
+	pm := NewTypedManager[appChannel](2, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evicted <- eviction.Key
+		},
+	}))
+	push := appChannel{AppID: 42, Channel: "push"}
+	pool, doneUsing := pm.GetPool(push, 2)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	pool.Submit(wg.Done)
+	wg.Wait()
+	close(doneUsing)
+
+	same, doneUsingSame := pm.GetPool(appChannel{AppID: 42, Channel: "push"}, 1)
+	assert.Equal(t, pool, same)
+	close(doneUsingSame)
+	email, doneUsingEmail, err := pm.GetPoolWithFactory(appChannel{AppID: 42, Channel: "email"}, 1, NewWorkerPool)
+	assert.Nil(t, err)
+	assert.NotEqual(t, pool, email)
+	close(doneUsingEmail)
+
+	name, ok := pm.Name(push)
+	assert.True(t, ok)
+	assert.Equal(t, "{AppID:42 Channel:push}", name)
+	key, ok := pm.Key(name)
+	assert.True(t, ok)
+	assert.Equal(t, push, key)
+	snapshots := pm.Snapshot()
+	assert.Len(t, snapshots, 2)
+	assert.Equal(t, uint64(1), snapshots[push].Completed)
+
+	assert.True(t, pm.PauseKey(push))
+	assert.True(t, pm.Snapshot()[push].Paused)
+	assert.True(t, pm.ResumeKey(push))
+	assert.False(t, pm.PauseKey(appChannel{AppID: 7}))
+
+	// Names are forgotten along with their pools
+	pm.Manager().workerPoolCache.Delete(name)
+	assert.Equal(t, name, <-evicted)
+	assert.Eventually(t, func() bool {
+		_, ok := pm.Name(push)
+		return !ok
+	}, time.Second, time.Millisecond)
+	_, ok = pm.Key(name)
+	assert.False(t, ok)
+	pm.Dispose()
+}
+
+func TestTypedManagerNamesKeysUniquely(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewTypedManager[formatsAlike](1, time.Hour, time.Hour)
+	first, doneUsingFirst := pm.GetPool(formatsAlike{id: 1}, 1)
+	second, doneUsingSecond := pm.GetPool(formatsAlike{id: 2}, 1)
+	assert.NotEqual(t, first, second)
+	close(doneUsingFirst)
+	close(doneUsingSecond)
+
+	firstName, _ := pm.Name(formatsAlike{id: 1})
+	secondName, _ := pm.Name(formatsAlike{id: 2})
+	assert.Equal(t, "alike", firstName)
+	assert.Equal(t, "alike#2", secondName)
+
+	// Blocked keys keep their names without a pool
+	pm.Block(formatsAlike{id: 3})
+	assert.True(t, pm.Blocked(formatsAlike{id: 3}))
+	assert.False(t, pm.Blocked(formatsAlike{id: 4}))
+	blockedName, ok := pm.Name(formatsAlike{id: 3})
+	assert.True(t, ok)
+	assert.True(t, pm.Manager().Blocked(blockedName))
+	pm.Unblock(formatsAlike{id: 3})
+	_, ok = pm.Name(formatsAlike{id: 3})
+	assert.False(t, ok)
+	pm.Dispose()
+}
@@ -0,0 +1,118 @@
+package pool
+
+import (
+	"bytes"
+	"runtime"
+	"strconv"
+	"sync/atomic"
+	"time"
+)
+
+// Watchdog configures detection of tasks which run for too long, set with WithWatchdog
+type Watchdog struct {
+	// Threshold is how long a task may run before it's reported as stuck
+	Threshold time.Duration
+	// CaptureStack includes the stuck worker's stack in reports. Capturing it briefly stops the world, so it should be
+	// reserved for thresholds which are only crossed rarely.
+	CaptureStack bool
+	// ReplaceStuckWorkers spawns a temporary extra worker for each stuck task, which keeps working through the pool's
+	// queue until the stuck task finishes. Temporary workers aren't counted in PoolSnapshot.Workers.
+	ReplaceStuckWorkers bool
+}
+
+// StuckTask describes a task reported by a pool's Watchdog
+type StuckTask struct {
+	Key  string
+	Info TaskInfo
+	// Running is how long the task had been running when it was reported
+	Running time.Duration
+	// Stack is the stuck worker's stack, if Watchdog.CaptureStack is set
+	Stack []byte
+	// SubmitSite is where the task was submitted, in debug mode - see WithDebug
+	SubmitSite *CallSite
+}
+
+// WithWatchdog reports tasks which run for longer than watchdog.Threshold to the OnStuckTask hook, and counts them in
+// PoolSnapshot.StuckTasks. Each task is reported at most once.
+func WithWatchdog(watchdog Watchdog) Option {
+	return func(o *options) {
+		if watchdog.Threshold > 0 {
+			o.watchdog = &watchdog
+		}
+	}
+}
+
+func (o *options) taskStuck(stuck StuckTask) {
+	for _, hooks := range o.registeredHooks() {
+		if hooks.OnStuckTask != nil {
+			hooks.OnStuckTask(stuck)
+		}
+	}
+}
+
+// Watch t while it executes on worker, returning a function to call once it finishes
+func (p *BaseWorkerPool) watch(t task, worker *Worker) func() {
+	if p.options == nil || p.options.watchdog == nil {
+		return func() {}
+	}
+	// Only what the report needs is passed on, as capturing t in the timer's closure would move every task executed to
+	// the heap, watched or not
+	return p.watchStuck(t.info, t.site, worker)
+}
+
+func (p *BaseWorkerPool) watchStuck(info TaskInfo, site *CallSite, worker *Worker) func() {
+	watchdog := p.options.watchdog
+	start := p.clock.Now()
+	finished := make(chan bool)
+	timer := p.clock.AfterFunc(watchdog.Threshold, func() {
+		atomic.AddUint64(&p.stats.stuck, 1)
+		p.options.count(MetricStuckTasks, p.key, 1)
+		if watchdog.ReplaceStuckWorkers {
+			p.workers.Add(1)
+			go p.runWorker(finished)
+		}
+		stuck := StuckTask{Key: p.key, Info: info, Running: p.clock.Now().Sub(start), SubmitSite: site}
+		if watchdog.CaptureStack {
+			stuck.Stack = goroutineStack(worker.goroutine)
+		}
+		p.options.taskStuck(stuck)
+	})
+	return func() {
+		timer.Stop()
+		close(finished)
+	}
+}
+
+// The id of the calling goroutine
+func currentGoroutine() uint64 {
+	buf := make([]byte, 64)
+	buf = buf[:runtime.Stack(buf, false)]
+	// The trace starts "goroutine 123 [running]:"
+	buf = bytes.TrimPrefix(buf, []byte("goroutine "))
+	if i := bytes.IndexByte(buf, ' '); i >= 0 {
+		buf = buf[:i]
+	}
+	id, _ := strconv.ParseUint(string(buf), 10, 64)
+	return id
+}
+
+// The current stack of the goroutine with the given id, or nil if it isn't running
+func goroutineStack(id uint64) []byte {
+	buf := make([]byte, 1<<16)
+	for {
+		n := runtime.Stack(buf, true)
+		if n < len(buf) {
+			buf = buf[:n]
+			break
+		}
+		buf = make([]byte, 2*len(buf))
+	}
+
+	header := []byte("goroutine " + strconv.FormatUint(id, 10) + " [")
+	for _, trace := range bytes.Split(buf, []byte("\n\n")) {
+		if bytes.HasPrefix(trace, header) {
+			return trace
+		}
+	}
+	return nil
+}
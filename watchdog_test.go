@@ -0,0 +1,66 @@
+package pool
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestWatchdogReportsStuckTasks(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	reports := make(chan StuckTask, 10)
+	pm := NewWorkerPoolManager(
+		1, time.Second, time.Hour,
+		WithWatchdog(Watchdog{Threshold: 20 * time.Millisecond, CaptureStack: true, ReplaceStuckWorkers: true}),
+		WithHooks(Hooks{
+			OnStuckTask: func(stuck StuckTask) {
+				reports <- stuck
+			},
+		}),
+	)
+	pool, doneUsing := pm.GetPool("key", 1)
+
+	unblock := make(chan bool)
+	SubmitTask(pool, TaskInfo{Label: "export"}, func() {
+		<-unblock
+	})
+	stuck := <-reports
+	assert.Equal(t, "key", stuck.Key)
+	assert.Equal(t, "export", stuck.Info.Label)
+	assert.GreaterOrEqual(t, stuck.Running, 20*time.Millisecond)
+	assert.Contains(t, string(stuck.Stack), "TestWatchdogReportsStuckTasks")
+
+	// The temporary worker keeps the pool going while its only worker is stuck
+	done := make(chan bool)
+	pool.Submit(func() {
+		close(done)
+	})
+	<-done
+	assert.Equal(t, uint64(1), pm.Snapshot()["key"].StuckTasks)
+
+	close(unblock)
+	close(doneUsing)
+	pm.Dispose()
+	assert.Empty(t, reports)
+}
+
+func TestWatchdogKeepsSubmitRunnerAllocationFree(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	// Pools without a watchdog mustn't pay for it, as a task captured by watch would be moved to the heap regardless
+	p, _ := NewWorkerPoolWithOptions(4, WithPanicRecovery())
+	p.spawnWorkers(4)
+	defer p.Dispose()
+
+	var wg sync.WaitGroup
+	runner := &countdownRunner{wg: &wg}
+	wg.Add(101)
+	allocs := testing.AllocsPerRun(100, func() {
+		p.SubmitRunner(runner)
+	})
+	wg.Wait()
+	assert.Equal(t, 0.0, allocs)
+}
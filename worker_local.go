@@ -0,0 +1,64 @@
+package pool
+
+import "sync/atomic"
+
+// Worker is the worker executing a task submitted with SubmitOnWorker. It's only ever used by one goroutine at a time,
+// so tasks can use its state and locals without locking.
+type Worker struct {
+	state  interface{}
+	locals map[uint64]interface{}
+	// The id of the worker's goroutine, only tracked when stuck task stacks are captured or in debug mode
+	goroutine uint64
+}
+
+// State returns the worker's state, as built by WithWorkerInit, or nil for pools without a worker init
+func (w *Worker) State() interface{} {
+	return w.state
+}
+
+// SubmitOnWorker submits w to be executed with the Worker which picks it up, giving it access to worker-local values
+func SubmitOnWorker(p WorkerPool, w func(worker *Worker)) {
+	_ = p.enqueue(task{onWorker: w})
+}
+
+// Source of WorkerLocal ids
+var workerLocalIDs uint64
+
+// WorkerLocal is a value of which each worker has its own copy, built the first time a task on that worker asks for
+// it. This suits scratch state which is expensive to build but unsafe to share, like per-worker rate limiters or
+// reusable serialization buffers.
+//
+// WorkerLocals are usually declared once, e.g. as package variables, and shared by every pool.
+type WorkerLocal[T any] struct {
+	id      uint64
+	newFunc func() T
+}
+
+// NewWorkerLocal builds a WorkerLocal whose copies are built by newFunc
+func NewWorkerLocal[T any](newFunc func() T) *WorkerLocal[T] {
+	return &WorkerLocal[T]{
+		id:      atomic.AddUint64(&workerLocalIDs, 1),
+		newFunc: newFunc,
+	}
+}
+
+// Get returns worker's copy of the value, building it if this is the first time worker has asked for it
+func (l *WorkerLocal[T]) Get(worker *Worker) T {
+	if value, ok := worker.locals[l.id]; ok {
+		return value.(T)
+	}
+	value := l.newFunc()
+	if worker.locals == nil {
+		worker.locals = make(map[uint64]interface{})
+	}
+	worker.locals[l.id] = value
+	return value
+}
+
+// Set replaces worker's copy of the value
+func (l *WorkerLocal[T]) Set(worker *Worker, value T) {
+	if worker.locals == nil {
+		worker.locals = make(map[uint64]interface{})
+	}
+	worker.locals[l.id] = value
+}
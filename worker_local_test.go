@@ -0,0 +1,57 @@
+package pool
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+var testBuffers = NewWorkerLocal(func() *bytes.Buffer {
+	return &bytes.Buffer{}
+})
+
+func TestWorkerLocalsAreBuiltOncePerWorker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPoolWithOptions(2, WithWorkerInit(func() (interface{}, error) {
+		return "state", nil
+	}))
+	p.spawnWorkers(2)
+
+	var wg sync.WaitGroup
+	var lock sync.Mutex
+	buffers := map[*bytes.Buffer]bool{}
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		SubmitOnWorker(p, func(worker *Worker) {
+			defer wg.Done()
+			assert.Equal(t, "state", worker.State())
+
+			// Nothing else touches this worker's buffer while the task runs, so no locking is needed to use it
+			buffer := testBuffers.Get(worker)
+			buffer.Reset()
+			buffer.WriteString("scratch")
+
+			lock.Lock()
+			buffers[buffer] = true
+			lock.Unlock()
+		})
+	}
+	wg.Wait()
+
+	assert.LessOrEqual(t, len(buffers), 2)
+	p.Dispose()
+}
+
+func TestWorkerLocalSet(t *testing.T) {
+	counter := NewWorkerLocal(func() int {
+		return 10
+	})
+	worker := &Worker{}
+	assert.Equal(t, 10, counter.Get(worker))
+	counter.Set(worker, counter.Get(worker)+1)
+	assert.Equal(t, 11, counter.Get(worker))
+	assert.Equal(t, 10, counter.Get(&Worker{}))
+}
@@ -0,0 +1,35 @@
+package pool
+
+// WithWorkerLoop customizes how each worker runs, while keeping the pool's queueing, reservation and lifetime
+// behavior. run is called on each worker's goroutine, and must call loop, which executes tasks until the pool is
+// disposed. This allows workers to, for example, lock themselves to an OS thread:
+//
+//	pool.WithWorkerLoop(func(loop func()) {
+//		runtime.LockOSThread()
+//		defer runtime.UnlockOSThread()
+//		loop()
+//	})
+//
+// A task which panics unwinds out of loop. loop can be called again to carry on executing tasks, so run can recover
+// from panics in its own way:
+//
+//	pool.WithWorkerLoop(func(loop func()) {
+//		for stopped := false; !stopped; {
+//			func() {
+//				defer func() {
+//					if r := recover(); r != nil {
+//						log.Printf("task panicked: %v", r)
+//					}
+//				}()
+//				loop()
+//				stopped = true
+//			}()
+//		}
+//	})
+//
+// State built by WithWorkerInit is kept across calls to loop, and torn down once run returns.
+func WithWorkerLoop(run func(loop func())) Option {
+	return func(o *options) {
+		o.workerLoop = run
+	}
+}
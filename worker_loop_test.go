@@ -0,0 +1,106 @@
+package pool
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestWorkerLoopWrapsEachWorker(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var running sync.WaitGroup
+	running.Add(2)
+	stopped := make(chan bool, 2)
+	p, _ := NewWorkerPoolWithOptions(2, WithWorkerLoop(func(loop func()) {
+		running.Done()
+		loop()
+		stopped <- true
+	}))
+	p.spawnWorkers(2)
+	running.Wait()
+
+	done := make(chan bool)
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+
+	p.Dispose()
+	<-stopped
+	<-stopped
+}
+
+func TestWorkerLoopCanRecoverAndResume(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	recovered := make(chan interface{}, 10)
+	p, _ := NewWorkerPoolWithOptions(1, WithWorkerLoop(func(loop func()) {
+		for stopped := false; !stopped; {
+			func() {
+				defer func() {
+					if r := recover(); r != nil {
+						recovered <- r
+					}
+				}()
+				loop()
+				stopped = true
+			}()
+		}
+	}))
+	p.spawnWorkers(1)
+
+	p.Submit(func() {
+		panic("poisoned task")
+	})
+	done := make(chan bool)
+	p.Submit(func() {
+		close(done)
+	})
+	<-done
+	assert.Equal(t, "poisoned task", <-recovered)
+
+	// The panicking task doesn't count as unfinished work
+	pm := NewWorkerPoolManager(1, 0, 0)
+	pm.workerPoolCache.Set("key", p, 0)
+	assert.Nil(t, pm.Quiesce(context.Background()))
+	pm.workerPoolCache.Delete("key")
+	pm.Dispose()
+}
+
+func TestWorkersWhichExitAreReplaced(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(2, time.Second, time.Hour, WithWorkerLoop(func(loop func()) {
+		// Recovers, but doesn't resume, so the worker exits
+		defer func() {
+			_ = recover()
+		}()
+		loop()
+	}))
+	pool, doneUsing := pm.GetPool("key", 2)
+	close(doneUsing)
+	pool.Submit(func() {
+		panic("worker exits")
+	})
+	assert.Eventually(t, func() bool {
+		return pm.Snapshot()["key"].AliveWorkers == 1
+	}, time.Second, time.Millisecond)
+	snapshot := pm.Snapshot()["key"]
+	assert.Equal(t, 1, snapshot.Workers)
+	assert.Equal(t, uint64(2), snapshot.SpawnedWorkers)
+
+	_, doneUsing = pm.GetPool("key", 2)
+	assert.Equal(t, 2, pm.Snapshot()["key"].Workers)
+	assert.Eventually(t, func() bool {
+		snapshot := pm.Snapshot()["key"]
+		return snapshot.AliveWorkers == 2 && snapshot.SpawnedWorkers == 3
+	}, time.Second, time.Millisecond)
+
+	close(doneUsing)
+	pm.Dispose()
+}
@@ -1,32 +1,145 @@
 package pool
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
+// ErrDisposed is returned for submissions to a pool which has been disposed, e.g. after it was evicted
+var ErrDisposed = errors.New("worker pool is disposed")
+
 // Work - a unit of work
 type Work func()
 
+// Runner is a unit of work as an object, for callers which reuse preallocated task objects. Submitting a pointer to
+// a Runner with SubmitRunner doesn't allocate, while a Work closure usually allocates once per submission.
+type Runner interface {
+	Run()
+}
+
 // Factory builds a new WorkerPool
 type Factory func(maxSize int) (WorkerPool, error)
 
 // WorkerPool is a fixed-size pool of workers.
 type WorkerPool interface {
 	Submit(w Work)
+	SubmitRunner(r Runner)
 	Dispose()
+	Pause()
+	Resume()
+	PeekQueue(n int) []TaskInfo
+	PurgeQueue(filter func(info TaskInfo) bool) int
+	Grow(n int) int
+	Shrink(n int) int
 
 	spawnWorkers(sendSize int)
 	reserve() bool
 	release()
+	reservations() int
 	age() time.Duration
+	submitCoalesce(
+		coalesceKey string, payload interface{}, merge func(old, new interface{}) interface{}, handler func(interface{}),
+	)
+	submitDebounce(debounceKey string, quietPeriod time.Duration, maxWait time.Duration, w Work)
+	submitRetry(info TaskInfo, retries int, w func() error) error
+	submitDurable(taskType string, idempotencyKey string, payload []byte) error
+	submitAndWait(w Work) error
+	submitStream(ctx context.Context, stream func(ctx context.Context)) error
+	recentKeys() *idempotencyWindow
+	configure(key string, o *options)
+	enqueue(t task) error
+	idle() bool
+	settled() bool
+	unfinishedWork() UnfinishedWork
+	waitForWorkers()
+	snapshot() PoolSnapshot
+	markEvicted(reason EvictionReason)
+	evictionReason() EvictionReason
+	needsRebuild() bool
+	setBlocked(blocked bool)
+	setFrozen(frozen bool, policy FreezePolicy)
+	setOwned(owned bool)
+	resize(maxSize int) bool
+	setSize(maxSize int)
+}
+
+// task is an item of Work waiting in a pool's queue
+type task struct {
+	// Exactly one of work, runner, onWorker and withState is set, unless it's a wakeup
+	work      Work
+	runner    Runner
+	onWorker  func(worker *Worker)
+	withState func(workerState interface{})
+	info      TaskInfo
+	enqueued  time.Time
+	// Where the task was submitted, only recorded in debug mode
+	site *CallSite
+	// The submission counts of the checkout the task was submitted through, see WithCheckoutLimit and GetPoolWithStats
+	checkout *checkoutCounts
+	// Filled in once the task is enqueued, see SubmitWithPosition
+	position *QueuePosition
+	// The pending submission the task executes, see SubmitCoalesce
+	coalesced *coalescedWork
+	// Whether the task only wakes up an idle worker to retire, see SetKeySize
+	wakeup bool
+	// Called instead of the task's work if it's dropped from the queue without executing, see PurgeQueue
+	dropped func()
+	// The context the task was submitted with, until its baggage has been copied, see SubmitContext
+	ctx context.Context
+}
+
+// ErrorDisposer can be implemented by custom pools whose disposal can fail, e.g. when closing a shared client. The
+// manager disposes pools implementing it by calling DisposeE instead of Dispose, and reports the returned error to
+// the OnPoolEvicted hook.
+//
+// BaseWorkerPool deliberately doesn't implement it, so DisposeE implementations must dispose their embedded
+// WorkerPool themselves.
+type ErrorDisposer interface {
+	DisposeE() error
+}
+
+// Dispose p, using DisposeE if it implements ErrorDisposer. Pools implementing io.Closer are then closed once their
+// workers have stopped, so resources attached to a custom pool can be released without racing in-flight tasks.
+func disposeWithError(p WorkerPool) error {
+	var err error
+	if disposer, ok := p.(ErrorDisposer); ok {
+		err = disposer.DisposeE()
+	} else {
+		p.Dispose()
+	}
+
+	closer, ok := p.(io.Closer)
+	if !ok {
+		return err
+	}
+	p.waitForWorkers()
+	if closeErr := closer.Close(); closeErr != nil {
+		if err != nil {
+			return fmt.Errorf("%w; closing pool: %v", err, closeErr)
+		}
+		return closeErr
+	}
+	return err
 }
 
 // BaseWorkerPool is the base implementation of WorkerPool
 type BaseWorkerPool struct {
+	// The key of the manager's cache this pool was built for, empty for standalone pools
+	key string
+	// Human-readable description, see WithPoolDescription
+	description string
+
 	workerCount int
+	workers     *sync.WaitGroup
 	maxSize     int
-	sends       chan Work
+	queue       taskQueue
+	// Guards workerCount and maxSize, so Grow and Shrink needn't hold the manager's lock
+	sizeLock *sync.Mutex
 
 	// Each active sender takes out a read lock on this, and when we want to destroy this bundle and clean its
 	// workers, we take a write lock
@@ -34,18 +147,169 @@ type BaseWorkerPool struct {
 
 	disposed     chan bool
 	creationTime time.Time
+	clock        Clock
+
+	// Why the manager evicted this pool, accessed atomically
+	evictedBecause int32
+	// Whether the pool's key is blocked, accessed atomically
+	blockedFlag int32
+	// Whether submissions are rejected while the pool is frozen, accessed atomically
+	rejectWhileFrozen int32
+	// Whether the pool's key is leased to another instance, accessed atomically
+	unownedFlag int32
+
+	// Optional behavior - nil until configured by NewWorkerPoolWithOptions or the manager which built this pool
+	options *options
+	pacer   *pacer
+	labels  *labelLimiter
+	backoff *failureBackoff
+
+	// Recent task panics, for pools with a quarantine
+	quarantine *quarantineState
+	failures   *failureRate
+	burst      *burstState
+	autoscale  *autoscaler
+	// The pool's turns on its manager's fleet, with WithSharedFleet
+	fleet *fleetMember
+	// Idempotency keys of recently executed durable tasks, with DurableTasks.IdempotencyWindow
+	idempotency *idempotencyWindow
+	// Memory held by unfinished tasks, with WithMemoryBudget
+	memory *memoryBudget
+	// Picks out the executions to instrument, with WithTaskSampling
+	sampling *taskSampler
+
+	// While paused, resumed is open and workers wait for it to be closed before starting tasks. Likewise for thawed,
+	// while the manager is frozen.
+	pauseLock   *sync.Mutex
+	resumed     chan bool
+	resumeTimer Timer
+	thawed      chan bool
+
+	// Submissions made with SubmitCoalesce which are still waiting in the queue, by coalesce key
+	coalesceLock *sync.Mutex
+	coalescing   map[string]*coalescedWork
+
+	// Submissions made with SubmitDebounce which haven't been enqueued yet, by debounce key
+	debounceLock *sync.Mutex
+	debouncing   map[string]*debouncedWork
+
+	// Queue wait latencies by submit site, only recorded in debug mode
+	siteLock      *sync.Mutex
+	siteQueueWait map[CallSite]*latencyHistogram
+
+	// Workers still to retire after the pool shrank, accessed atomically, see SetKeySize
+	retiring int32
+
+	stats *poolStats
 }
 
 // NewWorkerPool builds a new BaseWorkerPool and return it as a WorkerPool. This is the default pool factory.
 func NewWorkerPool(maxSize int) (WorkerPool, error) {
+	return newBaseWorkerPool(maxSize), nil
+}
+
+// NewWorkerPoolWithOptions builds a new BaseWorkerPool configured with opts. When used inside a custom Factory,
+// these options take the place of the options of the manager the pool is built for.
+func NewWorkerPoolWithOptions(maxSize int, opts ...Option) (WorkerPool, error) {
+	p := newBaseWorkerPool(maxSize)
+	p.configure("", newOptions(opts))
+	return p, nil
+}
+
+func newBaseWorkerPool(maxSize int) *BaseWorkerPool {
+	disposed := make(chan bool)
 	return &BaseWorkerPool{
-		sends:        make(chan Work, maxSize),
+		queue:        newChanQueue(maxSize, disposed),
 		maxSize:      maxSize,
 		deletionLock: &sync.RWMutex{},
-		disposed:     make(chan bool),
+		sizeLock:     &sync.Mutex{},
+		disposed:     disposed,
 		workerCount:  0,
+		workers:      &sync.WaitGroup{},
 		creationTime: time.Now(),
-	}, nil
+		clock:        realClock{},
+		coalesceLock: &sync.Mutex{},
+		coalescing:   make(map[string]*coalescedWork),
+		debounceLock: &sync.Mutex{},
+		debouncing:   make(map[string]*debouncedWork),
+		failures:     newFailureRate(defaultFailureRateWindow),
+		pauseLock:    &sync.Mutex{},
+		siteLock:     &sync.Mutex{},
+		stats:        &poolStats{},
+	}
+}
+
+// Apply options to this pool, unless it has already been configured. Must be called before any workers are spawned.
+func (p *BaseWorkerPool) configure(key string, o *options) {
+	if key != "" {
+		p.key = key
+	}
+	if p.options != nil {
+		return
+	}
+	p.options = o
+	p.clock = o.clock
+	if o.describe != nil {
+		p.description = o.describe(p.key)
+	}
+	p.creationTime = o.clock.Now()
+	capacity := p.queue.cap()
+	if o.queueCapacity > 0 {
+		capacity = o.queueCapacity
+	}
+	if o.subQueues != nil {
+		p.queue = newSubQueues(capacity, o.subQueues)
+	} else if o.rankTasks != nil {
+		p.queue = newRankedQueue(capacity, o.rankTasks)
+	} else if o.condvarDispatch || o.queueInspection {
+		p.queue = newCondQueue(capacity)
+	} else if capacity != p.queue.cap() {
+		p.queue = newChanQueue(capacity, p.disposed)
+	}
+	if o.throttleStarts > 0 {
+		p.pacer = newPacer(o.clock, o.throttleStarts, o.throttleInterval)
+		p.pacer.store, p.pacer.key, p.pacer.options = o.throttleStore, p.key, o
+	}
+	if len(o.labelLimits) > 0 || o.reservedFraction > 0 {
+		p.labels = newLabelLimiter(o.labelLimits)
+	}
+	if o.reservedFraction > 0 {
+		p.labels.reserve(o.reservedLabel, o.reservedFraction, p.maxSize)
+	}
+	if o.autoPause != nil {
+		p.failures = newFailureRate(o.autoPause.Window)
+	}
+	if o.failureBackoffMax > 0 {
+		p.backoff = newFailureBackoff(o.clock, o.failureBackoffMin, o.failureBackoffMax)
+	}
+	if o.quarantine != nil {
+		p.quarantine = &quarantineState{lock: &sync.Mutex{}}
+	}
+	if o.sampling != nil {
+		p.sampling = &taskSampler{TaskSampling: *o.sampling}
+	}
+	if o.memoryBudget != nil {
+		p.memory = newMemoryBudget(*o.memoryBudget, p.disposed)
+	}
+	if o.durable != nil && o.durable.IdempotencyWindow > 0 {
+		p.idempotency = newIdempotencyWindow(o.clock, o.durable.IdempotencyWindow)
+	}
+	if o.fleet != nil {
+		// The fleet takes the place of the pool's own workers, bursting and autoscaling included
+		p.fleet = &fleetMember{fleet: o.fleet, lock: &sync.Mutex{}, weight: o.fairShare.weight(p.key)}
+		return
+	}
+	if o.burstWorkers > 0 {
+		p.burst = newBurstState(o.burstWorkers, o.burstDuration)
+	}
+	if o.autoscalePolicy != nil {
+		p.autoscale = &autoscaler{
+			lock:       &sync.Mutex{},
+			policy:     o.autoscalePolicy,
+			interval:   o.autoscaleInterval,
+			maxWorkers: p.maxSize,
+		}
+	}
 }
 
 func min(x int, y int) int {
@@ -59,8 +323,82 @@ func min(x int, y int) int {
 //
 // When all workers are busy, and an additional workerPoolMaxSize of pending work beyond that is also already enqueued,
 // this method will block until workers become available.
+//
+// Work submitted to a blocked or quarantined pool is dropped - use SubmitTask to find out about rejected submissions.
 func (p *BaseWorkerPool) Submit(w Work) {
-	p.sends <- w
+	_ = p.enqueue(task{work: w})
+}
+
+// SubmitRunner submits r to be executed, like Submit. TestSubmitRunnerDoesNotAllocate holds it to zero allocations,
+// and BenchmarkSubmitRunner measures its cost.
+func (p *BaseWorkerPool) SubmitRunner(r Runner) {
+	_ = p.enqueue(task{runner: r})
+}
+
+func (p *BaseWorkerPool) enqueue(t task) error {
+	p.options.injectedFaults().delaySubmission()
+	p.options.carryBaggage(&t)
+	if err := p.admitSubmission(); err != nil {
+		p.options.count(MetricTasksRejected, p.key, 1)
+		return err
+	}
+	if err := p.intercept(t); err != nil {
+		p.options.count(MetricTasksRejected, p.key, 1)
+		return err
+	}
+	if err := p.shedLoad(t); err != nil {
+		p.options.count(MetricTasksRejected, p.key, 1)
+		return err
+	}
+	if err := p.memory.acquire(t.info.MemoryCost); err != nil {
+		p.options.count(MetricTasksRejected, p.key, 1)
+		return err
+	}
+	atomic.AddInt64(&p.stats.unfinished, 1)
+	t.enqueued = p.clock.Now()
+	if p.debugging() && t.site == nil {
+		t.site = submitSite()
+	}
+	if !p.queue.tryPush(t) {
+		p.options.queueSaturated(p.key)
+		p.startBurst()
+		if !p.queue.push(t) {
+			// The pool was disposed while the submission waited for room, so it will never execute
+			atomic.AddInt64(&p.stats.unfinished, -1)
+			p.memory.release(t.info.MemoryCost)
+			p.options.count(MetricTasksRejected, p.key, 1)
+			return ErrDisposed
+		}
+	}
+	if t.position != nil {
+		*t.position = p.queuePosition(t.enqueued)
+	}
+	p.offerTurn()
+	p.options.count(MetricTasksSubmitted, p.key, 1)
+	p.options.gauge(MetricQueueDepth, p.key, float64(p.queue.len()))
+	// Also reported here, so the gauge drops while a backlog builds up without tasks completing
+	p.reportThroughput(t.enqueued)
+	return nil
+}
+
+// Whether a submission may be enqueued right now
+func (p *BaseWorkerPool) admitSubmission() error {
+	if isClosed(p.disposed) {
+		return ErrDisposed
+	}
+	if p.blocked() {
+		return ErrKeyBlocked
+	}
+	if !p.owned() {
+		return ErrKeyNotOwned
+	}
+	if p.quarantined() {
+		return ErrPoolQuarantined
+	}
+	if p.rejectingWhileFrozen() {
+		return ErrPoolFrozen
+	}
+	return p.admitWithoutWorkers()
 }
 
 // It's not thread-safe, lock above this
@@ -69,27 +407,231 @@ func (p *BaseWorkerPool) spawnWorkers(sendSize int) {
 	// spawned workers. This way, when there are clients that are only ever doing a single unit of work at a time,
 	// we only ever spawn a single worker, but when there are clients doing large blasts of work concurrently, we'll
 	// spawn workerPoolMaxSize workers.
-	newWorkers := min(sendSize, p.maxSize-p.workerCount)
+	if p.autoscale != nil {
+		// The policy decides how many workers autoscaled pools run
+		p.startAutoscaling()
+		return
+	}
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	p.addWorkers(p.options.growth(sendSize, p.workerCount))
+}
+
+// Spawn up to n more workers, up until workerPoolMaxSize total, returning how many were spawned. It's not thread-safe,
+// lock sizeLock above this.
+func (p *BaseWorkerPool) addWorkers(n int) int {
+	newWorkers := min(n, p.maxSize-p.workerCount)
 	if newWorkers > 0 {
 		p.workerCount += newWorkers
+		if p.fleet != nil {
+			// Only the cap on the pool's turns grows, as fleet workers take the place of its own
+			p.setFleetLimit(p.workerCount)
+			p.options.gauge(MetricWorkers, p.key, float64(p.workerCount))
+			return newWorkers
+		}
 		// Build a fixed-size sender pool for this bundle. Each worker in the sender pool loops indefinitely,
 		// processing all the sends for this client, effectively throttling the number of simultaneous sends for a given
 		// client.
+		p.workers.Add(newWorkers)
 		for i := 0; i < newWorkers; i++ {
-			go func() {
-				for {
-					select {
-					case send := <-p.sends:
-						send()
-					case <-p.disposed:
-						return
-					}
-				}
-			}()
+			go p.runWorker(nil)
+		}
+		p.options.gauge(MetricWorkers, p.key, float64(p.workerCount))
+		return newWorkers
+	}
+	return 0
+}
+
+// Run a worker until the pool is disposed, or until stop is closed for temporary workers
+func (p *BaseWorkerPool) runWorker(stop <-chan bool) {
+	defer p.workers.Done()
+	atomic.AddUint64(&p.stats.spawnedWorkers, 1)
+	atomic.AddInt64(&p.stats.aliveWorkers, 1)
+	defer atomic.AddInt64(&p.stats.aliveWorkers, -1)
+	retired := false
+	if stop == nil {
+		defer func() {
+			if !retired {
+				p.workerExited()
+			}
+		}()
+	}
+	state, ok := p.initWorker()
+	if !ok {
+		return
+	}
+	defer p.teardownWorker(state)
+	worker := &Worker{state: state}
+	if p.options.tracksGoroutines() {
+		worker.goroutine = currentGoroutine()
+	}
+
+	loop := func() {
+		retired = p.processTasks(worker, stop)
+	}
+	p.withProfileLabels(func() {
+		if p.options != nil && p.options.workerLoop != nil {
+			p.options.workerLoop(loop)
+		} else {
+			loop()
+		}
+	})
+}
+
+// Execute tasks until the pool is disposed or stop is closed, or until the worker retires, returning whether it did
+func (p *BaseWorkerPool) processTasks(worker *Worker, stop <-chan bool) bool {
+	for {
+		// Temporary workers stop by themselves, so only the pool's own workers retire
+		if stop == nil && p.retire() {
+			return true
+		}
+		send, ok := p.queue.pop(stop)
+		if !ok {
+			return false
+		}
+		if send.wakeup {
+			continue
+		}
+		if p.blocked() {
+			p.finish(send)
+			continue
+		}
+		if !p.labels.admit(send) {
+			continue
+		}
+		if !p.run(send, worker) {
+			return false
+		}
+	}
+}
+
+// Stop counting one of the pool's own workers which has exited without retiring - e.g. after a custom worker loop
+// recovered a task's panic - so that later checkouts spawn a worker in its place. Workers exiting because the pool is
+// disposed leave the count alone, as nothing will be spawned in their place, and the pool may be read after disposal.
+func (p *BaseWorkerPool) workerExited() {
+	p.sizeLock.Lock()
+	defer p.sizeLock.Unlock()
+	if isClosed(p.disposed) {
+		return
+	}
+	p.workerCount--
+	p.options.gauge(MetricWorkers, p.key, float64(p.workerCount))
+}
+
+// Execute t, followed by any tasks parked behind it by its label limit, returning false if the pool is disposed first
+func (p *BaseWorkerPool) run(t task, worker *Worker) bool {
+	finished := false
+	defer func() {
+		// t panicked, so a parked task can't take over its slot on this worker - send it back through the queue
+		if !finished {
+			if next, ok := p.labels.abandon(t); ok {
+				go p.readmit(next)
+			}
+		}
+	}()
+
+	for {
+		if !p.waitWhilePaused() ||
+			p.pacer != nil && !p.pacer.wait(p.disposed) ||
+			!sleep(p.clock, p.backoff.wait(), p.disposed) {
+			finished = true
+			return false
+		}
+		p.options.yield(schedulePickup)
+		p.execute(t, worker)
+
+		next, ok := p.labels.finish(t)
+		if !ok {
+			finished = true
+			return true
 		}
+		t = next
+	}
+}
+
+// Put an already enqueued task back in the queue
+func (p *BaseWorkerPool) readmit(t task) {
+	p.queue.push(t)
+	p.offerTurn()
+}
+
+func (p *BaseWorkerPool) execute(t task, worker *Worker) {
+	// Deferred, so that work which panics into a custom worker loop's recovery isn't left unfinished forever
+	defer p.finish(t)
+	defer t.completed()
+
+	p.stats.startExecuting()
+	defer atomic.AddInt64(&p.stats.executing, -1)
+	start := p.clock.Now()
+	p.stats.queueWaitLatency.record(start.Sub(t.enqueued))
+	p.recordSiteQueueWait(t.site, start.Sub(t.enqueued))
+	p.options.observe(MetricQueueWait, p.key, start.Sub(t.enqueued))
+	p.options.gauge(MetricQueueDepth, p.key, float64(p.queue.len()))
+	defer p.watch(t, worker)()
+	if p.debugging() {
+		defer p.options.dependencies.enter(worker.goroutine, p.key)()
+	}
+	if p.recoversPanics() {
+		defer p.recoverPanic(t)
+	}
+	p.options.injectedFaults().panicTask()
+
+	if p.sampling.sample() {
+		p.sampleExecution(t, func() {
+			invoke(t, worker)
+		})
+	} else {
+		invoke(t, worker)
+	}
+
+	end := p.clock.Now()
+	elapsed := end.Sub(start)
+	p.stats.executionLatency.record(elapsed)
+	atomic.AddUint64(&p.stats.completed, 1)
+	p.stats.completions.record(end)
+	p.options.observe(MetricExecution, p.key, elapsed)
+	p.options.count(MetricTasksCompleted, p.key, 1)
+	p.reportThroughput(end)
+}
+
+// Call t's work on worker
+func invoke(t task, worker *Worker) {
+	switch {
+	case t.onWorker != nil:
+		t.onWorker(worker)
+	case t.withState != nil:
+		t.withState(worker.state)
+	case t.runner != nil:
+		t.runner.Run()
+	default:
+		t.work()
 	}
 }
 
+// Block until every worker has stopped, which only happens once the pool is disposed
+func (p *BaseWorkerPool) waitForWorkers() {
+	p.workers.Wait()
+}
+
+// Whether this pool has no work queued, executing, or waiting to be enqueued
+func (p *BaseWorkerPool) idle() bool {
+	if atomic.LoadInt64(&p.stats.unfinished) > 0 {
+		return false
+	}
+	p.debounceLock.Lock()
+	defer p.debounceLock.Unlock()
+	return len(p.debouncing) == 0
+}
+
+// Whether this pool has finished its work - either it's idle, or it's been disposed, which drops its queued tasks,
+// and none of its tasks is still executing
+func (p *BaseWorkerPool) settled() bool {
+	if isClosed(p.disposed) {
+		return atomic.LoadInt64(&p.stats.executing) == 0
+	}
+	return p.idle()
+}
+
 func (p *BaseWorkerPool) reserve() bool {
 	p.deletionLock.RLock()
 	select {
@@ -97,20 +639,35 @@ func (p *BaseWorkerPool) reserve() bool {
 		p.deletionLock.RUnlock()
 		return false
 	default:
+		atomic.AddInt64(&p.stats.reservations, 1)
 		return true
 	}
 }
 
 func (p *BaseWorkerPool) release() {
+	atomic.AddInt64(&p.stats.reservations, -1)
 	p.deletionLock.RUnlock()
 }
 
+func (p *BaseWorkerPool) reservations() int {
+	return int(atomic.LoadInt64(&p.stats.reservations))
+}
+
 func (p *BaseWorkerPool) age() time.Duration {
-	return time.Since(p.creationTime)
+	return p.clock.Now().Sub(p.creationTime)
+}
+
+func (p *BaseWorkerPool) markEvicted(reason EvictionReason) {
+	atomic.StoreInt32(&p.evictedBecause, int32(reason))
+}
+
+func (p *BaseWorkerPool) evictionReason() EvictionReason {
+	return EvictionReason(atomic.LoadInt32(&p.evictedBecause))
 }
 
 // Dispose the pool, closing down the workers and releasing any shared resources.
 func (p *BaseWorkerPool) Dispose() {
+	p.options.yield(scheduleDispose)
 	p.deletionLock.Lock()
 	defer p.deletionLock.Unlock()
 
@@ -122,4 +679,11 @@ func (p *BaseWorkerPool) Dispose() {
 	default:
 		close(p.disposed)
 	}
+	p.queue.close()
+	p.memory.close()
+	p.closeTurns()
+	p.stopDebouncing()
+	p.cancelResume()
+	p.stopBursting()
+	p.stopAutoscaling()
 }
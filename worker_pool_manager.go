@@ -2,12 +2,17 @@ package pool
 
 import (
 	"context"
+	"fmt"
+	"runtime"
 	"sync"
 	"time"
 
 	"github.com/jellydator/ttlcache/v3"
 )
 
+// How often Quiesce checks whether pools have finished their work
+const quiescePollInterval = 5 * time.Millisecond
+
 // WorkerPoolManager - Self-expiring, lazily constructed map of fixed-size worker pools safe for concurrent use
 type WorkerPoolManager struct {
 	workerPoolCache     *ttlcache.Cache[string, WorkerPool]
@@ -15,97 +20,316 @@ type WorkerPoolManager struct {
 	poolReservationLock *sync.Mutex
 	stalePoolExpiration time.Duration
 	maxPoolLifetime     time.Duration
+	options             *options
+	// The options new pools are configured with, which differ from options once replaced by UpdateConfig. Guarded by
+	// poolReservationLock.
+	poolOptions *options
+
+	// With a custom clock, stale pools are expired by timers on that clock rather than by the cache's janitor. Both
+	// maps are guarded by poolReservationLock.
+	clock        Clock
+	lastUsed     map[string]time.Time
+	expiryTimers map[string]Timer
+
+	// Keys suspended with Block, guarded by poolReservationLock
+	blocked map[string]bool
+	// The current FreezeAll, guarded by poolReservationLock
+	frozen *freeze
+	// Registered with AddPrewarm, guarded by poolReservationLock
+	prewarmers map[*prewarmer]bool
+	// Key ownership, with WithLeases
+	leases *leaseCoordinator
+	// Runs every pool's tasks, with WithSharedFleet
+	fleet *fleet
+	// Registered with RegisterFactory
+	factories *factoryRegistry
+	// Made with Child, by prefix, guarded by poolReservationLock
+	children map[string]*ChildManager
+	// Rotated pools which their keys' next pools wait on, with WithDrainBeforeRotate, guarded by poolReservationLock
+	draining map[string]drainingPool
+	// Counts each key's checkouts, with WithMaxCheckouts
+	checkouts *checkoutCounter
+	// Reports the cache's metrics, with WithMetrics
+	cacheSampler *cacheSampler
+
+	events *eventBus
+
+	// Evicted pools which haven't been disposed yet
+	evicted *evictedPools
+	// With WithDisposalQueue, evictions are queued for disposal here
+	disposals     *disposalQueue
+	stopEvictions func()
 }
 
 // NewWorkerPoolManager factory constructor
 //
 // * poolSize - The max number of workers for each key
 // * stalePoolExpiration - how long to cache unused pools for
-// * maxPoolLifetime - max time to allow pools to live
+// * maxPoolLifetime - max time to allow pools to live, see WithoutMaxLifetime to let them live as long as they're used
+// * opts - optional behavior for the manager and the pools it builds
 func NewWorkerPoolManager(
-	poolSize int, stalePoolExpiration time.Duration, maxPoolLifetime time.Duration,
+	poolSize int, stalePoolExpiration time.Duration, maxPoolLifetime time.Duration, opts ...Option,
 ) *WorkerPoolManager {
-	workerPoolCache := ttlcache.New(
-		ttlcache.WithTTL[string, WorkerPool](stalePoolExpiration),
-	)
-	workerPoolCache.OnEviction(func(context context.Context, reason ttlcache.EvictionReason, item *ttlcache.Item[string, WorkerPool]) {
-		item.Value().Dispose()
-	})
-	go workerPoolCache.Start()
-
-	return &WorkerPoolManager{
-		workerPoolCache:     workerPoolCache,
+	o := newOptions(opts)
+	events := newEventBus(o.clock)
+	o.hooks = append(o.hooks, events.hooks())
+	o.dependencies = newDependencyGraph()
+	if o.multiplexed {
+		o.fleet = newFleet(o, runtime.GOMAXPROCS(0), 1, multiplexedTasksPerTurn)
+	} else if o.fleetWorkers > 0 {
+		o.fleet = newFleet(o, 1, o.fleetWorkers, 1)
+	}
+	m := &WorkerPoolManager{
 		workerPoolMaxSize:   poolSize,
 		poolReservationLock: &sync.Mutex{},
 		stalePoolExpiration: stalePoolExpiration,
 		maxPoolLifetime:     maxPoolLifetime,
+		options:             o,
+		poolOptions:         o,
+		clock:               o.clock,
+		lastUsed:            make(map[string]time.Time),
+		expiryTimers:        make(map[string]Timer),
+		blocked:             make(map[string]bool),
+		prewarmers:          make(map[*prewarmer]bool),
+		draining:            make(map[string]drainingPool),
+		events:              events,
+		fleet:               o.fleet,
+		evicted:             newEvictedPools(),
+		factories:           &factoryRegistry{lock: &sync.RWMutex{}},
+		cacheSampler:        &cacheSampler{lock: &sync.Mutex{}},
+	}
+
+	cacheTTL := stalePoolExpiration
+	if m.clockDrivenExpiry() {
+		cacheTTL = ttlcache.NoTTL
+	}
+	m.workerPoolCache = ttlcache.New(
+		ttlcache.WithTTL[string, WorkerPool](cacheTTL),
+	)
+	m.handleEvictions()
+	if o.maxCheckouts > 0 {
+		m.checkouts = newCheckoutCounter(o.maxCheckouts)
+	}
+	if o.leases != nil {
+		m.leases = newLeaseCoordinator(m, *o.leases)
 	}
+	go m.workerPoolCache.Start()
+
+	return m
 }
 
 // GetPool returns the WorkerPool for this key, building a BaseWorkerPool and caching it if necessary.
 // Spawns sendSize workers, up to a max of the manager's poolSize.
 //
 // This returns the pool in an "unexpirable" state - the caller should signal the returned done channel when it
-// no longer requires the returned bundle.
+// no longer requires the returned bundle. With WithCheckoutLimit, each call returns its own handle on the pool, as
+// do the other ways of checking out a pool.
+//
+// Closing the done channel and sending on it both release the checkout. Only the first signal counts, and the channel
+// is buffered so a repeated send doesn't block, but as with any channel closing it twice panics - Release tolerates
+// that, for callers which can't tell whether they've already released.
+//
+// GetPool can't return an error, so it panics if the pool's factory fails - only a factory registered for key, see
+// RegisterFactory, one wrapped by WithFactoryMiddleware, or WithFaults can. Callers with such factories should use
+// GetPoolWithFactory or GetPoolContext.
 func (m *WorkerPoolManager) GetPool(key string, sendSize int) (WorkerPool, chan<- bool) {
-	// The default factory, NewWorkerPool, cannot return an error
-	pool, doneUsing, _ := m.GetPoolWithFactory(key, sendSize, NewWorkerPool)
+	pool, doneUsing, err := m.GetPoolContext(context.Background(), key, sendSize, nil)
+	if err != nil {
+		panic(fmt.Errorf("building the worker pool for %q: %w", key, err))
+	}
 	return pool, doneUsing
 }
 
 // GetPoolWithFactory returns the WorkerPool for this key, allowing you to specify a custom pool.Factory
 // if you want to build a custom WorkerPool implementation which embeds a BaseWorkerPool and attaches
-// supplimentary shared data for the pool.
+// supplimentary shared data for the pool. A nil factory uses the one registered for key, see RegisterFactory.
 func (m *WorkerPoolManager) GetPoolWithFactory(
 	key string, sendSize int, factory Factory,
 ) (WorkerPool, chan<- bool, error) {
-	var pool WorkerPool
-	var err error
+	return m.getPool(key, sendSize, 0, m.injectFactoryFaults(key, factory))
+}
+
+// GetPoolWithSize returns the WorkerPool for this key like GetPoolWithFactory, but a pool built for it is given at most
+// size workers rather than the manager's poolSize - e.g. an intentionally smaller pool for a small tenant. The size is
+// capped by the manager's, or by WithSizeResolver's for key. A pool which is already cached keeps its size, until it's
+// changed by SetKeySize, or by SetPoolSize along with every other pool's.
+func (m *WorkerPoolManager) GetPoolWithSize(
+	key string, sendSize int, size int, factory Factory,
+) (WorkerPool, chan<- bool, error) {
+	return m.getPool(key, sendSize, size, m.injectFactoryFaults(key, factory))
+}
+
+// Check out key's pool, building it with size workers, capped by sizeFor, unless size isn't positive
+func (m *WorkerPoolManager) getPool(
+	key string, sendSize int, size int, factory Factory,
+) (WorkerPool, chan<- bool, error) {
+	if !m.checkouts.acquire(key) {
+		return nil, nil, ErrTooManyCheckouts
+	}
+	// Leases may live in a remote backend, so ownership is settled before locking
+	owned := m.leases.owns(key)
 
 	m.poolReservationLock.Lock()
+	if old, ok := m.draining[key]; ok && !m.drained(old) {
+		m.poolReservationLock.Unlock()
+		m.checkouts.release(key)
+		m.waitForDrain(old)
+		return m.getPool(key, sendSize, size, factory)
+	}
 
-	cachedPoolItem := m.workerPoolCache.Get(key)
-	if cachedPoolItem != nil {
-		pool = cachedPoolItem.Value()
-	} else {
-		pool, err = factory(m.workerPoolMaxSize)
+	create := func() (WorkerPool, error) {
+		build := factory
+		if build == nil {
+			build = m.factories.factoryFor(key)
+		}
+		maxSize := m.sizeFor(key)
+		if size > 0 {
+			maxSize = min(size, maxSize)
+		}
+		pool, err := m.options.decorateFactory(build)(maxSize)
 		if err != nil {
-			m.poolReservationLock.Unlock()
-			return nil, nil, err
+			return nil, err
 		}
-		m.workerPoolCache.Set(key, pool, ttlcache.DefaultTTL)
+		pool.configure(key, m.poolOptionsFor(key))
+		poolConfigured(pool, key)
+		pool.setBlocked(m.blocked[key])
+		m.freezeIfFrozen(pool)
+		return pool, nil
+	}
+	pool, reused, err := m.getOrCreate(key, create)
+	if err != nil {
+		m.poolReservationLock.Unlock()
+		m.checkouts.release(key)
+		return nil, nil, err
 	}
+	if !reused {
+		m.options.poolCreated(key, pool)
+		if old, ok := m.draining[key]; ok {
+			delete(m.draining, key)
+			m.options.poolRotated(key, old.pool, pool)
+		}
+	}
+	pool.setOwned(owned)
+	m.touch(key)
 
 	// Prevent this from being deleted until we're done using it - if reserve returns false, it was
 	// closed before we obtained control - otherwise we have a read lock and we know it won't be closed
 	// until we're done with it
+	m.options.yield(scheduleReserve)
 	goodForUse := pool.reserve()
 	if !goodForUse {
 		m.poolReservationLock.Unlock()
-		return m.GetPoolWithFactory(key, sendSize, factory)
+		m.checkouts.release(key)
+		return m.getPool(key, sendSize, size, factory)
 	}
 
-	pool.spawnWorkers(sendSize)
+	poolReserved(pool)
+	if reused {
+		m.options.poolReused(PoolReuse{Key: key, Pool: pool, Age: pool.age(), Reservations: pool.reservations()})
+	}
+	if owned {
+		pool.spawnWorkers(sendSize)
+	}
 
-	// If the item is older than maxClientBundleExpiration, remove it from the cache and schedule it for disposal.
+	// If the item is older than maxClientBundleExpiration, remove it from the cache, which schedules it for disposal.
 	// Disposal won't actually occur until the caller has released it
-	if pool.age() > m.maxPoolLifetime {
+	if lifetime, rotates := m.maxLifetimeFor(key); rotates && pool.age() > lifetime {
+		pool.markEvicted(EvictionReasonMaxLifetime)
 		m.workerPoolCache.Delete(key)
-		go pool.Dispose()
+		m.rotate(key, pool, create)
 	}
 
-	doneUsing := make(chan bool)
+	released := m.watchCheckout(key)
+	// Buffered, so a second send doesn't block once the first has released the checkout
+	doneUsing := make(chan bool, 1)
 	go func() {
 		<-doneUsing
+		released()
+		m.options.yield(scheduleRelease)
+		poolReleased(pool)
 		pool.release()
+		m.checkouts.release(key)
 	}()
 
 	m.poolReservationLock.Unlock()
+	m.sampleCache()
+	if limit := m.options.checkoutLimit; limit > 0 {
+		return &limitedCheckout{limit: int64(limit), options: m.options, key: key, WorkerPool: pool}, doneUsing, nil
+	}
 	return pool, doneUsing, nil
 }
 
+// Release closes doneUsing, releasing the checkout it was returned with, if it hasn't been closed already. Unlike
+// close, it doesn't panic when a checkout is released twice, e.g. by both a deferred release and an error path.
+func Release(doneUsing chan<- bool) {
+	defer func() {
+		// From closing a channel which was already closed
+		_ = recover()
+	}()
+	close(doneUsing)
+}
+
+// Quiesce blocks until every cached pool has no queued or executing work, or until ctx is done, in which case the
+// context's error is returned. Pools which are built or receive new work while quiescing are waited on too, so
+// callers should stop producing work before quiescing.
+func (m *WorkerPoolManager) Quiesce(ctx context.Context) error {
+	ticker := time.NewTicker(quiescePollInterval)
+	defer ticker.Stop()
+
+	for {
+		quiet := true
+		for _, item := range m.workerPoolCache.Items() {
+			if !item.Value().idle() {
+				quiet = false
+				break
+			}
+		}
+		if quiet {
+			return nil
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
 // Dispose clears the underlying cache and stops launched goroutines
 func (m *WorkerPoolManager) Dispose() {
+	m.stopExpiryTimers()
+	m.stopFreeze()
+	m.stopPrewarms()
 	m.workerPoolCache.DeleteAll()
 	m.workerPoolCache.Stop()
+	m.leases.stop()
+	m.fleet.stop()
+	m.closeDisposals()
+	m.events.close()
+}
+
+// Dispose a pool which has been removed from the cache, once all its callers are done using it
+func (m *WorkerPoolManager) disposeEvicted(reason ttlcache.EvictionReason, key string, pool WorkerPool) {
+	m.options.faults.delayEviction()
+	err := disposeWithError(pool)
+	m.disposed(pool)
+	m.options.poolEvicted(PoolEviction{
+		Key:    key,
+		Pool:   pool,
+		Reason: evictionReason(reason, pool),
+		Age:    pool.age(),
+		Err:    err,
+	})
+}
+
+// Why pool was evicted, falling back on the cache's reason if the manager didn't mark it
+func evictionReason(reason ttlcache.EvictionReason, pool WorkerPool) EvictionReason {
+	if evictionReason := pool.evictionReason(); evictionReason != 0 {
+		return evictionReason
+	}
+	if reason == ttlcache.EvictionReasonExpired {
+		return EvictionReasonExpired
+	}
+	return EvictionReasonDeleted
 }
@@ -1,10 +1,12 @@
 package pool
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"reflect"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -263,3 +265,289 @@ func TestInterleavedUseAndExpiryDoesNotLeak(t *testing.T) {
 
 	pm.Dispose()
 }
+
+func TestQuiesceWaitsForQueuedAndExecutingWork(t *testing.T) {
+	pm := NewWorkerPoolManager(2, time.Second, 5*time.Second)
+
+	var completed int32
+	for _, key := range []string{"a", "b"} {
+		pool, doneUsing := pm.GetPool(key, 2)
+		for i := 0; i < 4; i++ {
+			pool.Submit(func() {
+				time.Sleep(10 * time.Millisecond)
+				atomic.AddInt32(&completed, 1)
+			})
+		}
+		close(doneUsing)
+	}
+
+	assert.Nil(t, pm.Quiesce(context.Background()))
+	assert.Equal(t, int32(8), atomic.LoadInt32(&completed))
+
+	pm.Dispose()
+}
+
+func TestQuiesceReturnsContextError(t *testing.T) {
+	pm := NewWorkerPoolManager(1, time.Second, 5*time.Second)
+
+	blocker := make(chan bool)
+	pool, doneUsing := pm.GetPool("key", 1)
+	pool.Submit(func() {
+		<-blocker
+	})
+	close(doneUsing)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pm.Quiesce(ctx))
+
+	close(blocker)
+	pm.Dispose()
+}
+
+type closingWorkerPool struct {
+	WorkerPool
+
+	taskRunning *int32
+	closedWhile chan int32
+}
+
+func (p *closingWorkerPool) Close() error {
+	p.closedWhile <- atomic.LoadInt32(p.taskRunning)
+	return errors.New("close failed")
+}
+
+func TestManagerClosesPoolsImplementingCloserAfterWorkersStop(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evictions := make(chan PoolEviction, 1)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evictions <- eviction
+		},
+	}))
+	var taskRunning int32
+	closedWhile := make(chan int32, 1)
+	var factory Factory = func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &closingWorkerPool{WorkerPool: basePool, taskRunning: &taskRunning, closedWhile: closedWhile}, nil
+	}
+
+	pool, doneUsing, _ := pm.GetPoolWithFactory("key", 1, factory)
+	started := make(chan bool)
+	pool.Submit(func() {
+		atomic.StoreInt32(&taskRunning, 1)
+		close(started)
+		time.Sleep(20 * time.Millisecond)
+		atomic.StoreInt32(&taskRunning, 0)
+	})
+	<-started
+	close(doneUsing)
+	pm.Dispose()
+
+	assert.Equal(t, int32(0), <-closedWhile, "Expected Close to wait for the running task to finish")
+	assert.EqualError(t, (<-evictions).Err, "close failed")
+}
+
+func TestCheckoutsTolerateRepeatedReleases(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour)
+	reservations := func() bool {
+		return pm.Snapshot()["key"].Reservations == 0
+	}
+
+	_, doneUsing := pm.GetPool("key", 1)
+	doneUsing <- true
+	doneUsing <- true
+	assert.Eventually(t, reservations, time.Second, time.Millisecond)
+	close(doneUsing)
+
+	_, doneUsing = pm.GetPool("key", 1)
+	Release(doneUsing)
+	assert.NotPanics(t, func() { Release(doneUsing) })
+	assert.Eventually(t, reservations, time.Second, time.Millisecond)
+	pm.Dispose()
+}
+
+func TestCheckoutRacingTheJanitorKeepsOnePoolPerKey(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	evicted := make(chan WorkerPool, 2)
+	pm := NewWorkerPoolManager(1, 10*time.Millisecond, time.Hour, WithHooks(Hooks{
+		OnPoolEvicted: func(eviction PoolEviction) {
+			evicted <- eviction.Pool
+		},
+	}))
+	// Hold the janitor off, so the first pool has expired but is still in the cache
+	pm.workerPoolCache.Stop()
+
+	expired, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	time.Sleep(20 * time.Millisecond)
+
+	replacement, doneUsing := pm.GetPool("key", 1)
+	assert.NotSame(t, expired, replacement)
+	close(doneUsing)
+	select {
+	case pool := <-evicted:
+		assert.Same(t, expired, pool)
+	case <-time.After(time.Second):
+		t.Error("the expired pool wasn't evicted when its key was checked out again")
+	}
+
+	// The janitor catching up mustn't drop the replacement from the cache
+	pm.workerPoolCache.DeleteExpired()
+	cached, doneUsing := pm.GetPool("key", 1)
+	assert.Same(t, replacement, cached)
+	close(doneUsing)
+
+	go pm.workerPoolCache.Start()
+	pm.Dispose()
+}
+
+func TestWithoutMaxLifetimeOnlyExpiresIdlePools(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	pm := NewWorkerPoolManager(1, 50*time.Millisecond, 0, WithoutMaxLifetime())
+	pool, doneUsing := pm.GetPool("key", 1)
+	close(doneUsing)
+	for i := 0; i < 5; i++ {
+		time.Sleep(5 * time.Millisecond)
+		again, doneUsing := pm.GetPool("key", 1)
+		assert.Same(t, pool, again)
+		close(doneUsing)
+	}
+	assert.Contains(t, pm.Config().Features, "without max lifetime")
+
+	// A child can rotate its own pools
+	rotating := pm.Child("rotating/", WithMaxPoolLifetime(time.Nanosecond))
+	pool, doneUsing = rotating.GetPool("key", 1)
+	close(doneUsing)
+	again, doneUsing := rotating.GetPool("key", 1)
+	assert.NotSame(t, pool, again)
+	close(doneUsing)
+
+	pm.Dispose()
+}
+
+func TestDrainBeforeRotateWaitsForTheOldPool(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Minute, WithClock(clock), WithDrainBeforeRotate())
+	defer pm.Dispose()
+
+	old, doneUsing := pm.GetPool("key", 1)
+	unblock := make(chan bool)
+	old.Submit(func() {
+		<-unblock
+	})
+	close(doneUsing)
+
+	clock.Advance(2 * time.Minute)
+	rotated, doneUsing := pm.GetPool("key", 1)
+	assert.Same(t, old, rotated)
+	close(doneUsing)
+
+	replaced := make(chan WorkerPool, 1)
+	go func() {
+		pool, doneUsing := pm.GetPool("key", 1)
+		close(doneUsing)
+		replaced <- pool
+	}()
+	select {
+	case <-replaced:
+		t.Fatal("Expected the checkout to wait for the old pool's task")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	close(unblock)
+	select {
+	case pool := <-replaced:
+		assert.NotSame(t, old, pool)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the checkout to get a new pool once the old one drained")
+	}
+	assert.Contains(t, pm.Config().Features, "drain before rotate")
+}
+
+func TestDrainBeforeRotateCountsDisposedPoolsAsDrained(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	evicted := make(chan bool, 1)
+	pm := NewWorkerPoolManager(1, time.Hour, time.Minute, WithClock(clock), WithDrainBeforeRotate(),
+		WithHooks(Hooks{OnPoolEvicted: func(PoolEviction) {
+			evicted <- true
+		}}))
+	defer pm.Dispose()
+
+	old, doneUsing := pm.GetPool("key", 1)
+	unblock := make(chan bool)
+	old.Submit(func() {
+		<-unblock
+	})
+	// Still queued when the old pool is released, and dropped when it's disposed
+	assert.Nil(t, SubmitTask(old, TaskInfo{}, func() {}))
+	clock.Advance(2 * time.Minute)
+	rotated, rotatedDoneUsing := pm.GetPool("key", 1)
+	assert.Same(t, old, rotated)
+	close(rotatedDoneUsing)
+	close(doneUsing)
+	select {
+	case <-evicted:
+	case <-time.After(time.Second):
+		t.Fatal("Expected the old pool to be disposed once released")
+	}
+	close(unblock)
+
+	replaced := make(chan WorkerPool, 1)
+	go func() {
+		pool, doneUsing := pm.GetPool("key", 1)
+		close(doneUsing)
+		replaced <- pool
+	}()
+	select {
+	case pool := <-replaced:
+		assert.NotSame(t, old, pool)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the checkout not to wait for the disposed pool's queued task")
+	}
+}
+
+func TestDrainBeforeRotateWaitsAtMostMaxDrainWait(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	clock := &steppedClock{lock: &sync.Mutex{}, now: time.Now()}
+	pm := NewWorkerPoolManager(1, time.Hour, time.Minute, WithClock(clock), WithDrainBeforeRotate())
+	defer pm.Dispose()
+
+	old, doneUsing := pm.GetPool("key", 1)
+	clock.Advance(2 * time.Minute)
+	rotated, rotatedDoneUsing := pm.GetPool("key", 1)
+	assert.Same(t, old, rotated)
+	close(rotatedDoneUsing)
+
+	// The checkout which rotated the old pool is never released in time
+	replaced := make(chan WorkerPool, 1)
+	go func() {
+		pool, doneUsing := pm.GetPool("key", 1)
+		close(doneUsing)
+		replaced <- pool
+	}()
+	select {
+	case <-replaced:
+		t.Fatal("Expected the checkout to wait for the old pool's release")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	clock.Advance(maxDrainWait)
+	select {
+	case pool := <-replaced:
+		assert.NotSame(t, old, pool)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the checkout to stop waiting after maxDrainWait")
+	}
+	close(doneUsing)
+}
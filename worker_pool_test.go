@@ -1,7 +1,10 @@
 package pool
 
 import (
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"go.uber.org/goleak"
@@ -29,3 +32,60 @@ func TestDisposeClosesWorkerPool(t *testing.T) {
 	}
 	p.Dispose()
 }
+
+func TestSubmitRunner(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(2)
+	p.spawnWorkers(2)
+
+	var wg sync.WaitGroup
+	wg.Add(10)
+	runner := &countdownRunner{wg: &wg}
+	for i := 0; i < 10; i++ {
+		p.SubmitRunner(runner)
+	}
+	wg.Wait()
+	assert.Equal(t, uint64(10), p.snapshot().Completed)
+	p.Dispose()
+}
+
+func TestSubmitRunnerDoesNotAllocate(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(2)
+	p.spawnWorkers(2)
+	defer p.Dispose()
+
+	var wg sync.WaitGroup
+	runner := &countdownRunner{wg: &wg}
+	// AllocsPerRun calls the function once more to warm up
+	wg.Add(101)
+	allocs := testing.AllocsPerRun(100, func() {
+		p.SubmitRunner(runner)
+	})
+	wg.Wait()
+	assert.Equal(t, 0.0, allocs, "Expected submitting and executing a *Runner not to allocate")
+}
+
+func TestSubmissionsToDisposedPoolsAreRejected(t *testing.T) {
+	defer goleak.VerifyNone(t)
+	p, _ := NewWorkerPool(1)
+
+	// Without workers, the first task fills the queue, and the second waits for room until the pool is disposed
+	assert.Nil(t, SubmitTask(p, TaskInfo{}, func() {}))
+	rejected := make(chan error)
+	go func() {
+		rejected <- SubmitTask(p, TaskInfo{}, func() {})
+	}()
+	time.Sleep(10 * time.Millisecond)
+	p.Dispose()
+	select {
+	case err := <-rejected:
+		assert.Equal(t, ErrDisposed, err)
+	case <-time.After(time.Second):
+		t.Fatal("Expected the waiting submission to be rejected")
+	}
+
+	assert.Equal(t, ErrDisposed, SubmitTask(p, TaskInfo{}, func() {}))
+	// Only the task which made it into the queue is unfinished
+	assert.Equal(t, int64(1), atomic.LoadInt64(&p.(*BaseWorkerPool).stats.unfinished))
+}
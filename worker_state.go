@@ -0,0 +1,70 @@
+package pool
+
+import "time"
+
+// Bounds on how long a worker waits before retrying a failed WithWorkerInit
+const (
+	minWorkerInitBackoff = 10 * time.Millisecond
+	maxWorkerInitBackoff = time.Second
+)
+
+// WithWorkerInit gives each worker a resource to own for its lifetime, such as a database session or reusable
+// buffer. init is called as each worker starts, and the state it returns is passed to tasks submitted with
+// SubmitWithWorkerState on that worker.
+//
+// A worker whose init fails doesn't pick up any tasks - it reports the error to the OnWorkerInitError hook, and
+// retries with backoff until init succeeds or the pool is disposed.
+func WithWorkerInit(init func() (workerState interface{}, err error)) Option {
+	return func(o *options) {
+		o.workerInit = init
+	}
+}
+
+// WithWorkerTeardown releases the state built by WithWorkerInit, and is called as each worker stops
+func WithWorkerTeardown(teardown func(workerState interface{})) Option {
+	return func(o *options) {
+		o.workerTeardown = teardown
+	}
+}
+
+// SubmitWithWorkerState submits w to be executed with the state of the worker that picks it up, as built by
+// WithWorkerInit. The state is nil for pools without a worker init.
+func SubmitWithWorkerState(p WorkerPool, w func(workerState interface{})) {
+	// Carried by the task itself rather than wrapped in an onWorker closure, to save an allocation per submission
+	_ = p.enqueue(task{withState: w})
+}
+
+// Build the calling worker's state, returning false if the pool is disposed before that succeeds
+func (p *BaseWorkerPool) initWorker() (interface{}, bool) {
+	return initWorkerState(p.options, p.key, p.clock, p.disposed)
+}
+
+// Build a worker's state with o's worker init, returning false if stop is closed before that succeeds
+func initWorkerState(o *options, key string, clock Clock, stop <-chan bool) (interface{}, bool) {
+	if o == nil || o.workerInit == nil {
+		return nil, true
+	}
+
+	backoff := minWorkerInitBackoff
+	for {
+		state, err := o.workerInit()
+		if err == nil {
+			return state, true
+		}
+		o.workerInitFailed(key, err)
+
+		if !sleep(clock, backoff, stop) {
+			return nil, false
+		}
+		backoff *= 2
+		if backoff > maxWorkerInitBackoff {
+			backoff = maxWorkerInitBackoff
+		}
+	}
+}
+
+func (p *BaseWorkerPool) teardownWorker(state interface{}) {
+	if p.options != nil && p.options.workerTeardown != nil {
+		p.options.workerTeardown(state)
+	}
+}
@@ -0,0 +1,101 @@
+package pool
+
+import (
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+type workerSession struct {
+	id int32
+}
+
+func TestWorkerInitStateIsPassedToTasksAndTornDown(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var sessions int32
+	var lock sync.Mutex
+	tornDown := map[int32]bool{}
+	var inits, teardowns sync.WaitGroup
+	inits.Add(3)
+	teardowns.Add(3)
+	p, _ := NewWorkerPoolWithOptions(
+		3,
+		WithWorkerInit(func() (interface{}, error) {
+			defer inits.Done()
+			return &workerSession{id: atomic.AddInt32(&sessions, 1)}, nil
+		}),
+		WithWorkerTeardown(func(workerState interface{}) {
+			lock.Lock()
+			tornDown[workerState.(*workerSession).id] = true
+			lock.Unlock()
+			teardowns.Done()
+		}),
+	)
+	p.spawnWorkers(3)
+
+	var wg sync.WaitGroup
+	seen := map[int32]bool{}
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		SubmitWithWorkerState(p, func(workerState interface{}) {
+			lock.Lock()
+			seen[workerState.(*workerSession).id] = true
+			lock.Unlock()
+			wg.Done()
+		})
+	}
+	// Plain Work still runs on workers with state
+	wg.Add(1)
+	p.Submit(wg.Done)
+	wg.Wait()
+
+	// Each worker builds its own state as it starts
+	inits.Wait()
+	assert.Equal(t, int32(3), atomic.LoadInt32(&sessions))
+	for id := range seen {
+		assert.True(t, id >= 1 && id <= 3)
+	}
+
+	p.Dispose()
+	teardowns.Wait()
+	assert.Equal(t, map[int32]bool{1: true, 2: true, 3: true}, tornDown)
+}
+
+func TestFailedWorkerInitIsRetriedAndReported(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var attempts int32
+	initErrors := make(chan error, 10)
+	pm := NewWorkerPoolManager(
+		1, time.Hour, time.Hour,
+		WithWorkerInit(func() (interface{}, error) {
+			if atomic.AddInt32(&attempts, 1) < 3 {
+				return nil, errors.New("connection refused")
+			}
+			return "session", nil
+		}),
+		WithHooks(Hooks{
+			OnWorkerInitError: func(key string, err error) {
+				assert.Equal(t, "key", key)
+				initErrors <- err
+			},
+		}),
+	)
+
+	pool, doneUsing := pm.GetPool("key", 1)
+	executed := make(chan interface{})
+	SubmitWithWorkerState(pool, func(workerState interface{}) {
+		executed <- workerState
+	})
+	assert.Equal(t, "session", <-executed)
+	assert.Len(t, initErrors, 2)
+	close(doneUsing)
+
+	pm.Dispose()
+}
@@ -0,0 +1,103 @@
+package pool
+
+import (
+	"io"
+	"sync"
+)
+
+// PoolHooks are callbacks for the lifecycle of a single pool wrapped with WrapPool. Any of them may be nil.
+type PoolHooks struct {
+	// OnConfigured is called once a manager has configured the pool for key, before handing it out for the first time
+	OnConfigured func(key string)
+	// OnReserve is called each time the pool is checked out
+	OnReserve func()
+	// OnRelease is called each time a checkout of the pool is released
+	OnRelease func()
+	// OnDispose is called once, after the pool has been disposed and its workers have stopped, so that resources its
+	// tasks use can be released
+	OnDispose func()
+}
+
+// WrapPool returns base with hooks attached to its lifecycle, for custom pools and WithFactoryMiddleware to build on.
+// Overriding the lifecycle of an embedded WorkerPool by hand is easy to get subtly wrong - a Dispose which doesn't
+// dispose the embedded pool leaks its workers, for one - whereas WrapPool always forwards to base first, so reserve,
+// release and disposal behave as they would without it. Pools implementing ErrorDisposer, io.Closer or ExtendedPool
+// keep doing so.
+func WrapPool(base WorkerPool, hooks PoolHooks) WorkerPool {
+	return &wrappedPool{WorkerPool: base, hooks: hooks, disposed: &sync.Once{}}
+}
+
+// wrappedPool is a pool built by WrapPool
+type wrappedPool struct {
+	WorkerPool
+	hooks    PoolHooks
+	disposed *sync.Once
+}
+
+func (p *wrappedPool) configure(key string, o *options) {
+	p.WorkerPool.configure(key, o)
+	if p.hooks.OnConfigured != nil {
+		p.hooks.OnConfigured(key)
+	}
+}
+
+func (p *wrappedPool) reserve() bool {
+	if !p.WorkerPool.reserve() {
+		return false
+	}
+	if p.hooks.OnReserve != nil {
+		p.hooks.OnReserve()
+	}
+	return true
+}
+
+func (p *wrappedPool) release() {
+	p.WorkerPool.release()
+	if p.hooks.OnRelease != nil {
+		p.hooks.OnRelease()
+	}
+}
+
+func (p *wrappedPool) Dispose() {
+	_ = p.DisposeE()
+}
+
+// DisposeE disposes base, with its own DisposeE if it implements ErrorDisposer
+func (p *wrappedPool) DisposeE() error {
+	var err error
+	if disposer, ok := p.WorkerPool.(ErrorDisposer); ok {
+		err = disposer.DisposeE()
+	} else {
+		p.WorkerPool.Dispose()
+	}
+	if p.hooks.OnDispose != nil {
+		p.disposed.Do(func() {
+			p.waitForWorkers()
+			p.hooks.OnDispose()
+		})
+	}
+	return err
+}
+
+// PoolConfigured forwards to base, if it implements ExtendedPool
+func (p *wrappedPool) PoolConfigured(key string) {
+	poolConfigured(p.WorkerPool, key)
+}
+
+// PoolReserved forwards to base, if it implements ExtendedPool
+func (p *wrappedPool) PoolReserved() {
+	poolReserved(p.WorkerPool)
+}
+
+// PoolReleased forwards to base, if it implements ExtendedPool
+func (p *wrappedPool) PoolReleased() {
+	poolReleased(p.WorkerPool)
+}
+
+// Close closes base, if it implements io.Closer
+func (p *wrappedPool) Close() error {
+	if closer, ok := p.WorkerPool.(io.Closer); ok {
+		return closer.Close()
+	}
+	return nil
+}
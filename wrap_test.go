@@ -0,0 +1,62 @@
+package pool
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/goleak"
+)
+
+func TestWrapPoolForwardsItsLifecycle(t *testing.T) {
+	defer goleak.VerifyNone(t)
+
+	var reserved, released int32
+	var configured string
+	disposed := make(chan bool)
+	evictions := make(chan PoolEviction, 1)
+	var executed int32
+	pm := NewWorkerPoolManager(1, time.Hour, time.Hour,
+		WithFactoryMiddleware(func(next Factory) Factory {
+			return func(maxSize int) (WorkerPool, error) {
+				base, err := next(maxSize)
+				return WrapPool(base, PoolHooks{
+					OnConfigured: func(key string) { configured = key },
+					OnReserve:    func() { atomic.AddInt32(&reserved, 1) },
+					OnRelease:    func() { atomic.AddInt32(&released, 1) },
+					OnDispose: func() {
+						// The pool's tasks have finished by now
+						assert.Equal(t, int32(1), atomic.LoadInt32(&executed))
+						close(disposed)
+					},
+				}), err
+			}
+		}),
+		WithHooks(Hooks{
+			OnPoolEvicted: func(eviction PoolEviction) {
+				evictions <- eviction
+			},
+		}),
+	)
+	pool, doneUsing, _ := pm.GetPoolWithFactory("key", 1, func(maxSize int) (WorkerPool, error) {
+		basePool, _ := NewWorkerPool(maxSize)
+		return &failingDisposePool{WorkerPool: basePool}, nil
+	})
+	pool.Submit(func() {
+		time.Sleep(5 * time.Millisecond)
+		atomic.AddInt32(&executed, 1)
+	})
+	close(doneUsing)
+	assert.Equal(t, "key", configured)
+	assert.Equal(t, int32(1), atomic.LoadInt32(&reserved))
+	assert.Eventually(t, func() bool {
+		return atomic.LoadInt32(&released) == 1
+	}, time.Second, time.Millisecond)
+	assert.Equal(t, 0, pm.Snapshot()["key"].Reservations)
+
+	pm.Dispose()
+	<-disposed
+	// The wrapped pool's DisposeE is still used
+	assert.EqualError(t, (<-evictions).Err, "couldn't close client")
+}